@@ -0,0 +1,391 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/constants"
+	"bytetrade.io/web3os/tapr/pkg/mongo"
+	"bytetrade.io/web3os/tapr/pkg/mysql"
+	"bytetrade.io/web3os/tapr/pkg/workload/mongodb"
+
+	kbappsv1 "github.com/apecloud/kubeblocks/apis/apps/v1"
+	elastic "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/gofiber/fiber/v2"
+	rabbithole "github.com/michaelklishin/rabbit-hole/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// AdminPasswordRotatedAnnotation is set on the middleware requests to re-propagate the credentials
+	// and the proxy configs after the admin password of the middleware is changed
+	AdminPasswordRotatedAnnotation = "apr.bytetrade.io/admin-password-rotated-at"
+
+	restartedAtAnnotation = "apr.bytetrade.io/restarted-at"
+
+	natsSecretName  = "nats-secrets"
+	natsPasswordKey = "nats_password"
+	natsName        = "nats"
+)
+
+// kbAdminAccount is the admin account of the middleware cluster managed by kubeblocks
+type kbAdminAccount struct {
+	component string
+	account   string
+}
+
+var kbAdminAccounts = map[aprv1.MiddlewareType]kbAdminAccount{
+	aprv1.TypeMongoDB:       {"mongodb", "root"},
+	aprv1.TypeMinio:         {"minio", "root"},
+	aprv1.TypeRabbitMQ:      {"rabbitmq", "root"},
+	aprv1.TypeElasticsearch: {"mdit", "elastic"},
+	aprv1.TypeMariaDB:       {"mariadb", "root"},
+	aprv1.TypeMysql:         {"mysql", "root"},
+}
+
+func (a *kbAdminAccount) secretName(cluster string) string {
+	return fmt.Sprintf("%s-%s-account-%s", cluster, a.component, a.account)
+}
+
+func (s *Server) getKBCluster(ctx context.Context, namespace, name string) (*kbappsv1.Cluster, error) {
+	var cluster kbappsv1.Cluster
+	err := s.ctrlClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &cluster)
+	if err != nil {
+		klog.Error("get cluster error, ", err, ", ", namespace, "/", name)
+		return nil, err
+	}
+
+	return &cluster, nil
+}
+
+// scaleKBCluster sets the replicas of the first component of the kubeblocks cluster
+func (s *Server) scaleKBCluster(ctx context.Context, namespace, name string, nodes int32) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := s.getKBCluster(ctx, namespace, name)
+		if err != nil {
+			return err
+		}
+
+		if len(cluster.Spec.ComponentSpecs) == 0 {
+			return fmt.Errorf("cluster %s/%s has no components", namespace, name)
+		}
+
+		cluster.Spec.ComponentSpecs[0].Replicas = nodes
+		return s.ctrlClient.Update(ctx, cluster)
+	})
+}
+
+// restartKBCluster rolls the pods of the components of the kubeblocks cluster
+func (s *Server) restartKBCluster(ctx context.Context, namespace, name string) error {
+	restartedAt := time.Now().Format(time.RFC3339)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := s.getKBCluster(ctx, namespace, name)
+		if err != nil {
+			return err
+		}
+
+		for i := range cluster.Spec.ComponentSpecs {
+			comp := &cluster.Spec.ComponentSpecs[i]
+			if comp.Annotations == nil {
+				comp.Annotations = make(map[string]string)
+			}
+			comp.Annotations[restartedAtAnnotation] = restartedAt
+		}
+
+		return s.ctrlClient.Update(ctx, cluster)
+	})
+}
+
+// changeKBAdminPassword changes the password of the admin account in the middleware with the current password,
+// and then saves the new password to the account secret of the cluster
+func (s *Server) changeKBAdminPassword(ctx context.Context, middleware aprv1.MiddlewareType, namespace, name, user, password string) error {
+	account, ok := kbAdminAccounts[middleware]
+	if !ok {
+		return fiber.ErrNotImplemented
+	}
+
+	secretName := account.secretName(name)
+	secret, err := s.k8sClientSet.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		klog.Error("get admin account secret error, ", err, ", ", namespace, "/", secretName)
+		return err
+	}
+
+	adminUser, adminPassword := string(secret.Data["username"]), string(secret.Data["password"])
+	if user != "" && user != adminUser {
+		return fiber.NewError(fiber.StatusBadRequest, "changing the admin user is not supported")
+	}
+
+	tlsConfig, err := s.kbClusterTLSConfig(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	switch middleware {
+	case aprv1.TypeMongoDB:
+		client := &mongo.MongoClient{
+			User:      adminUser,
+			Password:  adminPassword,
+			Addr:      fmt.Sprintf("%s-%s-headless.%s:27017", name, account.component, namespace),
+			TLSConfig: tlsConfig,
+		}
+		if err = client.Connect(ctx); err != nil {
+			klog.Error("connect mongodb error, ", err)
+			return err
+		}
+		defer client.Close(ctx)
+
+		err = client.ChangePassword(ctx, adminUser, "admin", password)
+
+	case aprv1.TypeMariaDB, aprv1.TypeMysql:
+		host := fmt.Sprintf("%s-%s-headless.%s.svc.cluster.local:3306", name, account.component, namespace)
+		client, err := mysql.NewClientBuilder(adminUser, adminPassword, host).WithTLSConfig(tlsConfig).Build()
+		if err != nil {
+			klog.Errorf("failed to connect to %s %v", host, err)
+			return err
+		}
+		defer client.Close()
+
+		err = client.ChangePassword(ctx, adminUser, password)
+		if err != nil {
+			return err
+		}
+
+	case aprv1.TypeRabbitMQ:
+		endpoint := fmt.Sprintf("http://%s-%s.%s:15672", name, account.component, namespace)
+		client, err := rabbithole.NewClient(endpoint, adminUser, adminPassword)
+		if err != nil {
+			klog.Errorf("failed to new rabbitmq client %v", err)
+			return err
+		}
+
+		u, err := client.GetUser(adminUser)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.PutUser(adminUser, rabbithole.UserSettings{Password: password, Tags: u.Tags})
+		if err != nil {
+			return err
+		}
+
+	case aprv1.TypeElasticsearch:
+		// the certificate is self-signed by kubeblocks before the cluster serves the certificates of the operator
+		if tlsConfig == nil {
+			tlsConfig, err = s.kbGeneratedTLSConfig(ctx, namespace, name, account.component)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = changeESPassword(ctx, fmt.Sprintf("https://%s-%s-http.%s:9200", name, account.component, namespace),
+			adminUser, adminPassword, password, tlsConfig)
+
+	case aprv1.TypeMinio:
+		// the root credentials of minio are loaded from the secret at startup
+	}
+
+	if err != nil {
+		klog.Error("change admin password error, ", err, ", ", middleware, ", ", namespace, "/", name)
+		return err
+	}
+
+	if err = s.updateSecretKey(ctx, namespace, secretName, "password", password); err != nil {
+		return err
+	}
+
+	if middleware == aprv1.TypeMinio {
+		return s.restartKBCluster(ctx, namespace, name)
+	}
+
+	return nil
+}
+
+func changeESPassword(ctx context.Context, endpoint, user, password, newPassword string, tlsConfig *tls.Config) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	es, err := elastic.NewClient(elastic.Config{
+		Addresses: []string{endpoint},
+		Username:  user,
+		Password:  password,
+		Transport: transport,
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]string{"password": newPassword})
+	if err != nil {
+		return err
+	}
+
+	res, err := esapi.SecurityChangePasswordRequest{Username: user, Body: bytes.NewReader(body)}.Do(ctx, es)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("change password failed: %s", res.String())
+	}
+
+	return nil
+}
+
+// changePerconaMongoAdminPassword saves the new password of the database admin to the users secret of
+// the legacy percona cluster, the percona operator changes the password of the user in mongodb
+func (s *Server) changePerconaMongoAdminPassword(ctx context.Context, namespace, name, user, password string) error {
+	psmdb, err := s.dynamicClient.Resource(mongodb.PSMDBClassGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.Error("get percona mongodb cluster error, ", err, ", ", namespace, "/", name)
+		return err
+	}
+
+	secretName, _, _ := unstructured.NestedString(psmdb.Object, "spec", "secrets", "users")
+	if secretName == "" {
+		secretName = mongodb.PSMDB_SECRET
+	}
+
+	secret, err := s.k8sClientSet.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		klog.Error("get percona users secret error, ", err, ", ", namespace, "/", secretName)
+		return err
+	}
+
+	if user != "" && user != string(secret.Data[mongodb.PSMDB_ADMIN_KEY]) {
+		return fiber.NewError(fiber.StatusBadRequest, "changing the admin user is not supported")
+	}
+
+	return s.updateSecretKey(ctx, namespace, secretName, mongodb.PSMDB_ADMIN_PASSWORD_KEY, password)
+}
+
+// changeKVRocksPassword updates the password of the kvrocks cluster, the redix cluster controller
+// rolls out kvrocks with the new password
+func (s *Server) changeKVRocksPassword(ctx context.Context, namespace, name, password string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := s.aprClientSet.AprV1alpha1().RedixClusters(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			klog.Error("get redix cluster error, ", err, ", ", namespace, "/", name)
+			return err
+		}
+
+		if cluster.Spec.Type != aprv1.KVRocks || cluster.Spec.KVRocks == nil {
+			return fiber.ErrNotImplemented
+		}
+
+		// keep the password in the referenced secret, the other components read the password from it
+		if ref := cluster.Spec.KVRocks.Password.ValueFrom; ref != nil && ref.SecretKeyRef != nil {
+			err = s.updateSecretKey(ctx, namespace, ref.SecretKeyRef.Name, ref.SecretKeyRef.Key, password)
+			if err != nil {
+				return err
+			}
+		} else {
+			cluster.Spec.KVRocks.Password.Value = password
+		}
+
+		if cluster.Annotations == nil {
+			cluster.Annotations = make(map[string]string)
+		}
+		cluster.Annotations[AdminPasswordRotatedAnnotation] = time.Now().Format(time.RFC3339)
+
+		_, err = s.aprClientSet.AprV1alpha1().RedixClusters(namespace).Update(ctx, cluster, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// changeNatsPassword updates the admin password of nats, nats loads the password from the env at startup
+func (s *Server) changeNatsPassword(ctx context.Context, password string) error {
+	err := s.updateSecretKey(ctx, constants.PlatformNamespace, natsSecretName, natsPasswordKey, password)
+	if err != nil {
+		return err
+	}
+
+	return s.restartStatefulSet(ctx, constants.PlatformNamespace, natsName)
+}
+
+func (s *Server) updateSecretKey(ctx context.Context, namespace, name, key, value string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := s.k8sClientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			klog.Error("get secret error, ", err, ", ", namespace, "/", name)
+			return err
+		}
+
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		secret.Data[key] = []byte(value)
+
+		_, err = s.k8sClientSet.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (s *Server) restartStatefulSet(ctx context.Context, namespace, name string) error {
+	restartedAt := time.Now().Format(time.RFC3339)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		sts, err := s.k8sClientSet.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			klog.Error("get statefulset error, ", err, ", ", namespace, "/", name)
+			return err
+		}
+
+		if sts.Spec.Template.Annotations == nil {
+			sts.Spec.Template.Annotations = make(map[string]string)
+		}
+		sts.Spec.Template.Annotations[restartedAtAnnotation] = restartedAt
+
+		_, err = s.k8sClientSet.AppsV1().StatefulSets(namespace).Update(ctx, sts, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// repropagateRequests annotates the requests of the middleware, so that the operator re-applies
+// the app credentials and the proxy configs with the new admin password
+func (s *Server) repropagateRequests(ctx context.Context, middleware aprv1.MiddlewareType) error {
+	requests, err := s.aprClientSet.AprV1alpha1().MiddlewareRequests(corev1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Error("list middleware requests error, ", err)
+		return err
+	}
+
+	rotatedAt := time.Now().Format(time.RFC3339)
+	for _, r := range requests.Items {
+		if r.Spec.Middleware != middleware {
+			continue
+		}
+
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			req, err := s.aprClientSet.AprV1alpha1().MiddlewareRequests(r.Namespace).Get(ctx, r.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if req.Annotations == nil {
+				req.Annotations = make(map[string]string)
+			}
+			req.Annotations[AdminPasswordRotatedAnnotation] = rotatedAt
+
+			_, err = s.aprClientSet.AprV1alpha1().MiddlewareRequests(r.Namespace).Update(ctx, req, metav1.UpdateOptions{})
+			return err
+		})
+		if err != nil {
+			klog.Error("annotate middleware request error, ", err, ", ", r.Namespace, "/", r.Name)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -584,7 +584,7 @@ func (s *Server) handleListMiddlewaresAll(ctx *fiber.Ctx) error {
 
 	// Elasticsearch
 	klog.Info("list elasticsearch cluster crd")
-	if elss, err := elasticsearch.ListRabbitMQClusters(ctx.UserContext(), s.ctrlClient, ""); err == nil {
+	if elss, err := elasticsearch.ListElasticsearchClusters(ctx.UserContext(), s.ctrlClient, ""); err == nil {
 		for _, m := range elss {
 			user, pwd, err := elasticsearch.FindElasticsearchAdminUser(ctx.UserContext(), s.k8sClientSet, m.Namespace)
 			if err != nil {
@@ -704,17 +704,50 @@ func (s *Server) handleScaleMiddleware(ctx *fiber.Ctx) error {
 		return err
 	}
 
+	if scaleReq.Nodes < 1 {
+		klog.Error("invalid nodes, ", scaleReq.Nodes)
+		return fiber.ErrNotAcceptable
+	}
+
 	switch scaleReq.Middleware {
 	case aprv1.TypeMongoDB:
-		err = mongodb.ScalePerconaMongoNodes(ctx.UserContext(), s.dynamicClient, scaleReq.Name, scaleReq.Namespace, scaleReq.Nodes)
+		// the mongodb managed by kubeblocks, or the legacy percona cluster
+		if _, err = s.getKBCluster(ctx.UserContext(), scaleReq.Namespace, scaleReq.Name); err == nil {
+			err = s.scaleKBCluster(ctx.UserContext(), scaleReq.Namespace, scaleReq.Name, scaleReq.Nodes)
+		} else if apierrors.IsNotFound(err) {
+			err = mongodb.ScalePerconaMongoNodes(ctx.UserContext(), s.dynamicClient, scaleReq.Name, scaleReq.Namespace, scaleReq.Nodes)
+		}
 		if err != nil {
 			return err
 		}
 	case aprv1.TypeRedis:
+		cluster, err := s.aprClientSet.AprV1alpha1().RedixClusters(scaleReq.Namespace).Get(ctx.UserContext(), scaleReq.Name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Error("get redix cluster error, ", err)
+			return err
+		}
+
+		if err == nil && cluster.Spec.Type == aprv1.KVRocks {
+			if scaleReq.Nodes != 1 {
+				return fiber.NewError(fiber.StatusBadRequest, "kvrocks runs in a single node")
+			}
+			break
+		}
+
 		err = rediscluster.ScaleRedisClusterNodes(ctx.UserContext(), s.dynamicClient, scaleReq.Name, scaleReq.Namespace, scaleReq.Nodes)
 		if err != nil {
 			return err
 		}
+	case aprv1.TypeNats:
+		if scaleReq.Nodes != 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "nats runs in a single node")
+		}
+	case aprv1.TypeMinio, aprv1.TypeRabbitMQ, aprv1.TypeElasticsearch, aprv1.TypeMariaDB, aprv1.TypeMysql:
+		err = s.scaleKBCluster(ctx.UserContext(), scaleReq.Namespace, scaleReq.Name, scaleReq.Nodes)
+		if err != nil {
+			klog.Error("scale cluster error, ", err, ", ", scaleReq.Middleware)
+			return err
+		}
 	case aprv1.TypePostgreSQL:
 		pgc, err := s.aprClientSet.AprV1alpha1().PGClusters(scaleReq.Namespace).Get(ctx.UserContext(), scaleReq.Name, metav1.GetOptions{})
 		if err != nil {
@@ -779,10 +812,51 @@ func (s *Server) handleUpdateMiddlewareAdminPassword(ctx *fiber.Ctx) error {
 			return err
 		}
 
+	case aprv1.TypeRedis:
+		err = s.changeKVRocksPassword(ctx.UserContext(), changePwdReq.Namespace, changePwdReq.Name, changePwdReq.Password)
+		if err != nil {
+			klog.Error("update kvrocks password error, ", err, ", ", changePwdReq.Name, ", ", changePwdReq.Namespace)
+			return err
+		}
+
+	case aprv1.TypeNats:
+		if changePwdReq.User != "" && changePwdReq.User != "admin" {
+			return fiber.NewError(fiber.StatusBadRequest, "changing the admin user is not supported")
+		}
+
+		if err = s.changeNatsPassword(ctx.UserContext(), changePwdReq.Password); err != nil {
+			klog.Error("update nats password error, ", err)
+			return err
+		}
+
+	case aprv1.TypeMongoDB:
+		// the mongodb managed by kubeblocks, or the legacy percona cluster
+		if _, err = s.getKBCluster(ctx.UserContext(), changePwdReq.Namespace, changePwdReq.Name); err == nil {
+			err = s.changeKBAdminPassword(ctx.UserContext(), changePwdReq.Middleware,
+				changePwdReq.Namespace, changePwdReq.Name, changePwdReq.User, changePwdReq.Password)
+		} else if apierrors.IsNotFound(err) {
+			err = s.changePerconaMongoAdminPassword(ctx.UserContext(),
+				changePwdReq.Namespace, changePwdReq.Name, changePwdReq.User, changePwdReq.Password)
+		}
+		if err != nil {
+			return err
+		}
+
+	case aprv1.TypeMinio, aprv1.TypeRabbitMQ, aprv1.TypeElasticsearch, aprv1.TypeMariaDB, aprv1.TypeMysql:
+		err = s.changeKBAdminPassword(ctx.UserContext(), changePwdReq.Middleware,
+			changePwdReq.Namespace, changePwdReq.Name, changePwdReq.User, changePwdReq.Password)
+		if err != nil {
+			return err
+		}
+
 	default:
 		return fiber.ErrNotImplemented
 	}
 
+	if err = s.repropagateRequests(ctx.UserContext(), changePwdReq.Middleware); err != nil {
+		return err
+	}
+
 	return ctx.JSON(fiber.Map{
 		"code":    fiber.StatusOK,
 		"message": "update success",
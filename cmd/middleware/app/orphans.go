@@ -0,0 +1,63 @@
+package app
+
+import (
+	"time"
+
+	"bytetrade.io/web3os/tapr/pkg/constants"
+	"bytetrade.io/web3os/tapr/pkg/orphans"
+
+	"github.com/gofiber/fiber/v2"
+	"k8s.io/klog/v2"
+)
+
+func (s *Server) handleListOrphans(ctx *fiber.Ctx) error {
+	list, quarantine, err := orphans.Load(ctx.UserContext(), s.k8sClientSet, constants.PlatformNamespace)
+	if err != nil {
+		klog.Error("load orphans error, ", err)
+		return err
+	}
+
+	resp := make([]*OrphanResp, 0, len(list))
+	for _, o := range list {
+		resp = append(resp, &OrphanResp{
+			Key:            o.Key(),
+			Orphan:         o,
+			QuarantineEnds: o.FirstSeen.Add(quarantine).Format(time.RFC3339),
+		})
+	}
+
+	return ctx.JSON(map[string]interface{}{
+		"code": fiber.StatusOK,
+		"data": resp,
+	})
+}
+
+func (s *Server) handleCleanupOrphans(ctx *fiber.Ctx) error {
+	cleanupReq := OrphanCleanupReq{}
+	err := ctx.BodyParser(&cleanupReq)
+	if err != nil {
+		klog.Error("parse request body error, ", err, ", ", string(ctx.Body()))
+		return err
+	}
+
+	if len(cleanupReq.Keys) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "keys of the orphans are required")
+	}
+
+	changed := 0
+	err = orphans.Update(ctx.UserContext(), s.k8sClientSet, constants.PlatformNamespace,
+		func(list []orphans.Orphan, _ time.Duration) []orphans.Orphan {
+			changed = orphans.Approve(list, cleanupReq.Keys, cleanupReq.Cleanup)
+			return list
+		})
+	if err != nil {
+		klog.Error("update orphans error, ", err)
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"code":    fiber.StatusOK,
+		"message": "update success",
+		"data":    changed,
+	})
+}
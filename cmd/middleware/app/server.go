@@ -2,24 +2,33 @@ package app
 
 import (
 	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
 
 	"bytetrade.io/web3os/tapr/pkg/app/middleware"
 	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
 	"bytetrade.io/web3os/tapr/pkg/generated/listers/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/usage"
 
 	kbappsv1 "github.com/apecloud/kubeblocks/apis/apps/v1"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// shutdownDrainDelay is the time for the endpoints to remove the replica being not ready before the server stops
+const shutdownDrainDelay = 5 * time.Second
+
 var rscheme = runtime.NewScheme()
 
 func init() {
@@ -37,7 +46,15 @@ type Server struct {
 	MrLister      v1alpha1.MiddlewareRequestLister
 	PgLister      v1alpha1.PGClusterLister
 	RedixLister   v1alpha1.RedixClusterLister
+	Usage         *usage.Store
 	ctrlClient    client.Client
+
+	// MetricsAddr is the address the metrics are served on, apart from the api port exposed to the apps
+	MetricsAddr string
+
+	// Synced reports whether the caches of the listers are synced, the server is not ready until then
+	Synced       []cache.InformerSynced
+	shuttingDown atomic.Bool
 }
 
 func (s *Server) ServerRun() {
@@ -57,6 +74,9 @@ func (s *Server) ServerRun() {
 	// middleware to allow all clients to communicate using http and allow cors
 	app.Use(cors.New())
 
+	app.Get("/healthz", func(ctx *fiber.Ctx) error { return ctx.SendString("ok") })
+	app.Get("/readyz", s.handleReady)
+
 	app.Post("/middleware/v1/request/info", middleware.GetUserInfo(s.KubeConfig, s.handleGetMiddlewareRequestInfo))
 	app.Get("/middleware/v1/requests", middleware.GetUserInfo(s.KubeConfig,
 		middleware.RequireAdmin(s.KubeConfig, s.handleListMiddlewareRequests)))
@@ -70,13 +90,58 @@ func (s *Server) ServerRun() {
 	app.Post("/middleware/v1/:middleware/password", middleware.GetUserInfo(s.KubeConfig,
 		middleware.RequireAdmin(s.KubeConfig, s.handleUpdateMiddlewareAdminPassword)))
 
+	app.Get("/middleware/v1/orphans", middleware.GetUserInfo(s.KubeConfig,
+		middleware.RequireAdmin(s.KubeConfig, s.handleListOrphans)))
+	app.Post("/middleware/v1/orphans/cleanup", middleware.GetUserInfo(s.KubeConfig,
+		middleware.RequireAdmin(s.KubeConfig, s.handleCleanupOrphans)))
+
+	app.Get("/middleware/v1/usage", middleware.GetUserInfo(s.KubeConfig,
+		middleware.RequireAdmin(s.KubeConfig, s.handleListUsage)))
+	app.Get("/middleware/v1/:middleware/usage", middleware.GetUserInfo(s.KubeConfig,
+		middleware.RequireAdmin(s.KubeConfig, s.handleListMiddlewareUsage)))
+
 	s.app = app
+	go s.serveMetrics()
+
 	err = app.Listen(":9080")
 	if err != nil {
 		klog.Fatal(err)
 	}
 }
 
+// serveMetrics serves the usage of the apps on the metrics address, the api port does not expose them
+func (s *Server) serveMetrics() {
+	if s.MetricsAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if err := http.ListenAndServe(s.MetricsAddr, mux); err != nil {
+		klog.Error("serve metrics error, ", err)
+	}
+}
+
+// handleReady reports ready once the listers are synced, and not ready as soon as the server is shutting down
+// to move the traffic to the other replicas
+func (s *Server) handleReady(ctx *fiber.Ctx) error {
+	if s.shuttingDown.Load() {
+		return ctx.Status(fiber.StatusServiceUnavailable).SendString("shutting down")
+	}
+
+	for _, synced := range s.Synced {
+		if !synced() {
+			return ctx.Status(fiber.StatusServiceUnavailable).SendString("caches not synced")
+		}
+	}
+
+	return ctx.SendString("ok")
+}
+
 func (s *Server) Shutdown() {
+	s.shuttingDown.Store(true)
+	time.Sleep(shutdownDrainDelay)
+
+	// wait for the requests in flight to complete
 	s.app.Shutdown()
 }
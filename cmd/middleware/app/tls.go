@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"bytetrade.io/web3os/tapr/pkg/certs"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+	"bytetrade.io/web3os/tapr/pkg/workload/kvrocks"
+	wutils "bytetrade.io/web3os/tapr/pkg/workload/utils"
+
+	kbappsv1 "github.com/apecloud/kubeblocks/apis/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// getTLSInfo returns the ca bundle of the certificates, or nil if the certificates are not issued
+func (s *Server) getTLSInfo(ctx context.Context, namespace, certName, uri string) (*TLSInfo, error) {
+	bundle, err := certs.GetCABundle(ctx, s.k8sClientSet, namespace, certName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		klog.Error("get ca bundle error, ", err, ", ", namespace, "/", certName)
+		return nil, err
+	}
+
+	return &TLSInfo{CABundle: string(bundle), URI: uri}, nil
+}
+
+// getPGTLSInfo returns the tls info after all nodes of the cluster are rolled out with ssl
+func (s *Server) getPGTLSInfo(ctx context.Context, uri string) (*TLSInfo, error) {
+	sts, err := s.k8sClientSet.AppsV1().StatefulSets(citus.PGClusterNamespace).Get(ctx, citus.PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		klog.Error("get pg cluster statefulset error, ", err)
+		return nil, err
+	}
+
+	if !citus.IsPGClusterTLSReady(sts) {
+		return nil, nil
+	}
+
+	return s.getTLSInfo(ctx, citus.PGClusterNamespace, citus.PGClusterCertName, uri)
+}
+
+// getKVRocksTLSInfo returns the tls info after kvrocks is rolled out with tls
+func (s *Server) getKVRocksTLSInfo(ctx context.Context, namespace, uri string) (*TLSInfo, error) {
+	sts, err := s.k8sClientSet.AppsV1().StatefulSets(namespace).Get(ctx, kvrocks.DefaultKVRocksName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		klog.Error("get kvrocks statefulset error, ", err)
+		return nil, err
+	}
+
+	if !kvrocks.IsKVRocksTLSReady(sts) {
+		return nil, nil
+	}
+
+	return s.getTLSInfo(ctx, namespace, kvrocks.KVRocksCertName, uri)
+}
+
+// getKBClusterTLSInfo returns the tls info after the kubeblocks cluster serves the certificates
+func (s *Server) getKBClusterTLSInfo(ctx context.Context, namespace, name, uri string) (*TLSInfo, error) {
+	var cluster kbappsv1.Cluster
+	err := s.ctrlClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &cluster)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		klog.Error("get cluster error, ", err, ", ", namespace, "/", name)
+		return nil, err
+	}
+
+	if !wutils.IsKBClusterTLSEnabled(&cluster) || cluster.Status.Phase != kbappsv1.RunningClusterPhase {
+		return nil, nil
+	}
+
+	return s.getTLSInfo(ctx, namespace, name, uri)
+}
+
+// kbClusterTLSConfig returns the client tls config to the kubeblocks cluster, nil if the cluster does not serve tls
+func (s *Server) kbClusterTLSConfig(ctx context.Context, namespace, name string) (*tls.Config, error) {
+	info, err := s.getKBClusterTLSInfo(ctx, namespace, name, "")
+	if err != nil || info == nil {
+		return nil, err
+	}
+
+	return certs.NewClientTLSConfig([]byte(info.CABundle))
+}
+
+// kbGeneratedTLSConfig returns the client tls config with the ca of the certificates generated by kubeblocks
+// for the component of the cluster
+func (s *Server) kbGeneratedTLSConfig(ctx context.Context, namespace, name, component string) (*tls.Config, error) {
+	secretName := fmt.Sprintf("%s-%s-tls-certs", name, component)
+	secret, err := s.k8sClientSet.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		klog.Error("get kubeblocks tls secret error, ", err, ", ", namespace, "/", secretName)
+		return nil, err
+	}
+
+	return certs.NewClientTLSConfig(secret.Data["ca.crt"])
+}
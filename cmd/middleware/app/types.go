@@ -1,6 +1,9 @@
 package app
 
-import aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+import (
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/orphans"
+)
 
 // MiddlewareType represents the type of middleware.
 type MiddlewareType string
@@ -84,8 +87,11 @@ type MiddlewareRequestResp struct {
 	Vhosts       map[string]string `json:"vhosts"`
 	Subjects     map[string]string `json:"subjects"`
 	Refs         map[string]string `json:"refs"`
+	Pooler       *Proxy            `json:"pooler,omitempty"`
+	ReadOnlyHost string            `json:"readOnlyHost,omitempty"`
 	BucketPrefix string            `json:"bucketPrefix,omitempty"`
 	IndexPrefix  string            `json:"indexPrefix,omitempty"`
+	TLS          *TLSInfo          `json:"tls,omitempty"`
 }
 
 type Proxy struct {
@@ -93,6 +99,12 @@ type Proxy struct {
 	Size     int32  `json:"size"`
 }
 
+// TLSInfo is the ca bundle to verify the server, and the uri to connect with tls
+type TLSInfo struct {
+	CABundle string `json:"caBundle"`
+	URI      string `json:"uri"`
+}
+
 type MiddlewareClusterResp struct {
 	MetaInfo
 	Nodes          int32          `json:"nodes"`
@@ -117,3 +129,15 @@ type ClusterChangePwdReq struct {
 	User     string `json:"user,omitempty"`
 	Password string `json:"password"`
 }
+
+type OrphanResp struct {
+	orphans.Orphan
+	Key            string `json:"key"`
+	QuarantineEnds string `json:"quarantineEnds"`
+}
+
+// OrphanCleanupReq approves or revokes the cleanup of the orphans with the keys, the keys are required
+type OrphanCleanupReq struct {
+	Keys    []string `json:"keys"`
+	Cleanup bool     `json:"cleanup"`
+}
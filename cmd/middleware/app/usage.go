@@ -0,0 +1,31 @@
+package app
+
+import (
+	"bytetrade.io/web3os/tapr/pkg/usage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// usageFilter matches the usage with the app and namespace in the query
+func usageFilter(ctx *fiber.Ctx, middleware string) func(u *usage.RequestUsage) bool {
+	app, namespace := ctx.Query("app"), ctx.Query("namespace")
+	return func(u *usage.RequestUsage) bool {
+		return (middleware == "" || string(u.Middleware) == middleware) &&
+			(app == "" || u.App == app) &&
+			(namespace == "" || u.Namespace == namespace || u.AppNamespace == namespace)
+	}
+}
+
+func (s *Server) handleListUsage(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{
+		"code": fiber.StatusOK,
+		"data": s.Usage.List(usageFilter(ctx, "")),
+	})
+}
+
+func (s *Server) handleListMiddlewareUsage(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{
+		"code": fiber.StatusOK,
+		"data": s.Usage.List(usageFilter(ctx, ctx.Params("middleware"))),
+	})
+}
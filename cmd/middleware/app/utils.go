@@ -6,6 +6,7 @@ import (
 	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	"bytetrade.io/web3os/tapr/pkg/workload/citus"
 	"bytetrade.io/web3os/tapr/pkg/workload/elasticsearch"
+	"bytetrade.io/web3os/tapr/pkg/workload/kvrocks"
 	"bytetrade.io/web3os/tapr/pkg/workload/mariadb"
 	"bytetrade.io/web3os/tapr/pkg/workload/minio"
 	"bytetrade.io/web3os/tapr/pkg/workload/mongodb"
@@ -19,6 +20,9 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// the amqps port of rabbitmq when the cluster serves tls
+const rabbitMQTLSPort = 5671
+
 func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv1.MiddlewareRequest) (*MiddlewareRequestResp, error) {
 	resp := &MiddlewareRequestResp{}
 
@@ -72,6 +76,48 @@ func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv
 			resp.Databases[db.Name] = citus.GetDatabaseName(m.Spec.AppNamespace, db.Name)
 		}
 
+		resp.Refs = make(map[string]string)
+		ownerName := nats.GetOwnerNameFromNs(m.Namespace)
+		for _, ref := range m.Spec.PostgreSQL.Refs {
+			refAppNs := citus.GetRefAppNamespace(ref.AppNamespace, m.Spec.AppNamespace, ownerName)
+			for _, db := range ref.Databases {
+				resp.Refs[fmt.Sprintf("%s_%s", ref.AppName, db.Name)] = citus.GetDatabaseName(refAppNs, db.Name)
+			}
+		}
+
+		pgc, err := s.PgLister.PGClusters(citus.PGClusterNamespace).Get(citus.PGClusterName)
+		if err != nil {
+			klog.Warning("get pg cluster error, ", err)
+			pgc = nil
+		}
+
+		// the read-only queries can be served by the standby of the coordinator
+		if pgc != nil && pgc.Spec.Standby != nil && !citus.IsCoordinatorFailedOver(pgc) {
+			resp.ReadOnlyHost = citus.PGReadOnlyServiceName + "." + citus.PGClusterNamespace
+		}
+
+		// connect through the pooler if it is deployed
+		if pgc != nil && pgc.Spec.Pooler != nil {
+			size := citus.DefaultPGPoolSize
+			if pgc.Spec.Pooler.DefaultPoolSize > 0 {
+				size = pgc.Spec.Pooler.DefaultPoolSize
+			}
+			if m.Spec.PostgreSQL.Pool != nil && m.Spec.PostgreSQL.Pool.Size > 0 {
+				size = m.Spec.PostgreSQL.Pool.Size
+			}
+
+			resp.Pooler = &Proxy{
+				Endpoint: fmt.Sprintf("%s.%s:%d", citus.PGPoolerServiceName, citus.PGClusterNamespace, citus.PGPoolerPort),
+				Size:     size,
+			}
+		}
+
+		resp.TLS, err = s.getPGTLSInfo(ctx.UserContext(),
+			fmt.Sprintf("postgres://%s:%d/?sslmode=verify-full", resp.Host, resp.Port))
+		if err != nil {
+			return nil, err
+		}
+
 		return resp, nil
 
 	case aprv1.TypeMongoDB:
@@ -90,6 +136,12 @@ func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv
 			resp.Databases[db.Name] = mongodb.GetDatabaseName(m.Spec.AppNamespace, db.Name)
 		}
 
+		resp.TLS, err = s.getKBClusterTLSInfo(ctx.UserContext(), "mongodb-middleware", "mongodb",
+			fmt.Sprintf("mongodb://%s:%d/?tls=true", resp.Host, resp.Port))
+		if err != nil {
+			return nil, err
+		}
+
 		return resp, nil
 
 	case aprv1.TypeRedis:
@@ -117,6 +169,12 @@ func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv
 		resp.Host = rediscluster.RedisClusterService + "." + mwReq.Namespace
 		resp.Databases[m.Spec.Redis.Namespace] = rediscluster.GetDatabaseName(m.Spec.AppNamespace, m.Spec.Redis.Namespace)
 
+		resp.TLS, err = s.getKVRocksTLSInfo(ctx.UserContext(), mwReq.Namespace,
+			fmt.Sprintf("rediss://%s:%d", resp.Host, kvrocks.KVRocksTLSServicePort))
+		if err != nil {
+			return nil, err
+		}
+
 		return resp, nil
 	case aprv1.TypeZinc:
 		resp.UserName = m.Spec.Zinc.User
@@ -152,6 +210,12 @@ func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv
 			}
 		}
 		resp.Refs = appSubjectMap
+		resp.TLS, err = s.getTLSInfo(ctx.UserContext(), mwReq.Namespace, nats.NatsCertName,
+			fmt.Sprintf("tls://%s:%d", resp.Host, resp.Port))
+		if err != nil {
+			return nil, err
+		}
+
 		return resp, nil
 	case aprv1.TypeMinio:
 		resp.UserName = m.Spec.Minio.User
@@ -169,6 +233,12 @@ func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv
 		}
 		resp.BucketPrefix = m.Spec.AppNamespace
 
+		resp.TLS, err = s.getKBClusterTLSInfo(ctx.UserContext(), "minio-middleware", "minio",
+			fmt.Sprintf("https://%s:%d", resp.Host, resp.Port))
+		if err != nil {
+			return nil, err
+		}
+
 		return resp, nil
 	case aprv1.TypeRabbitMQ:
 		resp.UserName = m.Spec.RabbitMQ.User
@@ -185,6 +255,12 @@ func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv
 			resp.Vhosts[v.Name] = rabbitmq.GetVhostName(m.Spec.AppNamespace, v.Name)
 		}
 
+		resp.TLS, err = s.getKBClusterTLSInfo(ctx.UserContext(), "rabbitmq-middleware", "rabbitmq",
+			fmt.Sprintf("amqps://%s:%d", resp.Host, rabbitMQTLSPort))
+		if err != nil {
+			return nil, err
+		}
+
 		return resp, nil
 	case aprv1.TypeElasticsearch:
 		resp.UserName = m.Spec.Elasticsearch.User
@@ -202,6 +278,12 @@ func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv
 		}
 		resp.IndexPrefix = m.Spec.AppNamespace
 
+		resp.TLS, err = s.getKBClusterTLSInfo(ctx.UserContext(), "elasticsearch-middleware", "elasticsearch",
+			fmt.Sprintf("https://%s:%d", resp.Host, resp.Port))
+		if err != nil {
+			return nil, err
+		}
+
 		return resp, nil
 	case aprv1.TypeMariaDB:
 		resp.UserName = m.Spec.MariaDB.User
@@ -218,6 +300,12 @@ func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv
 			resp.Databases[v.Name] = mariadb.GetDatabaseName(m.Spec.AppNamespace, v.Name)
 		}
 
+		resp.TLS, err = s.getKBClusterTLSInfo(ctx.UserContext(), "mariadb-middleware", "mariadb",
+			fmt.Sprintf("mysql://%s:%d/?tls=true", resp.Host, resp.Port))
+		if err != nil {
+			return nil, err
+		}
+
 		return resp, nil
 	case aprv1.TypeMysql:
 		resp.UserName = m.Spec.Mysql.User
@@ -234,6 +322,12 @@ func (s *Server) getMiddlewareInfo(ctx *fiber.Ctx, mwReq *MiddlewareReq, m *aprv
 			resp.Databases[v.Name] = mariadb.GetDatabaseName(m.Spec.AppNamespace, v.Name)
 		}
 
+		resp.TLS, err = s.getKBClusterTLSInfo(ctx.UserContext(), "mysql-middleware", "mysql",
+			fmt.Sprintf("mysql://%s:%d/?tls=true", resp.Host, resp.Port))
+		if err != nil {
+			return nil, err
+		}
+
 		return resp, nil
 
 	} // end of middleware type
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+const (
+	leaseName          = "middleware-operator"
+	leaseDuration      = 15 * time.Second
+	leaseRenewDeadline = 10 * time.Second
+	leaseRetryPeriod   = 2 * time.Second
+)
+
+// runLeaderElection runs the controllers while holding the lease, and blocks until the context is done.
+// The lease is released on shutdown so another replica takes over immediately. Losing the lease otherwise
+// exits the process, the controllers can not be restarted in place
+func runLeaderElection(ctx context.Context, config *rest.Config, namespace string, run func(ctx context.Context)) {
+	identity, err := os.Hostname()
+	if err != nil {
+		klog.Fatal("get hostname error, ", err)
+	}
+	identity = identity + "_" + uuid.NewString()
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{Namespace: namespace, Name: leaseName},
+		Client:    kubernetes.NewForConfigOrDie(config).CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   leaseRenewDeadline,
+		RetryPeriod:     leaseRetryPeriod,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					klog.Info("leader lease released, ", identity)
+					return
+				}
+
+				klog.Fatal("leader lease lost, ", identity)
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					klog.Info("new leader elected, ", current)
+				}
+			},
+		},
+	})
+}
@@ -21,8 +21,12 @@ import (
 	"k8s.io/klog/v2"
 
 	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/constants"
+	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
+	informers "bytetrade.io/web3os/tapr/pkg/generated/informers/externalversions"
 	"bytetrade.io/web3os/tapr/pkg/signals"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -30,6 +34,10 @@ func main() {
 	if flag.CommandLine.Lookup("add_dir_header") == nil {
 		klog.InitFlags(nil)
 	}
+	leaderElect := pflag.Bool("leader-elect", false, "run the controllers only on the replica holding the lease, requires the rbac of coordination.k8s.io/leases")
+	leaderElectNamespace := pflag.String("leader-elect-namespace", constants.PlatformNamespace, "namespace of the lease")
+	metricsAddr := pflag.String("metrics-bind-address", ":9091", "address the metrics are served on, empty to disable")
+	workers := pflag.Int("workers", 4, "number of workers reconciling the middleware requests, kvrocks backups and restores")
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	pflag.Parse()
 
@@ -47,20 +55,33 @@ func main() {
 	pgBackupController := pgclusterbackup.NewController(config, apiCtx, pgclusterLister)
 	pgRestoreController := pgclusterrestore.NewController(config, apiCtx, pgclusterLister)
 
-	redixClusterController, redixLister := redixcluster.NewController(config, apiCtx, func(cluster *aprv1.RedixCluster) {})
+	redixClusterController, _ := redixcluster.NewController(config, apiCtx, func(cluster *aprv1.RedixCluster) {})
 	kvrocksBackupController := kvrocksbakcup.NewController(config, apiCtx)
 	kvrocksRestoreController := kvrocksrestore.NewController(config, apiCtx)
 
-	runControllers := func() {
+	// the api server runs on every replica, and lists from its own informers
+	apiInformerFactory := informers.NewSharedInformerFactory(aprclientset.NewForConfigOrDie(config), 0)
+	apiRequestInformer := apiInformerFactory.Apr().V1alpha1().MiddlewareRequests()
+	apiPGClusterInformer := apiInformerFactory.Apr().V1alpha1().PGClusters()
+	apiRedixInformer := apiInformerFactory.Apr().V1alpha1().RedixClusters()
+	apiSynced := []cache.InformerSynced{
+		apiRequestInformer.Informer().HasSynced,
+		apiPGClusterInformer.Informer().HasSynced,
+		apiRedixInformer.Informer().HasSynced,
+	}
+
+	runControllers := func(ctx context.Context) {
+		klog.Info("start running controllers")
 		go func() { utilruntime.Must(pgclusterController.Run(1)) }()
-		go func() { utilruntime.Must(requestController.Run(1)) }()
+		go func() { utilruntime.Must(requestController.Run(*workers)) }()
 		go func() { utilruntime.Must(pgBackupController.Run(1)) }()
 		go func() { utilruntime.Must(pgRestoreController.Run(1)) }()
 		go func() { utilruntime.Must(redixClusterController.Run(1)) }()
-		go func() { utilruntime.Must(kvrocksBackupController.Run(1)) }()
-		go func() { utilruntime.Must(kvrocksRestoreController.Run(1)) }()
+		go func() { utilruntime.Must(kvrocksBackupController.Run(*workers)) }()
+		go func() { utilruntime.Must(kvrocksRestoreController.Run(*workers)) }()
 		go func() { utilruntime.Must(configMapController.Run(1)) }()
 		// go func() { backupWatcher.Start() }()
+		<-ctx.Done()
 	}
 
 	cmd := &cobra.Command{
@@ -68,14 +89,29 @@ func main() {
 		Short: "middleware operator server",
 		Long:  `The middleware operator server provides the os middleware services`,
 		Run: func(cmd *cobra.Command, args []string) {
-			runControllers()
+			apiInformerFactory.Start(apiCtx.Done())
+			go requestController.RunUsage(apiRequestInformer.Lister(), apiRequestInformer.Informer().HasSynced)
+
+			electionDone := make(chan struct{})
+			go func() {
+				defer close(electionDone)
+				if !*leaderElect {
+					runControllers(apiCtx)
+					return
+				}
+
+				runLeaderElection(apiCtx, config, *leaderElectNamespace, runControllers)
+			}()
 
 			s := &app.Server{
 				Ctx:         apiCtx,
 				KubeConfig:  config,
-				MrLister:    requestLister,
-				PgLister:    pgclusterLister,
-				RedixLister: redixLister,
+				MrLister:    apiRequestInformer.Lister(),
+				PgLister:    apiPGClusterInformer.Lister(),
+				RedixLister: apiRedixInformer.Lister(),
+				Usage:       requestController.Usage(),
+				Synced:      apiSynced,
+				MetricsAddr: *metricsAddr,
 			}
 
 			go func() {
@@ -90,6 +126,9 @@ func main() {
 			s.ServerRun()
 			cancel()
 
+			// wait for the lease released
+			<-electionDone
+
 			klog.Info("middleware operator shutdown")
 		},
 	}
@@ -2,7 +2,6 @@ package backup
 
 import (
 	"bytetrade.io/web3os/tapr/pkg/workload/citus"
-	"bytetrade.io/web3os/tapr/pkg/workload/utils"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 )
@@ -17,38 +16,9 @@ func (w *Watcher) backupPostgres() error {
 	klog.Info("start to backup all users' pg clusters, of ", len(clusters.Items))
 	for _, cluster := range clusters.Items {
 		klog.Info("create crd to backup cluster, ", cluster.Name, ", ", cluster.Namespace)
-		backup := citus.ClusterBackup.DeepCopy()
-		backup.Namespace = cluster.Namespace
-
-		if cluster.Spec.BackupStorage != "" {
-			backup.Spec.VolumeSpec.HostPath.Path = cluster.Spec.BackupStorage
-		} else {
-			klog.Info("find cluster hostpath, ", cluster.Namespace)
-			var pvc string
-			if cluster.Spec.Owner == "system" {
-				pvcRes, err := w.k8sClientSet.CoreV1().PersistentVolumeClaims(cluster.Namespace).Get(w.ctx, "citus-data-pvc", metav1.GetOptions{})
-				if err != nil {
-					klog.Error("find pg pvc error, ", err)
-					return err
-				}
-
-				pvRes, err := w.k8sClientSet.CoreV1().PersistentVolumes().Get(w.ctx, pvcRes.Spec.VolumeName, metav1.GetOptions{})
-				if err != nil {
-					klog.Error("backup find pg pv error, ", err)
-					return err
-				}
-
-				pvc = pvRes.Spec.HostPath.Path
-
-			} else {
-				bflNamespace := "user-space-" + cluster.Spec.Owner
-				pvc, err = utils.GetUserDBPVCName(w.ctx, w.k8sClientSet, bflNamespace)
-				if err != nil {
-					return err
-				}
-			}
-
-			backup.Spec.VolumeSpec.HostPath.Path = pvc + "/pg_backup"
+		backup, err := citus.NewPGClusterBackup(w.ctx, w.k8sClientSet, &cluster, citus.PGClusterBackup)
+		if err != nil {
+			return err
 		}
 
 		err = citus.ForceCreateNewPGClusterBackup(w.ctx, w.aprClientSet, backup)
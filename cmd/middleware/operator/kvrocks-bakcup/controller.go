@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"time"
 
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
 	informers "bytetrade.io/web3os/tapr/pkg/generated/informers/externalversions"
 	"bytetrade.io/web3os/tapr/pkg/generated/listers/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/workload/kvrocks"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
@@ -189,6 +191,10 @@ func (c *controller) processNextWorkItem() bool {
 }
 
 func (c *controller) syncHandler(obj enqueueObj) error {
+	if o, ok := obj.obj.(*aprv1.KVRocksBackup); ok && o.Spec.ClusterName != "" {
+		unlock := kvrocks.LockCluster(o.Namespace, o.Spec.ClusterName)
+		defer unlock()
+	}
 
 	return c.handler(obj.action, obj.obj)
 }
@@ -0,0 +1,245 @@
+package middlewarerequest
+
+import (
+	"fmt"
+	"strings"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// AdoptAnnotation set to true maps the existing databases and users to the request instead of creating them,
+	// the adoption runs until the Adopted condition of the request is true
+	AdoptAnnotation = "apr.bytetrade.io/adopt"
+
+	reasonAdopted = "Adopted"
+)
+
+// adopting returns true if the request is in the adoption mode, and not adopted yet
+func adopting(req *aprv1.MiddlewareRequest) bool {
+	return req.Annotations[AdoptAnnotation] == "true" &&
+		!meta.IsStatusConditionTrue(req.Status.Conditions, aprv1.MiddlewareConditionAdopted)
+}
+
+// claimedBy returns the other request of the same middleware owning the resource
+func (c *controller) claimedBy(req *aprv1.MiddlewareRequest, owns func(r *aprv1.MiddlewareRequest) bool) (*aprv1.MiddlewareRequest, error) {
+	requests, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list middleware requests error, ", err)
+		return nil, err
+	}
+
+	for _, r := range requests {
+		if r.Spec.Middleware != req.Spec.Middleware || (r.Namespace == req.Namespace && r.Name == req.Name) {
+			continue
+		}
+
+		if owns(r) {
+			return r, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// adoptPGRequest renames the databases to adopt, verifies the owners of the existing databases and user,
+// and transfers the databases to the user of the request. Returns nil if the request is not adopting
+func (c *controller) adoptPGRequest(req *aprv1.MiddlewareRequest) ([]string, error) {
+	if !adopting(req) {
+		return nil, nil
+	}
+
+	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	user := req.Spec.PostgreSQL.User
+	other, err := c.claimedBy(req, func(r *aprv1.MiddlewareRequest) bool { return r.Spec.PostgreSQL.User == user })
+	if err != nil {
+		return nil, err
+	}
+
+	if other != nil {
+		return nil, fmt.Errorf("user %s is owned by the request %s/%s", user, other.Namespace, other.Name)
+	}
+
+	adopted := []string{}
+	for index := int32(0); index < *sts.Spec.Replicas; index++ {
+		nodeHost := c.pgNodeHost(sts, index)
+		if err = func() error {
+			nodeClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).Build()
+			if err != nil {
+				klog.Error("connect to node error, ", err, ", ", nodeHost)
+				return err
+			}
+			defer nodeClient.Close()
+
+			if index == 0 {
+				exists, err := nodeClient.RoleExists(c.ctx, user)
+				if err != nil {
+					return err
+				}
+
+				if exists {
+					adopted = append(adopted, "role "+user)
+				}
+			}
+
+			for _, db := range req.Spec.PostgreSQL.Databases {
+				if !db.IsDistributed() && index != 0 {
+					continue
+				}
+
+				dbRealName := citus.GetDatabaseName(req.Spec.AppNamespace, db.Name)
+				owner, err := nodeClient.DatabaseOwner(c.ctx, dbRealName)
+				if err != nil {
+					return err
+				}
+
+				if owner == "" && db.AdoptFrom != "" {
+					owner, err = nodeClient.DatabaseOwner(c.ctx, db.AdoptFrom)
+					if err != nil {
+						return err
+					}
+
+					if owner != "" {
+						klog.Info("rename database to adopt, ", db.AdoptFrom, " -> ", dbRealName, ", ", nodeHost)
+						if err = nodeClient.RenameDatabase(c.ctx, db.AdoptFrom, dbRealName); err != nil {
+							klog.Error("rename database error, ", err, ", ", db.AdoptFrom)
+							return err
+						}
+					}
+				}
+
+				if owner == "" {
+					continue
+				}
+
+				if owner != user && owner != adminUser {
+					other, err := c.claimedBy(req, func(r *aprv1.MiddlewareRequest) bool { return r.Spec.PostgreSQL.User == owner })
+					if err != nil {
+						return err
+					}
+
+					if other != nil {
+						return fmt.Errorf("database %s is owned by the user %s of the request %s/%s", dbRealName, owner, other.Namespace, other.Name)
+					}
+				}
+
+				if err = func() error {
+					dbClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).WithDatabase(dbRealName).Build()
+					if err != nil {
+						klog.Error("connect to database error, ", err, ", ", dbRealName)
+						return err
+					}
+					defer dbClient.Close()
+
+					return dbClient.AdoptDatabase(c.ctx, dbRealName, owner, user)
+				}(); err != nil {
+					return err
+				}
+
+				if index == 0 {
+					adopted = append(adopted, "database "+dbRealName)
+				}
+			}
+
+			return nil
+		}(); err != nil {
+			return nil, err
+		}
+	}
+
+	return adopted, nil
+}
+
+// adoptMDBRequest verifies the user of the request, and returns the existing databases and user adopted.
+// The roles of the user are reset to the databases of the request by the update
+func (c *controller) adoptMDBRequest(req *aprv1.MiddlewareRequest) ([]string, error) {
+	if !adopting(req) {
+		return nil, nil
+	}
+
+	user := req.Spec.MongoDB.User
+	other, err := c.claimedBy(req, func(r *aprv1.MiddlewareRequest) bool { return r.Spec.MongoDB.User == user })
+	if err != nil {
+		return nil, err
+	}
+
+	if other != nil {
+		return nil, fmt.Errorf("user %s is owned by the request %s/%s", user, other.Namespace, other.Name)
+	}
+
+	client, err := c.connectToCluster(req)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close(c.ctx)
+
+	existing, err := client.ListDatabaseNames(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	adopted := []string{}
+	for _, db := range dbRealNames(req.Spec.AppNamespace, req.Spec.MongoDB.Databases) {
+		for _, name := range existing {
+			if name == db.Name {
+				adopted = append(adopted, "database "+db.Name)
+			}
+		}
+
+		exists, err := client.UserExists(c.ctx, user, db.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			adopted = append(adopted, fmt.Sprintf("user %s of %s", user, db.Name))
+		}
+	}
+
+	return adopted, nil
+}
+
+// setAdopted records the adopted resources in the status of the request, nothing to do if adopted is nil
+func (c *controller) setAdopted(req *aprv1.MiddlewareRequest, adopted []string) error {
+	if adopted == nil {
+		return nil
+	}
+
+	message := "no existing resources found"
+	if len(adopted) > 0 {
+		message = "adopted " + strings.Join(adopted, ", ")
+	}
+
+	klog.Info("middleware request adopted, ", req.Namespace, "/", req.Name, ", ", message)
+	c.recorder.Event(req, corev1.EventTypeNormal, reasonAdopted, message)
+
+	current, err := c.aprClientSet.AprV1alpha1().MiddlewareRequests(req.Namespace).Get(c.ctx, req.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	meta.SetStatusCondition(&current.Status.Conditions, metav1.Condition{
+		Type:               aprv1.MiddlewareConditionAdopted,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonAdopted,
+		Message:            message,
+		ObservedGeneration: current.Generation,
+	})
+
+	now := metav1.Now()
+	current.Status.StatusTime = &now
+	_, err = c.aprClientSet.AprV1alpha1().MiddlewareRequests(req.Namespace).UpdateStatus(c.ctx, current, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,109 @@
+package middlewarerequest
+
+import (
+	"encoding/json"
+	"reflect"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// AppliedAnnotation records the backend objects applied for the request, the objects removed
+// from the request since the last reconciliation are dropped by diffing against it
+const AppliedAnnotation = "apr.bytetrade.io/applied"
+
+// appliedObjects are the backend objects of the request applied in the last reconciliation
+type appliedObjects struct {
+	ReadOnlyUser string   `json:"readOnlyUser,omitempty"`
+	Databases    []string `json:"databases,omitempty"`
+
+	// RefDatabases are the databases of the other apps granted to the app user
+	RefDatabases []string `json:"refDatabases,omitempty"`
+
+	// RefGrants are the grants on the databases of RefDatabases, revoked when the refs are removed
+	RefGrants map[string]appliedRefGrant `json:"refGrants,omitempty"`
+
+	// ConnectionLimit is the connection limit of the app user set by the request
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+
+	// Grants are the privileges of the app user on the mysql databases, the ones removed are revoked
+	Grants map[string][]string `json:"grants,omitempty"`
+}
+
+type appliedRefGrant struct {
+	Owner  string `json:"owner,omitempty"`
+	Schema string `json:"schema,omitempty"`
+}
+
+// getApplied returns the objects applied in the last reconciliation, empty if never recorded
+func getApplied(req *aprv1.MiddlewareRequest) *appliedObjects {
+	applied := &appliedObjects{}
+	data, ok := req.Annotations[AppliedAnnotation]
+	if !ok {
+		return applied
+	}
+
+	if err := json.Unmarshal([]byte(data), applied); err != nil {
+		klog.Warning("invalid applied annotation of request, ", err, ", ", req.Namespace, "/", req.Name)
+		return &appliedObjects{}
+	}
+
+	return applied
+}
+
+// updateApplied updates the objects recorded in the request, the other objects recorded are kept
+func (c *controller) updateApplied(req *aprv1.MiddlewareRequest, update func(applied *appliedObjects)) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := c.aprClientSet.AprV1alpha1().MiddlewareRequests(req.Namespace).Get(c.ctx, req.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		applied := getApplied(current)
+		update(applied)
+		if reflect.DeepEqual(getApplied(current), applied) {
+			return nil
+		}
+
+		data, err := json.Marshal(applied)
+		if err != nil {
+			return err
+		}
+
+		if current.Annotations == nil {
+			current.Annotations = make(map[string]string)
+		}
+		current.Annotations[AppliedAnnotation] = string(data)
+
+		_, err = c.aprClientSet.AprV1alpha1().MiddlewareRequests(req.Namespace).Update(c.ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		klog.Error("record applied objects of request error, ", err, ", ", req.Namespace, "/", req.Name)
+	}
+
+	return err
+}
+
+// removed returns the items of the old list not in the new one
+func removed(old, new []string) []string {
+	var ret []string
+	for _, o := range old {
+		found := false
+		for _, n := range new {
+			if o == n {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			ret = append(ret, o)
+		}
+	}
+
+	return ret
+}
@@ -3,27 +3,52 @@ package middlewarerequest
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
+	aprscheme "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned/scheme"
 	informers "bytetrade.io/web3os/tapr/pkg/generated/informers/externalversions"
 	"bytetrade.io/web3os/tapr/pkg/generated/listers/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/usage"
 
 	kbappsv1 "github.com/apecloud/kubeblocks/apis/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/keymutex"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-const controllerAgentName = "middlewarerequest-controller"
+const (
+	controllerAgentName = "middlewarerequest-controller"
+
+	// lockSlots is the number of locks the reconciliation keys are hashed to
+	lockSlots = 64
+)
+
+// serializedMiddlewares are the middlewares whose backend configuration is shared by all the requests,
+// the nats users are written to the config file, the kvrocks namespaces are stored in the cluster config,
+// and the postgres pooler is configured from all the requests
+var serializedMiddlewares = map[aprv1.MiddlewareType]bool{
+	aprv1.TypeNats:       true,
+	aprv1.TypeRedis:      true,
+	aprv1.TypePostgreSQL: true,
+}
 
 type Action int
 
@@ -43,6 +68,9 @@ var rscheme = runtime.NewScheme()
 func init() {
 	utilruntime.Must(scheme.AddToScheme(rscheme))
 	utilruntime.Must(kbappsv1.AddToScheme(rscheme))
+
+	// to record the events of middleware requests
+	utilruntime.Must(aprscheme.AddToScheme(scheme.Scheme))
 }
 
 type controller struct {
@@ -55,8 +83,21 @@ type controller struct {
 	k8sClientSet    *kubernetes.Clientset
 	ctrlClient      client.Client
 	dynamicClient   *dynamic.DynamicClient
+	recorder        record.EventRecorder
+	usage           *usage.Store
+	locks           keymutex.KeyMutex
 	ctx             context.Context
 	cancel          context.CancelFunc
+
+	// backendLocks are held shared by the reconciliations of the requests, and exclusively by the loops
+	// working on the whole backend of a middleware, e.g. removing the orphans
+	backendLocks sync.Map
+
+	// deleted keeps the last state of the deleted requests until the deletion is reconciled, and reconciled
+	// are the requests reconciled since the controller started, the others are reconciled as added
+	stateMu    sync.Mutex
+	deleted    map[string]*aprv1.MiddlewareRequest
+	reconciled map[string]types.UID
 }
 
 type enqueueObj struct {
@@ -71,15 +112,24 @@ func NewController(kubeConfig *rest.Config, mainCtx context.Context) (*controlle
 	informer := informerFactory.Apr().V1alpha1().MiddlewareRequests()
 	lister := informer.Lister()
 
+	k8sClientSet := kubernetes.NewForConfigOrDie(kubeConfig)
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClientSet.CoreV1().Events("")})
+
 	ctrlr := &controller{
 		aprClientSet:    clientset,
-		k8sClientSet:    kubernetes.NewForConfigOrDie(kubeConfig),
+		k8sClientSet:    k8sClientSet,
 		dynamicClient:   dynamic.NewForConfigOrDie(kubeConfig),
 		informerFactory: informerFactory,
 		informer:        informer.Informer(),
 		lister:          lister,
 		synced:          informer.Informer().HasSynced,
 		workqueue:       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "middleware-request"),
+		recorder:        eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName}),
+		usage:           usage.NewStore(),
+		locks:           keymutex.NewHashed(lockSlots),
+		deleted:         make(map[string]*aprv1.MiddlewareRequest),
+		reconciled:      make(map[string]types.UID),
 	}
 	ctrlr.ctx, ctrlr.cancel = context.WithCancel(mainCtx)
 
@@ -98,6 +148,9 @@ func NewController(kubeConfig *rest.Config, mainCtx context.Context) (*controlle
 	_, err = informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: ctrlr.handleAddObject,
 		UpdateFunc: func(old, new interface{}) {
+			if !requestChanged(old, new) {
+				return
+			}
 			ctrlr.handleUpdateObject(new)
 		},
 		DeleteFunc: ctrlr.handleDeleteObject,
@@ -111,34 +164,86 @@ func NewController(kubeConfig *rest.Config, mainCtx context.Context) (*controlle
 	return ctrlr, lister
 }
 
-func (c *controller) enqueue(obj enqueueObj) {
-	// var key string
-	// var err error
-	// if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
-	// 	utilruntime.HandleError(err)
-	// 	return
-	// }
-	c.workqueue.Add(obj)
+// requestChanged returns false for the updates of the status and the applied annotation written
+// by the controller, the spec, the deletion and the other annotations are reconciled
+func requestChanged(old, new interface{}) bool {
+	oldReq, ok := old.(*aprv1.MiddlewareRequest)
+	if !ok {
+		return true
+	}
+
+	newReq, ok := new.(*aprv1.MiddlewareRequest)
+	if !ok {
+		return true
+	}
+
+	if oldReq.Generation != newReq.Generation ||
+		!equality.Semantic.DeepEqual(oldReq.DeletionTimestamp, newReq.DeletionTimestamp) {
+		return true
+	}
+
+	oldAnnotations, newAnnotations := make(map[string]string), make(map[string]string)
+	for k, v := range oldReq.Annotations {
+		oldAnnotations[k] = v
+	}
+	for k, v := range newReq.Annotations {
+		newAnnotations[k] = v
+	}
+	delete(oldAnnotations, AppliedAnnotation)
+	delete(newAnnotations, AppliedAnnotation)
+
+	return !equality.Semantic.DeepEqual(oldAnnotations, newAnnotations)
+}
+
+// enqueue adds the key of the request to the queue, the worker reconciles the current state of the request
+func (c *controller) enqueue(obj interface{}) {
+	var key string
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
 }
 
 func (c *controller) handleAddObject(obj interface{}) {
 	// filter obj
 	klog.Info("handle add object")
-	c.enqueue(enqueueObj{ADD, obj})
+	c.enqueue(obj)
 }
 
 func (c *controller) handleUpdateObject(obj interface{}) {
 	// filter obj
 	klog.Info("handle update object ")
 
-	c.enqueue(enqueueObj{UPDATE, obj})
+	c.enqueue(obj)
 }
 
 func (c *controller) handleDeleteObject(obj interface{}) {
 	// filter obj
 	klog.Info("handle delete object")
 
-	c.enqueue(enqueueObj{DELETE, obj})
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	request, ok := obj.(*aprv1.MiddlewareRequest)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("unexpected deleted object %#v", obj))
+		return
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(request)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	c.stateMu.Lock()
+	c.deleted[key] = request.DeepCopy()
+	c.stateMu.Unlock()
+
+	c.workqueue.Add(key)
 }
 
 func (c *controller) Run(workers int) error {
@@ -165,6 +270,12 @@ func (c *controller) Run(workers int) error {
 	}
 
 	klog.Info("Started workers")
+
+	go wait.Until(c.checkPGQuotas, pgQuotaCheckInterval, c.ctx.Done())
+	go wait.Until(c.checkCertificates, certificateCheckInterval, c.ctx.Done())
+	go wait.Until(c.auditRequests, driftAuditInterval, c.ctx.Done())
+	go wait.Until(c.collectOrphans, orphanGCInterval, c.ctx.Done())
+
 	<-c.ctx.Done()
 	klog.Info("Shutting down workers, ", controllerAgentName)
 
@@ -187,9 +298,9 @@ func (c *controller) processNextWorkItem() bool {
 
 	err := func(obj interface{}) error {
 		defer c.workqueue.Done(obj)
-		var eobj enqueueObj
+		var key string
 		var ok bool
-		if eobj, ok = obj.(enqueueObj); !ok {
+		if key, ok = obj.(string); !ok {
 			// As the item in the workqueue is actually invalid, we call
 			// Forget here else we'd go into a loop of attempting to
 			// process a work item that is invalid.
@@ -200,23 +311,16 @@ func (c *controller) processNextWorkItem() bool {
 
 		// Run the syncHandler, passing it the namespace/name string of the
 		// Foo resource to be synced.
-		for e := c.syncHandler(eobj); e != nil; e = c.syncHandler(eobj) {
-			if eobj.action != DELETE {
-				// Put the item back on the workqueue to handle any transient errors.
-				//c.workqueue.AddRateLimited(eobj)
-				c.workqueue.AddAfter(eobj, 5*time.Second)
-				return fmt.Errorf("error syncing '%v': %s, requeuing", eobj, e.Error())
-			}
-
-			// cause delete action cannot be requeued at the end,
-			klog.Errorf("error syncing '%v': %s, retry after 1 second", eobj, e.Error())
-			time.Sleep(time.Second)
+		if err := c.syncHandler(key); err != nil {
+			// Put the item back on the workqueue to handle any transient errors.
+			c.workqueue.AddAfter(key, 5*time.Second)
+			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
 		}
 
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		c.workqueue.Forget(obj)
-		klog.Infof("Successfully synced '%v'", eobj)
+		klog.Infof("Successfully synced '%s'", key)
 		return nil
 	}(obj)
 
@@ -228,9 +332,104 @@ func (c *controller) processNextWorkItem() bool {
 	return true
 }
 
-func (c *controller) syncHandler(obj enqueueObj) error {
+// syncHandler reconciles the current state of the request in the lister. The deletion of the request
+// is reconciled with its last state, before the request recreated with the same name
+func (c *controller) syncHandler(key string) error {
 	klog.Info("middleware request syncHandler......")
-	return c.handler(obj.action, obj.obj)
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return nil
+	}
+
+	request, err := c.lister.MiddlewareRequests(namespace).Get(name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		request = nil
+	}
+
+	c.stateMu.Lock()
+	last := c.deleted[key]
+	c.stateMu.Unlock()
+
+	if last != nil && (request == nil || request.UID != last.UID) {
+		if err = c.reconcile(DELETE, last); err != nil {
+			return err
+		}
+
+		c.stateMu.Lock()
+		if c.deleted[key] == last {
+			delete(c.deleted, key)
+		}
+		delete(c.reconciled, key)
+		c.stateMu.Unlock()
+	}
+
+	if request == nil {
+		return nil
+	}
+
+	c.stateMu.Lock()
+	action := ADD
+	if c.reconciled[key] == request.UID {
+		action = UPDATE
+	}
+	c.stateMu.Unlock()
+
+	if err = c.reconcile(action, request.DeepCopy()); err != nil {
+		return err
+	}
+
+	c.stateMu.Lock()
+	c.reconciled[key] = request.UID
+	c.stateMu.Unlock()
+
+	return nil
+}
+
+func (c *controller) reconcile(action Action, request *aprv1.MiddlewareRequest) error {
+	unlock := c.lockRequest(request)
+	defer unlock()
+
+	return c.handler(action, request)
+}
+
+// lockRequest locks the backend of the middleware shared, and the key of the request. Returns the unlock function
+func (c *controller) lockRequest(request *aprv1.MiddlewareRequest) func() {
+	backend := c.backendLock(request.Spec.Middleware)
+	backend.RLock()
+
+	key := lockKey(request)
+	c.locks.LockKey(key)
+
+	return func() {
+		c.locks.UnlockKey(key)
+		backend.RUnlock()
+	}
+}
+
+// backendLock returns the lock of the backend of the middleware
+func (c *controller) backendLock(middleware aprv1.MiddlewareType) *sync.RWMutex {
+	lock, _ := c.backendLocks.LoadOrStore(middleware, &sync.RWMutex{})
+	return lock.(*sync.RWMutex)
+}
+
+// lockKey returns the key serializing the reconciliation of the request. The requests of the middlewares
+// sharing the backend configuration are serialized by the middleware type, the others by the request
+func lockKey(obj interface{}) string {
+	request, ok := obj.(*aprv1.MiddlewareRequest)
+	if !ok {
+		return ""
+	}
+
+	if serializedMiddlewares[request.Spec.Middleware] {
+		return string(request.Spec.Middleware)
+	}
+
+	return string(request.Spec.Middleware) + "/" + request.Namespace + "/" + request.Name
 }
 
 func (c *controller) Cancel() {
@@ -0,0 +1,473 @@
+package middlewarerequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/constants"
+	"bytetrade.io/web3os/tapr/pkg/mysql"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+	wes "bytetrade.io/web3os/tapr/pkg/workload/elasticsearch"
+	"bytetrade.io/web3os/tapr/pkg/workload/kvrocks"
+	wmariadb "bytetrade.io/web3os/tapr/pkg/workload/mariadb"
+	wminio "bytetrade.io/web3os/tapr/pkg/workload/minio"
+	wmysql "bytetrade.io/web3os/tapr/pkg/workload/mysql"
+	workload_nats "bytetrade.io/web3os/tapr/pkg/workload/nats"
+	wrabbit "bytetrade.io/web3os/tapr/pkg/workload/rabbitmq"
+
+	esapi "github.com/elastic/go-elasticsearch/v8/esapi"
+	rabbithole "github.com/michaelklishin/rabbit-hole/v3"
+	"github.com/minio/madmin-go"
+	"github.com/thoas/go-funk"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const (
+	driftAuditInterval = 30 * time.Minute
+
+	// DriftPolicyAnnotation set to Report only reports the drift, the request is re-applied by default
+	DriftPolicyAnnotation = "apr.bytetrade.io/drift-policy"
+	DriftPolicyReport     = "Report"
+
+	reasonDrifted = "Drifted"
+	reasonResync  = "Resync"
+	reasonInSync  = "InSync"
+
+	minioNoSuchUser = "XMinioAdminNoSuchUser"
+)
+
+// auditRequests compares every request with the live state of the backend, the drifted requests
+// are re-applied, and reported by the events and the Drifted condition
+func (c *controller) auditRequests() {
+	requests, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list middleware requests error, ", err)
+		return
+	}
+
+	for _, req := range requests {
+		if req.DeletionTimestamp != nil {
+			continue
+		}
+
+		unlock := c.lockRequest(req)
+		err = c.auditRequest(req)
+		unlock()
+
+		if err != nil {
+			klog.Error("audit middleware request error, ", err, ", ", req.Namespace, "/", req.Name)
+		}
+	}
+}
+
+func (c *controller) auditRequest(req *aprv1.MiddlewareRequest) error {
+	diffs, err := c.diffRequest(req)
+	if err != nil {
+		// the backend is unavailable, nothing can be told about the drift
+		return err
+	}
+
+	condition := metav1.Condition{
+		Type:               aprv1.MiddlewareConditionDrifted,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonInSync,
+		Message:            "the backend is in sync with the request",
+		ObservedGeneration: req.Generation,
+	}
+
+	if len(diffs) > 0 {
+		message := strings.Join(diffs, "; ")
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonDrifted
+		condition.Message = message
+
+		klog.Warning("middleware request drifted, ", req.Namespace, "/", req.Name, ", ", message)
+		c.recorder.Event(req, corev1.EventTypeWarning, reasonDrifted, message)
+
+		if req.Annotations[DriftPolicyAnnotation] != DriftPolicyReport {
+			c.recorder.Event(req, corev1.EventTypeNormal, reasonResync, "re-apply the request to the backend")
+			c.enqueue(req)
+		}
+	} else if meta.IsStatusConditionTrue(req.Status.Conditions, aprv1.MiddlewareConditionDrifted) {
+		c.recorder.Event(req, corev1.EventTypeNormal, reasonInSync, condition.Message)
+	}
+
+	updated := req.DeepCopy()
+	if !meta.SetStatusCondition(&updated.Status.Conditions, condition) {
+		return nil
+	}
+
+	now := metav1.Now()
+	updated.Status.StatusTime = &now
+	_, err = c.aprClientSet.AprV1alpha1().MiddlewareRequests(req.Namespace).UpdateStatus(c.ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+// diffRequest returns the differences between the request and the backend, empty if they are in sync
+func (c *controller) diffRequest(req *aprv1.MiddlewareRequest) ([]string, error) {
+	switch req.Spec.Middleware {
+	case aprv1.TypePostgreSQL:
+		return c.diffPGRequest(req)
+	case aprv1.TypeMongoDB:
+		return c.diffMDBRequest(req)
+	case aprv1.TypeRedis:
+		return c.diffRedixRequest(req)
+	case aprv1.TypeNats:
+		return workload_nats.DiffUser(req, req.Namespace)
+	case aprv1.TypeMinio:
+		return c.diffMinioRequest(req)
+	case aprv1.TypeRabbitMQ:
+		return c.diffRabbitMQRequest(req)
+	case aprv1.TypeElasticsearch:
+		return c.diffElasticsearchRequest(req)
+	case aprv1.TypeMariaDB:
+		adminUser, adminPassword, err := wmariadb.FindMariaDBAdminUser(c.ctx, c.k8sClientSet, mariadbNamespace)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := c.getMariaDBRequest(req, adminUser, adminPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.tlsConfig, err = c.kbClusterTLSConfig(mariadbNamespace, "mariadb"); err != nil {
+			return nil, err
+		}
+
+		return c.diffMysqlRequest(c.ctx, r)
+	case aprv1.TypeMysql:
+		adminUser, adminPassword, err := wmysql.FindMysqlAdminUser(c.ctx, c.k8sClientSet, mysqlNamespace)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := c.getMysqlRequest(req, adminUser, adminPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.tlsConfig, err = c.kbClusterTLSConfig(mysqlNamespace, "mysql"); err != nil {
+			return nil, err
+		}
+
+		return c.diffMysqlRequest(c.ctx, r)
+	}
+
+	return nil, nil
+}
+
+func (c *controller) diffPGRequest(req *aprv1.MiddlewareRequest) ([]string, error) {
+	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []string
+	for index := int32(0); index < *sts.Spec.Replicas; index++ {
+		nodeHost := c.pgNodeHost(sts, index)
+		nodeDiffs, err := func() ([]string, error) {
+			nodeClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).Build()
+			if err != nil {
+				klog.Error("connect to node error, ", err, ", ", nodeHost)
+				return nil, err
+			}
+			defer nodeClient.Close()
+
+			var diffs []string
+			roles := []string{req.Spec.PostgreSQL.User}
+			for _, role := range req.Spec.PostgreSQL.Roles {
+				roles = append(roles, role.Name)
+			}
+
+			for _, role := range roles {
+				exists, err := nodeClient.RoleExists(c.ctx, role)
+				if err != nil {
+					return nil, err
+				}
+				if !exists {
+					diffs = append(diffs, fmt.Sprintf("role %s not found on %s", role, nodeHost))
+				}
+			}
+
+			databases, err := nodeClient.ListDatabases(c.ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, db := range req.Spec.PostgreSQL.Databases {
+				if !db.IsDistributed() && index != 0 {
+					continue
+				}
+
+				dbRealName := citus.GetDatabaseName(req.Spec.AppNamespace, db.Name)
+				if !funk.ContainsString(databases, dbRealName) {
+					diffs = append(diffs, fmt.Sprintf("database %s not found on %s", dbRealName, nodeHost))
+					continue
+				}
+
+				if len(db.Extensions) == 0 {
+					continue
+				}
+
+				if err = nodeClient.SwitchDatabase(dbRealName); err != nil {
+					return nil, err
+				}
+
+				extensions, err := nodeClient.ListExtensions(c.ctx)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, ext := range db.Extensions {
+					found := false
+					for _, e := range extensions {
+						if e.Name == ext {
+							found = true
+							break
+						}
+					}
+
+					if !found {
+						diffs = append(diffs, fmt.Sprintf("extension %s not found in database %s on %s", ext, dbRealName, nodeHost))
+					}
+				}
+			}
+
+			return diffs, nil
+		}()
+
+		if err != nil {
+			return nil, err
+		}
+
+		diffs = append(diffs, nodeDiffs...)
+	}
+
+	return diffs, nil
+}
+
+func (c *controller) diffMDBRequest(req *aprv1.MiddlewareRequest) ([]string, error) {
+	client, err := c.connectToCluster(req)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close(c.ctx)
+
+	var diffs []string
+	for _, db := range dbRealNames(req.Spec.AppNamespace, req.Spec.MongoDB.Databases) {
+		exists, err := client.UserExists(c.ctx, req.Spec.MongoDB.User, db.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			diffs = append(diffs, fmt.Sprintf("mongodb user %s not found in database %s", req.Spec.MongoDB.User, db.Name))
+		}
+	}
+
+	return diffs, nil
+}
+
+func (c *controller) diffRedixRequest(req *aprv1.MiddlewareRequest) ([]string, error) {
+	clusters, err := c.aprClientSet.AprV1alpha1().RedixClusters(constants.PlatformNamespace).List(c.ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Error("find redix cluster error, ", err)
+		return nil, err
+	}
+
+	// the password of redis cluster is not provisioned per request
+	if len(clusters.Items) == 0 || clusters.Items[0].Spec.Type != aprv1.KVRocks {
+		return nil, nil
+	}
+
+	cli, err := kvrocks.GetKVRocksClient(c.ctx, c.k8sClientSet, &clusters.Items[0])
+	if err != nil {
+		klog.Error("get kvrocks client error, ", err)
+		return nil, err
+	}
+	defer cli.Close()
+
+	requestNamespace := GetKVRocksNamespaceName(req.Namespace, req.Spec.Redis.Namespace)
+	ns, err := cli.GetNamespace(c.ctx, requestNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if ns == nil {
+		return []string{fmt.Sprintf("kvrocks namespace %s not found", requestNamespace)}, nil
+	}
+
+	return nil, nil
+}
+
+func (c *controller) diffMinioRequest(req *aprv1.MiddlewareRequest) ([]string, error) {
+	adminUser, adminPassword, err := c.findMinioAdminCredentials(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := c.getMinioEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	minioClient, madminClient, err := c.newMinioClients(endpoint, adminUser, adminPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []string
+	if _, err = madminClient.GetUserInfo(c.ctx, req.Spec.Minio.User); err != nil {
+		if madmin.ToErrorResponse(err).Code != minioNoSuchUser {
+			return nil, err
+		}
+
+		diffs = append(diffs, fmt.Sprintf("minio user %s not found", req.Spec.Minio.User))
+	}
+
+	for _, bucket := range req.Spec.Minio.Buckets {
+		bucketName := wminio.GetBucketName(req.Spec.AppNamespace, bucket.Name)
+		exists, err := minioClient.BucketExists(c.ctx, bucketName)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			diffs = append(diffs, fmt.Sprintf("minio bucket %s not found", bucketName))
+		}
+	}
+
+	return diffs, nil
+}
+
+func (c *controller) diffRabbitMQRequest(req *aprv1.MiddlewareRequest) ([]string, error) {
+	client, err := c.newRabbitMQClient()
+	if err != nil {
+		return nil, err
+	}
+
+	isNotFound := func(err error) bool {
+		var rerr rabbithole.ErrorResponse
+		return errors.As(err, &rerr) && rerr.StatusCode == http.StatusNotFound
+	}
+
+	var diffs []string
+	if _, err = client.GetUser(req.Spec.RabbitMQ.User); err != nil {
+		if !isNotFound(err) {
+			return nil, err
+		}
+
+		diffs = append(diffs, fmt.Sprintf("rabbitmq user %s not found", req.Spec.RabbitMQ.User))
+	}
+
+	for _, v := range req.Spec.RabbitMQ.Vhosts {
+		vhost := wrabbit.GetVhostName(req.Spec.AppNamespace, v.Name)
+		if _, err = client.GetVhost(vhost); err != nil {
+			if !isNotFound(err) {
+				return nil, err
+			}
+
+			diffs = append(diffs, fmt.Sprintf("rabbitmq vhost %s not found", vhost))
+			continue
+		}
+
+		if _, err = client.GetPermissionsIn(vhost, req.Spec.RabbitMQ.User); err != nil {
+			if !isNotFound(err) {
+				return nil, err
+			}
+
+			diffs = append(diffs, fmt.Sprintf("rabbitmq permissions of user %s in vhost %s not found", req.Spec.RabbitMQ.User, vhost))
+		}
+	}
+
+	return diffs, nil
+}
+
+func (c *controller) diffElasticsearchRequest(req *aprv1.MiddlewareRequest) ([]string, error) {
+	adminUser, adminPassword, err := wes.FindElasticsearchAdminUser(c.ctx, c.k8sClientSet, elasticNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	es, err := c.newESClient(c.getElasticsearchEndpoint(), adminUser, adminPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []string
+	res, err := esapi.SecurityGetUserRequest{Username: []string{req.Spec.Elasticsearch.User}}.Do(c.ctx, es)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		diffs = append(diffs, fmt.Sprintf("elasticsearch user %s not found", req.Spec.Elasticsearch.User))
+	case res.IsError():
+		return nil, fmt.Errorf("get user failed: %s", res.String())
+	}
+
+	for _, idx := range req.Spec.Elasticsearch.Indexes {
+		name := wes.GetIndexName(req.Spec.AppNamespace, idx.Name)
+		exists, err := checkIndexIfExists(es, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			diffs = append(diffs, fmt.Sprintf("elasticsearch index %s not found", name))
+		}
+	}
+
+	return diffs, nil
+}
+
+func (c *controller) diffMysqlRequest(ctx context.Context, req *mysqlRequest) ([]string, error) {
+	db, err := mysql.NewClientBuilder(req.adminUser, req.adminPassword, req.host).WithTLSConfig(req.tlsConfig).Build()
+	if err != nil {
+		klog.Errorf("failed to connect to %s %v", req.host, err)
+		return nil, err
+	}
+	defer db.Close()
+
+	var diffs []string
+	for _, user := range []string{req.user, req.readOnlyUser} {
+		if user == "" {
+			continue
+		}
+
+		exists, err := db.UserExists(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			diffs = append(diffs, fmt.Sprintf("user %s not found", user))
+		}
+	}
+
+	for _, d := range req.databases {
+		exists, err := db.DatabaseExists(ctx, d.name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			diffs = append(diffs, fmt.Sprintf("database %s not found", d.name))
+		}
+	}
+
+	return diffs, nil
+}
@@ -32,11 +32,25 @@ func (c *controller) handler(action Action, obj interface{}) error {
 				}
 			}
 
+			// the exported databases may be changed
+			c.reconcilePGRefsTo(request)
+
+			if err = c.syncPGPooler(); err != nil {
+				return err
+			}
+
 		case DELETE:
+			// revoke the privileges granted to the other apps before dropping the databases
+			c.reconcilePGRefsTo(request)
+
 			err := c.deletePGAll(request)
 			if err != nil {
 				return err
 			}
+
+			if err = c.syncPGPooler(); err != nil {
+				return err
+			}
 		}
 	case aprv1.TypeMongoDB:
 		switch action {
@@ -36,7 +36,7 @@ func (c *controller) createOrUpdateElasticsearchRequest(req *aprv1.MiddlewareReq
 		return fmt.Errorf("failed to get user password %v", err)
 	}
 
-	es, err := newESClient(endpoint, adminUser, adminPassword)
+	es, err := c.newESClient(endpoint, adminUser, adminPassword)
 	if err != nil {
 		return fmt.Errorf("failed to new esclient %v", err)
 	}
@@ -60,7 +60,7 @@ func (c *controller) createOrUpdateElasticsearchRequest(req *aprv1.MiddlewareReq
 	if req.Spec.Elasticsearch.AllowNamespaceIndexes {
 		indices = append(indices, fmt.Sprintf("%s_*", req.Spec.AppNamespace))
 	}
-	roleName := fmt.Sprintf("role-%s", req.Spec.Elasticsearch.User)
+	roleName := esRoleName(req.Spec.Elasticsearch.User)
 	err = esPutRole(es, roleName, indices)
 	if err != nil {
 		return err
@@ -84,11 +84,11 @@ func (c *controller) deleteElasticsearchRequest(req *aprv1.MiddlewareRequest) er
 		return err
 	}
 	endpoint := c.getElasticsearchEndpoint()
-	es, err := newESClient(endpoint, adminUser, adminPassword)
+	es, err := c.newESClient(endpoint, adminUser, adminPassword)
 	if err != nil {
 		return fmt.Errorf("failed to new esclient %v", err)
 	}
-	roleName := fmt.Sprintf("role-%s", req.Spec.Elasticsearch.User)
+	roleName := esRoleName(req.Spec.Elasticsearch.User)
 	err = esDeleteUser(es, req.Spec.Elasticsearch.User)
 	if err != nil {
 		return fmt.Errorf("failed to delete user %s %v", req.Spec.Elasticsearch.User, err)
@@ -134,12 +134,22 @@ func (c *controller) getElasticsearchEndpoint() string {
 	return fmt.Sprintf("https://elasticsearch-mdit-http.%s:9200", elasticNamespace)
 }
 
-func newESClient(endpoint, username, password string) (*elastic.Client, error) {
+func (c *controller) newESClient(endpoint, username, password string) (*elastic.Client, error) {
+	tlsConfig, err := c.kbClusterTLSConfig(elasticNamespace, "elasticsearch")
+	if err != nil {
+		return nil, err
+	}
+
+	// the certificate is self-signed by kubeblocks before the cluster serves the certificates of the operator
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
 	cfg := elastic.Config{
 		Addresses: []string{endpoint},
 		Username:  username,
 		Password:  password,
-		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		Transport: newTLSTransport(tlsConfig),
 	}
 	return elastic.NewClient(cfg)
 }
@@ -1,7 +1,6 @@
 package middlewarerequest
 
 import (
-	"database/sql"
 	"fmt"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -9,8 +8,6 @@ import (
 
 	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	wmariadb "bytetrade.io/web3os/tapr/pkg/workload/mariadb"
-
-	_ "github.com/go-sql-driver/mysql"
 )
 
 const mariadbNamespace = "mariadb-middleware"
@@ -21,47 +18,25 @@ func (c *controller) createOrUpdateMariaDBRequest(req *aprv1.MiddlewareRequest)
 		klog.Errorf("failed to get admin user %v", err)
 		return err
 	}
-	userPassword, err := req.Spec.MariaDB.Password.GetVarValue(c.ctx, c.k8sClientSet, req.Namespace)
-	if err != nil {
-		klog.Errorf("failed to mariadb ")
-		return err
-	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/", adminUser, adminPassword, c.getMariaDBHost())
-	db, err := sql.Open("mysql", dsn)
+	r, err := c.getMariaDBRequest(req, adminUser, adminPassword)
 	if err != nil {
-		klog.Errorf("failed to open mariadb %v", err)
 		return err
 	}
-	defer db.Close()
 
-	// create user if not exists
-	createUserSQL := fmt.Sprintf("CREATE USER IF NOT EXISTS `%s` IDENTIFIED BY '%s'", req.Spec.MariaDB.User, userPassword)
-	_, err = db.ExecContext(c.ctx, createUserSQL)
+	r.tlsConfig, err = c.kbClusterTLSConfig(mariadbNamespace, "mariadb")
 	if err != nil {
-		klog.Errorf("failed to create user %v", err)
 		return err
 	}
 
-	// create databases and grant privileges
-	for _, d := range req.Spec.MariaDB.Databases {
-		dbName := wmariadb.GetDatabaseName(req.Spec.AppNamespace, d.Name)
-		createDBSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", dbName)
-		if _, err = db.ExecContext(c.ctx, createDBSQL); err != nil {
-			klog.Errorf("failed to execute create database %v", err)
-			return err
-		}
-		grantSQL := fmt.Sprintf("GRANT ALL PRIVILEGES ON `%s`.* TO `%s`", dbName, req.Spec.MariaDB.User)
-		if _, err = db.ExecContext(c.ctx, grantSQL); err != nil {
-			klog.Errorf("failed to grant database %s privileges %v", dbName, err)
-			return err
-		}
-	}
-	if _, err = db.ExecContext(c.ctx, "FLUSH PRIVILEGES"); err != nil {
-		klog.Errorf("failed to flush user %s privileges %v", req.Spec.MariaDB.User, err)
+	r.applied = getApplied(req)
+	if err = c.reconcileMysqlRequest(c.ctx, r); err != nil {
 		return err
 	}
-	return nil
+
+	return c.updateApplied(req, func(applied *appliedObjects) {
+		*applied = *r.appliedObjects()
+	})
 }
 
 func (c *controller) deleteMariaDBRequest(req *aprv1.MiddlewareRequest) error {
@@ -75,30 +50,64 @@ func (c *controller) deleteMariaDBRequest(req *aprv1.MiddlewareRequest) error {
 		}
 		return err
 	}
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/", adminUser, adminPassword, c.getMariaDBHost())
-	db, err := sql.Open("mysql", dsn)
+
+	r := &mysqlRequest{
+		host:          c.getMariaDBHost(),
+		adminUser:     adminUser,
+		adminPassword: adminPassword,
+		user:          req.Spec.MariaDB.User,
+	}
+	if req.Spec.MariaDB.ReadOnlyUser != nil {
+		r.readOnlyUser = req.Spec.MariaDB.ReadOnlyUser.User
+	}
+	for _, d := range req.Spec.MariaDB.Databases {
+		r.databases = append(r.databases, mysqlDatabase{name: wmariadb.GetDatabaseName(req.Spec.AppNamespace, d.Name)})
+	}
+
+	r.tlsConfig, err = c.kbClusterTLSConfig(mariadbNamespace, "mariadb")
 	if err != nil {
-		klog.Errorf("failed to open connection %v", err)
 		return err
 	}
-	defer db.Close()
-	dropUserSQL := fmt.Sprintf("DROP USER IF EXISTS `%s`", req.Spec.MariaDB.User)
-	_, err = db.ExecContext(c.ctx, dropUserSQL)
+
+	return c.deleteMysqlRequestAll(c.ctx, r)
+}
+
+func (c *controller) getMariaDBRequest(req *aprv1.MiddlewareRequest, adminUser, adminPassword string) (*mysqlRequest, error) {
+	userPassword, err := req.Spec.MariaDB.Password.GetVarValue(c.ctx, c.k8sClientSet, req.Namespace)
 	if err != nil {
-		klog.Errorf("failed to drop user %s %v", req.Spec.MariaDB.User, err)
-		return err
+		klog.Errorf("failed to get mariadb user password %v", err)
+		return nil, err
 	}
 
-	for _, d := range req.Spec.MariaDB.Databases {
-		dbName := wmariadb.GetDatabaseName(req.Spec.AppNamespace, d.Name)
-		dropDBSQL := fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", dbName)
-		_, err = db.ExecContext(c.ctx, dropDBSQL)
+	r := &mysqlRequest{
+		host:           c.getMariaDBHost(),
+		adminUser:      adminUser,
+		adminPassword:  adminPassword,
+		user:           req.Spec.MariaDB.User,
+		password:       userPassword,
+		maxConnections: req.Spec.MariaDB.MaxUserConnections,
+	}
+
+	if req.Spec.MariaDB.ReadOnlyUser != nil {
+		r.readOnlyUser = req.Spec.MariaDB.ReadOnlyUser.User
+		r.readOnlyPassword, err = req.Spec.MariaDB.ReadOnlyUser.Password.GetVarValue(c.ctx, c.k8sClientSet, req.Namespace)
 		if err != nil {
-			klog.Errorf("failed to drop database %s, %v", dbName, err)
-			return err
+			klog.Errorf("failed to get mariadb read-only user password %v", err)
+			return nil, err
 		}
 	}
-	return nil
+
+	for _, d := range req.Spec.MariaDB.Databases {
+		r.databases = append(r.databases, mysqlDatabase{
+			name:      wmariadb.GetDatabaseName(req.Spec.AppNamespace, d.Name),
+			charset:   d.Charset,
+			collation: d.Collation,
+			scripts:   d.Scripts,
+			grants:    d.Grants,
+		})
+	}
+
+	return r, nil
 }
 
 func (c *controller) getMariaDBHost() string {
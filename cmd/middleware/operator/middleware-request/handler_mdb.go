@@ -20,6 +20,12 @@ func (c *controller) createOrUpdateMDBRequest(req *aprv1.MiddlewareRequest) erro
 		return err
 	}
 
+	adopted, err := c.adoptMDBRequest(req)
+	if err != nil {
+		klog.Error("adopt existing databases error, ", err, ", ", req.Namespace, "/", req.Name)
+		return err
+	}
+
 	client, err := c.connectToCluster(req)
 	if err != nil {
 		klog.Errorf("failed to connect to mongodb cluster %v", err)
@@ -27,7 +33,12 @@ func (c *controller) createOrUpdateMDBRequest(req *aprv1.MiddlewareRequest) erro
 	}
 	defer client.Close(c.ctx)
 
-	return client.CreateOrUpdateUserWithDatabase(c.ctx, req.Spec.MongoDB.User, pwd, dbRealNames(req.Spec.AppNamespace, req.Spec.MongoDB.Databases))
+	err = client.CreateOrUpdateUserWithDatabase(c.ctx, req.Spec.MongoDB.User, pwd, dbRealNames(req.Spec.AppNamespace, req.Spec.MongoDB.Databases))
+	if err != nil {
+		return err
+	}
+
+	return c.setAdopted(req, adopted)
 }
 
 func (c *controller) deleteMDBRequest(req *aprv1.MiddlewareRequest) error {
@@ -56,10 +67,16 @@ func (c *controller) connectToCluster(req *aprv1.MiddlewareRequest) (*mongo.Mong
 		return nil, err
 	}
 
+	tlsConfig, err := c.kbClusterTLSConfig("mongodb-middleware", "mongodb")
+	if err != nil {
+		return nil, err
+	}
+
 	client := &mongo.MongoClient{
-		User:     user,
-		Password: pwd,
-		Addr:     host + ":27017",
+		User:      user,
+		Password:  pwd,
+		Addr:      host + ":27017",
+		TLSConfig: tlsConfig,
 	}
 
 	err = client.Connect(c.ctx)
@@ -27,17 +27,9 @@ func (c *controller) createOrUpdateMinioRequest(req *aprv1.MiddlewareRequest) er
 		return fmt.Errorf("failed to get minio endpoint: %w", err)
 	}
 
-	minioClient, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(adminUser, adminPassword, ""),
-		Secure: false,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create minio client: %w", err)
-	}
-
-	madminClient, err := madmin.New(endpoint, adminUser, adminPassword, false)
+	minioClient, madminClient, err := c.newMinioClients(endpoint, adminUser, adminPassword)
 	if err != nil {
-		return fmt.Errorf("failed to create minio admin client: %v", err)
+		return err
 	}
 
 	klog.Info("create minio user and buckets, ", req.Spec.Minio.User)
@@ -95,16 +87,9 @@ func (c *controller) deleteMinioRequest(req *aprv1.MiddlewareRequest) error {
 		return fmt.Errorf("failed to get minio endpoint: %w", err)
 	}
 
-	minioClient, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(adminUser, adminPassword, ""),
-		Secure: false,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create minio client: %w", err)
-	}
-	madminClient, err := madmin.New(endpoint, adminUser, adminPassword, false)
+	minioClient, madminClient, err := c.newMinioClients(endpoint, adminUser, adminPassword)
 	if err != nil {
-		return fmt.Errorf("failed to create minio admin client: %v", err)
+		return err
 	}
 
 	klog.Info("delete minio user and buckets, ", req.Spec.Minio.User)
@@ -167,6 +152,37 @@ func (c *controller) getMinioEndpoint() (string, error) {
 	return fmt.Sprintf("minio-minio.%s.svc.cluster.local:9000", "minio-middleware"), nil
 }
 
+// newMinioClients connects to minio with https if the cluster serves tls
+func (c *controller) newMinioClients(endpoint, adminUser, adminPassword string) (*minio.Client, *madmin.AdminClient, error) {
+	tlsConfig, err := c.kbClusterTLSConfig("minio-middleware", "minio")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get minio tls config: %w", err)
+	}
+
+	opts := &minio.Options{
+		Creds:  credentials.NewStaticV4(adminUser, adminPassword, ""),
+		Secure: tlsConfig != nil,
+	}
+	if tlsConfig != nil {
+		opts.Transport = newTLSTransport(tlsConfig)
+	}
+
+	minioClient, err := minio.New(endpoint, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create minio client: %w", err)
+	}
+
+	madminClient, err := madmin.New(endpoint, adminUser, adminPassword, tlsConfig != nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create minio admin client: %v", err)
+	}
+	if tlsConfig != nil {
+		madminClient.SetCustomTransport(newTLSTransport(tlsConfig))
+	}
+
+	return minioClient, madminClient, nil
+}
+
 func (c *controller) createOrUpdateMinioUser(ctx context.Context, madminClient *madmin.AdminClient, username, password string) error {
 
 	err := madminClient.AddUser(ctx, username, password)
@@ -1,17 +1,13 @@
 package middlewarerequest
 
 import (
-	"database/sql"
 	"fmt"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog/v2"
 
 	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
-	wmariadb "bytetrade.io/web3os/tapr/pkg/workload/mariadb"
 	wmysql "bytetrade.io/web3os/tapr/pkg/workload/mysql"
-
-	_ "github.com/go-sql-driver/mysql"
 )
 
 const mysqlNamespace = "mysql-middleware"
@@ -22,47 +18,25 @@ func (c *controller) createOrUpdateMysqlRequest(req *aprv1.MiddlewareRequest) er
 		klog.Errorf("failed to get mysql admin user %v", err)
 		return err
 	}
-	userPassword, err := req.Spec.Mysql.Password.GetVarValue(c.ctx, c.k8sClientSet, req.Namespace)
-	if err != nil {
-		klog.Errorf("failed to get mysql user password %v", err)
-		return err
-	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/", adminUser, adminPassword, c.getMysqlHost())
-	db, err := sql.Open("mysql", dsn)
+	r, err := c.getMysqlRequest(req, adminUser, adminPassword)
 	if err != nil {
-		klog.Errorf("failed to open mysql %v", err)
 		return err
 	}
-	defer db.Close()
 
-	// create user if not exists
-	createUserSQL := fmt.Sprintf("CREATE USER IF NOT EXISTS `%s` IDENTIFIED BY '%s'", req.Spec.Mysql.User, userPassword)
-	_, err = db.ExecContext(c.ctx, createUserSQL)
+	r.tlsConfig, err = c.kbClusterTLSConfig(mysqlNamespace, "mysql")
 	if err != nil {
-		klog.Errorf("failed to create user %v", err)
 		return err
 	}
 
-	// create databases and grant privileges
-	for _, d := range req.Spec.Mysql.Databases {
-		dbName := wmariadb.GetDatabaseName(req.Spec.AppNamespace, d.Name)
-		createDBSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", dbName)
-		if _, err = db.ExecContext(c.ctx, createDBSQL); err != nil {
-			klog.Errorf("failed to execute create database %v", err)
-			return err
-		}
-		grantSQL := fmt.Sprintf("GRANT ALL PRIVILEGES ON `%s`.* TO `%s`", dbName, req.Spec.Mysql.User)
-		if _, err = db.ExecContext(c.ctx, grantSQL); err != nil {
-			klog.Errorf("failed to grant database %s privileges %v", dbName, err)
-			return err
-		}
-	}
-	if _, err = db.ExecContext(c.ctx, "FLUSH PRIVILEGES"); err != nil {
-		klog.Errorf("failed to flush user %s privileges %v", req.Spec.Mysql.User, err)
+	r.applied = getApplied(req)
+	if err = c.reconcileMysqlRequest(c.ctx, r); err != nil {
 		return err
 	}
-	return nil
+
+	return c.updateApplied(req, func(applied *appliedObjects) {
+		*applied = *r.appliedObjects()
+	})
 }
 
 func (c *controller) deleteMysqlRequest(req *aprv1.MiddlewareRequest) error {
@@ -76,30 +50,64 @@ func (c *controller) deleteMysqlRequest(req *aprv1.MiddlewareRequest) error {
 		}
 		return err
 	}
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/", adminUser, adminPassword, c.getMysqlHost())
-	db, err := sql.Open("mysql", dsn)
+
+	r := &mysqlRequest{
+		host:          c.getMysqlHost(),
+		adminUser:     adminUser,
+		adminPassword: adminPassword,
+		user:          req.Spec.Mysql.User,
+	}
+	if req.Spec.Mysql.ReadOnlyUser != nil {
+		r.readOnlyUser = req.Spec.Mysql.ReadOnlyUser.User
+	}
+	for _, d := range req.Spec.Mysql.Databases {
+		r.databases = append(r.databases, mysqlDatabase{name: wmysql.GetDatabaseName(req.Spec.AppNamespace, d.Name)})
+	}
+
+	r.tlsConfig, err = c.kbClusterTLSConfig(mysqlNamespace, "mysql")
 	if err != nil {
-		klog.Errorf("failed to open connection %v", err)
 		return err
 	}
-	defer db.Close()
-	dropUserSQL := fmt.Sprintf("DROP USER IF EXISTS `%s`", req.Spec.Mysql.User)
-	_, err = db.ExecContext(c.ctx, dropUserSQL)
+
+	return c.deleteMysqlRequestAll(c.ctx, r)
+}
+
+func (c *controller) getMysqlRequest(req *aprv1.MiddlewareRequest, adminUser, adminPassword string) (*mysqlRequest, error) {
+	userPassword, err := req.Spec.Mysql.Password.GetVarValue(c.ctx, c.k8sClientSet, req.Namespace)
 	if err != nil {
-		klog.Errorf("failed to drop user %s %v", req.Spec.MariaDB.User, err)
-		return err
+		klog.Errorf("failed to get mysql user password %v", err)
+		return nil, err
 	}
 
-	for _, d := range req.Spec.Mysql.Databases {
-		dbName := wmysql.GetDatabaseName(req.Spec.AppNamespace, d.Name)
-		dropDBSQL := fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", dbName)
-		_, err = db.ExecContext(c.ctx, dropDBSQL)
+	r := &mysqlRequest{
+		host:           c.getMysqlHost(),
+		adminUser:      adminUser,
+		adminPassword:  adminPassword,
+		user:           req.Spec.Mysql.User,
+		password:       userPassword,
+		maxConnections: req.Spec.Mysql.MaxUserConnections,
+	}
+
+	if req.Spec.Mysql.ReadOnlyUser != nil {
+		r.readOnlyUser = req.Spec.Mysql.ReadOnlyUser.User
+		r.readOnlyPassword, err = req.Spec.Mysql.ReadOnlyUser.Password.GetVarValue(c.ctx, c.k8sClientSet, req.Namespace)
 		if err != nil {
-			klog.Errorf("failed to drop database %s, %v", dbName, err)
-			return err
+			klog.Errorf("failed to get mysql read-only user password %v", err)
+			return nil, err
 		}
 	}
-	return nil
+
+	for _, d := range req.Spec.Mysql.Databases {
+		r.databases = append(r.databases, mysqlDatabase{
+			name:      wmysql.GetDatabaseName(req.Spec.AppNamespace, d.Name),
+			charset:   d.Charset,
+			collation: d.Collation,
+			scripts:   d.Scripts,
+			grants:    d.Grants,
+		})
+	}
+
+	return r, nil
 }
 
 func (c *controller) getMysqlHost() string {
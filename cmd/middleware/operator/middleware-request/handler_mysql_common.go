@@ -0,0 +1,176 @@
+package middlewarerequest
+
+import (
+	"context"
+	"crypto/tls"
+
+	"bytetrade.io/web3os/tapr/pkg/mysql"
+
+	"k8s.io/klog/v2"
+)
+
+// mysqlDatabase is the common define of the mysql and mariadb databases in request
+type mysqlDatabase struct {
+	name      string
+	charset   string
+	collation string
+	scripts   []string
+	grants    []string
+}
+
+// mysqlRequest is the common define of the mysql and mariadb requests,
+// both of them are reconciled by reconcileMysqlRequest
+type mysqlRequest struct {
+	host          string
+	adminUser     string
+	adminPassword string
+
+	user           string
+	password       string
+	maxConnections int32
+
+	readOnlyUser     string
+	readOnlyPassword string
+
+	databases []mysqlDatabase
+
+	// applied are the objects applied in the last reconciliation, the ones removed from the request are dropped
+	applied *appliedObjects
+
+	tlsConfig *tls.Config
+}
+
+// appliedObjects returns the objects of the request to record after the reconciliation
+func (r *mysqlRequest) appliedObjects() *appliedObjects {
+	applied := &appliedObjects{ReadOnlyUser: r.readOnlyUser, Grants: make(map[string][]string)}
+	for _, d := range r.databases {
+		applied.Databases = append(applied.Databases, d.name)
+		if grants, err := mysql.Privileges(d.grants); err == nil {
+			applied.Grants[d.name] = grants
+		}
+	}
+
+	return applied
+}
+
+func (c *controller) reconcileMysqlRequest(ctx context.Context, req *mysqlRequest) error {
+	db, err := mysql.NewClientBuilder(req.adminUser, req.adminPassword, req.host).WithTLSConfig(req.tlsConfig).Build()
+	if err != nil {
+		klog.Errorf("failed to open connection %v", err)
+		return err
+	}
+	defer db.Close()
+
+	// only the scripts are executed with multiple statements
+	scriptDB := db
+	for _, d := range req.databases {
+		if len(d.scripts) > 0 {
+			scriptDB, err = mysql.NewClientBuilder(req.adminUser, req.adminPassword, req.host).
+				WithTLSConfig(req.tlsConfig).WithMultiStatements().Build()
+			if err != nil {
+				klog.Errorf("failed to open connection %v", err)
+				return err
+			}
+			defer scriptDB.Close()
+			break
+		}
+	}
+
+	if err = db.CreateOrUpdateUser(ctx, req.user, req.password, req.maxConnections); err != nil {
+		klog.Errorf("failed to create user %s %v", req.user, err)
+		return err
+	}
+
+	if req.readOnlyUser != "" {
+		if err = db.CreateOrUpdateUser(ctx, req.readOnlyUser, req.readOnlyPassword, req.maxConnections); err != nil {
+			klog.Errorf("failed to create read-only user %s %v", req.readOnlyUser, err)
+			return err
+		}
+	}
+
+	// create databases and grant privileges
+	for _, d := range req.databases {
+		if err = db.CreateOrUpdateDatabase(ctx, d.name, d.charset, d.collation); err != nil {
+			klog.Errorf("failed to create database %s %v", d.name, err)
+			return err
+		}
+
+		var prev []string
+		if req.applied != nil {
+			prev = req.applied.Grants[d.name]
+		}
+
+		if err = db.GrantPrivileges(ctx, d.name, req.user, d.grants, prev); err != nil {
+			klog.Errorf("failed to grant database %s privileges %v", d.name, err)
+			return err
+		}
+
+		if req.readOnlyUser != "" {
+			if err = db.GrantPrivileges(ctx, d.name, req.readOnlyUser, mysql.ReadOnlyPrivileges, nil); err != nil {
+				klog.Errorf("failed to grant database %s read-only privileges %v", d.name, err)
+				return err
+			}
+		}
+
+		if len(d.scripts) > 0 {
+			if err = scriptDB.ExecuteScript(ctx, d.name, req.user, d.scripts); err != nil {
+				klog.Errorf("failed to execute script on database %s %v", d.name, err)
+				return err
+			}
+		}
+	}
+
+	// drop the read-only user and the databases removed from the request
+	if req.applied != nil {
+		if u := req.applied.ReadOnlyUser; u != "" && u != req.readOnlyUser && u != req.user {
+			if err = db.DropUser(ctx, u); err != nil {
+				klog.Errorf("failed to drop read-only user %s %v", u, err)
+				return err
+			}
+		}
+
+		for _, name := range removed(req.applied.Databases, req.appliedObjects().Databases) {
+			klog.Infof("drop database %s removed from request", name)
+			if err = db.DropDatabase(ctx, name); err != nil {
+				klog.Errorf("failed to drop database %s, %v", name, err)
+				return err
+			}
+		}
+	}
+
+	if err = db.FlushPrivileges(ctx); err != nil {
+		klog.Errorf("failed to flush user %s privileges %v", req.user, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *controller) deleteMysqlRequestAll(ctx context.Context, req *mysqlRequest) error {
+	db, err := mysql.NewClientBuilder(req.adminUser, req.adminPassword, req.host).WithTLSConfig(req.tlsConfig).Build()
+	if err != nil {
+		klog.Errorf("failed to open connection %v", err)
+		return err
+	}
+	defer db.Close()
+
+	for _, user := range []string{req.user, req.readOnlyUser} {
+		if user == "" {
+			continue
+		}
+
+		if err = db.DropUser(ctx, user); err != nil {
+			klog.Errorf("failed to drop user %s %v", user, err)
+			return err
+		}
+	}
+
+	for _, d := range req.databases {
+		if err = db.DropDatabase(ctx, d.name); err != nil {
+			klog.Errorf("failed to drop database %s, %v", d.name, err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,8 +1,6 @@
 package middlewarerequest
 
 import (
-	"strconv"
-
 	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	"bytetrade.io/web3os/tapr/pkg/postgres"
 	"bytetrade.io/web3os/tapr/pkg/workload/citus"
@@ -13,14 +11,21 @@ import (
 )
 
 func (c *controller) createOrUpdatePGRequest(req *aprv1.MiddlewareRequest) error {
+	adopted, err := c.adoptPGRequest(req)
+	if err != nil {
+		klog.Error("adopt existing databases error, ", err, ", ", req.Namespace, "/", req.Name)
+		return err
+	}
+
 	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
 	if err != nil {
 		return err
 	}
 
+	connectionLimit := pgConnectionLimit(req)
 	var index int32 = 0
 	for index < *sts.Spec.Replicas {
-		nodeHost := sts.Name + "-" + strconv.Itoa(int(index)) + ".citus-headless." + sts.Namespace
+		nodeHost := c.pgNodeHost(sts, index)
 
 		if err := func() error {
 			nodeClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).Build()
@@ -41,6 +46,20 @@ func (c *controller) createOrUpdatePGRequest(req *aprv1.MiddlewareRequest) error
 				return err
 			}
 
+			if limit := connectionLimit; limit != nil {
+				err = nodeClient.SetConnectionLimit(c.ctx, req.Spec.PostgreSQL.User, *limit)
+				if err != nil {
+					klog.Error("set user connection limit error, ", err, ", ", req.Spec.PostgreSQL.User)
+					return err
+				}
+			}
+
+			err = c.createOrUpdatePGRoles(nodeClient, req)
+			if err != nil {
+				klog.Error("create roles error, ", err, ", ", nodeHost)
+				return err
+			}
+
 			for _, db := range req.Spec.PostgreSQL.Databases {
 
 				klog.Info("create db for user, ", db.Name, ", ", db.IsDistributed(), ", ", req.Spec.PostgreSQL.User)
@@ -51,6 +70,11 @@ func (c *controller) createOrUpdatePGRequest(req *aprv1.MiddlewareRequest) error
 						return err
 					}
 
+					err = nodeClient.SetDatabaseLimits(c.ctx, dbRealName, pgLimitSettings(req))
+					if err != nil {
+						return err
+					}
+
 					err = nodeClient.SwitchDatabase(dbRealName)
 					if err != nil {
 						return err
@@ -95,7 +119,31 @@ func (c *controller) createOrUpdatePGRequest(req *aprv1.MiddlewareRequest) error
 		index += 1
 	} // end loop replicas
 
-	return c.addWorkerNode(req)
+	err = c.updateApplied(req, func(applied *appliedObjects) {
+		applied.ConnectionLimit = connectionLimit
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.addWorkerNode(req); err != nil {
+		return err
+	}
+
+	if err = c.grantPGRolePrivileges(req); err != nil {
+		klog.Error("grant role privileges error, ", err)
+		return err
+	}
+
+	if err = c.deletePGRolesIfNotExists(req, false); err != nil {
+		return err
+	}
+
+	if err = c.reconcilePGRefs(req); err != nil {
+		return err
+	}
+
+	return c.setAdopted(req, adopted)
 }
 
 func (c *controller) addWorkerNode(req *aprv1.MiddlewareRequest) error {
@@ -104,12 +152,12 @@ func (c *controller) addWorkerNode(req *aprv1.MiddlewareRequest) error {
 		return err
 	}
 
-	masterHost := sts.Name + "-0.citus-headless." + sts.Namespace
+	masterHost := c.pgNodeHost(sts, 0)
 	masterClientBuilder := postgres.NewClientBuidler(adminUser, adminPwd, masterHost, postgres.PG_PORT)
 
 	var index int32 = 1
 	for index < *sts.Spec.Replicas {
-		nodeHost := sts.Name + "-" + strconv.Itoa(int(index)) + ".citus-headless." + sts.Namespace
+		nodeHost := c.pgNodeHost(sts, index)
 		for _, db := range req.Spec.PostgreSQL.Databases {
 			if db.IsDistributed() {
 				dbRealName := citus.GetDatabaseName(req.Spec.AppNamespace, db.Name)
@@ -155,7 +203,7 @@ func (c *controller) deleteDatabaseIfNotExists(req *aprv1.MiddlewareRequest) err
 		return err
 	}
 
-	masterHost := sts.Name + "-0.citus-headless." + sts.Namespace
+	masterHost := c.pgNodeHost(sts, 0)
 	masterClient, err := postgres.NewClientBuidler(adminUser, adminPwd, masterHost, postgres.PG_PORT).Build()
 	if err != nil {
 		klog.Error("connect to master error, ", err, ", ", masterHost)
@@ -183,7 +231,7 @@ func (c *controller) deleteDatabaseIfNotExists(req *aprv1.MiddlewareRequest) err
 			// not found in request, delete it
 			var index int32 = 0
 			for index < *sts.Spec.Replicas {
-				nodeHost := sts.Name + "-" + strconv.Itoa(int(index)) + ".citus-headless." + sts.Namespace
+				nodeHost := c.pgNodeHost(sts, index)
 				nodeClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).Build()
 				if err != nil {
 					klog.Error("cannot connect to host, ", err, ", ", nodeHost)
@@ -210,9 +258,13 @@ func (c *controller) deletePGAll(req *aprv1.MiddlewareRequest) error {
 		return err
 	}
 
+	if err = c.deletePGRolesIfNotExists(req, true); err != nil {
+		return err
+	}
+
 	var index int32 = 0
 	for index < *sts.Spec.Replicas {
-		nodeHost := sts.Name + "-" + strconv.Itoa(int(index)) + ".citus-headless." + sts.Namespace
+		nodeHost := c.pgNodeHost(sts, index)
 		nodeClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).Build()
 		if err != nil {
 			klog.Error("cannot connect to host, ", err, ", ", nodeHost)
@@ -247,8 +299,8 @@ func (c *controller) deletePGAll(req *aprv1.MiddlewareRequest) error {
 				}
 			}
 
-			// delete user
-			err = nodeClient.DeleteUser(c.ctx, req.Spec.PostgreSQL.User)
+			// delete user, and the privileges granted on the referenced databases
+			err = nodeClient.DropRole(c.ctx, req.Spec.PostgreSQL.User, "")
 
 			return err
 		}(); err != nil {
@@ -261,6 +313,36 @@ func (c *controller) deletePGAll(req *aprv1.MiddlewareRequest) error {
 	return nil
 }
 
+// syncPGPooler updates the userlist and pools of the cluster pooler if it is deployed
+func (c *controller) syncPGPooler() error {
+	cluster, err := c.aprClientSet.AprV1alpha1().PGClusters(citus.PGClusterNamespace).Get(c.ctx, citus.PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		klog.Error("get pg cluster error, ", err)
+		return err
+	}
+
+	if cluster.Spec.Pooler == nil {
+		return nil
+	}
+
+	return citus.ReconcilePGPooler(c.ctx, c.k8sClientSet, c.aprClientSet, c.lister, cluster)
+}
+
+// pgNodeHost returns the host of the node of the cluster, node 0 is the active coordinator
+func (c *controller) pgNodeHost(sts *appsv1.StatefulSet, index int32) string {
+	var cluster *aprv1.PGCluster
+	if index == 0 {
+		var err error
+		cluster, err = c.aprClientSet.AprV1alpha1().PGClusters(sts.Namespace).Get(c.ctx, citus.PGClusterName, metav1.GetOptions{})
+		if err != nil {
+			klog.Warning("get pg cluster error, ", err)
+			cluster = nil
+		}
+	}
+
+	return citus.GetNodeHost(cluster, sts.Namespace, index)
+}
+
 func (c *controller) findClusterWorkloadAndAdminuserAndPassword() (sts *appsv1.StatefulSet, adminUser, adminPwd string, err error) {
 	sts, err = c.k8sClientSet.AppsV1().StatefulSets(citus.PGClusterNamespace).Get(c.ctx, citus.PGClusterName, metav1.GetOptions{})
 	if err != nil {
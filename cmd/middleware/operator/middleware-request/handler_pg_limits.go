@@ -0,0 +1,198 @@
+package middlewarerequest
+
+import (
+	"fmt"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const (
+	pgQuotaCheckInterval = 5 * time.Minute
+
+	reasonQuotaExceeded = "QuotaExceeded"
+	reasonQuotaRestored = "QuotaRestored"
+)
+
+func pgLimitSettings(req *aprv1.MiddlewareRequest) map[string]string {
+	limits := req.Spec.PostgreSQL.Limits
+	if limits == nil {
+		return nil
+	}
+
+	return map[string]string{
+		postgres.StatementTimeout:                limits.StatementTimeout,
+		postgres.IdleInTransactionSessionTimeout: limits.IdleInTransactionSessionTimeout,
+		postgres.WorkMem:                         limits.WorkMem,
+	}
+}
+
+// pgConnectionLimit returns the connection limit of the app user to set, the limit removed from the request
+// is reset to unlimited. Returns nil if the request never set a limit, the default limit of the user is kept
+func pgConnectionLimit(req *aprv1.MiddlewareRequest) *int32 {
+	if limits := req.Spec.PostgreSQL.Limits; limits != nil && limits.ConnectionLimit != nil {
+		return limits.ConnectionLimit
+	}
+
+	if getApplied(req).ConnectionLimit != nil {
+		unlimited := int32(-1)
+		return &unlimited
+	}
+
+	return nil
+}
+
+// checkPGQuotas checks the size of the databases of every request with a quota,
+// and notifies or revokes the CONNECT privilege when the quota is exceeded
+func (c *controller) checkPGQuotas() {
+	requests, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list middleware requests error, ", err)
+		return
+	}
+
+	for _, req := range requests {
+		if req.Spec.Middleware != aprv1.TypePostgreSQL || req.Spec.PostgreSQL.Limits == nil ||
+			req.Spec.PostgreSQL.Limits.DatabaseSizeQuota == nil {
+			continue
+		}
+
+		unlock := c.lockRequest(req)
+		err = c.checkPGQuota(req)
+		unlock()
+
+		if err != nil {
+			klog.Error("check pg quota error, ", err, ", ", req.Namespace, "/", req.Name)
+		}
+	}
+}
+
+func (c *controller) checkPGQuota(req *aprv1.MiddlewareRequest) error {
+	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
+	if err != nil {
+		return err
+	}
+
+	var (
+		size  int64
+		index int32 = 0
+	)
+	for index < *sts.Spec.Replicas {
+		nodeHost := c.pgNodeHost(sts, index)
+		if err = func() error {
+			nodeClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).Build()
+			if err != nil {
+				klog.Error("cannot connect to host, ", err, ", ", nodeHost)
+				return err
+			}
+			defer nodeClient.Close()
+
+			for _, db := range req.Spec.PostgreSQL.Databases {
+				dbSize, err := nodeClient.DatabaseSize(c.ctx, citus.GetDatabaseName(req.Spec.AppNamespace, db.Name))
+				if err != nil {
+					return err
+				}
+
+				size += dbSize
+			}
+
+			return nil
+		}(); err != nil {
+			return err
+		}
+
+		index += 1
+	}
+
+	limits := req.Spec.PostgreSQL.Limits
+	exceeded := size > limits.DatabaseSizeQuota.Value()
+	if err = c.setQuotaExceeded(req, exceeded, size); err != nil {
+		klog.Error("update quota condition of request error, ", err, ", ", req.Namespace, "/", req.Name)
+	}
+
+	// revoke the connect privilege, or restore it if the quota is not exceeded any more
+	revoke := exceeded && limits.QuotaExceededAction == aprv1.PGQuotaActionRevoke
+	masterHost := c.pgNodeHost(sts, 0)
+	masterClient, err := postgres.NewClientBuidler(adminUser, adminPwd, masterHost, postgres.PG_PORT).Build()
+	if err != nil {
+		klog.Error("connect to master error, ", err, ", ", masterHost)
+		return err
+	}
+	defer masterClient.Close()
+
+	user := req.Spec.PostgreSQL.User
+	for _, db := range req.Spec.PostgreSQL.Databases {
+		dbRealName := citus.GetDatabaseName(req.Spec.AppNamespace, db.Name)
+		granted, err := masterClient.HasConnectPrivilege(c.ctx, dbRealName, user)
+		if err != nil {
+			klog.Error("check connect privilege error, ", err, ", ", dbRealName)
+			return err
+		}
+
+		switch {
+		case revoke && granted:
+			klog.Info("revoke connect privilege, ", dbRealName, ", ", user)
+			if err = masterClient.RevokeConnect(c.ctx, dbRealName, user); err != nil {
+				return err
+			}
+		case !revoke && !granted:
+			klog.Info("restore connect privilege, ", dbRealName, ", ", user)
+			if err = masterClient.GrantConnect(c.ctx, dbRealName, user); err != nil {
+				return err
+			}
+
+			c.recorder.Eventf(req, corev1.EventTypeNormal, reasonQuotaRestored,
+				"connect privilege on database %s is restored", db.Name)
+		}
+	}
+
+	return nil
+}
+
+// setQuotaExceeded records the quota state in the conditions of the request,
+// the events are only emitted when the state changes
+func (c *controller) setQuotaExceeded(req *aprv1.MiddlewareRequest, exceeded bool, size int64) error {
+	limits := req.Spec.PostgreSQL.Limits
+	condition := metav1.Condition{
+		Type:               aprv1.MiddlewareConditionQuotaExceeded,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonQuotaRestored,
+		Message:            fmt.Sprintf("database size %s is within the quota %s", resource.NewQuantity(size, resource.BinarySI), limits.DatabaseSizeQuota),
+		ObservedGeneration: req.Generation,
+	}
+
+	if exceeded {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonQuotaExceeded
+		condition.Message = fmt.Sprintf("database size %s exceeds the quota %s", resource.NewQuantity(size, resource.BinarySI), limits.DatabaseSizeQuota)
+	}
+
+	if meta.IsStatusConditionTrue(req.Status.Conditions, aprv1.MiddlewareConditionQuotaExceeded) != exceeded {
+		eventType := corev1.EventTypeNormal
+		if exceeded {
+			eventType = corev1.EventTypeWarning
+		}
+
+		klog.Info("pg database size quota state changed, ", req.Namespace, "/", req.Name, ", ", condition.Message)
+		c.recorder.Event(req, eventType, condition.Reason, condition.Message)
+	}
+
+	updated := req.DeepCopy()
+	if !meta.SetStatusCondition(&updated.Status.Conditions, condition) {
+		return nil
+	}
+
+	now := metav1.Now()
+	updated.Status.StatusTime = &now
+	_, err := c.aprClientSet.AprV1alpha1().MiddlewareRequests(req.Namespace).UpdateStatus(c.ctx, updated, metav1.UpdateOptions{})
+	return err
+}
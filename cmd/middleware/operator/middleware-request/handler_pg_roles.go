@@ -0,0 +1,356 @@
+package middlewarerequest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/utils"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+	"bytetrade.io/web3os/tapr/pkg/workload/nats"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+type pgRoleClient interface {
+	CreateOrUpdateRole(ctx context.Context, role, pwd, comment string) error
+	GrantRoleMembership(ctx context.Context, group, role string, grant bool) error
+}
+
+// pgRoleComment is set on the additional roles, to find out the roles of a request
+func pgRoleComment(req *aprv1.MiddlewareRequest) string {
+	return fmt.Sprintf("tapr:%s/%s", req.Namespace, req.Name)
+}
+
+// createOrUpdatePGRoles creates the additional roles of the request on a node
+func (c *controller) createOrUpdatePGRoles(nodeClient pgRoleClient, req *aprv1.MiddlewareRequest) error {
+	for _, role := range req.Spec.PostgreSQL.Roles {
+		if role.Name == req.Spec.PostgreSQL.User {
+			return fmt.Errorf("role %s conflicts with the app user", role.Name)
+		}
+
+		klog.Info("create role, ", role.Name)
+		pwd, err := role.Password.GetVarValue(c.ctx, c.k8sClientSet, req.Namespace)
+		if err != nil {
+			return err
+		}
+
+		if err = nodeClient.CreateOrUpdateRole(c.ctx, role.Name, pwd, pgRoleComment(req)); err != nil {
+			return err
+		}
+
+		if err = nodeClient.GrantRoleMembership(c.ctx, req.Spec.PostgreSQL.User, role.Name, role.InheritOwner); err != nil {
+			klog.Error("grant role membership error, ", err, ", ", role.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// grantPGRolePrivileges grants the privileges of the additional roles on the master node
+func (c *controller) grantPGRolePrivileges(req *aprv1.MiddlewareRequest) error {
+	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
+	if err != nil {
+		return err
+	}
+
+	masterHost := c.pgNodeHost(sts, 0)
+	for _, role := range req.Spec.PostgreSQL.Roles {
+		for _, grant := range role.Grants {
+			if !func() bool {
+				for _, db := range req.Spec.PostgreSQL.Databases {
+					if db.Name == grant.Database {
+						return true
+					}
+				}
+				return false
+			}() {
+				return fmt.Errorf("database %s of role %s not found in request", grant.Database, role.Name)
+			}
+
+			dbRealName := citus.GetDatabaseName(req.Spec.AppNamespace, grant.Database)
+			if err = func() error {
+				masterClient, err := postgres.NewClientBuidler(adminUser, adminPwd, masterHost, postgres.PG_PORT).WithDatabase(dbRealName).Build()
+				if err != nil {
+					klog.Error("connect to master error, ", err, ", ", masterHost)
+					return err
+				}
+				defer masterClient.Close()
+
+				klog.Info("grant privileges to role, ", role.Name, ", ", dbRealName)
+				return masterClient.GrantDatabasePrivileges(c.ctx, dbRealName, req.Spec.PostgreSQL.User, role.Name,
+					grant.Schema, grant.Tables, grant.Privileges, grant.SchemaPrivileges)
+			}(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deletePGRolesIfNotExists drops the roles created by the request but not in the request any more.
+// All roles of the request will be dropped if all is true.
+func (c *controller) deletePGRolesIfNotExists(req *aprv1.MiddlewareRequest, all bool) error {
+	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
+	if err != nil {
+		return err
+	}
+
+	var index int32 = 0
+	for index < *sts.Spec.Replicas {
+		nodeHost := c.pgNodeHost(sts, index)
+		if err = func() error {
+			nodeClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).Build()
+			if err != nil {
+				klog.Error("cannot connect to host, ", err, ", ", nodeHost)
+				return err
+			}
+			defer nodeClient.Close()
+
+			roles, err := nodeClient.ListRolesByComment(c.ctx, pgRoleComment(req))
+			if err != nil {
+				klog.Error("list roles of request error, ", err, ", ", req.Namespace, "/", req.Name)
+				return err
+			}
+
+			for _, role := range roles {
+				if !all && func() bool {
+					for _, r := range req.Spec.PostgreSQL.Roles {
+						if r.Name == role {
+							return true
+						}
+					}
+					return false
+				}() {
+					continue
+				}
+
+				klog.Info("drop role, ", role, ", ", nodeHost)
+				if err = nodeClient.DropRole(c.ctx, role, req.Spec.PostgreSQL.User); err != nil {
+					klog.Error("drop role error, ", err, ", ", role)
+					return err
+				}
+			}
+
+			return nil
+		}(); err != nil {
+			return err
+		}
+
+		index += 1
+	}
+
+	return nil
+}
+
+type pgRefGrant struct {
+	database   string
+	owner      string
+	schema     string
+	privileges []string
+}
+
+// findPGRefGrants resolves the refs of the request to the databases exported by other apps
+func (c *controller) findPGRefGrants(req *aprv1.MiddlewareRequest) ([]pgRefGrant, error) {
+	if len(req.Spec.PostgreSQL.Refs) == 0 {
+		return nil, nil
+	}
+
+	requests, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list middleware requests error, ", err)
+		return nil, err
+	}
+
+	ownerName := nats.GetOwnerNameFromNs(req.Namespace)
+	var (
+		grants []pgRefGrant
+		errs   []error
+	)
+	for _, ref := range req.Spec.PostgreSQL.Refs {
+		var exporter *aprv1.MiddlewareRequest
+		for _, r := range requests {
+			if r.Spec.Middleware == aprv1.TypePostgreSQL && r.Spec.App == ref.AppName &&
+				r.Spec.AppNamespace == citus.GetRefAppNamespace(ref.AppNamespace, req.Spec.AppNamespace, ownerName) {
+				exporter = r
+				break
+			}
+		}
+
+		if exporter == nil {
+			errs = append(errs, fmt.Errorf("referenced app %s not found", ref.AppName))
+			continue
+		}
+
+		for _, refDB := range ref.Databases {
+			if err = postgres.ValidateTablePrivileges(refDB.Privileges); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			exported := func() []string {
+				for _, db := range exporter.Spec.PostgreSQL.Databases {
+					if db.Name == refDB.Name {
+						for _, e := range db.Export {
+							if e.AppName == req.Spec.App {
+								return e.Privileges
+							}
+						}
+					}
+				}
+				return nil
+			}()
+
+			denied := func() string {
+				for _, p := range refDB.Privileges {
+					if !utils.ListContains(exported, strings.ToUpper(p)) &&
+						!utils.ListContains(exported, strings.ToLower(p)) &&
+						!utils.ListContains(exported, "ALL") {
+						return p
+					}
+				}
+				return ""
+			}()
+
+			if len(exported) == 0 || denied != "" {
+				errs = append(errs, fmt.Errorf("not found export permission %s for database %s of app %s", denied, refDB.Name, ref.AppName))
+				continue
+			}
+
+			grants = append(grants, pgRefGrant{
+				database:   citus.GetDatabaseName(exporter.Spec.AppNamespace, refDB.Name),
+				owner:      exporter.Spec.PostgreSQL.User,
+				schema:     refDB.Schema,
+				privileges: refDB.Privileges,
+			})
+		}
+	}
+
+	return grants, utils.AggregateErrs(errs)
+}
+
+// reconcilePGRefs grants the privileges on the referenced databases to the app user on the master node,
+// and revokes the privileges on the databases granted in the last reconciliation but not referenced
+// or exported any more. The workers are not granted, the apps connect to the coordinator only, and
+// citus propagates the grants and revokes on the distributed tables to their shards on the workers.
+func (c *controller) reconcilePGRefs(req *aprv1.MiddlewareRequest) error {
+	grants, refErr := c.findPGRefGrants(req)
+	if refErr != nil {
+		// grant the permitted refs anyway
+		klog.Error("find pg refs error, ", refErr, ", ", req.Namespace, "/", req.Name)
+	}
+
+	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
+	if err != nil {
+		return err
+	}
+
+	masterHost := c.pgNodeHost(sts, 0)
+	masterClient, err := postgres.NewClientBuidler(adminUser, adminPwd, masterHost, postgres.PG_PORT).Build()
+	if err != nil {
+		klog.Error("connect to master error, ", err, ", ", masterHost)
+		return err
+	}
+	defer masterClient.Close()
+
+	user := req.Spec.PostgreSQL.User
+	owned, err := masterClient.ListDatabaseByOwner(c.ctx, user)
+	if err != nil {
+		klog.Error("list db by owner error, ", err, ", ", user)
+		return err
+	}
+
+	databases, err := masterClient.ListDatabases(c.ctx)
+	if err != nil {
+		klog.Error("list databases error, ", err)
+		return err
+	}
+
+	applied := getApplied(req)
+	var granted []string
+	refGrants := make(map[string]appliedRefGrant)
+	for _, g := range grants {
+		granted = append(granted, g.database)
+		refGrants[g.database] = appliedRefGrant{Owner: g.owner, Schema: g.schema}
+	}
+
+	for _, db := range removed(applied.RefDatabases, granted) {
+		// the database is dropped with the privileges
+		if utils.ListContains(owned, db) || !utils.ListContains(databases, db) {
+			continue
+		}
+
+		klog.Info("revoke privileges on the database not referenced, ", db, ", ", user)
+		if err = masterClient.SwitchDatabase(db); err != nil {
+			return err
+		}
+
+		// the grants recorded without the schema are on the default schema, by the owner of the database
+		ref, ok := applied.RefGrants[db]
+		if !ok {
+			if ref.Owner, err = masterClient.DatabaseOwner(c.ctx, db); err != nil {
+				klog.Error("get database owner error, ", err, ", ", db)
+				return err
+			}
+		}
+
+		if err = masterClient.RevokeDatabasePrivileges(c.ctx, db, ref.Owner, user, ref.Schema); err != nil {
+			klog.Error("revoke privileges error, ", err, ", ", db, ", ", user)
+			return err
+		}
+	}
+
+	for _, g := range grants {
+		if err = masterClient.SwitchDatabase(g.database); err != nil {
+			return err
+		}
+
+		klog.Info("grant privileges on referenced database, ", g.database, ", ", user)
+		if err = masterClient.GrantDatabasePrivileges(c.ctx, g.database, g.owner, user, g.schema, nil, g.privileges, nil); err != nil {
+			klog.Error("grant privileges on referenced database error, ", err, ", ", g.database)
+			return err
+		}
+	}
+
+	err = c.updateApplied(req, func(applied *appliedObjects) {
+		applied.RefDatabases = granted
+		applied.RefGrants = refGrants
+	})
+	if err != nil {
+		return err
+	}
+
+	return refErr
+}
+
+// reconcilePGRefsTo reconciles the refs of the other apps which reference the app of the request,
+// to make the changes of the exported databases take effect. The privileges on the databases of the
+// request deleted are revoked, as the request is not in the lister any more
+func (c *controller) reconcilePGRefsTo(req *aprv1.MiddlewareRequest) {
+	requests, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list middleware requests error, ", err)
+		return
+	}
+
+	for _, r := range requests {
+		if r.Spec.Middleware != aprv1.TypePostgreSQL {
+			continue
+		}
+
+		for _, ref := range r.Spec.PostgreSQL.Refs {
+			if ref.AppName == req.Spec.App {
+				klog.Info("reconcile pg refs of request, ", r.Namespace, "/", r.Name)
+				if err = c.reconcilePGRefs(r); err != nil {
+					klog.Error("reconcile pg refs error, ", err, ", ", r.Namespace, "/", r.Name)
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,733 @@
+package middlewarerequest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/constants"
+	"bytetrade.io/web3os/tapr/pkg/mysql"
+	"bytetrade.io/web3os/tapr/pkg/orphans"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+	wes "bytetrade.io/web3os/tapr/pkg/workload/elasticsearch"
+	"bytetrade.io/web3os/tapr/pkg/workload/kvrocks"
+	wmariadb "bytetrade.io/web3os/tapr/pkg/workload/mariadb"
+	wminio "bytetrade.io/web3os/tapr/pkg/workload/minio"
+	"bytetrade.io/web3os/tapr/pkg/workload/mongodb"
+	wmysql "bytetrade.io/web3os/tapr/pkg/workload/mysql"
+	wrabbit "bytetrade.io/web3os/tapr/pkg/workload/rabbitmq"
+
+	esapi "github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/minio/madmin-go"
+	"github.com/minio/minio-go/v7"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const orphanGCInterval = time.Hour
+
+// orphanBackend lists the objects of the backend following the tapr naming scheme, and removes the orphans
+type orphanBackend struct {
+	middleware aprv1.MiddlewareType
+	list       func() (map[string][]string, error)
+	remove     func(kind, name string) error
+}
+
+// ownedNames are the names of the objects owned by the middleware requests
+type ownedNames struct {
+	names    map[string]bool
+	prefixes map[string][]string
+}
+
+func newOwnedNames() *ownedNames {
+	return &ownedNames{names: make(map[string]bool), prefixes: make(map[string][]string)}
+}
+
+func (o *ownedNames) add(middleware aprv1.MiddlewareType, kind, name string) {
+	o.names[string(middleware)+"/"+kind+"/"+name] = true
+}
+
+func (o *ownedNames) addPrefix(middleware aprv1.MiddlewareType, kind, prefix string) {
+	key := string(middleware) + "/" + kind
+	o.prefixes[key] = append(o.prefixes[key], prefix)
+}
+
+func (o *ownedNames) owns(middleware aprv1.MiddlewareType, kind, name string) bool {
+	if o.names[string(middleware)+"/"+kind+"/"+name] {
+		return true
+	}
+
+	for _, prefix := range o.prefixes[string(middleware)+"/"+kind] {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func collectOwnedNames(requests []*aprv1.MiddlewareRequest) *ownedNames {
+	owned := newOwnedNames()
+	for _, req := range requests {
+		appNs := req.Spec.AppNamespace
+		switch req.Spec.Middleware {
+		case aprv1.TypePostgreSQL:
+			owned.add(aprv1.TypePostgreSQL, orphans.KindRole, req.Spec.PostgreSQL.User)
+			for _, role := range req.Spec.PostgreSQL.Roles {
+				owned.add(aprv1.TypePostgreSQL, orphans.KindRole, role.Name)
+			}
+			for _, db := range req.Spec.PostgreSQL.Databases {
+				owned.add(aprv1.TypePostgreSQL, orphans.KindDatabase, citus.GetDatabaseName(appNs, db.Name))
+				if db.AdoptFrom != "" {
+					owned.add(aprv1.TypePostgreSQL, orphans.KindDatabase, db.AdoptFrom)
+				}
+			}
+		case aprv1.TypeMongoDB:
+			for _, db := range req.Spec.MongoDB.Databases {
+				owned.add(aprv1.TypeMongoDB, orphans.KindDatabase, mongodb.GetDatabaseName(appNs, db.Name))
+			}
+		case aprv1.TypeRedis:
+			owned.add(aprv1.TypeRedis, orphans.KindNamespace, GetKVRocksNamespaceName(req.Namespace, req.Spec.Redis.Namespace))
+		case aprv1.TypeMinio:
+			owned.add(aprv1.TypeMinio, orphans.KindUser, req.Spec.Minio.User)
+			for _, b := range req.Spec.Minio.Buckets {
+				owned.add(aprv1.TypeMinio, orphans.KindBucket, wminio.GetBucketName(appNs, b.Name))
+			}
+			if req.Spec.Minio.AllowNamespaceBuckets {
+				owned.addPrefix(aprv1.TypeMinio, orphans.KindBucket, appNs+"-")
+			}
+		case aprv1.TypeRabbitMQ:
+			owned.add(aprv1.TypeRabbitMQ, orphans.KindUser, req.Spec.RabbitMQ.User)
+			for _, v := range req.Spec.RabbitMQ.Vhosts {
+				owned.add(aprv1.TypeRabbitMQ, orphans.KindVhost, wrabbit.GetVhostName(appNs, v.Name))
+			}
+		case aprv1.TypeElasticsearch:
+			owned.add(aprv1.TypeElasticsearch, orphans.KindRole, esRoleName(req.Spec.Elasticsearch.User))
+			for _, idx := range req.Spec.Elasticsearch.Indexes {
+				owned.add(aprv1.TypeElasticsearch, orphans.KindIndex, wes.GetIndexName(appNs, idx.Name))
+			}
+			if req.Spec.Elasticsearch.AllowNamespaceIndexes {
+				owned.addPrefix(aprv1.TypeElasticsearch, orphans.KindIndex, appNs+"_")
+			}
+		case aprv1.TypeMariaDB:
+			for _, db := range req.Spec.MariaDB.Databases {
+				owned.add(aprv1.TypeMariaDB, orphans.KindDatabase, wmariadb.GetDatabaseName(appNs, db.Name))
+			}
+		case aprv1.TypeMysql:
+			for _, db := range req.Spec.Mysql.Databases {
+				owned.add(aprv1.TypeMysql, orphans.KindDatabase, wmysql.GetDatabaseName(appNs, db.Name))
+			}
+		}
+	}
+
+	return owned
+}
+
+// collectOrphans finds the objects in the backends owned by no request, and removes the orphans
+// approved by the admin after the quarantine. The backend is locked from the reconciliations of the
+// requests while its objects are listed or removed
+func (c *controller) collectOrphans() {
+	requests, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list middleware requests error, ", err)
+		return
+	}
+
+	appNamespaces, err := c.appNamespaces(requests)
+	if err != nil {
+		klog.Error("list app namespaces error, ", err)
+		return
+	}
+
+	backends := c.orphanBackends(appNamespaces)
+
+	var found []orphans.Orphan
+	failed := make(map[aprv1.MiddlewareType]bool)
+	for _, backend := range backends {
+		orphaned, err := c.listOrphans(backend)
+		if err != nil {
+			klog.Warning("list objects of backend error, ", err, ", ", backend.middleware)
+			failed[backend.middleware] = true
+			continue
+		}
+
+		found = append(found, orphaned...)
+	}
+
+	now := time.Now()
+	var merged []orphans.Orphan
+	var quarantine time.Duration
+	err = orphans.Update(c.ctx, c.k8sClientSet, constants.PlatformNamespace, func(stored []orphans.Orphan, q time.Duration) []orphans.Orphan {
+		current := found
+		// keep the orphans of the backends unavailable now
+		for _, o := range stored {
+			if failed[o.Middleware] {
+				current = append(current, o)
+			}
+		}
+
+		merged, quarantine = orphans.Merge(stored, current, now), q
+		return merged
+	})
+	if err != nil {
+		klog.Error("save orphans error, ", err)
+		return
+	}
+
+	removers := make(map[aprv1.MiddlewareType]func(kind, name string) error)
+	for _, backend := range backends {
+		removers[backend.middleware] = backend.remove
+	}
+
+	for _, o := range merged {
+		if !o.Due(quarantine, now) || failed[o.Middleware] {
+			continue
+		}
+
+		if err = c.removeOrphan(o, removers[o.Middleware]); err != nil {
+			klog.Error("remove orphan error, ", err, ", ", o.Key())
+		}
+	}
+}
+
+// listOrphans lists the objects of the backend owned by no request
+func (c *controller) listOrphans(backend orphanBackend) ([]orphans.Orphan, error) {
+	lock := c.backendLock(backend.middleware)
+	lock.Lock()
+	defer lock.Unlock()
+
+	requests, err := c.lister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	owned := collectOwnedNames(requests)
+
+	objects, err := backend.list()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []orphans.Orphan
+	for kind, names := range objects {
+		for _, name := range names {
+			if !owned.owns(backend.middleware, kind, name) {
+				found = append(found, orphans.Orphan{Middleware: backend.middleware, Kind: kind, Name: name})
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// removeOrphan removes the orphan, unless a request owns it since it was found
+func (c *controller) removeOrphan(o orphans.Orphan, remove func(kind, name string) error) error {
+	lock := c.backendLock(o.Middleware)
+	lock.Lock()
+	defer lock.Unlock()
+
+	requests, err := c.lister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	if collectOwnedNames(requests).owns(o.Middleware, o.Kind, o.Name) {
+		klog.Info("orphan is owned by a request now, ", o.Key())
+		return nil
+	}
+
+	klog.Info("remove orphan, ", o.Key(), ", first seen at ", o.FirstSeen)
+	return remove(o.Kind, o.Name)
+}
+
+// appNamespaces returns the namespaces in the cluster and the app namespaces of the requests, the names of
+// the buckets, the vhosts and the indexes are prefixed by them
+func (c *controller) appNamespaces(requests []*aprv1.MiddlewareRequest) ([]string, error) {
+	namespaces, err := c.k8sClientSet.CoreV1().Namespaces().List(c.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, ns := range namespaces.Items {
+		ret = append(ret, ns.Name)
+	}
+
+	for _, req := range requests {
+		if req.Spec.AppNamespace != "" {
+			ret = append(ret, req.Spec.AppNamespace)
+		}
+	}
+
+	return ret, nil
+}
+
+// appPrefixed returns true if the name is built from an app namespace followed by one of the separators
+func appPrefixed(name string, appNamespaces []string, separators ...string) bool {
+	for _, ns := range appNamespaces {
+		for _, sep := range separators {
+			prefix := ns + sep
+			if len(name) > len(prefix) && strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *controller) orphanBackends(appNamespaces []string) []orphanBackend {
+	return []orphanBackend{
+		{aprv1.TypePostgreSQL, c.listPGObjects, c.removePGObject},
+		{aprv1.TypeMongoDB, c.listMongoObjects, c.removeMongoObject},
+		{aprv1.TypeRedis, c.listKVRocksObjects, c.removeKVRocksObject},
+		{aprv1.TypeMinio,
+			func() (map[string][]string, error) { return c.listMinioObjects(appNamespaces) },
+			c.removeMinioObject},
+		{aprv1.TypeRabbitMQ,
+			func() (map[string][]string, error) { return c.listRabbitMQObjects(appNamespaces) },
+			c.removeRabbitMQObject},
+		{aprv1.TypeElasticsearch,
+			func() (map[string][]string, error) { return c.listElasticsearchObjects(appNamespaces) },
+			c.removeElasticsearchObject},
+		{aprv1.TypeMariaDB,
+			func() (map[string][]string, error) { return c.listMysqlObjects(mariadbNamespace, "mariadb") },
+			func(kind, name string) error { return c.removeMysqlObject(mariadbNamespace, "mariadb", name) }},
+		{aprv1.TypeMysql,
+			func() (map[string][]string, error) { return c.listMysqlObjects(mysqlNamespace, "mysql") },
+			func(kind, name string) error { return c.removeMysqlObject(mysqlNamespace, "mysql", name) }},
+	}
+}
+
+func (c *controller) listPGObjects() (map[string][]string, error) {
+	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	masterHost := c.pgNodeHost(sts, 0)
+	masterClient, err := postgres.NewClientBuidler(adminUser, adminPwd, masterHost, postgres.PG_PORT).Build()
+	if err != nil {
+		klog.Error("connect to master error, ", err, ", ", masterHost)
+		return nil, err
+	}
+	defer masterClient.Close()
+
+	dbs, err := masterClient.ListDatabases(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := masterClient.ListLoginRoles(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string][]string)
+	for _, db := range dbs {
+		if db != "postgres" && strings.Contains(db, "_") {
+			objects[orphans.KindDatabase] = append(objects[orphans.KindDatabase], db)
+		}
+	}
+
+	for _, role := range roles {
+		if role != adminUser {
+			objects[orphans.KindRole] = append(objects[orphans.KindRole], role)
+		}
+	}
+
+	return objects, nil
+}
+
+func (c *controller) removePGObject(kind, name string) error {
+	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
+	if err != nil {
+		return err
+	}
+
+	for index := int32(0); index < *sts.Spec.Replicas; index++ {
+		nodeHost := c.pgNodeHost(sts, index)
+		if err = func() error {
+			nodeClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).Build()
+			if err != nil {
+				klog.Error("cannot connect to host, ", err, ", ", nodeHost)
+				return err
+			}
+			defer nodeClient.Close()
+
+			if kind == orphans.KindRole {
+				return nodeClient.DropRole(c.ctx, name, "")
+			}
+
+			return nodeClient.DropDatabase(c.ctx, name)
+		}(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *controller) listMongoObjects() (map[string][]string, error) {
+	client, err := c.connectToCluster(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close(c.ctx)
+
+	dbs, err := client.ListDatabaseNames(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string][]string)
+	for _, db := range dbs {
+		if strings.Contains(db, "_") {
+			objects[orphans.KindDatabase] = append(objects[orphans.KindDatabase], db)
+		}
+	}
+
+	return objects, nil
+}
+
+func (c *controller) removeMongoObject(_, name string) error {
+	client, err := c.connectToCluster(nil)
+	if err != nil {
+		return err
+	}
+	defer client.Close(c.ctx)
+
+	return client.DropUserAndDatabase(c.ctx, "", []aprv1.MongoDatabase{{Name: name}})
+}
+
+func (c *controller) kvrocksCluster() (*aprv1.RedixCluster, error) {
+	clusters, err := c.aprClientSet.AprV1alpha1().RedixClusters(constants.PlatformNamespace).List(c.ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Error("find redix cluster error, ", err)
+		return nil, err
+	}
+
+	if len(clusters.Items) == 0 || clusters.Items[0].Spec.Type != aprv1.KVRocks {
+		return nil, nil
+	}
+
+	return &clusters.Items[0], nil
+}
+
+func (c *controller) listKVRocksObjects() (map[string][]string, error) {
+	cluster, err := c.kvrocksCluster()
+	if err != nil || cluster == nil {
+		return nil, err
+	}
+
+	cli, err := kvrocks.GetKVRocksClient(c.ctx, c.k8sClientSet, cluster)
+	if err != nil {
+		klog.Error("get kvrocks client error, ", err)
+		return nil, err
+	}
+	defer cli.Close()
+
+	namespaces, err := cli.ListNamespace(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string][]string)
+	for _, ns := range namespaces {
+		if strings.Contains(ns.Name, "_") {
+			objects[orphans.KindNamespace] = append(objects[orphans.KindNamespace], ns.Name)
+		}
+	}
+
+	return objects, nil
+}
+
+func (c *controller) removeKVRocksObject(_, name string) error {
+	cluster, err := c.kvrocksCluster()
+	if err != nil || cluster == nil {
+		return err
+	}
+
+	cli, err := kvrocks.GetKVRocksClient(c.ctx, c.k8sClientSet, cluster)
+	if err != nil {
+		klog.Error("get kvrocks client error, ", err)
+		return err
+	}
+	defer cli.Close()
+
+	return cli.DeleteNamespace(c.ctx, name)
+}
+
+// listMinioObjects lists the buckets of the apps, and the users with the policy created for the request user
+func (c *controller) listMinioObjects(appNamespaces []string) (map[string][]string, error) {
+	minioClient, madminClient, err := c.minioAdminClients()
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, err := minioClient.ListBuckets(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := madminClient.ListUsers(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string][]string)
+	for _, b := range buckets {
+		if appPrefixed(b.Name, appNamespaces, "-") {
+			objects[orphans.KindBucket] = append(objects[orphans.KindBucket], b.Name)
+		}
+	}
+
+	for user, info := range users {
+		if info.PolicyName == fmt.Sprintf("%s-policy", user) {
+			objects[orphans.KindUser] = append(objects[orphans.KindUser], user)
+		}
+	}
+
+	return objects, nil
+}
+
+func (c *controller) removeMinioObject(kind, name string) error {
+	minioClient, madminClient, err := c.minioAdminClients()
+	if err != nil {
+		return err
+	}
+
+	if kind == orphans.KindUser {
+		return madminClient.RemoveUser(c.ctx, name)
+	}
+
+	if err = c.removeAllObjectsInBucket(c.ctx, minioClient, name); err != nil {
+		return err
+	}
+
+	return minioClient.RemoveBucket(c.ctx, name)
+}
+
+func (c *controller) minioAdminClients() (*minio.Client, *madmin.AdminClient, error) {
+	adminUser, adminPassword, err := c.findMinioAdminCredentials("")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	endpoint, err := c.getMinioEndpoint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.newMinioClients(endpoint, adminUser, adminPassword)
+}
+
+// listRabbitMQObjects lists the vhosts of the apps, and the users not administrator with permissions
+// only in the vhosts of the apps
+func (c *controller) listRabbitMQObjects(appNamespaces []string) (map[string][]string, error) {
+	client, err := c.newRabbitMQClient()
+	if err != nil {
+		return nil, err
+	}
+
+	vhosts, err := client.ListVhosts()
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := client.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string][]string)
+	for _, v := range vhosts {
+		if appPrefixed(v.Name, appNamespaces, "-") {
+			objects[orphans.KindVhost] = append(objects[orphans.KindVhost], v.Name)
+		}
+	}
+
+	for _, u := range users {
+		isAdmin := false
+		for _, tag := range u.Tags {
+			if tag == "administrator" {
+				isAdmin = true
+			}
+		}
+
+		if isAdmin {
+			continue
+		}
+
+		permissions, err := client.ListPermissionsOf(u.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		appUser := len(permissions) > 0
+		for _, p := range permissions {
+			if !appPrefixed(p.Vhost, appNamespaces, "-") {
+				appUser = false
+			}
+		}
+
+		if appUser {
+			objects[orphans.KindUser] = append(objects[orphans.KindUser], u.Name)
+		}
+	}
+
+	return objects, nil
+}
+
+func (c *controller) removeRabbitMQObject(kind, name string) error {
+	client, err := c.newRabbitMQClient()
+	if err != nil {
+		return err
+	}
+
+	if kind == orphans.KindUser {
+		return c.deleteRabbitUser(client, name)
+	}
+
+	return c.deleteRabbitVhost(client, name)
+}
+
+// listElasticsearchObjects lists the indexes of the apps, and the roles of the request users
+func (c *controller) listElasticsearchObjects(appNamespaces []string) (map[string][]string, error) {
+	adminUser, adminPassword, err := wes.FindElasticsearchAdminUser(c.ctx, c.k8sClientSet, elasticNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	es, err := c.newESClient(c.getElasticsearchEndpoint(), adminUser, adminPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	indices, err := esListIndices(es, "*")
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := esapi.SecurityGetRoleRequest{}.Do(c.ctx, es)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("get roles failed: %s", res.String())
+	}
+
+	var roles map[string]struct {
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&roles); err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string][]string)
+	for _, index := range indices {
+		if appPrefixed(index, appNamespaces, "-", "_") {
+			objects[orphans.KindIndex] = append(objects[orphans.KindIndex], index)
+		}
+	}
+
+	for name, role := range roles {
+		if reserved, _ := role.Metadata["_reserved"].(bool); !reserved && strings.HasPrefix(name, esRoleName("")) {
+			objects[orphans.KindRole] = append(objects[orphans.KindRole], name)
+		}
+	}
+
+	return objects, nil
+}
+
+func (c *controller) removeElasticsearchObject(kind, name string) error {
+	adminUser, adminPassword, err := wes.FindElasticsearchAdminUser(c.ctx, c.k8sClientSet, elasticNamespace)
+	if err != nil {
+		return err
+	}
+
+	es, err := c.newESClient(c.getElasticsearchEndpoint(), adminUser, adminPassword)
+	if err != nil {
+		return err
+	}
+
+	if kind == orphans.KindRole {
+		return esDeleteRole(es, name)
+	}
+
+	return esDeleteIndex(es, name)
+}
+
+func (c *controller) mysqlAdminConfig(namespace, name string) (*mysqlRequest, error) {
+	r := &mysqlRequest{}
+	var err error
+	if namespace == mariadbNamespace {
+		r.adminUser, r.adminPassword, err = wmariadb.FindMariaDBAdminUser(c.ctx, c.k8sClientSet, namespace)
+		r.host = c.getMariaDBHost()
+	} else {
+		r.adminUser, r.adminPassword, err = wmysql.FindMysqlAdminUser(c.ctx, c.k8sClientSet, namespace)
+		r.host = c.getMysqlHost()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.tlsConfig, err = c.kbClusterTLSConfig(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (c *controller) listMysqlObjects(namespace, name string) (map[string][]string, error) {
+	r, err := c.mysqlAdminConfig(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := mysql.NewClientBuilder(r.adminUser, r.adminPassword, r.host).WithTLSConfig(r.tlsConfig).Build()
+	if err != nil {
+		klog.Errorf("failed to connect to %s %v", r.host, err)
+		return nil, err
+	}
+	defer db.Close()
+
+	dbs, err := db.ListDatabases(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string][]string)
+	for _, d := range dbs {
+		if strings.Contains(d, "_") {
+			objects[orphans.KindDatabase] = append(objects[orphans.KindDatabase], d)
+		}
+	}
+
+	return objects, nil
+}
+
+func (c *controller) removeMysqlObject(namespace, name, database string) error {
+	r, err := c.mysqlAdminConfig(namespace, name)
+	if err != nil {
+		return err
+	}
+
+	db, err := mysql.NewClientBuilder(r.adminUser, r.adminPassword, r.host).WithTLSConfig(r.tlsConfig).Build()
+	if err != nil {
+		klog.Errorf("failed to connect to %s %v", r.host, err)
+		return err
+	}
+	defer db.Close()
+
+	return db.DropDatabase(c.ctx, database)
+}
+
+func esRoleName(user string) string {
+	return fmt.Sprintf("role-%s", user)
+}
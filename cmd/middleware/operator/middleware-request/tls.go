@@ -0,0 +1,168 @@
+package middlewarerequest
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"os"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/certs"
+	"bytetrade.io/web3os/tapr/pkg/constants"
+	wes "bytetrade.io/web3os/tapr/pkg/workload/elasticsearch"
+	wmariadb "bytetrade.io/web3os/tapr/pkg/workload/mariadb"
+	wminio "bytetrade.io/web3os/tapr/pkg/workload/minio"
+	wmongodb "bytetrade.io/web3os/tapr/pkg/workload/mongodb"
+	wmysql "bytetrade.io/web3os/tapr/pkg/workload/mysql"
+	workload_nats "bytetrade.io/web3os/tapr/pkg/workload/nats"
+	wrabbit "bytetrade.io/web3os/tapr/pkg/workload/rabbitmq"
+	wutils "bytetrade.io/web3os/tapr/pkg/workload/utils"
+
+	kbappsv1 "github.com/apecloud/kubeblocks/apis/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	certificateCheckInterval = 10 * time.Minute
+
+	// changing the annotation of the pods restarts the components to load the renewed certificates
+	tlsRenewedAnnotation = "apr.bytetrade.io/tls-renewed-at"
+
+	// TLSAnnotation opts the kubeblocks cluster in to serve tls, with the value "enabled".
+	// MinIO and Elasticsearch serve https only once tls is enabled, so set it after their clients switched to tls
+	TLSAnnotation = "apr.bytetrade.io/tls"
+	tlsEnabled    = "enabled"
+)
+
+type listKBClusters func(ctx context.Context, ctrlClient client.Client, namespace string) ([]kbappsv1.Cluster, error)
+
+// kbClusterLister lists the kubeblocks clusters of the middleware
+type kbClusterLister struct {
+	middleware aprv1.MiddlewareType
+	list       listKBClusters
+}
+
+var kbClusterListers = map[string]kbClusterLister{
+	mysqlNamespace:       {aprv1.TypeMysql, wmysql.ListMysqlClusters},
+	mariadbNamespace:     {aprv1.TypeMariaDB, wmariadb.ListMariadbClusters},
+	"mongodb-middleware": {aprv1.TypeMongoDB, wmongodb.ListMongoClusters},
+	"minio-middleware":   {aprv1.TypeMinio, wminio.ListMinioClusters},
+	rabbitMQNs:           {aprv1.TypeRabbitMQ, wrabbit.ListRabbitMQClusters},
+	elasticNamespace:     {aprv1.TypeElasticsearch, wes.ListElasticsearchClusters},
+}
+
+// checkCertificates issues and renews the certificates of nats and the kubeblocks clusters opted in to tls,
+// and turns on tls of the servers. The backend is locked from the reconciliations while its tls is changed
+func (c *controller) checkCertificates() {
+	natsLock := c.backendLock(aprv1.TypeNats)
+	natsLock.Lock()
+	if err := c.checkNatsCertificate(); err != nil {
+		klog.Error("check nats certificate error, ", err)
+	}
+	natsLock.Unlock()
+
+	for namespace, lister := range kbClusterListers {
+		backend := c.backendLock(lister.middleware)
+		backend.Lock()
+
+		clusters, err := lister.list(c.ctx, c.ctrlClient, namespace)
+		if err != nil {
+			klog.Error("list clusters error, ", err, ", ", namespace)
+		}
+
+		for i := range clusters {
+			// the clusters served tls already keep renewing the certificates
+			if clusters[i].Annotations[TLSAnnotation] != tlsEnabled && !wutils.IsKBClusterTLSEnabled(&clusters[i]) {
+				continue
+			}
+
+			if err = c.checkKBClusterCertificate(&clusters[i]); err != nil {
+				klog.Error("check cluster certificate error, ", err, ", ", namespace, "/", clusters[i].Name)
+			}
+		}
+
+		backend.Unlock()
+	}
+}
+
+func (c *controller) checkNatsCertificate() error {
+	// the config is written by the operator, nats is not installed if not found
+	if _, err := os.Stat(workload_nats.ConfPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	secret, _, err := certs.EnsureCertificates(c.ctx, c.k8sClientSet, constants.PlatformNamespace,
+		workload_nats.NatsCertName, workload_nats.NatsDNSNames(constants.PlatformNamespace))
+	if err != nil {
+		return err
+	}
+
+	return workload_nats.EnableTLS(secret)
+}
+
+func (c *controller) checkKBClusterCertificate(cluster *kbappsv1.Cluster) error {
+	secret, renewed, err := certs.EnsureCertificates(c.ctx, c.k8sClientSet, cluster.Namespace,
+		cluster.Name, wutils.KBClusterDNSNames(cluster))
+	if err != nil {
+		return err
+	}
+
+	changed := wutils.EnableKBClusterTLS(cluster, secret.Name)
+	if renewed && !changed {
+		renewedAt := time.Now().UTC().Format(time.RFC3339)
+		for i := range cluster.Spec.ComponentSpecs {
+			comp := &cluster.Spec.ComponentSpecs[i]
+			if comp.Annotations == nil {
+				comp.Annotations = make(map[string]string)
+			}
+			comp.Annotations[tlsRenewedAnnotation] = renewedAt
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	klog.Info("update cluster tls, ", cluster.Namespace, "/", cluster.Name)
+	return c.ctrlClient.Update(c.ctx, cluster)
+}
+
+// kbClusterTLSConfig returns the tls config to connect to the kubeblocks cluster, or nil if
+// the cluster does not serve tls yet
+func (c *controller) kbClusterTLSConfig(namespace, name string) (*tls.Config, error) {
+	var cluster kbappsv1.Cluster
+	err := c.ctrlClient.Get(c.ctx, types.NamespacedName{Namespace: namespace, Name: name}, &cluster)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		klog.Error("get cluster error, ", err, ", ", namespace, "/", name)
+		return nil, err
+	}
+
+	if !wutils.IsKBClusterTLSEnabled(&cluster) || cluster.Status.Phase != kbappsv1.RunningClusterPhase {
+		return nil, nil
+	}
+
+	bundle, err := certs.GetCABundle(c.ctx, c.k8sClientSet, namespace, name)
+	if err != nil {
+		klog.Error("get cluster ca bundle error, ", err, ", ", namespace, "/", name)
+		return nil, err
+	}
+
+	return certs.NewClientTLSConfig(bundle)
+}
+
+func newTLSTransport(tlsConfig *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport
+}
@@ -0,0 +1,340 @@
+package middlewarerequest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/generated/listers/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/usage"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+	wes "bytetrade.io/web3os/tapr/pkg/workload/elasticsearch"
+	"bytetrade.io/web3os/tapr/pkg/workload/kvrocks"
+	wminio "bytetrade.io/web3os/tapr/pkg/workload/minio"
+	wrabbit "bytetrade.io/web3os/tapr/pkg/workload/rabbitmq"
+	"bytetrade.io/web3os/tapr/pkg/workload/zinc"
+
+	elastic "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const usageCollectInterval = 5 * time.Minute
+
+// Usage returns the store of the usage collected from the backends
+func (c *controller) Usage() *usage.Store {
+	return c.usage
+}
+
+// RunUsage collects the usage of the requests in the lister periodically until the context is done.
+// It runs on every replica to serve the usage api, whether or not the replica is leading the controllers
+func (c *controller) RunUsage(lister v1alpha1.MiddlewareRequestLister, synced cache.InformerSynced) {
+	if ok := cache.WaitForCacheSync(c.ctx.Done(), synced); !ok {
+		klog.Error("failed to wait for caches to sync")
+		return
+	}
+
+	wait.Until(func() { c.collectUsage(lister) }, usageCollectInterval, c.ctx.Done())
+}
+
+// collectUsage collects the usage of the objects owned by the requests from the backends
+func (c *controller) collectUsage(lister v1alpha1.MiddlewareRequestLister) {
+	requests, err := lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list middleware requests error, ", err)
+		return
+	}
+
+	var all []*usage.RequestUsage
+	for _, req := range requests {
+		u := &usage.RequestUsage{
+			Namespace:    req.Namespace,
+			Name:         req.Name,
+			App:          req.Spec.App,
+			AppNamespace: req.Spec.AppNamespace,
+			Middleware:   req.Spec.Middleware,
+			CollectTime:  metav1.Now(),
+		}
+
+		var objects []usage.Object
+		switch req.Spec.Middleware {
+		case aprv1.TypePostgreSQL:
+			objects, err = c.pgUsage(req)
+		case aprv1.TypeMongoDB:
+			objects, err = c.mdbUsage(req)
+		case aprv1.TypeRedis:
+			objects, err = c.kvrocksUsage(req)
+		case aprv1.TypeMinio:
+			objects, err = c.minioUsage(req)
+		case aprv1.TypeRabbitMQ:
+			objects, err = c.rabbitMQUsage(req)
+		case aprv1.TypeElasticsearch:
+			objects, err = c.elasticsearchUsage(req)
+		case aprv1.TypeZinc:
+			objects, err = c.zincUsage(req)
+		default:
+			continue
+		}
+
+		if err != nil {
+			klog.Warning("collect usage error, ", err, ", ", req.Namespace, "/", req.Name)
+			u.Error = err.Error()
+		}
+
+		u.Objects = objects
+		all = append(all, u)
+	}
+
+	c.usage.Replace(all)
+}
+
+func (c *controller) pgUsage(req *aprv1.MiddlewareRequest) ([]usage.Object, error) {
+	sts, adminUser, adminPwd, err := c.findClusterWorkloadAndAdminuserAndPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	// the distributed tables are sharded to all nodes
+	objects := make(map[string]*usage.Object)
+	for index := int32(0); index < *sts.Spec.Replicas; index++ {
+		nodeHost := c.pgNodeHost(sts, index)
+		if err = func() error {
+			nodeClient, err := postgres.NewClientBuidler(adminUser, adminPwd, nodeHost, postgres.PG_PORT).Build()
+			if err != nil {
+				klog.Error("connect to node error, ", err, ", ", nodeHost)
+				return err
+			}
+			defer nodeClient.Close()
+
+			for _, db := range req.Spec.PostgreSQL.Databases {
+				if !db.IsDistributed() && index != 0 {
+					continue
+				}
+
+				dbRealName := citus.GetDatabaseName(req.Spec.AppNamespace, db.Name)
+				size, err := nodeClient.DatabaseSize(c.ctx, dbRealName)
+				if err != nil {
+					return err
+				}
+
+				connections, err := nodeClient.ConnectionCount(c.ctx, dbRealName)
+				if err != nil {
+					return err
+				}
+
+				o, ok := objects[dbRealName]
+				if !ok {
+					o = &usage.Object{Name: dbRealName, Metrics: make(map[string]int64)}
+					objects[dbRealName] = o
+				}
+				o.Metrics[usage.MetricSizeBytes] += size
+				o.Metrics[usage.MetricConnections] += connections
+			}
+
+			return nil
+		}(); err != nil {
+			return nil, err
+		}
+	}
+
+	var ret []usage.Object
+	for _, db := range req.Spec.PostgreSQL.Databases {
+		if o, ok := objects[citus.GetDatabaseName(req.Spec.AppNamespace, db.Name)]; ok {
+			ret = append(ret, *o)
+		}
+	}
+
+	return ret, nil
+}
+
+func (c *controller) mdbUsage(req *aprv1.MiddlewareRequest) ([]usage.Object, error) {
+	client, err := c.connectToCluster(req)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close(c.ctx)
+
+	var objects []usage.Object
+	for _, db := range dbRealNames(req.Spec.AppNamespace, req.Spec.MongoDB.Databases) {
+		size, err := client.DatabaseStorageSize(c.ctx, db.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, usage.Object{Name: db.Name, Metrics: map[string]int64{usage.MetricSizeBytes: size}})
+	}
+
+	return objects, nil
+}
+
+// kvrocksUsage collects the keys of the namespace. The disk usage is not collected, the namespaces share
+// the column families of one rocksdb instance and kvrocks only reports the disk usage of the whole instance
+func (c *controller) kvrocksUsage(req *aprv1.MiddlewareRequest) ([]usage.Object, error) {
+	cluster, err := c.kvrocksCluster()
+	if err != nil || cluster == nil {
+		return nil, err
+	}
+
+	cli, err := kvrocks.GetKVRocksClient(c.ctx, c.k8sClientSet, cluster)
+	if err != nil {
+		klog.Error("get kvrocks client error, ", err)
+		return nil, err
+	}
+	defer cli.Close()
+
+	name := GetKVRocksNamespaceName(req.Namespace, req.Spec.Redis.Namespace)
+	ns, err := cli.GetNamespace(c.ctx, name)
+	if err != nil || ns == nil {
+		return nil, err
+	}
+
+	keys, err := cli.NamespaceKeys(c.ctx, ns.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return []usage.Object{{Name: name, Metrics: map[string]int64{usage.MetricKeys: keys}}}, nil
+}
+
+func (c *controller) minioUsage(req *aprv1.MiddlewareRequest) ([]usage.Object, error) {
+	_, madminClient, err := c.minioAdminClients()
+	if err != nil {
+		return nil, err
+	}
+
+	// the data usage is updated by the scanner of minio periodically
+	info, err := madminClient.DataUsageInfo(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []usage.Object
+	for _, b := range req.Spec.Minio.Buckets {
+		bucket := wminio.GetBucketName(req.Spec.AppNamespace, b.Name)
+		bucketUsage := info.BucketsUsage[bucket]
+		objects = append(objects, usage.Object{Name: bucket, Metrics: map[string]int64{
+			usage.MetricSizeBytes: int64(bucketUsage.Size),
+			usage.MetricObjects:   int64(bucketUsage.ObjectsCount),
+		}})
+	}
+
+	return objects, nil
+}
+
+func (c *controller) rabbitMQUsage(req *aprv1.MiddlewareRequest) ([]usage.Object, error) {
+	client, err := c.newRabbitMQClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []usage.Object
+	for _, v := range req.Spec.RabbitMQ.Vhosts {
+		vhost := wrabbit.GetVhostName(req.Spec.AppNamespace, v.Name)
+		queues, err := client.ListQueuesIn(vhost)
+		if err != nil {
+			return nil, err
+		}
+
+		var messages int64
+		for _, q := range queues {
+			messages += int64(q.Messages)
+		}
+
+		objects = append(objects, usage.Object{Name: vhost, Metrics: map[string]int64{usage.MetricMessages: messages}})
+	}
+
+	return objects, nil
+}
+
+func (c *controller) elasticsearchUsage(req *aprv1.MiddlewareRequest) ([]usage.Object, error) {
+	adminUser, adminPassword, err := wes.FindElasticsearchAdminUser(c.ctx, c.k8sClientSet, elasticNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	es, err := c.newESClient(c.getElasticsearchEndpoint(), adminUser, adminPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []usage.Object
+	for _, idx := range req.Spec.Elasticsearch.Indexes {
+		index := wes.GetIndexName(req.Spec.AppNamespace, idx.Name)
+		docs, size, err := esIndexStats(c.ctx, es, index)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, usage.Object{Name: index, Metrics: map[string]int64{
+			usage.MetricDocuments: docs,
+			usage.MetricSizeBytes: size,
+		}})
+	}
+
+	return objects, nil
+}
+
+func (c *controller) zincUsage(req *aprv1.MiddlewareRequest) ([]usage.Object, error) {
+	pwd, err := req.Spec.Zinc.Password.GetVarValue(c.ctx, c.k8sClientSet, req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []usage.Object
+	for _, idx := range req.Spec.Zinc.Indexes {
+		index := zinc.GetIndexName(req.Spec.AppNamespace, idx.Name)
+		stats, err := zinc.GetIndexStats(req.Spec.Zinc.User, pwd, index)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, usage.Object{Name: index, Metrics: map[string]int64{
+			usage.MetricDocuments: stats.DocNum,
+			usage.MetricSizeBytes: stats.StorageSize,
+		}})
+	}
+
+	return objects, nil
+}
+
+// esIndexStats returns the document count and the store size in bytes of the index, zero if it does not exist
+func esIndexStats(ctx context.Context, es *elastic.Client, index string) (docs, size int64, err error) {
+	req := esapi.CatIndicesRequest{Index: []string{index}, Format: "json", Bytes: "b"}
+	res, err := req.Do(ctx, es)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return 0, 0, nil
+	}
+	if res.IsError() {
+		return 0, 0, fmt.Errorf("cat indices failed: %s", res.String())
+	}
+
+	var payload []struct {
+		DocsCount string `json:"docs.count"`
+		StoreSize string `json:"store.size"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		return 0, 0, err
+	}
+
+	for _, it := range payload {
+		d, _ := strconv.ParseInt(it.DocsCount, 10, 64)
+		s, _ := strconv.ParseInt(it.StoreSize, 10, 64)
+		docs += d
+		size += s
+	}
+
+	return docs, size, nil
+}
@@ -112,7 +112,7 @@ func (c *controller) handler(action Action, obj interface{}) error {
 		jobEnv := []corev1.EnvVar{
 			{
 				Name:  "PG_HOST",
-				Value: "citus-0.citus-headless",
+				Value: citus.GetCoordinatorHost(cluster),
 			},
 			{
 				Name:  "PG_PORT",
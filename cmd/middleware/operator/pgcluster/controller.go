@@ -2,6 +2,7 @@ package pgcluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
 	informers "bytetrade.io/web3os/tapr/pkg/generated/informers/externalversions"
 	"bytetrade.io/web3os/tapr/pkg/generated/listers/apr/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
@@ -41,6 +43,10 @@ type controller struct {
 	cancel               context.CancelFunc
 	requestLister        v1alpha1.MiddlewareRequestLister
 	notifyClusterCreated func(cluster *aprv1.PGCluster)
+
+	// the time the coordinator is found not ready, only accessed by the coordinator checking
+	coordinatorDownSince map[string]time.Time
+	certificateRenewed   map[string]bool
 }
 
 type enqueueObj struct {
@@ -64,11 +70,21 @@ func NewController(kubeConfig *rest.Config, mainCtx context.Context, mrLister v1
 		synced:               informer.Informer().HasSynced,
 		workqueue:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "pgcluster"),
 		notifyClusterCreated: notifyFn,
+		coordinatorDownSince: make(map[string]time.Time),
+		certificateRenewed:   make(map[string]bool),
 	}
 
 	_, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: ctrlr.handleAddObject,
 		UpdateFunc: func(old, new interface{}) {
+			// ignore the status updating
+			oldCluster, ok1 := old.(*aprv1.PGCluster)
+			newCluster, ok2 := new.(*aprv1.PGCluster)
+			if ok1 && ok2 && equality.Semantic.DeepEqual(oldCluster.Spec, newCluster.Spec) &&
+				equality.Semantic.DeepEqual(oldCluster.ObjectMeta.Annotations, newCluster.ObjectMeta.Annotations) {
+				return
+			}
+
 			ctrlr.handleUpdateObject(new)
 		},
 		DeleteFunc: ctrlr.handleDeleteObject,
@@ -136,6 +152,10 @@ func (c *controller) Run(workers int) error {
 		go wait.Until(c.runWorker, time.Second, c.ctx.Done())
 	}
 
+	// check the replication and fail over the coordinators
+	go wait.Until(c.checkCoordinators, pgCoordinatorCheckInterval, c.ctx.Done())
+	go wait.Until(c.checkCertificates, pgCertificateCheckInterval, c.ctx.Done())
+
 	klog.Info("Started workers")
 	<-c.ctx.Done()
 	klog.Info("Shutting down workers, ", controllerAgentName)
@@ -172,7 +192,15 @@ func (c *controller) processNextWorkItem() bool {
 
 		// Run the syncHandler, passing it the namespace/name string of the
 		// Foo resource to be synced.
-		if err := c.syncHandler(eobj); err != nil {
+		err := c.syncHandler(eobj)
+		if errors.Is(err, errUpgradeInProgress) {
+			// check the progress of the upgrade later, without backing off
+			c.workqueue.Forget(obj)
+			c.workqueue.AddAfter(eobj, upgradeRequeueInterval)
+			return nil
+		}
+
+		if err != nil {
 			// Put the item back on the workqueue to handle any transient errors.
 			c.workqueue.AddRateLimited(eobj)
 			return fmt.Errorf("error syncing '%v': %s, requeuing", eobj, err.Error())
@@ -52,6 +52,11 @@ func (c *controller) handler(action Action, obj interface{}) error {
 		return nil
 	case apierrors.IsNotFound(err):
 		// create cluster:
+		if _, _, err = citus.EnsurePGClusterCertificates(c.ctx, c.k8sClientSet, cluster.Namespace); err != nil {
+			klog.Error("issue pg cluster certificates error, ", err)
+			return err
+		}
+
 		currentSts, err = citus.CreatePGClusterForUser(c.ctx, c.k8sClientSet, c.aprClientSet, c.lister, currentCluster.Spec.Owner, currentCluster)
 		if err != nil {
 			klog.Error("create pg cluster error, ", err)
@@ -171,7 +176,7 @@ func (c *controller) handler(action Action, obj interface{}) error {
 		}
 
 		effected, err := citus.ScalePGClusterNodes(c.ctx, c.k8sClientSet, currentSts.Namespace,
-			currentCluster.Spec.Replicas, currentCluster.Spec.AdminUser, pwd)
+			currentCluster.Spec.Replicas, currentCluster.Spec.AdminUser, pwd, citus.HasPGStandby(currentCluster))
 		if err != nil {
 			return err
 		}
@@ -182,7 +187,7 @@ func (c *controller) handler(action Action, obj interface{}) error {
 			// find out all distributed db request
 			// connect to the master node
 			masterClientBuilder := postgres.NewClientBuidler(currentCluster.Spec.AdminUser, pwd,
-				postgres.PG_MASTER_HOST+"."+currentSts.Namespace, postgres.PG_PORT)
+				citus.GetCoordinatorHost(currentCluster), postgres.PG_PORT)
 
 			requests, err := c.requestLister.MiddlewareRequests(currentSts.Namespace).List(labels.Everything())
 			if err != nil {
@@ -293,5 +298,77 @@ func (c *controller) handler(action Action, obj interface{}) error {
 		}
 	}
 
+	// upgrade the cluster if the image is changed
+	if err = c.upgradeCluster(currentCluster); err != nil {
+		if !errors.Is(err, errUpgradeInProgress) {
+			klog.Error("upgrade pg cluster error, ", err)
+		}
+		return err
+	}
+
+	// apply the server parameters, resources and scheduling
+	if err = c.applyClusterConfig(currentCluster); err != nil {
+		return err
+	}
+
+	// deploy or remove the standby of the coordinator
+	if err = c.reconcileStandby(currentCluster); err != nil {
+		klog.Error("reconcile pg standby error, ", err)
+		return err
+	}
+
+	// deploy or remove the pooler
+	err = citus.ReconcilePGPooler(c.ctx, c.k8sClientSet, c.aprClientSet, c.requestLister, currentCluster)
+	if err != nil {
+		klog.Error("reconcile pg pooler error, ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *controller) reconcileStandby(cluster *aprv1.PGCluster) error {
+	pwd, err := citus.GetPGClusterDefinedPassword(c.ctx, c.k8sClientSet, cluster.Namespace, cluster)
+	if err != nil {
+		return err
+	}
+
+	return citus.ReconcilePGStandby(c.ctx, c.k8sClientSet, cluster, cluster.Spec.AdminUser, pwd)
+}
+
+func (c *controller) applyClusterConfig(cluster *aprv1.PGCluster) error {
+	// the replicas may be scaled
+	sts, err := c.k8sClientSet.AppsV1().StatefulSets(cluster.Namespace).Get(c.ctx, citus.PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	pwd, err := citus.GetPGClusterDefinedPassword(c.ctx, c.k8sClientSet, sts.Namespace, cluster)
+	if err != nil {
+		return err
+	}
+
+	restart, err := citus.ApplyPGClusterConfig(c.ctx, sts, cluster, cluster.Spec.AdminUser, pwd)
+	if err != nil {
+		klog.Error("apply pg cluster config error, ", err)
+		return err
+	}
+
+	// the certificates are mounted into the nodes
+	if _, _, err = citus.EnsurePGClusterCertificates(c.ctx, c.k8sClientSet, sts.Namespace); err != nil {
+		klog.Error("issue pg cluster certificates error, ", err)
+		return err
+	}
+
+	updated, err := citus.UpdatePGClusterWorkload(c.ctx, c.k8sClientSet, sts, cluster)
+	if err != nil {
+		return err
+	}
+
+	if restart && !updated {
+		// the workload update restarts the nodes already
+		return citus.RestartPGCluster(c.ctx, c.k8sClientSet, sts, cluster.Generation)
+	}
+
 	return nil
 }
@@ -0,0 +1,183 @@
+package pgcluster
+
+import (
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const (
+	pgCoordinatorCheckInterval = 10 * time.Second
+	defaultFailoverTimeout     = 60 * time.Second
+)
+
+// checkCoordinators updates the replication state of the clusters with the standby,
+// and fails over to the standby if the coordinator is not ready for the failover timeout
+func (c *controller) checkCoordinators() {
+	clusters, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list pg clusters error, ", err)
+		return
+	}
+
+	for _, cluster := range clusters {
+		if cluster.Spec.Standby == nil && !citus.IsCoordinatorFailedOver(cluster) {
+			continue
+		}
+
+		if err = c.checkCoordinator(cluster.DeepCopy()); err != nil {
+			klog.Error("check pg coordinator error, ", err, ", ", cluster.Namespace, "/", cluster.Name)
+		}
+	}
+}
+
+func (c *controller) checkCoordinator(cluster *aprv1.PGCluster) error {
+	admin, pwd, err := citus.GetPGClusterAdminUserAndPassword(c.ctx, c.aprClientSet, c.k8sClientSet, cluster.Namespace)
+	if err != nil {
+		return err
+	}
+
+	status := cluster.Status.Coordinator.DeepCopy()
+	if status == nil {
+		status = &aprv1.PGCoordinatorStatus{Primary: citus.GetCoordinatorHost(cluster)}
+	}
+
+	oldCoordinator := citus.PGClusterName + "-0"
+	if citus.IsCoordinatorFailedOver(cluster) {
+		// keep the old coordinator from accepting writes if it is back
+		if c.isPodReady(cluster.Namespace, oldCoordinator) {
+			host := citus.GetNodeHost(nil, cluster.Namespace, 0)
+			if err = citus.FencePGNode(c.ctx, host, admin, pwd); err != nil {
+				klog.Error("fence the old pg coordinator error, ", err, ", ", host)
+			}
+		}
+
+		return nil
+	}
+
+	key := cluster.Namespace + "/" + cluster.Name
+	if !c.isPodReady(cluster.Namespace, oldCoordinator) {
+		since, ok := c.coordinatorDownSince[key]
+		if !ok {
+			since = time.Now()
+			c.coordinatorDownSince[key] = since
+		}
+
+		timeout := defaultFailoverTimeout
+		if cluster.Spec.Standby.FailoverTimeoutSeconds > 0 {
+			timeout = time.Duration(cluster.Spec.Standby.FailoverTimeoutSeconds) * time.Second
+		}
+
+		if cluster.Spec.Standby.AutoFailover && time.Since(since) >= timeout {
+			if !c.isPodReady(cluster.Namespace, citus.PGStandbyName+"-0") {
+				klog.Warning("pg coordinator is down, but the standby is not ready to fail over, ", key)
+				return nil
+			}
+
+			return c.failoverCoordinator(cluster, status, admin, pwd)
+		}
+
+		return nil
+	}
+	delete(c.coordinatorDownSince, key)
+
+	masterHost := citus.GetCoordinatorHost(cluster)
+	masterClient, err := postgres.NewClientBuidler(admin, pwd, masterHost, postgres.PG_PORT).Build()
+	if err != nil {
+		klog.Error("connect to master error, ", err, ", ", masterHost)
+		return err
+	}
+	defer masterClient.Close()
+
+	stat, err := masterClient.ReplicationStat(c.ctx, citus.PGStandbySlotName)
+	if err != nil {
+		klog.Error("get replication stat error, ", err)
+		return err
+	}
+
+	newStatus := status.DeepCopy()
+	newStatus.Standby, newStatus.ReplicationState = "", ""
+	newStatus.ReplicationLagBytes, newStatus.ReplayLagMilliseconds = 0, 0
+	if stat != nil {
+		newStatus.Standby = citus.GetStandbyHost(cluster.Namespace)
+		newStatus.ReplicationState = stat.State
+		newStatus.ReplicationLagBytes = stat.LagBytes
+		newStatus.ReplayLagMilliseconds = stat.ReplayLagMs
+	}
+
+	newStatus.UpdateTime = nil
+	status.UpdateTime = nil
+	if equality.Semantic.DeepEqual(newStatus, status) && cluster.Status.Coordinator != nil {
+		return nil
+	}
+
+	now := metav1.Now()
+	newStatus.UpdateTime = &now
+	return c.updateCoordinatorStatus(cluster, newStatus)
+}
+
+func (c *controller) failoverCoordinator(cluster *aprv1.PGCluster, status *aprv1.PGCoordinatorStatus, admin, pwd string) error {
+	klog.Info("pg coordinator is down, fail over to the standby, ", cluster.Namespace, "/", cluster.Name)
+	if err := citus.PromotePGStandby(c.ctx, c.k8sClientSet, cluster, admin, pwd); err != nil {
+		klog.Error("promote pg standby error, ", err)
+		return err
+	}
+
+	now := metav1.Now()
+	status.Primary = citus.GetStandbyHost(cluster.Namespace)
+	status.Standby = ""
+	status.ReplicationState = ""
+	status.ReplicationLagBytes = 0
+	status.ReplayLagMilliseconds = 0
+	status.LastFailoverTime = &now
+	status.UpdateTime = &now
+	if err := c.updateCoordinatorStatus(cluster, status); err != nil {
+		return err
+	}
+
+	delete(c.coordinatorDownSince, cluster.Namespace+"/"+cluster.Name)
+
+	// reconcile the pooler and the other nodes with the new coordinator
+	c.enqueue(enqueueObj{UPDATE, cluster})
+	return nil
+}
+
+func (c *controller) updateCoordinatorStatus(cluster *aprv1.PGCluster, status *aprv1.PGCoordinatorStatus) error {
+	current, err := c.aprClientSet.AprV1alpha1().PGClusters(cluster.Namespace).Get(c.ctx, cluster.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	current.Status.Coordinator = status
+	updated, err := c.aprClientSet.AprV1alpha1().PGClusters(cluster.Namespace).Update(c.ctx, current, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Error("update pg coordinator status error, ", err)
+		return err
+	}
+
+	cluster.Status = updated.Status
+	return nil
+}
+
+func (c *controller) isPodReady(namespace, name string) bool {
+	pod, err := c.k8sClientSet.CoreV1().Pods(namespace).Get(c.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+package pgcluster
+
+import (
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/certs"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const pgCertificateCheckInterval = 10 * time.Minute
+
+// checkCertificates renews the certificates of the clusters before expiry. The renewed certificates
+// are reloaded on the nodes in the next round, after the kubelet syncs the secret into the pods.
+func (c *controller) checkCertificates() {
+	clusters, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list pg clusters error, ", err)
+		return
+	}
+
+	for _, cluster := range clusters {
+		if err = c.checkCertificate(cluster.DeepCopy()); err != nil {
+			klog.Error("check pg cluster certificates error, ", err, ", ", cluster.Namespace, "/", cluster.Name)
+		}
+	}
+}
+
+func (c *controller) checkCertificate(cluster *aprv1.PGCluster) error {
+	secret, renewed, err := citus.EnsurePGClusterCertificates(c.ctx, c.k8sClientSet, cluster.Namespace)
+	if err != nil {
+		return err
+	}
+
+	key := cluster.Namespace + "/" + cluster.Name
+	if renewed {
+		c.certificateRenewed[key] = true
+
+		// restart the pooler with the new certificate
+		c.enqueue(enqueueObj{UPDATE, cluster})
+	}
+
+	sts, err := c.k8sClientSet.AppsV1().StatefulSets(cluster.Namespace).Get(c.ctx, citus.PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if !citus.IsPGClusterTLSReady(sts) {
+		return nil
+	}
+
+	// the clients of the operator verify the nodes by the ca bundle, which trusts
+	// both the old and the new ca while the ca is being renewed
+	postgres.SetDefaultRootCA(secret.Data[certs.CACertKey])
+
+	if c.certificateRenewed[key] && !renewed {
+		admin, pwd, err := citus.GetPGClusterAdminUserAndPassword(c.ctx, c.aprClientSet, c.k8sClientSet, cluster.Namespace)
+		if err != nil {
+			return err
+		}
+
+		if err = citus.ReloadPGClusterCertificates(c.ctx, c.k8sClientSet, sts, cluster, admin, pwd); err != nil {
+			return err
+		}
+
+		delete(c.certificateRenewed, key)
+	}
+
+	return nil
+}
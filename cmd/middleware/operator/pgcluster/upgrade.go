@@ -0,0 +1,315 @@
+package pgcluster
+
+import (
+	"errors"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/workload/citus"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// upgradeRequeueInterval is the interval to check the progress of the upgrade phase
+	upgradeRequeueInterval = 10 * time.Second
+
+	upgradePreflightTimeout = 10 * time.Minute
+	upgradeBackupTimeout    = time.Hour
+	upgradeRolloutTimeout   = 30 * time.Minute
+)
+
+// errUpgradeInProgress requeues the cluster to continue the upgrade, the phases waiting for
+// the backup, the restore or the rollout do not block the worker
+var errUpgradeInProgress = errors.New("pg cluster upgrade in progress")
+
+// upgradeCluster upgrades the cluster if the image is changed. The cluster is backed up before
+// upgrading, and restored into the new data directory if the major version is changed. The image
+// is rolled back if the upgrade fails after the image changed.
+// Each call runs one step of the current phase, errUpgradeInProgress is returned until the upgrade finished.
+func (c *controller) upgradeCluster(cluster *aprv1.PGCluster) error {
+	sts, err := c.k8sClientSet.AppsV1().StatefulSets(cluster.Namespace).Get(c.ctx, citus.PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	toImage := citus.GetPGClusterImage(cluster)
+	fromImage := citus.GetPGClusterWorkloadImage(sts)
+	upgrade := cluster.Status.Upgrade.DeepCopy()
+	sameTarget := upgrade != nil && upgrade.ToImage == toImage
+
+	switch {
+	case sameTarget && !upgradeFinished(upgrade.Phase):
+		// resume the upgrade interrupted by the operator restarting
+		fromImage = upgrade.FromImage
+	case fromImage == toImage:
+		return nil
+	case sameTarget && (upgrade.Phase == aprv1.PGUpgradeRolledBack || upgrade.Phase == aprv1.PGUpgradeFailed):
+		klog.Info("pg cluster upgrade failed before, change the image to retry, ", upgrade.ToImage)
+		return nil
+	default:
+		_, fromErr := citus.CitusMajorVersion(fromImage)
+		_, toErr := citus.CitusMajorVersion(toImage)
+		if fromErr != nil && toErr != nil {
+			// no version to upgrade between, e.g. the latest tag to a digest
+			klog.Info("pg cluster image changed without version, ", fromImage, " -> ", toImage)
+			return citus.SetPGClusterImage(c.ctx, c.k8sClientSet, cluster.Namespace, toImage)
+		}
+
+		now := metav1.Now()
+		upgrade = &aprv1.PGClusterUpgrade{
+			Phase:          aprv1.PGUpgradePreflight,
+			FromImage:      fromImage,
+			ToImage:        toImage,
+			StartedAt:      &now,
+			PhaseStartedAt: &now,
+		}
+		klog.Info("pg cluster upgrade, ", fromImage, " -> ", toImage)
+	}
+
+	// the major versions are required to upgrade the data directory
+	fromMajor, err := citus.CitusMajorVersion(fromImage)
+	if err != nil {
+		return c.failUpgrade(cluster, upgrade, aprv1.PGUpgradeFailed, err)
+	}
+
+	toMajor, err := citus.CitusMajorVersion(toImage)
+	if err != nil {
+		return c.failUpgrade(cluster, upgrade, aprv1.PGUpgradeFailed, err)
+	}
+
+	if upgrade.Phase == aprv1.PGUpgradePreflight && toMajor != fromMajor &&
+		(cluster.Spec.Standby != nil || citus.IsCoordinatorFailedOver(cluster)) {
+		return c.failUpgrade(cluster, upgrade, aprv1.PGUpgradeFailed,
+			errors.New("major version upgrade is not supported with the coordinator standby"))
+	}
+
+	pwd, err := citus.GetPGClusterDefinedPassword(c.ctx, c.k8sClientSet, cluster.Namespace, cluster)
+	if err != nil {
+		return err
+	}
+	admin := cluster.Spec.AdminUser
+
+	switch upgrade.Phase {
+	case aprv1.PGUpgradePreflight:
+		if err = c.updateUpgradeStatus(cluster, upgrade); err != nil {
+			return err
+		}
+
+		backup, err := citus.NewPGClusterBackup(c.ctx, c.k8sClientSet, cluster, citus.PGClusterUpgradeBackup)
+		if err != nil {
+			return err
+		}
+
+		done, err := citus.PreflightPGClusterUpgrade(c.ctx, c.k8sClientSet, sts, backup, fromImage, toImage, admin, pwd)
+		if err == nil && !done && phaseTimedOut(upgrade, upgradePreflightTimeout) {
+			err = errors.New("timed out checking the disk space")
+		}
+
+		if err != nil {
+			klog.Error("pg cluster upgrade preflight error, ", err)
+			return c.failUpgrade(cluster, upgrade, aprv1.PGUpgradeFailed, err)
+		}
+
+		if !done {
+			return errUpgradeInProgress
+		}
+
+		klog.Info("backup pg cluster before upgrading, ", backup.Name)
+		if err = citus.ForceCreateNewPGClusterBackup(c.ctx, c.aprClientSet, backup); err != nil {
+			klog.Error("backup pg cluster error, ", err)
+			return c.failUpgrade(cluster, upgrade, aprv1.PGUpgradeFailed, err)
+		}
+
+		upgrade.BackupName = backup.Name
+		return c.nextUpgradePhase(cluster, upgrade, aprv1.PGUpgradeBackingUp)
+
+	case aprv1.PGUpgradeBackingUp:
+		ready, err := citus.PGClusterBackupReady(c.ctx, c.aprClientSet, cluster.Namespace, upgrade.BackupName)
+		if err == nil && !ready && phaseTimedOut(upgrade, upgradeBackupTimeout) {
+			err = errors.New("timed out waiting for the backup")
+		}
+
+		if err != nil {
+			klog.Error("backup pg cluster error, ", err)
+			return c.failUpgrade(cluster, upgrade, aprv1.PGUpgradeFailed, err)
+		}
+
+		if !ready {
+			return errUpgradeInProgress
+		}
+
+		return c.nextUpgradePhase(cluster, upgrade, aprv1.PGUpgradeUpgrading)
+
+	case aprv1.PGUpgradeUpgrading:
+		if err = citus.SetPGClusterImage(c.ctx, c.k8sClientSet, cluster.Namespace, toImage); err != nil {
+			klog.Error("upgrade pg cluster image error, ", err)
+			return c.rollbackUpgrade(cluster, upgrade, err)
+		}
+
+		done, err := citus.PGClusterRolledOut(c.ctx, c.k8sClientSet, cluster.Namespace)
+		if err == nil && !done && phaseTimedOut(upgrade, upgradeRolloutTimeout) {
+			err = errors.New("timed out waiting for the new image rolled out")
+		}
+
+		if err != nil {
+			klog.Error("upgrade pg cluster image error, ", err)
+			return c.rollbackUpgrade(cluster, upgrade, err)
+		}
+
+		if !done {
+			return errUpgradeInProgress
+		}
+
+		if toMajor == fromMajor {
+			return c.nextUpgradePhase(cluster, upgrade, aprv1.PGUpgradeUpdatingExtensions)
+		}
+
+		// the nodes are initialized in the new data directories
+		if err = citus.SetPGClusterHBA(c.ctx, c.k8sClientSet, cluster.Namespace, admin, pwd, citus.HasPGStandby(cluster)); err != nil {
+			klog.Error("set pg cluster hba error, ", err)
+			return c.rollbackUpgrade(cluster, upgrade, err)
+		}
+
+		restore := citus.ClusterRestore.DeepCopy()
+		restore.Name = citus.PGClusterUpgradeBackup
+		restore.Namespace = cluster.Namespace
+		restore.Spec.BackupName = upgrade.BackupName
+
+		klog.Info("restore pg cluster into the new version, ", upgrade.BackupName)
+		if err = citus.ForceCreateNewPGClusterRestore(c.ctx, c.aprClientSet, restore); err != nil {
+			klog.Error("restore pg cluster error, ", err)
+			return c.rollbackUpgrade(cluster, upgrade, err)
+		}
+
+		return c.nextUpgradePhase(cluster, upgrade, aprv1.PGUpgradeRestoring)
+
+	case aprv1.PGUpgradeRestoring:
+		ready, err := citus.PGClusterRestoreReady(c.ctx, c.aprClientSet, cluster.Namespace, citus.PGClusterUpgradeBackup)
+		if err == nil && !ready && phaseTimedOut(upgrade, upgradeBackupTimeout) {
+			err = errors.New("timed out waiting for the restore")
+		}
+
+		if err != nil {
+			klog.Error("restore pg cluster error, ", err)
+			return c.rollbackUpgrade(cluster, upgrade, err)
+		}
+
+		if !ready {
+			return errUpgradeInProgress
+		}
+
+		// set up the distributed databases on the workers again
+		c.notifyClusterCreated(cluster)
+		return c.nextUpgradePhase(cluster, upgrade, aprv1.PGUpgradeUpdatingExtensions)
+
+	case aprv1.PGUpgradeUpdatingExtensions:
+		if err = citus.UpdatePGClusterExtensions(c.ctx, c.k8sClientSet, cluster, admin, pwd); err != nil {
+			klog.Error("update pg cluster extensions error, ", err)
+			return c.rollbackUpgrade(cluster, upgrade, err)
+		}
+
+		klog.Info("pg cluster upgraded, ", fromImage, " -> ", toImage)
+		now := metav1.Now()
+		upgrade.Phase = aprv1.PGUpgradeCompleted
+		upgrade.Message = ""
+		upgrade.CompletedAt = &now
+
+		return c.updateUpgradeStatus(cluster, upgrade)
+
+	case aprv1.PGUpgradeRollingBack:
+		if err = citus.SetPGClusterImage(c.ctx, c.k8sClientSet, cluster.Namespace, upgrade.FromImage); err != nil {
+			klog.Error("rollback pg cluster image error, ", err)
+			return c.failUpgrade(cluster, upgrade, aprv1.PGUpgradeFailed, err)
+		}
+
+		done, err := citus.PGClusterRolledOut(c.ctx, c.k8sClientSet, cluster.Namespace)
+		if err == nil && !done && phaseTimedOut(upgrade, upgradeRolloutTimeout) {
+			err = errors.New("timed out waiting for the old image rolled out")
+		}
+
+		if err != nil {
+			klog.Error("rollback pg cluster image error, ", err)
+			return c.failUpgrade(cluster, upgrade, aprv1.PGUpgradeFailed, err)
+		}
+
+		if !done {
+			return errUpgradeInProgress
+		}
+
+		return c.failUpgrade(cluster, upgrade, aprv1.PGUpgradeRolledBack, nil)
+	}
+
+	return nil
+}
+
+// nextUpgradePhase moves the upgrade to the phase, and requeues the cluster to run it
+func (c *controller) nextUpgradePhase(cluster *aprv1.PGCluster, upgrade *aprv1.PGClusterUpgrade, phase aprv1.PGUpgradePhase) error {
+	now := metav1.Now()
+	upgrade.Phase = phase
+	upgrade.PhaseStartedAt = &now
+	if err := c.updateUpgradeStatus(cluster, upgrade); err != nil {
+		return err
+	}
+
+	klog.Info("pg cluster upgrade phase, ", phase, ", ", upgrade.FromImage, " -> ", upgrade.ToImage)
+	return errUpgradeInProgress
+}
+
+// rollbackUpgrade restores the image of the cluster, the data directory of the old major version is untouched
+func (c *controller) rollbackUpgrade(cluster *aprv1.PGCluster, upgrade *aprv1.PGClusterUpgrade, cause error) error {
+	if cause != nil {
+		upgrade.Message = cause.Error()
+	}
+
+	klog.Info("rollback pg cluster upgrade, ", upgrade.ToImage, " -> ", upgrade.FromImage)
+	return c.nextUpgradePhase(cluster, upgrade, aprv1.PGUpgradeRollingBack)
+}
+
+func (c *controller) failUpgrade(cluster *aprv1.PGCluster, upgrade *aprv1.PGClusterUpgrade,
+	phase aprv1.PGUpgradePhase, cause error) error {
+	now := metav1.Now()
+	upgrade.Phase = phase
+	upgrade.CompletedAt = &now
+	if cause != nil {
+		upgrade.Message = cause.Error()
+	}
+
+	return c.updateUpgradeStatus(cluster, upgrade)
+}
+
+func (c *controller) updateUpgradeStatus(cluster *aprv1.PGCluster, upgrade *aprv1.PGClusterUpgrade) error {
+	if equality.Semantic.DeepEqual(cluster.Status.Upgrade, upgrade) {
+		return nil
+	}
+
+	current, err := c.aprClientSet.AprV1alpha1().PGClusters(cluster.Namespace).Get(c.ctx, cluster.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	current.Status.Upgrade = upgrade.DeepCopy()
+	_, err = c.aprClientSet.AprV1alpha1().PGClusters(cluster.Namespace).Update(c.ctx, current, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Error("update pg cluster upgrade status error, ", err)
+		return err
+	}
+
+	cluster.Status.Upgrade = upgrade.DeepCopy()
+	return nil
+}
+
+func upgradeFinished(phase aprv1.PGUpgradePhase) bool {
+	switch phase {
+	case aprv1.PGUpgradeCompleted, aprv1.PGUpgradeRolledBack, aprv1.PGUpgradeFailed:
+		return true
+	}
+
+	return false
+}
+
+func phaseTimedOut(upgrade *aprv1.PGClusterUpgrade, timeout time.Duration) bool {
+	return upgrade.PhaseStartedAt != nil && time.Since(upgrade.PhaseStartedAt.Time) > timeout
+}
@@ -136,6 +136,9 @@ func (c *controller) Run(workers int) error {
 	}
 
 	klog.Info("Started workers")
+
+	go wait.Until(c.checkCertificates, kvrocksCertificateCheckInterval, c.ctx.Done())
+
 	<-c.ctx.Done()
 	klog.Info("Shutting down workers, ", controllerAgentName)
 
@@ -0,0 +1,40 @@
+package redixcluster
+
+import (
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/workload/kvrocks"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const kvrocksCertificateCheckInterval = 10 * time.Minute
+
+// checkCertificates renews the certificates of the kvrocks before expiry, the kvrocks is restarted
+// with the renewed certificate by the update of the cluster
+func (c *controller) checkCertificates() {
+	clusters, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list redix clusters error, ", err)
+		return
+	}
+
+	for _, cluster := range clusters {
+		if cluster.Spec.Type != aprv1.KVRocks {
+			continue
+		}
+
+		_, renewed, err := kvrocks.EnsureKVRocksCertificates(c.ctx, c.k8sClientSet, cluster.Namespace)
+		if err != nil {
+			klog.Error("check kvrocks certificates error, ", err, ", ", cluster.Namespace, "/", cluster.Name)
+			continue
+		}
+
+		if renewed {
+			klog.Info("kvrocks certificate renewed, restart the kvrocks, ", cluster.Namespace, "/", cluster.Name)
+			c.enqueue(enqueueObj{UPDATE, cluster})
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package apiserver
+
+import (
+	"strconv"
+	"time"
+
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
+	"bytetrade.io/web3os/tapr/pkg/constants"
+	"bytetrade.io/web3os/tapr/pkg/kubesphere"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/history"
+	"github.com/gofiber/fiber/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+type replayRequest struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// After is the cursor, the seq of the last event the subscriber received
+	After int64 `json:"after"`
+	Limit int   `json:"limit,omitempty"`
+}
+
+// currentUser returns the user of the request, and whether the user is the admin
+func (s *Server) currentUser(ctx *fiber.Ctx) (string, bool, error) {
+	user := ctx.Context().UserValueBytes(constants.UsernameCtxKey).(string)
+	role, err := kubesphere.GetUserRole(ctx.UserContext(), s.config, user)
+	if err != nil {
+		klog.Error("get user role error, ", err, ", ", user)
+		return "", false, err
+	}
+
+	return user, role == "owner" || role == "admin", nil
+}
+
+// listEvents queries the events of the history, the users other than the admin only see their own events
+func (s *Server) listEvents(ctx *fiber.Ctx) error {
+	user, admin, err := s.currentUser(ctx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	q := history.Query{
+		Type:  ctx.Query("type"),
+		User:  ctx.Query("user"),
+		App:   ctx.Query("app"),
+		Limit: ctx.QueryInt("limit"),
+	}
+
+	if !admin {
+		if q.User != "" && q.User != user {
+			return fiber.NewError(fiber.StatusForbidden, "can not query the events of other users")
+		}
+		q.User = user
+	}
+
+	if q.After, err = queryInt64(ctx, "after"); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if q.Since, err = queryTime(ctx, "since"); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if q.Until, err = queryTime(ctx, "until"); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	events, err := s.eventWatchers.History().List(ctx.UserContext(), q)
+	if err != nil {
+		klog.Error("list history events error, ", err)
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(fiber.Map{
+		"code": 0,
+		"data": fiber.Map{
+			"items":  events,
+			"cursor": cursor(events, q.After),
+		},
+	})
+}
+
+// replayEvents saves the events after the cursor subscribed by the registry to the outbox again,
+// the users other than the admin only replay to the registries in their own namespaces
+func (s *Server) replayEvents(ctx *fiber.Ctx) error {
+	var req replayRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		klog.Error("parse request body error, ", err, ", ", string(ctx.Body()))
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if req.Namespace == "" || req.Name == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "registry required")
+	}
+
+	user, admin, err := s.currentUser(ctx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	registries, err := s.eventWatchers.Registries(ctx.UserContext())
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	cb, err := registries.SysEventRegistries(req.Namespace).Get(req.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	if !admin {
+		namespaces, err := s.eventWatchers.Namespaces(ctx.UserContext())
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+
+		ns, err := namespaces.Get(req.Namespace)
+		if err != nil || ns.Labels[watchers.NamespaceOwnerLabel] != user {
+			return fiber.NewError(fiber.StatusForbidden, "can not replay the events to the registry of other users")
+		}
+	}
+
+	events, err := s.eventWatchers.History().List(ctx.UserContext(), history.Query{After: req.After, Limit: req.Limit})
+	if err != nil {
+		klog.Error("list history events error, ", err)
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	count, err := s.invoker.Replay(ctx.UserContext(), cb, events)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	// the events after the cursor remain if the page is full
+	q := history.Query{Limit: req.Limit}
+	q.Normalize()
+
+	klog.Info("replay events to, ", req.Name, ", ", req.Namespace, ", ", count)
+	return ctx.JSON(fiber.Map{
+		"code": 0,
+		"data": fiber.Map{
+			"count":  count,
+			"cursor": cursor(events, req.After),
+			"more":   len(events) >= q.Limit,
+		},
+	})
+}
+
+// cursor returns the seq of the last event, or the cursor queried if no events
+func cursor(events []*history.Event, after int64) int64 {
+	if len(events) == 0 {
+		return after
+	}
+
+	return events[len(events)-1].Seq
+}
+
+func queryInt64(ctx *fiber.Ctx, key string) (int64, error) {
+	v := ctx.Query(key)
+	if v == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// queryTime parses the time in RFC3339 or the unix seconds
+func queryTime(ctx *fiber.Ctx, key string) (time.Time, error) {
+	v := ctx.Query(key)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+
+	return time.Parse(time.RFC3339, v)
+}
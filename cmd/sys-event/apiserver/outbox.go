@@ -0,0 +1,73 @@
+package apiserver
+
+import (
+	"bytetrade.io/web3os/tapr/pkg/sysevent/outbox"
+	"github.com/gofiber/fiber/v2"
+	"k8s.io/klog/v2"
+)
+
+func (s *Server) listDeadLetters(ctx *fiber.Ctx) error {
+	filter := outbox.Filter{
+		RegistryNamespace: ctx.Query("namespace"),
+		RegistryName:      ctx.Query("name"),
+	}
+
+	messages, err := s.eventWatchers.Outbox().ListDead(ctx.UserContext(), filter)
+	if err != nil {
+		klog.Error("list dead-lettered events error, ", err)
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(fiber.Map{
+		"code": 0,
+		"data": messages,
+	})
+}
+
+// redriveDeadLetters moves the dead-lettered events back to the outbox, and enables the callbacks
+// disabled after too many consecutive failures
+func (s *Server) redriveDeadLetters(ctx *fiber.Ctx) error {
+	filter := outbox.Filter{}
+	err := ctx.BodyParser(&filter)
+	if err != nil {
+		klog.Error("parse request body error, ", err, ", ", string(ctx.Body()))
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if filter.RegistryNamespace == "" && filter.RegistryName == "" && len(filter.IDs) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "registry or event ids required")
+	}
+
+	store := s.eventWatchers.Outbox()
+	messages, err := store.ListDead(ctx.UserContext(), filter)
+	if err != nil {
+		klog.Error("list dead-lettered events error, ", err)
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	enabled := make(map[string]bool)
+	for _, m := range messages {
+		key := m.RegistryNamespace + "/" + m.RegistryName
+		if enabled[key] {
+			continue
+		}
+
+		if err = s.dispatcher.Enable(ctx.UserContext(), m.RegistryNamespace, m.RegistryName); err != nil {
+			klog.Warning("enable sys event callback error, ", err, ", ", key)
+		}
+		enabled[key] = true
+	}
+
+	count, err := store.Redrive(ctx.UserContext(), filter)
+	if err != nil {
+		klog.Error("redrive dead-lettered events error, ", err)
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	klog.Info("redrive dead-lettered events, ", count)
+	return ctx.JSON(fiber.Map{
+		"code": 0,
+		"msg":  "success",
+		"data": count,
+	})
+}
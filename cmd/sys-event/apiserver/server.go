@@ -3,8 +3,12 @@ package apiserver
 import (
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/custom"
+	"bytetrade.io/web3os/tapr/pkg/app/middleware"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 )
 
@@ -12,11 +16,20 @@ type Server struct {
 	eventWatchers *watchers.Watchers
 	app           *fiber.App
 	subscriber    *custom.Subscriber
+	dispatcher    *watchers.Dispatcher
+	invoker       *watchers.CallbackInvoker
+	config        *rest.Config
 }
 
-func NewServer(w *watchers.Watchers, n *watchers.Notification) *Server {
+func NewServer(w *watchers.Watchers, n *watchers.Notification, d *watchers.Dispatcher, config *rest.Config) *Server {
 
-	s := &Server{eventWatchers: w, subscriber: (&custom.Subscriber{}).WithNotification(n)}
+	s := &Server{
+		eventWatchers: w,
+		subscriber:    (&custom.Subscriber{}).WithNotification(n).WithWatchers(w),
+		dispatcher:    d,
+		invoker:       watchers.NewCallbackInvoker(w, config),
+		config:        config,
+	}
 	// create new fiber instance  and use across whole app
 	app := fiber.New()
 
@@ -24,6 +37,17 @@ func NewServer(w *watchers.Watchers, n *watchers.Notification) *Server {
 	app.Use(cors.New())
 
 	app.Post("/events/fire", s.fireEvent)
+	app.Get(cloudevents.SchemaPath, s.listSchemas)
+
+	app.Get("/events", middleware.GetUserInfo(config, s.listEvents))
+	app.Post("/events/replay", middleware.GetUserInfo(config, s.replayEvents))
+	app.Get("/events/stream", middleware.RequireAuth(config, s.streamEvents))
+	app.Get("/events/ws", middleware.RequireAuth(config, s.prepareWebSocket), websocket.New(s.streamWebSocket))
+
+	app.Get("/events/deadletters", middleware.GetUserInfo(config,
+		middleware.RequireAdmin(config, s.listDeadLetters)))
+	app.Post("/events/deadletters/redrive", middleware.GetUserInfo(config,
+		middleware.RequireAdmin(config, s.redriveDeadLetters)))
 
 	s.app = app
 
@@ -60,3 +84,10 @@ func (s *Server) fireEvent(ctx *fiber.Ctx) error {
 	})
 	return nil
 }
+
+func (s *Server) listSchemas(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{
+		"code": 0,
+		"data": cloudevents.Schemas(),
+	})
+}
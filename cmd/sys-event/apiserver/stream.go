@@ -0,0 +1,289 @@
+package apiserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/history"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// streamHeartbeat keeps the idle streams alive, and detects the clients gone
+	streamHeartbeat = 15 * time.Second
+
+	subscriptionKey = "sys-event-subscription"
+)
+
+// subscription is the filter of the events streamed to the client, the same as the SysEventRegistry
+type subscription struct {
+	event  aprv1.EventType
+	filter *aprv1.SysEventFilter
+	users  []string
+
+	// after is the cursor to resume from, the seq of the last event received
+	after int64
+}
+
+// streamMessage is the event sent on the websocket
+type streamMessage struct {
+	Seq   int64              `json:"seq"`
+	Event *cloudevents.Event `json:"event"`
+}
+
+// newSubscription reads the filter of the request. The users other than the admin only receive
+// their own events and the events of the cluster
+func (s *Server) newSubscription(ctx *fiber.Ctx) (*subscription, error) {
+	user, admin, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	sub := &subscription{
+		event: aprv1.EventType(ctx.Query("event", "*")),
+		filter: &aprv1.SysEventFilter{
+			Users:      splitQuery(ctx.Query("users")),
+			Apps:       splitQuery(ctx.Query("apps")),
+			Expression: ctx.Query("expression"),
+		},
+	}
+
+	if selector := ctx.Query("selector"); selector != "" {
+		sub.filter.Selector, err = metav1.ParseToLabelSelector(selector)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	if err = filter.Validate(sub.filter); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	sub.users = filter.Users(sub.filter, user)
+	if admin {
+		sub.users = filter.Users(sub.filter, "")
+	}
+
+	// the browsers send the Last-Event-ID header reconnecting the event source
+	after := ctx.Get("Last-Event-ID", ctx.Query("after"))
+	if after != "" {
+		if sub.after, err = strconv.ParseInt(after, 10, 64); err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid last event id")
+		}
+	}
+
+	return sub, nil
+}
+
+func (sub *subscription) match(e *filter.Event, data []byte) bool {
+	if !filter.MatchType(sub.event, e.Type) {
+		return false
+	}
+
+	match, err := filter.Match(sub.filter, sub.users, e, data)
+	if err != nil {
+		klog.Warning("match the event filter error, ", err)
+		return false
+	}
+
+	return match
+}
+
+// stream sends the events missed after the cursor from the history, then the live events,
+// until the client is gone or the subscription can not keep up
+func (s *Server) stream(ctx context.Context, sub *subscription,
+	send func(e *watchers.StreamEvent) error, heartbeat func() error) {
+	live := s.eventWatchers.Subscribe(func(e *watchers.StreamEvent) bool {
+		return sub.match(e.Meta, e.Event.Data)
+	})
+	defer live.Close()
+
+	// the history is written out of the order of the seq, so the events sent from it
+	// are skipped by the seq, not by the cursor
+	var sent map[int64]struct{}
+	if sub.after > 0 {
+		var err error
+		if sent, err = s.resume(ctx, sub, sub.after, live.After, send); err != nil {
+			klog.Warning("resume the event stream error, ", err)
+			return
+		}
+	}
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return
+			}
+		case e, ok := <-live.C:
+			if !ok {
+				// closed if the client is too slow, it reconnects and resumes from the history
+				return
+			}
+
+			// sent from the history already
+			if _, ok := sent[e.Seq]; ok && e.Seq > 0 {
+				continue
+			}
+
+			if err := send(e); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// resume sends the events of the history after the cursor, and returns the seqs of the ones sent
+// after 'live', the seq the live subscription starts from
+func (s *Server) resume(ctx context.Context, sub *subscription, after, live int64,
+	send func(e *watchers.StreamEvent) error) (map[int64]struct{}, error) {
+	sent := make(map[int64]struct{})
+	for {
+		events, err := s.eventWatchers.History().List(ctx, history.Query{After: after, Limit: history.MaxLimit})
+		if err != nil {
+			return sent, err
+		}
+
+		for _, h := range events {
+			after = h.Seq
+
+			var ce cloudevents.Event
+			if err = json.Unmarshal(h.Payload, &ce); err != nil {
+				klog.Warning("decode history event error, ", err, ", ", h.ID)
+				continue
+			}
+
+			meta := &filter.Event{Type: aprv1.EventType(h.Type), Owner: h.User, App: h.App, Labels: h.Labels}
+			if !sub.match(meta, ce.Data) {
+				continue
+			}
+
+			if err = send(&watchers.StreamEvent{Seq: h.Seq, Meta: meta, Event: &ce}); err != nil {
+				return sent, err
+			}
+
+			if h.Seq > live {
+				sent[h.Seq] = struct{}{}
+			}
+		}
+
+		if len(events) < history.MaxLimit {
+			return sent, nil
+		}
+	}
+}
+
+// streamEvents streams the events in Server-Sent Events, the id of the event is the cursor to resume from
+func (s *Server) streamEvents(ctx *fiber.Ctx) error {
+	sub, err := s.newSubscription(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no")
+
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// the first comment flushes the headers to the client
+		if err := writeSSE(w, ": connected\n\n"); err != nil {
+			return
+		}
+
+		s.stream(context.Background(), sub,
+			func(e *watchers.StreamEvent) error {
+				data, err := json.Marshal(e.Event)
+				if err != nil {
+					return err
+				}
+
+				var b strings.Builder
+				if e.Seq > 0 {
+					fmt.Fprintf(&b, "id: %d\n", e.Seq)
+				}
+				fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", e.Event.Type, data)
+				return writeSSE(w, b.String())
+			},
+			func() error {
+				return writeSSE(w, ": ping\n\n")
+			},
+		)
+	})
+
+	return nil
+}
+
+func writeSSE(w *bufio.Writer, s string) error {
+	if _, err := w.WriteString(s); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// prepareWebSocket reads the subscription before upgrading to the websocket
+func (s *Server) prepareWebSocket(ctx *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(ctx) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	sub, err := s.newSubscription(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx.Locals(subscriptionKey, sub)
+	return ctx.Next()
+}
+
+// streamWebSocket streams the events in the websocket messages with the cursors
+func (s *Server) streamWebSocket(conn *websocket.Conn) {
+	sub := conn.Locals(subscriptionKey).(*subscription)
+
+	// the client only closes the connection, stop streaming once it is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	s.stream(ctx, sub,
+		func(e *watchers.StreamEvent) error {
+			return conn.WriteJSON(&streamMessage{Seq: e.Seq, Event: e.Event})
+		},
+		func() error {
+			return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamHeartbeat))
+		},
+	)
+}
+
+func splitQuery(v string) []string {
+	if v == "" {
+		return nil
+	}
+
+	return strings.Split(v, ",")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"bytetrade.io/web3os/tapr/pkg/sysevent/history"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const historyPurgeInterval = time.Hour
+
+// newHistory connects to the history database configured by the environments, and waits for it.
+// The events are kept in memory if no database is configured
+func newHistory(ctx context.Context) history.Store {
+	if os.Getenv("PG_ADDR") == "" {
+		klog.Warning("history database is not configured, the events are lost on restart")
+		return history.NewMemoryStore()
+	}
+
+	for {
+		store, err := connectPostgres(ctx, history.NewPostgresStore)
+		if err == nil {
+			return store
+		}
+
+		klog.Info("connecting history postgres error, ", err, ".  Waiting ... ")
+		waitOrDie(ctx)
+	}
+}
+
+// retainHistory purges the events older than the retention configured by HISTORY_RETENTION, e.g. 168h
+func retainHistory(ctx context.Context, store history.Store) {
+	retention := history.DefaultRetention
+	if v := os.Getenv("HISTORY_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			klog.Warning("invalid history retention, ", v, ", use the default ", retention)
+		} else {
+			retention = d
+		}
+	}
+
+	wait.Until(func() {
+		if err := store.Purge(ctx, time.Now().Add(-retention)); err != nil {
+			klog.Error("purge history events error, ", err)
+		}
+	}, historyPurgeInterval, ctx.Done())
+}
@@ -6,10 +6,15 @@ import (
 	"bytetrade.io/web3os/tapr/cmd/sys-event/apiserver"
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/apps"
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/certs"
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/dnspod"
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/metrics"
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/nodes"
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/registry"
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/users"
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/workflows"
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers/workloads"
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	"bytetrade.io/web3os/tapr/pkg/app/application"
 	"bytetrade.io/web3os/tapr/pkg/signals"
 	corev1 "k8s.io/api/core/v1"
@@ -28,25 +33,39 @@ func main() {
 	notification := watchers.Notification{
 		DynamicClient: dynamic.NewForConfigOrDie(config),
 	}
-	w := watchers.NewWatchers(ctx, config, 0)
+	w := watchers.NewWatchers(ctx, config, 0, newOutbox(ctx), newPublisher(ctx), newHistory(ctx))
 
 	// add event subscriber to watchers
 	watchers.AddToWatchers[application.Application](w, application.GVR,
-		(&apps.Subscriber{Subscriber: watchers.NewSubscriber(w).WithNotification(&notification)}).HandleEvent())
+		(&apps.Subscriber{Subscriber: watchers.NewSubscriber(w).WithNotification(&notification)}).WithKubeConfig(config).HandleEvent())
 	watchers.AddToWatchers[corev1.Namespace](w, corev1.SchemeGroupVersion.WithResource("namespaces"),
 		(&workflows.Subscriber{Subscriber: watchers.NewSubscriber(w).WithNotification(&notification)}).WithKubeConfig(config).HandleEvent())
 	watchers.AddToWatchers[corev1.Pod](w, corev1.SchemeGroupVersion.WithResource("pods"),
 		(&dnspod.PodSubscriber{Subscriber: watchers.NewSubscriber(w).WithNotification(&notification)}).WithKubeConfig(config).HandleEvent())
 	watchers.AddToWatchers[corev1.Node](w, corev1.SchemeGroupVersion.WithResource("nodes"),
 		(&dnspod.NodeSubscriber{Subscriber: watchers.NewSubscriber(w).WithNotification(&notification)}).WithKubeConfig(config).HandleEvent())
+	watchers.AddToWatchers[corev1.Node](w, corev1.SchemeGroupVersion.WithResource("nodes"),
+		(&nodes.Subscriber{Subscriber: watchers.NewSubscriber(w).WithNotification(&notification)}).WithKubeConfig(config).HandleEvent())
+	watchers.AddToWatchers[corev1.Pod](w, corev1.SchemeGroupVersion.WithResource("pods"),
+		(&workloads.Subscriber{Subscriber: watchers.NewSubscriber(w).WithNotification(&notification)}).WithKubeConfig(config).HandleEvent())
+	watchers.AddToWatchers[aprv1.SysEventRegistry](w, registry.GVR,
+		(&registry.Subscriber{Subscriber: watchers.NewSubscriber(w)}).WithKubeConfig(config).HandleEvent())
 
 	go w.Run(1)
 
 	// metrics monitoring
 	go metrics.NewMetricsWatcherOrDie(ctx, w, &notification, config).Run()
 
+	// certificates expiry
+	go certs.NewWatcher(ctx, w, &notification, config).Run()
+
+	// deliver the events to the callbacks
+	dispatcher := watchers.NewDispatcher(watchers.NewCallbackInvoker(w, config))
+	go dispatcher.Run(ctx)
+	go retainHistory(ctx, w.History())
+
 	// api server
-	api := apiserver.NewServer(w, &notification)
+	api := apiserver.NewServer(w, &notification, dispatcher, config)
 	go api.Run()
 
 	userWatcher := users.NewWatcher(ctx, config, w, &notification)
@@ -54,5 +73,6 @@ func main() {
 
 	<-ctx.Done()
 	api.ShutDown()
+	w.Publisher().Close()
 	klog.Info("shutdown sys-event manager")
 }
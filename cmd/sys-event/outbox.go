@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"bytetrade.io/web3os/tapr/pkg/sysevent/outbox"
+	"github.com/lib/pq"
+	"k8s.io/klog/v2"
+)
+
+// sslModePrefer connects with ssl if the server supports it, the driver does not support it
+const sslModePrefer = "prefer"
+
+// newOutbox connects to the outbox database configured by the environments, and waits for it.
+// The events in flight are kept in memory if no database is configured
+func newOutbox(ctx context.Context) outbox.Store {
+	if os.Getenv("PG_ADDR") == "" {
+		klog.Warning("outbox database is not configured, the events in flight are lost on restart")
+		return outbox.NewMemoryStore()
+	}
+
+	for {
+		store, err := connectPostgres(ctx, outbox.NewPostgresStore)
+		if err == nil {
+			return store
+		}
+
+		klog.Info("connecting outbox postgres error, ", err, ".  Waiting ... ")
+		waitOrDie(ctx)
+	}
+}
+
+// connectPostgres connects to the database of the outbox and the history with the ssl mode set by PG_SSLMODE,
+// prefer by default, which falls back to the plain connection if the server does not support ssl
+func connectPostgres[T any](ctx context.Context, connect func(ctx context.Context, dsn string) (T, error)) (T, error) {
+	mode := getenvOrDefault("PG_SSLMODE", sslModePrefer)
+	if mode != sslModePrefer {
+		return connect(ctx, postgresDSN(mode))
+	}
+
+	store, err := connect(ctx, postgresDSN("require"))
+	if errors.Is(err, pq.ErrSSLNotSupported) {
+		klog.Warning("ssl is not enabled on the outbox and history database, connect without ssl")
+		return connect(ctx, postgresDSN("disable"))
+	}
+
+	return store, err
+}
+
+// postgresDSN returns the dsn of the database of the outbox and the history with the ssl mode
+func postgresDSN(sslmode string) string {
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
+		url.QueryEscape(getenvOrDefault("PG_USER", "sys_event")),
+		url.QueryEscape(os.Getenv("PG_PASSWORD")),
+		os.Getenv("PG_ADDR"),
+		getenvOrDefault("PG_DB", "sys_event"),
+		url.QueryEscape(sslmode))
+}
+
+// waitOrDie waits a second before connecting again, exits if shutdown
+func waitOrDie(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		klog.Fatal("shutdown before the database connected")
+	case <-time.After(time.Second):
+	}
+}
+
+func getenvOrDefault(env string, d string) string {
+	v := os.Getenv(env)
+	if v == "" {
+		return d
+	}
+
+	return v
+}
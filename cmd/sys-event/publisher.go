@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"bytetrade.io/web3os/tapr/pkg/sysevent/publisher"
+	"k8s.io/klog/v2"
+)
+
+// newPublisher connects to the nats server configured by the environments, and waits for it.
+// The events are not published to the os stream if no server is configured
+func newPublisher(ctx context.Context) publisher.Publisher {
+	addr := os.Getenv("NATS_ADDR")
+	if addr == "" {
+		klog.Warning("nats is not configured, the events are not published to the os stream")
+		return publisher.NewNopPublisher()
+	}
+
+	for {
+		p, err := publisher.NewNatsPublisher(ctx, "nats://"+addr,
+			getenvOrDefault("NATS_USER", "admin"), os.Getenv("NATS_PASSWORD"))
+		if err == nil {
+			return p
+		}
+
+		klog.Info("connecting nats error, ", err, ".  Waiting ... ")
+		select {
+		case <-ctx.Done():
+			klog.Fatal("shutdown before nats connected")
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -2,9 +2,20 @@ package watchers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
+	informers "bytetrade.io/web3os/tapr/pkg/generated/informers/externalversions"
+	"bytetrade.io/web3os/tapr/pkg/generated/listers/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/history"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/outbox"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/publisher"
+	"github.com/google/uuid"
+
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -12,6 +23,9 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -38,20 +52,163 @@ type EnqueueObj struct {
 	Obj       interface{}
 	Action    Action
 	Subscribe SubscribeFunc // func is unhashable
+
+	// ID is set on enqueue and kept on the retries, the events emitted for the obj derive their ids from it
+	ID string
+}
+
+type eventIDKey struct{}
+
+// withEventID returns the context carrying the id of the queued obj
+func withEventID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, eventIDKey{}, id)
 }
 
 type Watchers struct {
 	ctx             context.Context
 	workqueue       workqueue.RateLimitingInterface
 	informerFactory dynamicinformer.DynamicSharedInformerFactory
+	outbox          outbox.Store
+	publisher       publisher.Publisher
+	history         history.Store
+	stream          *stream
+
+	aprInformerFactory informers.SharedInformerFactory
+	registryLister     v1alpha1.SysEventRegistryLister
+	registrySynced     cache.InformerSynced
+	alertRuleLister    v1alpha1.AlertRuleLister
+	alertRuleSynced    cache.InformerSynced
+
+	kubeInformerFactory kubeinformers.SharedInformerFactory
+	namespaceLister     corelisters.NamespaceLister
+	namespaceSynced     cache.InformerSynced
 }
 
-func NewWatchers(ctx context.Context, kubeconfig *rest.Config, resync time.Duration) *Watchers {
+func NewWatchers(ctx context.Context, kubeconfig *rest.Config, resync time.Duration, o outbox.Store, p publisher.Publisher, h history.Store) *Watchers {
 	client := dynamic.NewForConfigOrDie(kubeconfig)
+	aprInformerFactory := informers.NewSharedInformerFactory(aprclientset.NewForConfigOrDie(kubeconfig), resync)
+	registryInformer := aprInformerFactory.Apr().V1alpha1().SysEventRegistries()
+	alertRuleInformer := aprInformerFactory.Apr().V1alpha1().AlertRules()
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubernetes.NewForConfigOrDie(kubeconfig), resync)
+	namespaceInformer := kubeInformerFactory.Core().V1().Namespaces()
 	return &Watchers{
-		ctx:             ctx,
-		workqueue:       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Watchers"),
-		informerFactory: dynamicinformer.NewDynamicSharedInformerFactory(client, resync),
+		ctx:                ctx,
+		workqueue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Watchers"),
+		informerFactory:    dynamicinformer.NewDynamicSharedInformerFactory(client, resync),
+		outbox:             o,
+		publisher:          p,
+		history:            h,
+		stream:             newStream(),
+		aprInformerFactory: aprInformerFactory,
+		registryLister:     registryInformer.Lister(),
+		registrySynced:     registryInformer.Informer().HasSynced,
+		alertRuleLister:    alertRuleInformer.Lister(),
+		alertRuleSynced:    alertRuleInformer.Informer().HasSynced,
+
+		kubeInformerFactory: kubeInformerFactory,
+		namespaceLister:     namespaceInformer.Lister(),
+		namespaceSynced:     namespaceInformer.Informer().HasSynced,
+	}
+}
+
+// Registries returns the lister of the cached SysEventRegistries, waits for the cache synced
+func (l *Watchers) Registries(ctx context.Context) (v1alpha1.SysEventRegistryLister, error) {
+	l.aprInformerFactory.Start(l.ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), l.registrySynced) {
+		return nil, fmt.Errorf("failed to wait for sys event registries to sync")
+	}
+
+	return l.registryLister, nil
+}
+
+// AlertRules returns the lister of the cached AlertRules, waits for the cache synced
+func (l *Watchers) AlertRules(ctx context.Context) (v1alpha1.AlertRuleLister, error) {
+	l.aprInformerFactory.Start(l.ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), l.alertRuleSynced) {
+		return nil, fmt.Errorf("failed to wait for alert rules to sync")
+	}
+
+	return l.alertRuleLister, nil
+}
+
+// Namespaces returns the lister of the cached Namespaces, waits for the cache synced
+func (l *Watchers) Namespaces(ctx context.Context) (corelisters.NamespaceLister, error) {
+	l.kubeInformerFactory.Start(l.ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), l.namespaceSynced) {
+		return nil, fmt.Errorf("failed to wait for namespaces to sync")
+	}
+
+	return l.namespaceLister, nil
+}
+
+// NamespaceOwner returns the user owning the namespace, empty for the namespaces of the system
+func (l *Watchers) NamespaceOwner(ctx context.Context, namespace string) string {
+	namespaces, err := l.Namespaces(ctx)
+	if err != nil {
+		klog.Error("list namespaces error, ", err)
+		return ""
+	}
+
+	ns, err := namespaces.Get(namespace)
+	if err != nil {
+		return ""
+	}
+
+	return ns.Labels[NamespaceOwnerLabel]
+}
+
+// Outbox returns the store of the events to deliver to the callbacks
+func (l *Watchers) Outbox() outbox.Store {
+	return l.outbox
+}
+
+// Publisher returns the publisher of the events to the os stream
+func (l *Watchers) Publisher() publisher.Publisher {
+	return l.publisher
+}
+
+// History returns the store of the events emitted
+func (l *Watchers) History() history.Store {
+	return l.history
+}
+
+// Emit records the event in the history, sends it to the live subscriptions, and publishes it to the os stream
+func (l *Watchers) Emit(ctx context.Context, e *filter.Event, ce *cloudevents.Event) {
+	payload, err := json.Marshal(ce)
+	if err != nil {
+		klog.Error("encode event error, ", err)
+		return
+	}
+
+	record := &history.Event{
+		ID:      ce.ID,
+		Type:    ce.Type,
+		User:    e.Owner,
+		App:     e.App,
+		Labels:  e.Labels,
+		Time:    ce.Time,
+		Payload: payload,
+	}
+
+	// the live subscriptions receive the events in the order of the seq, to resume from the last one.
+	// Only the seq is reserved in the order, the producers do not wait for each other writing the history
+	l.stream.order.Lock()
+	if record.Seq, err = l.history.NextSeq(ctx); err != nil {
+		klog.Warning("reserve event seq in the history error, ", err, ", ", ce.ID)
+	}
+
+	l.stream.broadcast(&StreamEvent{Seq: record.Seq, Meta: e, Event: ce})
+	l.stream.order.Unlock()
+
+	if record.Seq > 0 {
+		if err = l.history.Append(ctx, record); err != nil {
+			klog.Warning("record event in the history error, ", err, ", ", ce.ID)
+		}
+	}
+
+	// the os stream receives all the events, the consumers filter them by the subjects
+	if err = l.publisher.Publish(ctx, ce); err != nil {
+		klog.Warning("publish event to the os stream error, ", err, ", ", ce.ID)
 	}
 }
 
@@ -83,11 +240,16 @@ func (l *Watchers) Run(workers int) error {
 	klog.Info("Started workers")
 	<-l.ctx.Done()
 	klog.Info("Shutting down workers, ", WatcherName)
+	l.stream.closeAll()
 
 	return nil
 }
 
 func (l *Watchers) Enqueue(obj EnqueueObj) {
+	if obj.ID == "" {
+		obj.ID = uuid.NewString()
+	}
+
 	l.workqueue.Add(obj)
 }
 
@@ -115,7 +277,7 @@ func (l *Watchers) processNextWorkItem() bool {
 
 		// Run the syncHandler, passing it the namespace/name string of the
 		// Foo resource to be synced.
-		if err := eobj.Subscribe.Do(l.ctx, eobj.Obj, eobj.Action); err != nil {
+		if err := eobj.Subscribe.Do(withEventID(l.ctx, eobj.ID), eobj.Obj, eobj.Action); err != nil {
 			// Put the item back on the workqueue to handle any transient errors.
 			l.workqueue.AddRateLimited(eobj)
 			return fmt.Errorf("error syncing '%v': %s, requeuing", eobj, err.Error())
@@ -7,17 +7,32 @@ import (
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
 	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	"bytetrade.io/web3os/tapr/pkg/app/application"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
 
 type Subscriber struct {
 	*watchers.Subscriber
+	invoker *watchers.CallbackInvoker
+}
+
+func (s *Subscriber) WithKubeConfig(config *rest.Config) *Subscriber {
+	s.invoker = watchers.NewCallbackInvoker(s.Watchers, config)
+	return s
 }
 
 const suspendAnnotation = "bytetrade.io/suspend-by"
 const suspendCauseAnnotation = "bytetrade.io/suspend-cause"
 
+var appEvents = map[watchers.Action]aprv1.EventType{
+	watchers.ADD:     aprv1.AppInstall,
+	watchers.DELETE:  aprv1.AppUninstall,
+	watchers.SUSPEND: aprv1.AppSuspend,
+}
+
 func (s *Subscriber) HandleEvent() cache.ResourceEventHandler {
 	return cache.FilteringResourceEventHandler{
 		FilterFunc: func(obj interface{}) bool {
@@ -73,6 +88,17 @@ func (s *Subscriber) HandleEvent() cache.ResourceEventHandler {
 
 func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Action) error {
 	app := obj.(*application.Application)
+	if t, ok := appEvents[action]; ok {
+		err := s.invoker.Invoke(ctx,
+			&filter.Event{Type: t, Owner: app.Spec.Owner, App: app.Spec.Name, Labels: app.Labels},
+			appData(app),
+		)
+
+		if err != nil {
+			klog.Warning(err)
+		}
+	}
+
 	switch action {
 	case watchers.ADD:
 		klog.Info("app ", app.Spec.Namespace, "/", app.Spec.Name, " is installed")
@@ -119,3 +145,12 @@ func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Ac
 	}
 	return nil
 }
+
+func appData(app *application.Application) *cloudevents.AppData {
+	return &cloudevents.AppData{
+		Name:      app.Spec.Name,
+		Namespace: app.Spec.Namespace,
+		Owner:     app.Spec.Owner,
+		Cause:     app.Annotations[suspendCauseAnnotation],
+	}
+}
@@ -2,76 +2,307 @@ package watchers
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
 	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
-	"github.com/emicklei/go-restful"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/history"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/outbox"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/signature"
 	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 )
 
-const InvokeRetry = 10
+// NamespaceOwnerLabel is the label of the user owning the namespace
+const NamespaceOwnerLabel = "bytetrade.io/ns-owner"
+
+// CallbackTimeout is the timeout of a delivery to the callback
+const CallbackTimeout = 30 * time.Second
+
+var callbackClient = resty.New().SetTimeout(CallbackTimeout)
+
+// ErrCallbackForbidden is returned if the callback rejects the event permanently
+var ErrCallbackForbidden = errors.New("callback response forbidden")
 
 type CallbackInvoker struct {
-	AprClient *aprclientset.Clientset
-	Retriable func(error) bool
+	AprClient  *aprclientset.Clientset
+	KubeClient kubernetes.Interface
+	Outbox     outbox.Store
+	watchers   *Watchers
+
+	// the signing secrets by the uid of the registry, reloaded after secretCacheTTL
+	secrets sync.Map
+}
+
+type cachedSecret struct {
+	key      []byte
+	loadTime time.Time
+}
+
+const secretCacheTTL = 5 * time.Minute
+
+func NewCallbackInvoker(w *Watchers, config *rest.Config) *CallbackInvoker {
+	return &CallbackInvoker{
+		AprClient:  aprclientset.NewForConfigOrDie(config),
+		KubeClient: kubernetes.NewForConfigOrDie(config),
+		Outbox:     w.Outbox(),
+		watchers:   w,
+	}
+}
+
+// EnsureSigningSecret returns the signing secret of the registry, and generates it if not exists.
+// The Secret is owned by the registry, so removed with it
+func EnsureSigningSecret(ctx context.Context, client kubernetes.Interface, cb *aprv1.SysEventRegistry) ([]byte, error) {
+	name := signature.SecretName(cb.Name)
+	secret, err := client.CoreV1().Secrets(cb.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		if key, ok := secret.Data[signature.SecretKey]; ok && len(key) > 0 {
+			return key, nil
+		}
+	} else if !apierrors.IsNotFound(err) {
+		klog.Error("get sys event signing secret error, ", err, ", ", cb.Name, ", ", cb.Namespace)
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	key = []byte(hex.EncodeToString(key))
+
+	if secret != nil && secret.Name != "" {
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		secret.Data[signature.SecretKey] = key
+		_, err = client.CoreV1().Secrets(cb.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	} else {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: cb.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(cb, aprv1.SchemeGroupVersion.WithKind("SysEventRegistry")),
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{signature.SecretKey: key},
+		}
+		_, err = client.CoreV1().Secrets(cb.Namespace).Create(ctx, secret, metav1.CreateOptions{})
+	}
+
+	if err != nil {
+		klog.Error("save sys event signing secret error, ", err, ", ", cb.Name, ", ", cb.Namespace)
+		return nil, err
+	}
+
+	klog.Info("sys event signing secret generated, ", name, ", ", cb.Namespace)
+	return key, nil
 }
 
-// invoke callback with 'data' when 'filter' is true
-func (s *CallbackInvoker) Invoke(ctx context.Context, filter func(cb *aprv1.SysEventRegistry) bool, data interface{}) (err error) {
-	callbacks, err := s.AprClient.AprV1alpha1().SysEventRegistries("").List(ctx, metav1.ListOptions{})
+// Invoke saves the event with 'data' to the outbox for every callback subscribing it,
+// the event is delivered to each of the callbacks independently by the dispatcher
+func (s *CallbackInvoker) Invoke(ctx context.Context, e *filter.Event, data interface{}) (err error) {
+	registries, err := s.watchers.Registries(ctx)
+	if err != nil {
+		klog.Error("list sys event callbacks error, ", err)
+		return err
+	}
+
+	callbacks, err := registries.List(labels.Everything())
 	if err != nil {
 		klog.Error("list sys event callbacks error, ", err)
 		return err
 	}
 
-	backoff := wait.Backoff{
-		Duration: time.Second,
-		Factor:   2,
-		Jitter:   0.1,
-		Steps:    InvokeRetry,
-		Cap:      120 * time.Second,
-	}
-
-	for _, cb := range callbacks.Items {
-		if filter(&cb) {
-			if cb.Spec.Callback == "" {
-				klog.Error("callback url is empty, ", cb.Name, ", ", cb.Namespace)
-				return errors.New("callback url is empty")
-			}
-
-			retriable := func(e error) bool {
-				return s.Retriable(e)
-			}
-
-			if err = retry.OnError(backoff,
-				retriable,
-				func() error {
-					klog.Info("send event ", cb.Spec.Event, " to, ", cb.Name, ", ", cb.Spec.Callback)
-					return s.sendEvent(ctx, &cb, data)
-				}); err != nil {
-				return err
-			}
+	return s.InvokeTo(ctx, callbacks, e, data)
+}
+
+// InvokeTo saves the event with 'data' to the outbox for every callback of 'callbacks' subscribing it
+func (s *CallbackInvoker) InvokeTo(ctx context.Context, callbacks []*aprv1.SysEventRegistry, e *filter.Event, data interface{}) error {
+	// the same id for the retries and all the callbacks of the event, to be deduplicated by the subscribers
+	ce, err := NewCloudEvent(ctx, e, data)
+	if err != nil {
+		klog.Error("encode event error, ", err)
+		return err
+	}
+
+	payload, err := json.Marshal(ce)
+	if err != nil {
+		klog.Error("encode event error, ", err)
+		return err
+	}
+
+	var messages []*outbox.Message
+	for _, cb := range callbacks {
+		if !s.subscribed(ctx, cb, e, ce.Data) {
+			continue
 		}
+
+		m := &outbox.Message{
+			EventID:           ce.ID,
+			Event:             string(e.Type),
+			RegistryNamespace: cb.Namespace,
+			RegistryName:      cb.Name,
+			Payload:           payload,
+		}
+
+		// keep the event to redrive
+		switch {
+		case cb.Spec.Callback == "":
+			klog.Error("callback url is empty, ", cb.Name, ", ", cb.Namespace)
+			m.State, m.LastError = outbox.StateDead, "callback url is empty"
+		case cb.Status.Disabled:
+			klog.Warning("callback is disabled, ", cb.Name, ", ", cb.Namespace)
+			m.State, m.LastError = outbox.StateDead, "callback is disabled"
+		}
+
+		messages = append(messages, m)
 	}
 
-	klog.Info("success to send events to all callbacks")
+	// emitted only after saved to the outbox, the retry on the failure does not emit it twice.
+	// The outbox and the history may be in the different stores, so not in a transaction
+	if len(messages) > 0 {
+		if err = s.Outbox.Enqueue(ctx, messages...); err != nil {
+			klog.Error("save events to outbox error, ", err)
+			return err
+		}
+
+		klog.Info("event ", e.Type, " ", ce.ID, " saved to outbox for ", len(messages), " callbacks")
+	}
+
+	s.watchers.Emit(ctx, e, ce)
 	return nil
 }
 
-func (s *CallbackInvoker) sendEvent(ctx context.Context, cb *aprv1.SysEventRegistry, data interface{}) error {
-	client := resty.New().SetTimeout(2 * time.Minute)
+// NewCloudEvent wraps the data of the event in the CloudEvents envelope.
+// The event of a queued obj has the same id on the retries
+func NewCloudEvent(ctx context.Context, e *filter.Event, data interface{}) (*cloudevents.Event, error) {
+	subject := e.App
+	if subject == "" {
+		subject = e.Owner
+	}
+
+	ce, err := cloudevents.New(string(e.Type), cloudevents.SourceOf(string(e.Type)), subject, data)
+	if err != nil {
+		return nil, err
+	}
+	ce.User = e.Owner
 
-	// TODO: invoke with auth if callback url is in the user space
-	res, err := client.R().
-		SetHeader(restful.HEADER_ContentType, restful.MIME_JSON).
-		SetBody(data).
+	// an obj may emit several events, they are told apart by the type and the owner
+	if id, ok := ctx.Value(eventIDKey{}).(string); ok && id != "" {
+		ce.ID = uuid.NewSHA1(uuid.NameSpaceOID, []byte(id+"/"+string(e.Type)+"/"+e.Owner+"/"+e.App)).String()
+	}
+
+	return ce, nil
+}
+
+// subscribed returns true if the event is subscribed by the callback, and selected by its filter.
+// The callback in a namespace owned by a user only receives the events of the owner
+func (s *CallbackInvoker) subscribed(ctx context.Context, cb *aprv1.SysEventRegistry, e *filter.Event, payload []byte) bool {
+	if cb.Spec.Type != aprv1.Subscriber || !filter.MatchType(cb.Spec.Event, e.Type) {
+		return false
+	}
+
+	namespaces, err := s.watchers.Namespaces(ctx)
+	if err != nil {
+		klog.Error("list namespaces error, ", err)
+		return false
+	}
+
+	var owner string
+	ns, err := namespaces.Get(cb.Namespace)
+	switch {
+	case err == nil:
+		owner = ns.Labels[NamespaceOwnerLabel]
+	case apierrors.IsNotFound(err):
+		return false
+	default:
+		klog.Error("get callback namespace error, ", err, ", ", cb.Namespace)
+		return false
+	}
+
+	match, err := filter.Match(cb.Spec.Filter, filter.Users(cb.Spec.Filter, owner), e, payload)
+	if err != nil {
+		klog.Error("match the event filter error, ", err, ", ", cb.Name, ", ", cb.Namespace)
+		return false
+	}
+
+	return match
+}
+
+func (s *CallbackInvoker) signingSecret(ctx context.Context, cb *aprv1.SysEventRegistry) ([]byte, error) {
+	if cached, ok := s.secrets.Load(cb.UID); ok && time.Since(cached.(*cachedSecret).loadTime) < secretCacheTTL {
+		return cached.(*cachedSecret).key, nil
+	}
+
+	key, err := EnsureSigningSecret(ctx, s.KubeClient, cb)
+	if err != nil {
+		return nil, err
+	}
+
+	s.secrets.Store(cb.UID, &cachedSecret{key: key, loadTime: time.Now()})
+	return key, nil
+}
+
+// envelope returns the CloudEvent saved in the message, the payloads saved
+// before the events were wrapped in the envelope are wrapped on delivery
+func envelope(m *outbox.Message) *cloudevents.Event {
+	var ce cloudevents.Event
+	if err := json.Unmarshal(m.Payload, &ce); err == nil && ce.Validate() == nil {
+		return &ce
+	}
+
+	return &cloudevents.Event{
+		SpecVersion:     cloudevents.SpecVersion,
+		ID:              m.EventID,
+		Source:          cloudevents.SourceOf(m.Event),
+		Type:            m.Event,
+		Time:            m.CreatedAt.UTC(),
+		DataContentType: cloudevents.ContentTypeJSON,
+		Data:            m.Payload,
+	}
+}
+
+// Send posts the event of the message to the callback in the content mode of the registry,
+// signed by the secret of the registry
+func (s *CallbackInvoker) Send(ctx context.Context, cb *aprv1.SysEventRegistry, m *outbox.Message) error {
+	key, err := s.signingSecret(ctx, cb)
+	if err != nil {
+		return err
+	}
+
+	ce := envelope(m)
+	header := http.Header{}
+	body, err := ce.Encode(cloudevents.ContentMode(cb.Spec.ContentMode), header)
+	if err != nil {
+		klog.Error("encode event error, ", err)
+		return err
+	}
+
+	signature.SetHeaders(header, key, ce.ID, body)
+	header.Set(signature.HeaderEventType, ce.Type)
+
+	res, err := callbackClient.R().
+		SetContext(ctx).
+		SetHeaderMultiValues(header).
+		SetBody(body).
 		Post(cb.Spec.Callback)
 
 	if err != nil {
@@ -82,7 +313,7 @@ func (s *CallbackInvoker) sendEvent(ctx context.Context, cb *aprv1.SysEventRegis
 	if res.StatusCode() >= 400 {
 		klog.Error("invoke callback response error code, ", res.StatusCode(), ", ", cb.Name, ", ", cb.Namespace)
 		if res.StatusCode() == 493 {
-			return fmt.Errorf("[%s] response forbidden, canceled", cb.Name)
+			return fmt.Errorf("[%s] %w, canceled", cb.Name, ErrCallbackForbidden)
 		}
 		return fmt.Errorf("invoke callback [%s] response error", cb.Name)
 	}
@@ -91,3 +322,44 @@ func (s *CallbackInvoker) sendEvent(ctx context.Context, cb *aprv1.SysEventRegis
 
 	return nil
 }
+
+// Replay saves the events of the history subscribed by the callback to the outbox again,
+// the events keep their ids to be deduplicated by the subscriber, returns the count replayed
+func (s *CallbackInvoker) Replay(ctx context.Context, cb *aprv1.SysEventRegistry, events []*history.Event) (int, error) {
+	if cb.Spec.Callback == "" || cb.Status.Disabled {
+		return 0, fmt.Errorf("callback [%s] is not available", cb.Name)
+	}
+
+	var messages []*outbox.Message
+	for _, h := range events {
+		var ce cloudevents.Event
+		if err := json.Unmarshal(h.Payload, &ce); err != nil {
+			klog.Warning("decode history event error, ", err, ", ", h.ID)
+			continue
+		}
+
+		e := &filter.Event{Type: aprv1.EventType(h.Type), Owner: h.User, App: h.App, Labels: h.Labels}
+		if !s.subscribed(ctx, cb, e, ce.Data) {
+			continue
+		}
+
+		messages = append(messages, &outbox.Message{
+			EventID:           h.ID,
+			Event:             h.Type,
+			RegistryNamespace: cb.Namespace,
+			RegistryName:      cb.Name,
+			Payload:           h.Payload,
+		})
+	}
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	if err := s.Outbox.Enqueue(ctx, messages...); err != nil {
+		klog.Error("save replayed events to outbox error, ", err)
+		return 0, err
+	}
+
+	return len(messages), nil
+}
@@ -0,0 +1,30 @@
+package watchers
+
+import (
+	"context"
+	"testing"
+
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
+)
+
+func TestNewCloudEventID(t *testing.T) {
+	ctx := withEventID(context.Background(), "obj")
+	login := &filter.Event{Type: "user.login", Owner: "alice"}
+
+	first, _ := NewCloudEvent(ctx, login, nil)
+	retry, _ := NewCloudEvent(ctx, login, nil)
+	if first.ID != retry.ID {
+		t.Errorf("expected the same id on the retry, %s, %s", first.ID, retry.ID)
+	}
+
+	other, _ := NewCloudEvent(ctx, &filter.Event{Type: "user.login", Owner: "bob"}, nil)
+	if other.ID == first.ID {
+		t.Errorf("expected the events of the obj told apart, %s", other.ID)
+	}
+
+	a, _ := NewCloudEvent(context.Background(), login, nil)
+	b, _ := NewCloudEvent(context.Background(), login, nil)
+	if a.ID == b.ID {
+		t.Errorf("expected the new ids out of the queue, %s", a.ID)
+	}
+}
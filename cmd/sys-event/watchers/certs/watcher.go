@@ -0,0 +1,236 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
+	"bytetrade.io/web3os/tapr/pkg/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultExpiryThreshold is the time before the expiry the certificates are alerted
+	DefaultExpiryThreshold = 30 * 24 * time.Hour
+
+	// APIServerCert is the name of the serving certificate of the kube-apiserver in the events
+	APIServerCert = "kube-apiserver"
+
+	checkInterval  = time.Hour
+	resendInterval = 24 * time.Hour
+)
+
+// Cert is a certificate of the cluster nearing the expiry
+type Cert struct {
+	Name      string
+	Namespace string
+	Cert      *x509.Certificate
+}
+
+// Watcher checks the serving certificate of the kube-apiserver and the tls secrets of the cluster,
+// sends the events of the certificates nearing the expiry once a day
+type Watcher struct {
+	ctx           context.Context
+	config        *rest.Config
+	kubeClient    kubernetes.Interface
+	eventWatchers *watchers.Watchers
+	notification  *watchers.Notification
+	invoker       *watchers.CallbackInvoker
+	threshold     time.Duration
+
+	lastSent map[string]time.Time
+}
+
+func NewWatcher(ctx context.Context, w *watchers.Watchers, n *watchers.Notification, kubeconfig *rest.Config) *Watcher {
+	threshold := DefaultExpiryThreshold
+	if env := os.Getenv("CERT_EXPIRY_THRESHOLD"); env != "" {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			klog.Warning("invalid CERT_EXPIRY_THRESHOLD, ", err)
+		} else {
+			threshold = d
+		}
+	}
+
+	return &Watcher{
+		ctx:           ctx,
+		config:        kubeconfig,
+		kubeClient:    kubernetes.NewForConfigOrDie(kubeconfig),
+		eventWatchers: w,
+		notification:  n,
+		invoker:       watchers.NewCallbackInvoker(w, kubeconfig),
+		threshold:     threshold,
+		lastSent:      make(map[string]time.Time),
+	}
+}
+
+func (w *Watcher) Run() {
+	klog.Info("start cluster certificates watcher")
+
+	wait.PollImmediateInfiniteWithContext(w.ctx, checkInterval, func(ctx context.Context) (done bool, err error) {
+		if ctx.Err() != nil {
+			return true, nil
+		}
+
+		now := time.Now()
+		for _, c := range w.expiring(ctx, now) {
+			key := c.Namespace + "/" + c.Name + "/" + c.Cert.SerialNumber.String()
+			if now.Sub(w.lastSent[key]) < resendInterval {
+				continue
+			}
+
+			if err := w.send(ctx, c, now); err != nil {
+				klog.Warning("send certificate expiring event error, ", err, ", ", key)
+				continue
+			}
+			w.lastSent[key] = now
+		}
+
+		return false, nil
+	})
+}
+
+// expiring returns the certificates expiring within the threshold
+func (w *Watcher) expiring(ctx context.Context, now time.Time) []*Cert {
+	var certs []*Cert
+	if cert, err := w.apiServerCert(); err != nil {
+		klog.Warning("get kube-apiserver certificate error, ", err)
+	} else {
+		certs = append(certs, &Cert{Name: APIServerCert, Cert: cert})
+	}
+
+	secrets, err := w.kubeClient.CoreV1().Secrets("").List(ctx, metav1.ListOptions{FieldSelector: "type=" + string(corev1.SecretTypeTLS)})
+	if err != nil {
+		klog.Error("list tls secrets error, ", err)
+	} else {
+		for _, secret := range secrets.Items {
+			cert, err := parseCert(secret.Data[corev1.TLSCertKey])
+			if err != nil {
+				klog.V(4).Info("parse certificate of secret error, ", err, ", ", secret.Namespace, "/", secret.Name)
+				continue
+			}
+
+			certs = append(certs, &Cert{Name: secret.Name, Namespace: secret.Namespace, Cert: cert})
+		}
+	}
+
+	var ret []*Cert
+	for _, c := range certs {
+		if c.Cert.NotAfter.Sub(now) < w.threshold {
+			ret = append(ret, c)
+		}
+	}
+
+	return ret
+}
+
+func (w *Watcher) send(ctx context.Context, c *Cert, now time.Time) error {
+	data := &cloudevents.CertData{
+		Name:      c.Name,
+		Namespace: c.Namespace,
+		Subject:   c.Cert.Subject.String(),
+		DNSNames:  c.Cert.DNSNames,
+		NotAfter:  c.Cert.NotAfter,
+		DaysLeft:  int(c.Cert.NotAfter.Sub(now).Hours() / 24),
+	}
+
+	var owner string
+	if c.Namespace != "" {
+		owner = w.eventWatchers.NamespaceOwner(ctx, c.Namespace)
+	}
+
+	klog.InfoS("certificate is nearing the expiry", "name", c.Name, "namespace", c.Namespace, "notAfter", c.Cert.NotAfter)
+	err := w.invoker.Invoke(ctx, &filter.Event{Type: aprv1.CertExpiring, Owner: owner}, data)
+	if err != nil {
+		klog.Warning(err)
+	}
+
+	if w.notification == nil {
+		return nil
+	}
+
+	name := c.Name
+	if c.Namespace != "" {
+		name = c.Namespace + "/" + c.Name
+	}
+	msg := fmt.Sprintf("certificate %s expires in %d days", name, data.DaysLeft)
+	if data.DaysLeft < 0 {
+		msg = fmt.Sprintf("certificate %s has expired", name)
+	}
+	payload := &watchers.EventPayload{Type: string(aprv1.CertExpiring), Data: data}
+
+	admin, err := w.notification.AdminUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if err = w.notification.Send(ctx, admin, msg, payload); err != nil {
+		errs = append(errs, err)
+	}
+
+	if owner != "" && owner != admin {
+		if err = w.notification.Send(ctx, owner, msg, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utils.AggregateErrs(errs)
+}
+
+// apiServerCert returns the serving certificate of the kube-apiserver, the certificate is not verified
+// to be read even if it has expired
+func (w *Watcher) apiServerCert() (*x509.Certificate, error) {
+	u, err := url.Parse(w.config.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("kube-apiserver is not served in https, %s", w.config.Host)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", host,
+		&tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate of kube-apiserver")
+	}
+
+	return certs[0], nil
+}
+
+func parseCert(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
 	"k8s.io/klog/v2"
 )
 
 type Subscriber struct {
 	notification *watchers.Notification
+	watchers     *watchers.Watchers
 }
 
 func (s *Subscriber) WithNotification(n *watchers.Notification) *Subscriber {
@@ -16,11 +20,28 @@ func (s *Subscriber) WithNotification(n *watchers.Notification) *Subscriber {
 	return s
 }
 
+func (s *Subscriber) WithWatchers(w *watchers.Watchers) *Subscriber {
+	s.watchers = w
+	return s
+}
+
 func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Action) error {
 	event := obj.(*CustomEvent)
 	switch action {
 	case watchers.ADD:
 		klog.Info("user ", event.User, " fire event ", event.Type, ", ", event.Message)
+		if s.watchers != nil {
+			e := &filter.Event{Type: aprv1.EventType(event.Type), Owner: event.User}
+			ce, err := watchers.NewCloudEvent(ctx, e, event.Data)
+			if err != nil {
+				klog.Error("encode event error, ", err)
+				return err
+			}
+			ce.Source = cloudevents.CustomSource
+
+			s.watchers.Emit(ctx, e, ce)
+		}
+
 		if s.notification != nil {
 			return s.notification.Send(ctx, event.User, event.Message, &watchers.EventPayload{
 				Type: event.Type,
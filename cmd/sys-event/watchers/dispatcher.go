@@ -0,0 +1,314 @@
+package watchers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/outbox"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const (
+	dispatchInterval = time.Second
+	dispatchBatch    = 100
+	purgeInterval    = time.Hour
+
+	// DispatchWorkers is the number of the callbacks delivered concurrently
+	DispatchWorkers = 16
+
+	// maxInflight bounds the messages claimed and not delivered yet
+	maxInflight = 1000
+
+	// AutoDisableThreshold is the consecutive failures of the callback to disable the delivery
+	AutoDisableThreshold = 50
+
+	// successStatsInterval throttles the status updates of the healthy callbacks
+	successStatsInterval = time.Minute
+)
+
+// Dispatcher delivers the events in the outbox to the callbacks through a bounded worker pool.
+// The messages of every callback are queued in a lane and delivered in order, the workqueue
+// guarantees a lane is processed by one worker at a time, so a slow callback only delays itself.
+// The lane stops at the message failed to deliver, the messages behind it wait for its retry
+type Dispatcher struct {
+	invoker *CallbackInvoker
+	queue   workqueue.Interface
+
+	mu       sync.Mutex
+	lanes    map[string][]*outbox.Message
+	inflight map[int64]bool
+
+	// blocked are the lanes stopped until the retry of the failed message
+	blocked map[string]time.Time
+}
+
+func NewDispatcher(invoker *CallbackInvoker) *Dispatcher {
+	return &Dispatcher{
+		invoker:  invoker,
+		queue:    workqueue.NewNamed("sys-event-dispatcher"),
+		lanes:    make(map[string][]*outbox.Message),
+		inflight: make(map[int64]bool),
+		blocked:  make(map[string]time.Time),
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	klog.Info("start sys event dispatcher")
+	defer d.queue.ShutDown()
+
+	go wait.Until(func() {
+		now := time.Now()
+		if err := d.invoker.Outbox.Purge(ctx, now.Add(-outbox.DeliveredRetention), now.Add(-outbox.DeadRetention)); err != nil {
+			klog.Error("purge delivered and dead-lettered events error, ", err)
+		}
+	}, purgeInterval, ctx.Done())
+
+	for i := 0; i < DispatchWorkers; i++ {
+		go wait.Until(func() {
+			for d.processNextLane(ctx) {
+			}
+		}, time.Second, ctx.Done())
+	}
+
+	wait.Until(func() { d.claim(ctx) }, dispatchInterval, ctx.Done())
+}
+
+// claim moves the messages due from the outbox to the lanes of the callbacks
+func (d *Dispatcher) claim(ctx context.Context) {
+	for {
+		d.mu.Lock()
+		limit := maxInflight - len(d.inflight)
+		d.mu.Unlock()
+		if limit <= 0 {
+			return
+		}
+
+		if limit > dispatchBatch {
+			limit = dispatchBatch
+		}
+
+		messages, err := d.invoker.Outbox.Claim(ctx, limit)
+		if err != nil || len(messages) == 0 {
+			return
+		}
+
+		now := time.Now()
+		deferred := make(map[time.Time][]int64)
+		d.mu.Lock()
+		for _, m := range messages {
+			// claimed again after the claim timeout, still waiting in the lane
+			if d.inflight[m.ID] {
+				continue
+			}
+
+			key := m.RegistryNamespace + "/" + m.RegistryName
+			if until, ok := d.blocked[key]; ok {
+				if until.After(now) {
+					// keep the message behind the failed one
+					deferred[until] = append(deferred[until], m.ID)
+					continue
+				}
+				delete(d.blocked, key)
+			}
+
+			d.inflight[m.ID] = true
+			d.lanes[key] = append(d.lanes[key], m)
+			d.queue.Add(key)
+		}
+		d.mu.Unlock()
+
+		for until, ids := range deferred {
+			if err = d.invoker.Outbox.Defer(ctx, ids, until); err != nil {
+				klog.Error("defer events error, ", err)
+			}
+		}
+	}
+}
+
+// processNextLane delivers the messages queued in the lane in order
+func (d *Dispatcher) processNextLane(ctx context.Context) bool {
+	key, shutdown := d.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer d.queue.Done(key)
+
+	for {
+		d.mu.Lock()
+		lane := d.lanes[key.(string)]
+		if len(lane) == 0 {
+			delete(d.lanes, key.(string))
+			d.mu.Unlock()
+			return true
+		}
+		m := lane[0]
+		d.lanes[key.(string)] = lane[1:]
+		d.mu.Unlock()
+
+		retry := d.deliver(ctx, m)
+
+		d.mu.Lock()
+		delete(d.inflight, m.ID)
+		var rest []int64
+		if retry != nil {
+			// stop the lane, the rest of the lane is claimed again after the failed message
+			for _, r := range d.lanes[key.(string)] {
+				delete(d.inflight, r.ID)
+				rest = append(rest, r.ID)
+			}
+			delete(d.lanes, key.(string))
+			d.blocked[key.(string)] = *retry
+		}
+		d.mu.Unlock()
+
+		if retry != nil {
+			if err := d.invoker.Outbox.Defer(ctx, rest, *retry); err != nil {
+				klog.Error("defer events error, ", err)
+			}
+			return true
+		}
+
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+}
+
+// deliver sends the message to the callback, returns the time of the next attempt if the message
+// is failed to deliver and not dead-lettered
+func (d *Dispatcher) deliver(ctx context.Context, m *outbox.Message) *time.Time {
+	registries, err := d.invoker.watchers.Registries(ctx)
+	if err != nil {
+		return d.fail(ctx, m, err, false)
+	}
+
+	cb, err := registries.SysEventRegistries(m.RegistryNamespace).Get(m.RegistryName)
+	switch {
+	case apierrors.IsNotFound(err):
+		return d.fail(ctx, m, errors.New("callback is unregistered"), true)
+	case err != nil:
+		klog.Error("get sys event callback error, ", err, ", ", m.RegistryName, ", ", m.RegistryNamespace)
+		return d.fail(ctx, m, err, false)
+	case cb.Status.Disabled:
+		return d.fail(ctx, m, errors.New("callback is disabled"), true)
+	}
+
+	klog.Info("send event ", m.Event, " ", m.EventID, " to, ", cb.Name, ", ", cb.Spec.Callback)
+	sendCtx, cancel := context.WithTimeout(ctx, CallbackTimeout)
+	err = d.invoker.Send(sendCtx, cb, m)
+	cancel()
+
+	var retry *time.Time
+	if err == nil {
+		if err = d.invoker.Outbox.Delivered(ctx, m.ID); err != nil {
+			klog.Error("mark event delivered error, ", err, ", ", m.ID)
+		}
+	} else {
+		retry = d.fail(ctx, m, err, errors.Is(err, ErrCallbackForbidden))
+	}
+
+	d.updateStats(ctx, cb, err)
+	return retry
+}
+
+// fail schedules the next attempt of the message with backoff, or dead-letters it.
+// Returns the time of the next attempt, nil if dead-lettered
+func (d *Dispatcher) fail(ctx context.Context, m *outbox.Message, cause error, dead bool) *time.Time {
+	attempts := m.Attempts + 1
+	dead = dead || attempts >= outbox.MaxAttempts
+	if dead {
+		klog.Warning("event dead-lettered, ", m.EventID, ", ", m.RegistryName, ", ", m.RegistryNamespace, ", ", cause)
+	}
+
+	next := time.Now().Add(outbox.Backoff(attempts))
+	if err := d.invoker.Outbox.Failed(ctx, m.ID, attempts, next, dead, cause.Error()); err != nil {
+		klog.Error("mark event failed error, ", err, ", ", m.ID)
+	}
+
+	if dead {
+		return nil
+	}
+
+	return &next
+}
+
+// updateStats records the delivery result in the status of the registry,
+// and disables the delivery after too many consecutive failures
+func (d *Dispatcher) updateStats(ctx context.Context, cb *aprv1.SysEventRegistry, deliverErr error) {
+	if deliverErr == nil && cb.Status.ConsecutiveFailures == 0 && cb.Status.LastSuccessTime != nil &&
+		time.Since(cb.Status.LastSuccessTime.Time) < successStatsInterval {
+		return
+	}
+
+	client := d.invoker.AprClient.AprV1alpha1().SysEventRegistries(cb.Namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := client.Get(ctx, cb.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		now := metav1.Now()
+		if deliverErr == nil {
+			current.Status.LastSuccessTime = &now
+			current.Status.ConsecutiveFailures = 0
+			current.Status.State = aprv1.SysEventRegistryActive
+		} else {
+			current.Status.LastFailureTime = &now
+			current.Status.LastError = deliverErr.Error()
+			current.Status.ConsecutiveFailures++
+			if current.Status.ConsecutiveFailures >= AutoDisableThreshold && !current.Status.Disabled {
+				klog.Warning("disable the callback after consecutive failures, ", cb.Name, ", ", cb.Namespace)
+				current.Status.Disabled = true
+				current.Status.State = aprv1.SysEventRegistryDisabled
+			}
+		}
+		current.Status.StatusTime = &now
+		if current.Status.UpdateTime == nil {
+			// required by the status subresource, not set if the status is dropped on creation
+			current.Status.UpdateTime = &now
+		}
+
+		_, err = client.UpdateStatus(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+
+	if err != nil {
+		klog.Error("update sys event callback status error, ", err, ", ", cb.Name, ", ", cb.Namespace)
+	}
+}
+
+// Enable resets the delivery stats of the registry, and enables the delivery if disabled
+func (d *Dispatcher) Enable(ctx context.Context, namespace, name string) error {
+	client := d.invoker.AprClient.AprV1alpha1().SysEventRegistries(namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if !current.Status.Disabled && current.Status.ConsecutiveFailures == 0 {
+			return nil
+		}
+
+		now := metav1.Now()
+		current.Status.Disabled = false
+		current.Status.ConsecutiveFailures = 0
+		current.Status.State = aprv1.SysEventRegistryActive
+		current.Status.StatusTime = &now
+		if current.Status.UpdateTime == nil {
+			// required by the status subresource, not set if the status is dropped on creation
+			current.Status.UpdateTime = &now
+		}
+
+		_, err = client.UpdateStatus(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+}
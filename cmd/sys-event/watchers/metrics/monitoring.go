@@ -5,134 +5,67 @@ import (
 	"time"
 
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/alert"
 	"bytetrade.io/web3os/tapr/pkg/utils"
 
-	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 )
 
-var DefaultMetricThreshold = 0.9
+// evaluateTick is the resolution of the evaluation intervals of the rules
+const evaluateTick = time.Second
 
 type Watcher struct {
 	ctx           context.Context
 	subscriber    *Subscriber
 	eventWatchers *watchers.Watchers
 	monitoring    Monitoring
+
+	// the states of the alerts, keyed by the rule, the user and the labels of the series
+	alerts map[string]*alert.Alert
+	// the last evaluation time of the rules
+	evaluated map[string]time.Time
 }
 
 func NewMetricsWatcherOrDie(ctx context.Context,
 	w *watchers.Watchers, n *watchers.Notification, kubeconfig *rest.Config) *Watcher {
 	return &Watcher{
 		ctx:           ctx,
-		subscriber:    (&Subscriber{notification: n}).WithKubeConfig(kubeconfig),
+		subscriber:    (&Subscriber{notification: n, invoker: watchers.NewCallbackInvoker(w, kubeconfig)}).WithKubeConfig(kubeconfig),
 		eventWatchers: w,
 		monitoring:    utils.ValueMust[Monitoring](NewPrometheus(PrometheusEndpoint)),
+		alerts:        make(map[string]*alert.Alert),
+		evaluated:     make(map[string]time.Time),
 	}
 }
 
 func (w *Watcher) Run() {
 	klog.Info("start cluster metrics watcher")
 
-	latestSend := map[string]time.Time{
-		"cluster_cpu_utilisation":    time.Now().Add(-2 * time.Minute),
-		"cluster_memory_utilisation": time.Now().Add(-2 * time.Minute),
-		"user_cpu_usage":             time.Now().Add(-2 * time.Minute),
-		"user_memory_usage":          time.Now().Add(-2 * time.Minute),
+	if err := w.createDefaultRules(w.ctx); err != nil {
+		klog.Warning("create default alert rules error, ", err)
+	}
+
+	rules, err := w.eventWatchers.AlertRules(w.ctx)
+	if err != nil {
+		klog.Error("list alert rules error, ", err)
+		return
 	}
 
-	wait.PollImmediateInfiniteWithContext(w.ctx, 5*time.Second, func(ctx context.Context) (done bool, err error) {
+	wait.PollImmediateInfiniteWithContext(w.ctx, evaluateTick, func(ctx context.Context) (done bool, err error) {
 		if ctx.Err() != nil {
 			return true, nil
 		}
-		opts := QueryOptions{
-			Level: LevelCluster,
-		}
 
-		metrics := w.monitoring.GetNamedMetrics(ctx, []string{"cluster_cpu_utilisation", "cluster_memory_utilisation"}, time.Now(), opts)
-
-		for _, m := range metrics {
-			if GetValue(&m) > DefaultMetricThreshold {
-				now := time.Now()
-				if now.Sub(latestSend[m.MetricName]) > 1*time.Minute {
-					w.eventWatchers.Enqueue(watchers.EnqueueObj{
-						Obj:       &m,
-						Action:    watchers.UNKNOWN,
-						Subscribe: w.subscriber,
-					})
-					latestSend[m.MetricName] = now
-				}
-			}
-		}
-		users, err := w.subscriber.notification.DynamicClient.Resource(watchers.UserSchemeGroupVersionResource).List(ctx, metav1.ListOptions{})
+		list, err := rules.List(labels.Everything())
 		if err != nil {
-			klog.Error("get user list error, ", err)
-			return false, err
-		}
-
-		userResourceMap := make(map[string]UserMetrics)
-
-		filteredUsers := make([]string, 0)
-		for _, user := range users.Items {
-			c, err := resource.ParseQuantity(user.GetAnnotations()["bytetrade.io/user-cpu-limit"])
-			if err != nil {
-				continue
-			}
-			m, err := resource.ParseQuantity(user.GetAnnotations()["bytetrade.io/user-memory-limit"])
-			if err != nil {
-				continue
-			}
-			userResourceMap[user.GetName()] = UserMetrics{
-				CPU: Value{
-					Total: c.AsApproximateFloat64(),
-				},
-				Memory: Value{
-					Total: m.AsApproximateFloat64(),
-				},
-			}
-			filteredUsers = append(filteredUsers, user.GetName())
-		}
-
-		for _, username := range filteredUsers {
-			opts = QueryOptions{
-				Level:    LevelUser,
-				UserName: username,
-			}
-			metrics = w.monitoring.GetNamedMetrics(ctx, []string{"user_cpu_usage", "user_memory_usage"}, time.Now(), opts)
-			for _, m := range metrics {
-				switch m.MetricName {
-				case "user_cpu_usage":
-					if GetValue(&m) > userResourceMap[username].CPU.Total*DefaultMetricThreshold {
-						now := time.Now()
-						if now.Sub(latestSend[m.MetricName]) > 1*time.Minute {
-							w.eventWatchers.Enqueue(watchers.EnqueueObj{
-								Obj:       &m,
-								Action:    watchers.UNKNOWN,
-								Subscribe: w.subscriber,
-							})
-							latestSend[m.MetricName] = now
-						}
-					}
-				case "user_memory_usage":
-					if GetValue(&m) > userResourceMap[username].Memory.Total*DefaultMetricThreshold {
-						now := time.Now()
-						if now.Sub(latestSend[m.MetricName]) > 1*time.Minute {
-							w.eventWatchers.Enqueue(watchers.EnqueueObj{
-								Obj:       &m,
-								Action:    watchers.UNKNOWN,
-								Subscribe: w.subscriber,
-							})
-							latestSend[m.MetricName] = now
-						}
-					}
-
-				}
-
-			}
+			klog.Error("list alert rules error, ", err)
+			return false, nil
 		}
 
+		w.evaluate(ctx, list, time.Now())
 		return false, nil
 	})
 }
@@ -39,6 +39,7 @@ type Value struct {
 
 type Monitoring interface {
 	GetNamedMetrics(ctx context.Context, metrics []string, ts time.Time, opts QueryOptions) []Metric
+	Query(ctx context.Context, name, expr string, ts time.Time) Metric
 }
 
 // prometheus implements monitoring interface backed by Prometheus
@@ -83,14 +84,7 @@ func (p prometheus) GetNamedMetrics(ctx context.Context, metrics []string, ts ti
 	for _, metric := range metrics {
 		wg.Add(1)
 		go func(metric string) {
-			parsedResp := Metric{MetricName: metric}
-
-			value, _, err := p.client.Query(ctx, makeExpr(metric, opts), ts)
-			if err != nil {
-				parsedResp.Error = err.Error()
-			} else {
-				parsedResp.MetricData = parseQueryResp(value)
-			}
+			parsedResp := p.Query(ctx, metric, makeExpr(metric, opts), ts)
 
 			mtx.Lock()
 			res = append(res, parsedResp)
@@ -105,6 +99,20 @@ func (p prometheus) GetNamedMetrics(ctx context.Context, metrics []string, ts ti
 	return res
 }
 
+// Query evaluates the PromQL expression at the time, the result is named the name
+func (p prometheus) Query(ctx context.Context, name, expr string, ts time.Time) Metric {
+	res := Metric{MetricName: name}
+
+	value, _, err := p.client.Query(ctx, expr, ts)
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		res.MetricData = parseQueryResp(value)
+	}
+
+	return res
+}
+
 func parseQueryResp(value model.Value) MetricData {
 	res := MetricData{MetricType: MetricTypeVector}
 
@@ -0,0 +1,252 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/alert"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// UserLimitAnnotation is the annotation of the user's limit of the resource, cpu or memory
+const UserLimitAnnotation = "bytetrade.io/user-%s-limit"
+
+// Alert is the alert of a series of the rule fired or resolved
+type Alert struct {
+	Rule      *aprv1.AlertRule
+	User      string
+	Status    alert.Status
+	Value     float64
+	Threshold float64
+	Labels    map[string]string
+}
+
+// defaultRules are the rules created if not exist, they can be edited or disabled
+var defaultRules = []*aprv1.AlertRule{
+	newDefaultRule("cluster-cpu-high", "cluster_cpu_utilisation", aprv1.AlertLevelCluster, "cpu",
+		aprv1.CPUHigh, aprv1.CPUResolved, time.Minute, "High CPU load alert"),
+	newDefaultRule("cluster-memory-high", "cluster_memory_utilisation", aprv1.AlertLevelCluster, "memory",
+		aprv1.MemoryHigh, aprv1.MemoryResolved, time.Minute, "High memory usage alert"),
+	newDefaultRule("user-cpu-high", "user_cpu_usage", aprv1.AlertLevelUser, "cpu",
+		aprv1.UserCPUHigh, aprv1.UserCPUResolved, time.Hour, "High cpu usage alert"),
+	newDefaultRule("user-memory-high", "user_memory_usage", aprv1.AlertLevelUser, "memory",
+		aprv1.UserMemoryHigh, aprv1.UserMemoryResolved, time.Hour, "High memory usage alert"),
+	newDefaultRule("node-disk-high", "node_disk_size_utilisation", aprv1.AlertLevelCluster, "",
+		aprv1.NodeDiskHigh, aprv1.NodeDiskResolved, 6*time.Hour, "Node disk is nearly full"),
+	newDefaultRule("node-inode-high", "node_disk_inode_utilisation", aprv1.AlertLevelCluster, "",
+		aprv1.NodeInodeHigh, aprv1.NodeInodeResolved, 6*time.Hour, "Node inodes are nearly exhausted"),
+	newDefaultRule("pvc-nearly-full", "pvc_bytes_utilisation", aprv1.AlertLevelCluster, "",
+		aprv1.PVCFull, aprv1.PVCFullResolved, 6*time.Hour, "Persistent volume is nearly full"),
+}
+
+func newDefaultRule(name, metric string, level aprv1.AlertLevel, res string,
+	event, resolved aprv1.EventType, repeat time.Duration, message string) *aprv1.AlertRule {
+	return &aprv1.AlertRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: aprv1.AlertRuleSpec{
+			Metric:          metric,
+			Level:           level,
+			Operator:        aprv1.AlertGreaterThan,
+			Threshold:       resource.MustParse("0.9"),
+			Resource:        res,
+			RelativeToLimit: level == aprv1.AlertLevelUser,
+			RepeatInterval:  &metav1.Duration{Duration: repeat},
+			Severity:        aprv1.AlertWarning,
+			Event:           event,
+			ResolvedEvent:   resolved,
+			Message:         message,
+		},
+	}
+}
+
+func (w *Watcher) createDefaultRules(ctx context.Context) error {
+	client := w.subscriber.aprClient.AprV1alpha1().AlertRules()
+	for _, rule := range defaultRules {
+		_, err := client.Create(ctx, rule, metav1.CreateOptions{})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			klog.Error("create alert rule error, ", err, ", ", rule.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evaluate evaluates the rules due at the time, and sends the events of the alerts fired or resolved
+func (w *Watcher) evaluate(ctx context.Context, rules []*aprv1.AlertRule, now time.Time) {
+	existing := make(map[string]bool)
+	var limits map[string]map[string]string
+
+	for _, rule := range rules {
+		existing[rule.Name] = !rule.Spec.Disabled
+		if rule.Spec.Disabled || now.Sub(w.evaluated[rule.Name]) < alert.Interval(&rule.Spec) {
+			continue
+		}
+		w.evaluated[rule.Name] = now
+
+		if err := alert.Validate(&rule.Spec); err != nil {
+			klog.Warning("invalid alert rule, ", err, ", ", rule.Name)
+			continue
+		}
+
+		if rule.Spec.Level != aprv1.AlertLevelUser {
+			w.evaluateSeries(ctx, rule, "", 1, now)
+			continue
+		}
+
+		if limits == nil {
+			var err error
+			if limits, err = w.userAnnotations(ctx); err != nil {
+				continue
+			}
+		}
+
+		evaluated := make(map[string]bool)
+		for user, annotations := range limits {
+			scale := float64(1)
+			if rule.Spec.RelativeToLimit {
+				limit, err := resource.ParseQuantity(annotations[fmt.Sprintf(UserLimitAnnotation, rule.Spec.Resource)])
+				if err != nil {
+					continue
+				}
+				scale = limit.AsApproximateFloat64()
+			}
+
+			evaluated[user] = true
+			w.evaluateSeries(ctx, rule, user, scale, now)
+		}
+
+		// forget the alerts of the users deleted
+		for key := range w.alerts {
+			name, rest, _ := strings.Cut(key, "/")
+			user, _, _ := strings.Cut(rest, "/")
+			if name == rule.Name && !evaluated[user] {
+				delete(w.alerts, key)
+			}
+		}
+	}
+
+	// forget the alerts of the rules deleted or disabled
+	for key := range w.alerts {
+		name, _, _ := strings.Cut(key, "/")
+		if !existing[name] {
+			delete(w.alerts, key)
+			delete(w.evaluated, name)
+		}
+	}
+}
+
+// evaluateSeries evaluates the query of the rule for the cluster or the user, every series is alerted separately
+func (w *Watcher) evaluateSeries(ctx context.Context, rule *aprv1.AlertRule, user string, scale float64, now time.Time) {
+	expr := rule.Spec.Expr
+	if rule.Spec.Metric != "" {
+		var ok bool
+		if expr, ok = promQLTemplates[rule.Spec.Metric]; !ok {
+			klog.Warning("unknown metric of the alert rule, ", rule.Spec.Metric, ", ", rule.Name)
+			return
+		}
+	}
+
+	// the selector is empty in the cluster level
+	expr = makeUserMetricExpr(expr, user)
+
+	m := w.monitoring.Query(ctx, rule.Name, expr, now)
+	if m.Error != "" {
+		klog.Warning("query the metric of the alert rule error, ", m.Error, ", ", rule.Name)
+		return
+	}
+
+	r := alert.NewRule(&rule.Spec, scale)
+	prefix := rule.Name + "/" + user + "/"
+	seen := make(map[string]bool)
+	for _, v := range m.MetricValues {
+		if v.Sample == nil {
+			continue
+		}
+
+		key := prefix + seriesKey(v.Metadata)
+		seen[key] = true
+
+		a, ok := w.alerts[key]
+		if !ok {
+			a = &alert.Alert{Status: alert.Inactive}
+			w.alerts[key] = a
+		}
+
+		a.Labels = v.Metadata
+		if r.Evaluate(a, v.Sample[1], now) != alert.None {
+			w.send(ctx, rule, user, r, a)
+		}
+	}
+
+	for key, a := range w.alerts {
+		if !strings.HasPrefix(key, prefix) || seen[key] {
+			continue
+		}
+
+		if r.Vanish(a) != alert.None {
+			w.send(ctx, rule, user, r, a)
+		}
+		delete(w.alerts, key)
+	}
+}
+
+func (w *Watcher) send(ctx context.Context, rule *aprv1.AlertRule, user string, r *alert.Rule, a *alert.Alert) {
+	// the alerts of the series in a namespace, e.g. the pvc, are sent to the owner of the namespace
+	if ns := a.Labels["namespace"]; user == "" && ns != "" {
+		user = w.eventWatchers.NamespaceOwner(ctx, ns)
+	}
+
+	w.eventWatchers.Enqueue(watchers.EnqueueObj{
+		Obj: &Alert{
+			Rule:      rule,
+			User:      user,
+			Status:    a.Status,
+			Value:     a.Value,
+			Threshold: r.Threshold,
+			Labels:    a.Labels,
+		},
+		Action:    watchers.UNKNOWN,
+		Subscribe: w.subscriber,
+	})
+}
+
+// userAnnotations returns the annotations of the users, the limits of the users are in the annotations
+func (w *Watcher) userAnnotations(ctx context.Context) (map[string]map[string]string, error) {
+	users, err := w.subscriber.notification.DynamicClient.Resource(watchers.UserSchemeGroupVersionResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Error("get user list error, ", err)
+		return nil, err
+	}
+
+	ret := make(map[string]map[string]string)
+	for _, user := range users.Items {
+		ret[user.GetName()] = user.GetAnnotations()
+	}
+
+	return ret, nil
+}
+
+func seriesKey(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + metadata[k] + ",")
+	}
+
+	return b.String()
+}
@@ -3,22 +3,18 @@ package metrics
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
-	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/alert"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
 	"bytetrade.io/web3os/tapr/pkg/utils"
 
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 )
 
-var latestSend = map[string]time.Time{
-	"user_cpu_usage":    time.Now().Add(-2 * time.Hour),
-	"user_memory_usage": time.Now().Add(-2 * time.Hour),
-}
-
 type Subscriber struct {
 	notification *watchers.Notification
 	aprClient    *aprclientset.Clientset
@@ -27,161 +23,64 @@ type Subscriber struct {
 
 func (s *Subscriber) WithKubeConfig(config *rest.Config) *Subscriber {
 	s.aprClient = aprclientset.NewForConfigOrDie(config)
-	s.invoker = &watchers.CallbackInvoker{
-		AprClient: s.aprClient,
-		Retriable: func(err error) bool { return true },
-	}
 	return s
 }
 
 func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Action) error {
+	a := obj.(*Alert)
+	spec := &a.Rule.Spec
+
+	eventType := spec.Event
+	if a.Status == alert.Resolved {
+		eventType = alert.ResolvedEvent(spec)
+	}
+
+	klog.InfoS("metrics alert", "rule", a.Rule.Name, "status", a.Status, "user", a.User, "value", a.Value)
+	data := &cloudevents.MetricsData{
+		User:      a.User,
+		Rule:      a.Rule.Name,
+		Status:    string(a.Status),
+		Severity:  string(spec.Severity),
+		Value:     a.Value,
+		Threshold: a.Threshold,
+		Labels:    a.Labels,
+	}
+
+	switch spec.Resource {
+	case "cpu":
+		data.CPU = a.Value
+	case "memory":
+		data.Memory = a.Value
+	}
+
+	err := s.invoker.Invoke(ctx, &filter.Event{Type: eventType, Owner: a.User}, data)
+	if err != nil {
+		klog.Warning(err)
+	}
+
+	// only the alerts firing are notified
+	if s.notification == nil || spec.Message == "" || a.Status != alert.Firing {
+		return nil
+	}
+
 	admin, err := s.notification.AdminUser(ctx)
 	if err != nil {
 		return err
 	}
 
-	metric := obj.(*Metric)
-
-	switch metric.MetricName {
-	case "cluster_cpu_utilisation":
-		klog.Info("CPU load is HIGH")
-		postMetricInfo := &struct {
-			CPU    float64 `json:"cpu"`
-			Memory float64 `json:"memory"`
-		}{
-			CPU:    GetValue(metric),
-			Memory: 0,
-		}
-		err = s.invoker.Invoke(ctx,
-			func(cb *aprv1.SysEventRegistry) bool {
-				return cb.Spec.Type == aprv1.Subscriber && cb.Spec.Event == aprv1.CPUHigh
-			},
-			postMetricInfo,
-		)
-
-		if err != nil {
-			klog.Warning(err)
-		}
-
-		if s.notification != nil {
-			return s.notification.Send(ctx, admin, "High CPU load alert", &watchers.EventPayload{
-				Type: string(aprv1.CPUHigh),
-			})
-		}
-
-	case "cluster_memory_utilisation":
-		klog.Info("Memory usage is HIGH")
-		postMetricInfo := &struct {
-			CPU    float64 `json:"cpu"`
-			Memory float64 `json:"memory"`
-		}{
-			CPU:    0,
-			Memory: GetValue(metric),
-		}
-		err = s.invoker.Invoke(ctx,
-			func(cb *aprv1.SysEventRegistry) bool {
-				return cb.Spec.Type == aprv1.Subscriber && cb.Spec.Event == aprv1.MemoryHigh
-			},
-			postMetricInfo,
-		)
-
-		if err != nil {
-			klog.Warning(err)
-		}
-
-		if s.notification != nil {
-			return s.notification.Send(ctx, admin, "High memory usage alert", &watchers.EventPayload{
-				Type: string(aprv1.MemoryHigh),
-			})
-		}
-	case "user_cpu_usage":
-		user := metric.MetricValues[0].Metadata["user"]
-		klog.InfoS("User's cpu usage is HIGH", "user", user)
-		postMetricInfo := &struct {
-			CPU    float64 `json:"cpu"`
-			Memory float64 `json:"memory"`
-			User   string  `json:"user"`
-		}{
-			CPU:    GetValue(metric),
-			Memory: 0,
-			User:   user,
-		}
-		err = s.invoker.Invoke(ctx,
-			func(cb *aprv1.SysEventRegistry) bool {
-				return cb.Spec.Type == aprv1.Subscriber && cb.Spec.Event == aprv1.UserCPUHigh
-			},
-			postMetricInfo,
-		)
-
-		if err != nil {
-			klog.Warning(err)
-		}
-
-		if s.notification != nil {
-			var errs = make([]error, 0)
-			now := time.Now()
-			if now.Sub(latestSend[metric.MetricName]) > 1*time.Hour {
-				err = s.notification.Send(ctx, admin, fmt.Sprintf("user: %s High cpu usage alert", user), &watchers.EventPayload{
-					Type: string(aprv1.UserCPUHigh),
-				})
-				if err != nil {
-					errs = append(errs, err)
-				}
-				err = s.notification.Send(ctx, user, "High cpu usage alert", &watchers.EventPayload{
-					Type: string(aprv1.UserCPUHigh),
-				})
-				if err != nil {
-					errs = append(errs, err)
-				}
-				latestSend[metric.MetricName] = now
-				return utils.AggregateErrs(errs)
-			}
-		}
-	case "user_memory_usage":
-		user := metric.MetricValues[0].Metadata["user"]
-		klog.InfoS("User's memory usage is HIGH", "user", user)
-		postMetricInfo := &struct {
-			CPU    float64 `json:"cpu"`
-			Memory float64 `json:"memory"`
-			User   string  `json:"user"`
-		}{
-			CPU:    0,
-			Memory: GetValue(metric),
-			User:   user,
-		}
-		err = s.invoker.Invoke(ctx,
-			func(cb *aprv1.SysEventRegistry) bool {
-				return cb.Spec.Type == aprv1.Subscriber && cb.Spec.Event == aprv1.UserMemoryHigh
-			},
-			postMetricInfo,
-		)
-
-		if err != nil {
-			klog.Warning(err)
-		}
-
-		if s.notification != nil {
-			var errs = make([]error, 0)
-			now := time.Now()
-			if now.Sub(latestSend[metric.MetricName]) > 1*time.Hour {
-				err = s.notification.Send(ctx, admin, fmt.Sprintf("user: %s High memory usage alert", user), &watchers.EventPayload{
-					Type: string(aprv1.UserMemoryHigh),
-				})
-				if err != nil {
-					errs = append(errs, err)
-				}
-				err = s.notification.Send(ctx, user, "High memory usage alert", &watchers.EventPayload{
-					Type: string(aprv1.UserMemoryHigh),
-				})
-				if err != nil {
-					errs = append(errs, err)
-				}
-				latestSend[metric.MetricName] = now
-				return utils.AggregateErrs(errs)
-			}
-
-		}
+	payload := &watchers.EventPayload{Type: string(eventType), Data: data}
+	if a.User == "" {
+		return s.notification.Send(ctx, admin, spec.Message, payload)
+	}
+
+	var errs []error
+	if err = s.notification.Send(ctx, admin, fmt.Sprintf("user: %s %s", a.User, spec.Message), payload); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err = s.notification.Send(ctx, a.User, spec.Message, payload); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	return utils.AggregateErrs(errs)
 }
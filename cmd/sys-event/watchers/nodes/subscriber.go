@@ -0,0 +1,99 @@
+package nodes
+
+import (
+	"context"
+
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// Subscriber sends the events of the nodes becoming not ready, and ready again
+type Subscriber struct {
+	*watchers.Subscriber
+	invoker *watchers.CallbackInvoker
+}
+
+func (s *Subscriber) WithKubeConfig(config *rest.Config) *Subscriber {
+	s.invoker = watchers.NewCallbackInvoker(s.Watchers, config)
+	return s
+}
+
+func (s *Subscriber) HandleEvent() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			// the node not ready already when the watcher starts
+			if ready, _ := nodeReady(obj.(*corev1.Node)); !ready {
+				s.Watchers.Enqueue(watchers.EnqueueObj{
+					Subscribe: s,
+					Obj:       obj,
+					Action:    watchers.ADD,
+				})
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldReady, _ := nodeReady(oldObj.(*corev1.Node))
+			newReady, _ := nodeReady(newObj.(*corev1.Node))
+			if oldReady != newReady {
+				s.Watchers.Enqueue(watchers.EnqueueObj{
+					Subscribe: s,
+					Obj:       newObj,
+					Action:    watchers.UPDATE,
+				})
+			}
+		},
+	}
+}
+
+func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Action) error {
+	node := obj.(*corev1.Node)
+	ready, cond := nodeReady(node)
+
+	data := &cloudevents.NodeData{Name: node.Name, Ready: ready}
+	if cond != nil {
+		data.Reason = cond.Reason
+		data.Message = cond.Message
+	}
+
+	eventType, msg := aprv1.NodeReady, "node "+node.Name+" is ready again"
+	if !ready {
+		eventType, msg = aprv1.NodeNotReady, "node "+node.Name+" is not ready"
+	}
+
+	klog.Info(msg)
+	err := s.invoker.Invoke(ctx, &filter.Event{Type: eventType, Labels: node.Labels}, data)
+	if err != nil {
+		klog.Warning(err)
+	}
+
+	if s.Notification != nil {
+		admin, err := s.Notification.AdminUser(ctx)
+		if err != nil {
+			return err
+		}
+
+		return s.Notification.Send(ctx, admin, msg, &watchers.EventPayload{
+			Type: string(eventType),
+			Data: data,
+		})
+	}
+
+	return nil
+}
+
+// nodeReady returns true if the node is ready, and the ready condition of the node
+func nodeReady(node *corev1.Node) (bool, *corev1.NodeCondition) {
+	for i := range node.Status.Conditions {
+		cond := &node.Status.Conditions[i]
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue, cond
+		}
+	}
+
+	return false, nil
+}
@@ -22,7 +22,7 @@ type Notification struct {
 	DynamicClient *dynamic.DynamicClient
 }
 
-func (n *Notification) Send(ctx context.Context, user, msg string, data interface{}) error {
+func (n *Notification) Send(ctx context.Context, user, msg string, payload *EventPayload) error {
 	appKey, appSecret, err := n.getUserAppKey(ctx, user)
 	if err != nil {
 		return err
@@ -30,7 +30,7 @@ func (n *Notification) Send(ctx context.Context, user, msg string, data interfac
 
 	client := NewEventClient(appKey, appSecret, "system-server.user-system-"+user)
 
-	return client.CreateEvent("notification", msg, data)
+	return client.CreateEvent("notification", msg, payload)
 }
 
 func (n *Notification) getUserAppKey(ctx context.Context, user string) (appKey, appSecret string, err error) {
@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"context"
+
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+var GVR = aprv1.SchemeGroupVersion.WithResource("syseventregistries")
+
+// Subscriber generates the signing secret of the registry once it is created,
+// so the subscriber is able to mount the secret before any event is sent
+type Subscriber struct {
+	*watchers.Subscriber
+	kubeClient kubernetes.Interface
+}
+
+func (s *Subscriber) WithKubeConfig(config *rest.Config) *Subscriber {
+	s.kubeClient = kubernetes.NewForConfigOrDie(config)
+	return s
+}
+
+func (s *Subscriber) HandleEvent() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.Watchers.Enqueue(watchers.EnqueueObj{
+				Subscribe: s,
+				Obj:       obj,
+				Action:    watchers.ADD,
+			})
+		},
+	}
+}
+
+func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Action) error {
+	cb := obj.(*aprv1.SysEventRegistry)
+	_, err := watchers.EnsureSigningSecret(ctx, s.kubeClient, cb)
+	return err
+}
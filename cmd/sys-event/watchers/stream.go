@@ -0,0 +1,114 @@
+package watchers
+
+import (
+	"sync"
+
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
+	"k8s.io/klog/v2"
+)
+
+// subscriptionBuffer is the events buffered for a live subscription,
+// the subscription can not keep up is closed, and resumes from the history
+const subscriptionBuffer = 256
+
+// StreamEvent is the event emitted, the seq is the cursor of the event in the history
+type StreamEvent struct {
+	Seq   int64
+	Meta  *filter.Event
+	Event *cloudevents.Event
+}
+
+// Subscription receives the events emitted matching, C is closed if the subscription is closed
+type Subscription struct {
+	C chan *StreamEvent
+
+	// After is the seq of the last event broadcast before subscribing, the events received are after it
+	After int64
+
+	match     func(e *StreamEvent) bool
+	stream    *stream
+	closeOnce sync.Once
+}
+
+// Close stops the subscription receiving the events
+func (s *Subscription) Close() {
+	s.stream.remove(s)
+}
+
+type stream struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	last int64
+
+	// order serializes recording and broadcasting the events
+	order sync.Mutex
+}
+
+func newStream() *stream {
+	return &stream{subs: make(map[*Subscription]struct{})}
+}
+
+func (st *stream) remove(s *Subscription) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	delete(st.subs, s)
+	s.closeOnce.Do(func() { close(s.C) })
+}
+
+// broadcast sends the event to the subscriptions matching without blocking
+func (st *stream) broadcast(e *StreamEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if e.Seq > 0 {
+		st.last = e.Seq
+	}
+
+	for s := range st.subs {
+		if !s.match(e) {
+			continue
+		}
+
+		select {
+		case s.C <- e:
+		default:
+			klog.Warning("event subscription can not keep up, closed")
+			delete(st.subs, s)
+			s.closeOnce.Do(func() { close(s.C) })
+		}
+	}
+}
+
+func (st *stream) closeAll() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for s := range st.subs {
+		delete(st.subs, s)
+		s.closeOnce.Do(func() { close(s.C) })
+	}
+}
+
+// Subscribe returns a live subscription of the events emitted matching,
+// the subscriptions are closed when the watchers shutdown
+func (l *Watchers) Subscribe(match func(e *StreamEvent) bool) *Subscription {
+	s := &Subscription{
+		C:      make(chan *StreamEvent, subscriptionBuffer),
+		match:  match,
+		stream: l.stream,
+	}
+
+	l.stream.mu.Lock()
+	defer l.stream.mu.Unlock()
+
+	if l.ctx.Err() != nil {
+		close(s.C)
+		return s
+	}
+
+	s.After = l.stream.last
+	l.stream.subs[s] = struct{}{}
+	return s
+}
@@ -2,26 +2,22 @@ package users
 
 import (
 	"context"
-	"errors"
-	"time"
 
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
 	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
 	"bytetrade.io/web3os/tapr/pkg/kubesphere"
-	"github.com/emicklei/go-restful"
-	"github.com/go-resty/resty/v2"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 )
 
@@ -33,10 +29,9 @@ type watcher struct {
 	eventWatchers *watchers.Watchers
 	subscriber    *Subscriber
 	activingUsers map[string]string
+	invoker       *watchers.CallbackInvoker
 }
 
-const InvokeRetry = 10
-
 const UserTerminusWizardStatus = "bytetrade.io/wizard-status"
 
 type WizardStatus string
@@ -55,6 +50,7 @@ func NewWatcher(ctx context.Context, kubeconfig *rest.Config,
 		ctx:           ctx,
 		dynamicClient: dynamicClient,
 		aprClient:     aprclientset.NewForConfigOrDie(kubeconfig),
+		invoker:       watchers.NewCallbackInvoker(w, kubeconfig),
 		cacheEvent:    make(map[string]map[string]runtime.Object),
 		eventWatchers: w,
 		subscriber:    &Subscriber{tasks: []task{&Notify{notification: n}, &UserDomain{kubeClient: kubeClient, dynamicClient: dynamicClient}}},
@@ -209,7 +205,7 @@ func (w *watcher) Start() {
 						continue
 					}
 
-					if callback.Spec.Type != aprv1.Subscriber || callback.Spec.Event != aprv1.UserCreate {
+					if callback.Spec.Type != aprv1.Subscriber || !filter.MatchType(callback.Spec.Event, aprv1.UserCreate) {
 						continue
 					}
 
@@ -250,156 +246,52 @@ func (w *watcher) Start() {
 	}()
 }
 
-func (w *watcher) invokeCallbacks(callbacks *aprv1.SysEventRegistryList, trigger func(cb *aprv1.SysEventRegistry) bool, action func(cb *aprv1.SysEventRegistry) error) (err error) {
+// invokeCallbacks saves the event to the outbox for the callbacks, all the callbacks registered if nil
+func (w *watcher) invokeCallbacks(callbacks *aprv1.SysEventRegistryList, e *filter.Event, data interface{}) error {
 	if callbacks == nil {
-		callbacks, err = w.aprClient.AprV1alpha1().SysEventRegistries("").List(w.ctx, metav1.ListOptions{})
-		if err != nil {
-			klog.Error("list sys event callbacks error, ", err)
-			return err
-		}
-	}
-
-	backoff := wait.Backoff{
-		Duration: time.Second,
-		Factor:   2,
-		Jitter:   0.1,
-		Steps:    InvokeRetry,
-		Cap:      120 * time.Second,
+		return w.invoker.Invoke(w.ctx, e, data)
 	}
 
-	for _, cb := range callbacks.Items {
-		if trigger(&cb) {
-			if cb.Spec.Callback == "" {
-				klog.Error("callback url is empty, ", cb.Name, ", ", cb.Namespace)
-				return errors.New("callback url is empty")
-			}
-
-			if err = retry.OnError(backoff, func(err error) bool { return true }, func() error {
-				return action(&cb)
-			}); err != nil {
-				return err
-			}
-		}
+	items := make([]*aprv1.SysEventRegistry, 0, len(callbacks.Items))
+	for i := range callbacks.Items {
+		items = append(items, &callbacks.Items[i])
 	}
 
-	klog.Info("success to send events to all callbacks")
-	return nil
+	return w.invoker.InvokeTo(w.ctx, items, e, data)
 }
 
 func (w *watcher) invokeUserCreatedCB(callbacks *aprv1.SysEventRegistryList, user *kubesphere.User) error {
+	klog.Info("send user created event, ", user.Name)
 	return w.invokeCallbacks(callbacks,
-		func(cb *aprv1.SysEventRegistry) bool {
-			return cb.Spec.Type == aprv1.Subscriber && cb.Spec.Event == aprv1.UserCreate
-		},
-		func(cb *aprv1.SysEventRegistry) error {
-			klog.Info("start to send event to [", cb.Spec.Type, ", ", cb.Spec.Event, "], ", cb.Spec.Callback)
-
-			postUserInfo := &struct {
-				Name  string `json:"name"`
-				Role  string `json:"role"`
-				Email string `json:"email"`
-			}{
-				Name:  user.Name,
-				Role:  user.Annotations["bytetrade.io/owner-role"],
-				Email: user.Spec.Email,
-			}
-			client := resty.New().SetTimeout(2 * time.Second)
-			res, err := client.R().
-				SetHeader(restful.HEADER_ContentType, restful.MIME_JSON).
-				SetBody(postUserInfo).
-				Post(cb.Spec.Callback)
-
-			if err != nil {
-				klog.Error("invoke callback error, ", err, ", ", cb.Name, ", ", cb.Namespace)
-				return err
-			}
-
-			if res.StatusCode() >= 400 {
-				klog.Error("invoke callback response error code, ", res.StatusCode(), ", ", cb.Name, ", ", cb.Namespace)
-				return errors.New("invoke callback response error")
-			}
-
-			klog.Info("success to invoke callback, ", cb.Name, ", ", cb.Namespace, ", ", string(res.Body()))
-
-			return nil
+		&filter.Event{Type: aprv1.UserCreate, Owner: user.Name, Labels: user.Labels},
+		&cloudevents.UserData{
+			Name:  user.Name,
+			Role:  user.Annotations["bytetrade.io/owner-role"],
+			Email: user.Spec.Email,
 		},
 	)
 }
 
 func (w *watcher) invokeUserDeletedCB(callbacks *aprv1.SysEventRegistryList, user *kubesphere.User) error {
+	klog.Info("send user deleted event, ", user.Name)
 	return w.invokeCallbacks(callbacks,
-		func(cb *aprv1.SysEventRegistry) bool {
-			return cb.Spec.Type == aprv1.Subscriber && cb.Spec.Event == aprv1.UserDelete
-		},
-		func(cb *aprv1.SysEventRegistry) error {
-			klog.Info("start to send event to [", cb.Spec.Type, ", ", cb.Spec.Event, "], ", cb.Spec.Callback)
-
-			postUserInfo := &struct {
-				Name  string `json:"name"`
-				Email string `json:"email"`
-			}{
-				Name: user.Name,
-				// Role:  user.Annotations["bytetrade.io/owner-role"],
-				Email: user.Spec.Email,
-			}
-			client := resty.New().SetTimeout(2 * time.Second)
-			res, err := client.R().
-				SetHeader(restful.HEADER_ContentType, restful.MIME_JSON).
-				SetBody(postUserInfo).
-				Post(cb.Spec.Callback)
-
-			if err != nil {
-				klog.Error("invoke callback error, ", err, ", ", cb.Name, ", ", cb.Namespace)
-				return err
-			}
-
-			if res.StatusCode() >= 400 {
-				klog.Error("invoke callback response error code, ", res.StatusCode(), ", ", cb.Name, ", ", cb.Namespace)
-				return errors.New("invoke callback response error")
-			}
-
-			klog.Info("success to invoke callback, ", cb.Name, ", ", cb.Namespace, ", ", string(res.Body()))
-
-			return nil
+		&filter.Event{Type: aprv1.UserDelete, Owner: user.Name, Labels: user.Labels},
+		&cloudevents.UserData{
+			Name: user.Name,
+			// Role:  user.Annotations["bytetrade.io/owner-role"],
+			Email: user.Spec.Email,
 		},
 	)
 }
 
 func (w *watcher) invokeUserActivedCB(callbacks *aprv1.SysEventRegistryList, user *kubesphere.User) error {
+	klog.Info("send user actived event, ", user.Name)
 	return w.invokeCallbacks(callbacks,
-		func(cb *aprv1.SysEventRegistry) bool {
-			return cb.Spec.Type == aprv1.Subscriber && cb.Spec.Event == aprv1.UserActive
-		},
-		func(cb *aprv1.SysEventRegistry) error {
-			klog.Info("start to send event to [", cb.Spec.Type, ", ", cb.Spec.Event, "], ", cb.Spec.Callback)
-
-			postUserInfo := &struct {
-				Name  string `json:"name"`
-				Email string `json:"email"`
-			}{
-				Name: user.Name,
-				// Role:  user.Annotations["bytetrade.io/owner-role"],
-				Email: user.Spec.Email,
-			}
-			client := resty.New().SetTimeout(2 * time.Second)
-			res, err := client.R().
-				SetHeader(restful.HEADER_ContentType, restful.MIME_JSON).
-				SetBody(postUserInfo).
-				Post(cb.Spec.Callback)
-
-			if err != nil {
-				klog.Error("invoke callback error, ", err, ", ", cb.Name, ", ", cb.Namespace)
-				return err
-			}
-
-			if res.StatusCode() >= 400 {
-				klog.Error("invoke callback response error code, ", res.StatusCode(), ", ", cb.Name, ", ", cb.Namespace)
-				return errors.New("invoke callback response error")
-			}
-
-			klog.Info("success to invoke callback, ", cb.Name, ", ", cb.Namespace, ", ", string(res.Body()))
-
-			return nil
+		&filter.Event{Type: aprv1.UserActive, Owner: user.Name, Labels: user.Labels},
+		&cloudevents.UserData{
+			Name: user.Name,
+			// Role:  user.Annotations["bytetrade.io/owner-role"],
+			Email: user.Spec.Email,
 		},
 	)
 }
@@ -8,6 +8,8 @@ import (
 	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
 	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
 	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -32,10 +34,7 @@ type Subscriber struct {
 func (s *Subscriber) WithKubeConfig(config *rest.Config) *Subscriber {
 	s.aprClient = aprclientset.NewForConfigOrDie(config)
 	s.dynamicClient = dynamic.NewForConfigOrDie(config)
-	s.invoker = &watchers.CallbackInvoker{
-		AprClient: s.aprClient,
-		Retriable: func(err error) bool { return true },
-	}
+	s.invoker = watchers.NewCallbackInvoker(s.Watchers, config)
 	return s
 }
 
@@ -91,17 +90,21 @@ func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Ac
 	ns := obj.(*corev1.Namespace)
 	name := ns.Labels[WorkflowNameLabel]
 	title := ns.Annotations[WorkflowTitleAnnotation]
-	owner, ownerOK := ns.Labels["bytetrade.io/ns-owner"]
+	owner, ownerOK := ns.Labels[watchers.NamespaceOwnerLabel]
+	if !ownerOK {
+		var err error
+		owner, err = s.getWorkflowOwner(ctx, ns.Name)
+		if err != nil {
+			klog.Warning(err)
+		}
+	}
+
 	switch action {
 	case watchers.ADD:
 		klog.Info("recommend ", ns, "/", name, " is installed")
 		err := s.invoker.Invoke(ctx,
-			func(cb *aprv1.SysEventRegistry) bool {
-				return cb.Spec.Type == aprv1.Subscriber && cb.Spec.Event == aprv1.RecommendInstall
-			},
-			map[string]interface{}{
-				"name": name,
-			},
+			&filter.Event{Type: aprv1.RecommendInstall, Owner: owner, App: name, Labels: ns.Labels},
+			&cloudevents.RecommendData{Name: name},
 		)
 
 		if err != nil {
@@ -109,20 +112,11 @@ func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Ac
 		}
 
 		if s.Notification != nil {
-			if !ownerOK {
-				owner, err = s.getWorkflowOwner(ctx, ns.Name)
-				if err != nil {
-					klog.Warning(err)
-				}
-			}
-
 			if owner != "" {
 				return s.Notification.Send(ctx, owner, "recommend "+title+" is installed",
 					&watchers.EventPayload{
 						Type: string(aprv1.RecommendInstall),
-						Data: map[string]interface{}{
-							"name": name,
-						},
+						Data: &cloudevents.RecommendData{Name: name},
 					},
 				)
 			}
@@ -131,12 +125,8 @@ func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Ac
 	case watchers.DELETE:
 		klog.Info("recommend ", ns, "/", name, " is uninstalled")
 		err := s.invoker.Invoke(ctx,
-			func(cb *aprv1.SysEventRegistry) bool {
-				return cb.Spec.Type == aprv1.Subscriber && cb.Spec.Event == aprv1.RecommendUninstall
-			},
-			map[string]interface{}{
-				"name": name,
-			},
+			&filter.Event{Type: aprv1.RecommendUninstall, Owner: owner, App: name, Labels: ns.Labels},
+			&cloudevents.RecommendData{Name: name},
 		)
 
 		if err != nil {
@@ -144,20 +134,11 @@ func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Ac
 		}
 
 		if s.Notification != nil {
-			if !ownerOK {
-				owner, err = s.getWorkflowOwner(ctx, ns.Name)
-				if err != nil {
-					klog.Warning(err)
-				}
-			}
-
 			if owner != "" {
 				return s.Notification.Send(ctx, owner, "recommend "+title+" is uninstalled",
 					&watchers.EventPayload{
 						Type: string(aprv1.RecommendUninstall),
-						Data: map[string]interface{}{
-							"name": name,
-						},
+						Data: &cloudevents.RecommendData{Name: name},
 					},
 				)
 			}
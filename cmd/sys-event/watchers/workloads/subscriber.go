@@ -0,0 +1,199 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"bytetrade.io/web3os/tapr/cmd/sys-event/watchers"
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	"bytetrade.io/web3os/tapr/pkg/sysevent/filter"
+	"bytetrade.io/web3os/tapr/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	AppNameLabel = "applications.app.bytetrade.io/name"
+
+	ReasonCrashLoopBackOff = "CrashLoopBackOff"
+	ReasonOOMKilled        = "OOMKilled"
+
+	// resendInterval is the interval the same container is alerted again, the crash loop restarts the container repeatedly
+	resendInterval = time.Hour
+)
+
+var reasonEvents = map[string]aprv1.EventType{
+	ReasonCrashLoopBackOff: aprv1.WorkloadCrashLoop,
+	ReasonOOMKilled:        aprv1.WorkloadOOMKilled,
+}
+
+// Abnormal is a container of the pod crash looping or killed by out of memory
+type Abnormal struct {
+	Pod          *corev1.Pod
+	Container    string
+	Reason       string
+	RestartCount int32
+	Message      string
+}
+
+// Subscriber sends the events of the containers of the pods crash looping or killed by out of memory
+type Subscriber struct {
+	*watchers.Subscriber
+	invoker *watchers.CallbackInvoker
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+func (s *Subscriber) WithKubeConfig(config *rest.Config) *Subscriber {
+	s.invoker = watchers.NewCallbackInvoker(s.Watchers, config)
+	s.lastSent = make(map[string]time.Time)
+	return s
+}
+
+func (s *Subscriber) HandleEvent() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			for _, a := range abnormalContainers(oldObj.(*corev1.Pod), newObj.(*corev1.Pod)) {
+				if !s.due(a, time.Now()) {
+					continue
+				}
+
+				s.Watchers.Enqueue(watchers.EnqueueObj{
+					Subscribe: s,
+					Obj:       a,
+					Action:    watchers.UPDATE,
+				})
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			s.forget(obj.(*corev1.Pod))
+		},
+	}
+}
+
+func (s *Subscriber) Do(ctx context.Context, obj interface{}, action watchers.Action) error {
+	a := obj.(*Abnormal)
+	pod := a.Pod
+
+	data := &cloudevents.WorkloadData{
+		Pod:          pod.Name,
+		Namespace:    pod.Namespace,
+		App:          pod.Labels[AppNameLabel],
+		Owner:        s.Watchers.NamespaceOwner(ctx, pod.Namespace),
+		Container:    a.Container,
+		Reason:       a.Reason,
+		RestartCount: a.RestartCount,
+		Message:      a.Message,
+	}
+
+	eventType := reasonEvents[a.Reason]
+	klog.InfoS("container of the pod is abnormal", "pod", pod.Namespace+"/"+pod.Name, "container", a.Container, "reason", a.Reason)
+	err := s.invoker.Invoke(ctx,
+		&filter.Event{Type: eventType, Owner: data.Owner, App: data.App, Labels: pod.Labels},
+		data,
+	)
+	if err != nil {
+		klog.Warning(err)
+	}
+
+	if s.Notification == nil {
+		return nil
+	}
+
+	name := pod.Namespace + "/" + pod.Name
+	if data.App != "" {
+		name = "app " + data.App
+	}
+	msg := fmt.Sprintf("%s container %s is %s, restarted %d times", name, a.Container, a.Reason, a.RestartCount)
+	payload := &watchers.EventPayload{Type: string(eventType), Data: data}
+
+	admin, err := s.Notification.AdminUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if err = s.Notification.Send(ctx, admin, msg, payload); err != nil {
+		errs = append(errs, err)
+	}
+
+	if data.Owner != "" && data.Owner != admin {
+		if err = s.Notification.Send(ctx, data.Owner, msg, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utils.AggregateErrs(errs)
+}
+
+// due returns true if the container is not alerted for the reason recently
+func (s *Subscriber) due(a *Abnormal, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := string(a.Pod.UID) + "/" + a.Container + "/" + a.Reason
+	if now.Sub(s.lastSent[key]) < resendInterval {
+		return false
+	}
+
+	s.lastSent[key] = now
+	return true
+}
+
+func (s *Subscriber) forget(pod *corev1.Pod) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range pod.Status.ContainerStatuses {
+		for reason := range reasonEvents {
+			delete(s.lastSent, string(pod.UID)+"/"+c.Name+"/"+reason)
+		}
+	}
+}
+
+// abnormalContainers returns the containers of the pod began crash looping, or killed by out of memory since the old pod
+func abnormalContainers(oldPod, newPod *corev1.Pod) []*Abnormal {
+	old := make(map[string]*corev1.ContainerStatus)
+	for i := range oldPod.Status.ContainerStatuses {
+		old[oldPod.Status.ContainerStatuses[i].Name] = &oldPod.Status.ContainerStatuses[i]
+	}
+
+	var ret []*Abnormal
+	for _, c := range newPod.Status.ContainerStatuses {
+		prev := old[c.Name]
+
+		if w := c.State.Waiting; w != nil && w.Reason == ReasonCrashLoopBackOff &&
+			(prev == nil || prev.State.Waiting == nil || prev.State.Waiting.Reason != ReasonCrashLoopBackOff) {
+			ret = append(ret, &Abnormal{Pod: newPod, Container: c.Name, Reason: ReasonCrashLoopBackOff,
+				RestartCount: c.RestartCount, Message: w.Message})
+		}
+
+		if t := terminated(&c); t != nil && t.Reason == ReasonOOMKilled && (prev == nil || !sameTermination(terminated(prev), t)) {
+			ret = append(ret, &Abnormal{Pod: newPod, Container: c.Name, Reason: ReasonOOMKilled,
+				RestartCount: c.RestartCount, Message: t.Message})
+		}
+	}
+
+	return ret
+}
+
+// terminated returns the current or the last termination of the container
+func terminated(c *corev1.ContainerStatus) *corev1.ContainerStateTerminated {
+	if c.State.Terminated != nil {
+		return c.State.Terminated
+	}
+
+	return c.LastTerminationState.Terminated
+}
+
+// sameTermination returns true if the terminations are of the same container run,
+// the termination is moved to the last state once the container restarts
+func sameTermination(a, b *corev1.ContainerStateTerminated) bool {
+	return a != nil && b != nil && a.ContainerID == b.ContainerID && a.FinishedAt.Equal(&b.FinishedAt)
+}
@@ -0,0 +1,51 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func podWith(statuses ...corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: statuses}}
+}
+
+func TestAbnormalContainers(t *testing.T) {
+	running := corev1.ContainerStatus{Name: "app", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+	crashLoop := corev1.ContainerStatus{Name: "app", RestartCount: 3,
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: ReasonCrashLoopBackOff}}}
+
+	killed := &corev1.ContainerStateTerminated{Reason: ReasonOOMKilled, ContainerID: "c1", FinishedAt: metav1.NewTime(time.Now())}
+	oomKilled := corev1.ContainerStatus{Name: "app", RestartCount: 1, State: corev1.ContainerState{Terminated: killed}}
+	restarted := corev1.ContainerStatus{Name: "app", RestartCount: 2,
+		State:                corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		LastTerminationState: corev1.ContainerState{Terminated: killed}}
+
+	cases := []struct {
+		name     string
+		old, new *corev1.Pod
+		reasons  []string
+	}{
+		{"running", podWith(running), podWith(running), nil},
+		{"began crash looping", podWith(running), podWith(crashLoop), []string{ReasonCrashLoopBackOff}},
+		{"still crash looping", podWith(crashLoop), podWith(crashLoop), nil},
+		{"oom killed", podWith(running), podWith(oomKilled), []string{ReasonOOMKilled}},
+		{"restarted after oom killed", podWith(oomKilled), podWith(restarted), nil},
+	}
+
+	for _, c := range cases {
+		got := abnormalContainers(c.old, c.new)
+		if len(got) != len(c.reasons) {
+			t.Errorf("%s: got %d abnormal containers, expected %v", c.name, len(got), c.reasons)
+			continue
+		}
+
+		for i, a := range got {
+			if a.Reason != c.reasons[i] {
+				t.Errorf("%s: got %s, expected %s", c.name, a.Reason, c.reasons[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// +kubebuilder:printcolumn:name="Metric",type=string,JSONPath=".spec.metric"
+// +kubebuilder:printcolumn:name="Operator",type=string,JSONPath=".spec.operator"
+// +kubebuilder:printcolumn:name="Threshold",type=string,JSONPath=".spec.threshold"
+// +kubebuilder:printcolumn:name="Event",type=string,JSONPath=".spec.event"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster, shortName={ar}, categories={all}
+// AlertRule is the Schema for the rules of the metrics alerts sent as sys events
+type AlertRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AlertRuleSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type AlertRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []AlertRule `json:"items"`
+}
+
+type AlertRuleSpec struct {
+	// the name of the query in the metrics templates, e.g. cluster_cpu_utilisation
+	// +optional
+	Metric string `json:"metric,omitempty"`
+
+	// the raw PromQL evaluated if the metric is not set. In the user level, $1 is replaced
+	// by the selector of the user, e.g. namespace:container_cpu_usage:sum{$1}
+	// +optional
+	Expr string `json:"expr,omitempty"`
+
+	// the query is evaluated for the cluster, or for every user
+	// +kubebuilder:validation:Enum=cluster;user
+	// +optional
+	Level AlertLevel `json:"level,omitempty"`
+
+	// the comparison of the value to the threshold, > by default
+	// +kubebuilder:validation:Enum=">";">=";"<";"<=";"==";"!="
+	// +optional
+	Operator AlertOperator `json:"operator,omitempty"`
+
+	Threshold resource.Quantity `json:"threshold"`
+
+	// the resource of the value, cpu or memory. It is reported in the cpu or memory of the event data
+	// +kubebuilder:validation:Enum=cpu;memory
+	// +optional
+	Resource string `json:"resource,omitempty"`
+
+	// the threshold is the ratio of the user's limit of the resource, the bytetrade.io/user-<resource>-limit annotation.
+	// Only in the user level
+	// +optional
+	RelativeToLimit bool `json:"relativeToLimit,omitempty"`
+
+	// the alert fires if the condition holds for the duration, immediately if not set
+	// +optional
+	For *metav1.Duration `json:"for,omitempty"`
+
+	// the alert firing is resolved once the value crosses back the threshold by the hysteresis,
+	// it avoids the alert flapping around the threshold
+	// +optional
+	Hysteresis *resource.Quantity `json:"hysteresis,omitempty"`
+
+	// the interval of the evaluation, 5s by default
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// the firing event is sent again after the interval while the alert is firing, only once if not set
+	// +optional
+	RepeatInterval *metav1.Duration `json:"repeatInterval,omitempty"`
+
+	// +kubebuilder:validation:Enum=info;warning;critical
+	// +optional
+	Severity AlertSeverity `json:"severity,omitempty"`
+
+	// the event type sent when the alert fires
+	Event EventType `json:"event"`
+
+	// the event type sent when the alert is resolved, <event>.resolved by default
+	// +optional
+	ResolvedEvent EventType `json:"resolvedEvent,omitempty"`
+
+	// the message of the notification sent when the alert fires, no notification if empty
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+type AlertLevel string
+
+const (
+	AlertLevelCluster AlertLevel = "cluster"
+	AlertLevelUser    AlertLevel = "user"
+)
+
+type AlertOperator string
+
+const (
+	AlertGreaterThan      AlertOperator = ">"
+	AlertGreaterThanEqual AlertOperator = ">="
+	AlertLessThan         AlertOperator = "<"
+	AlertLessThanEqual    AlertOperator = "<="
+	AlertEqual            AlertOperator = "=="
+	AlertNotEqual         AlertOperator = "!="
+)
+
+type AlertSeverity string
+
+const (
+	AlertInfo     AlertSeverity = "info"
+	AlertWarning  AlertSeverity = "warning"
+	AlertCritical AlertSeverity = "critical"
+)
@@ -12,6 +12,7 @@ import (
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp",description="Created time"
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Namespaced, shortName={ser}, categories={all}
+// +kubebuilder:subresource:status
 // SysEventRegistry is the Schema for the Sys Event publisher and subscriber
 type SysEventRegistry struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -30,9 +31,41 @@ type SysEventRegistryList struct {
 }
 
 type SysEventRegistrySpec struct {
-	Type     ActionType `json:"type"`
-	Event    EventType  `json:"event"`
-	Callback string     `json:"callback"`
+	Type ActionType `json:"type"`
+
+	// the event type subscribed, the wildcard suffix matches a group of events, e.g. app.*
+	Event    EventType `json:"event"`
+	Callback string    `json:"callback"`
+
+	// the HTTP content mode of the CloudEvents delivered to the callback, binary by default
+	// +kubebuilder:validation:Enum=structured;binary
+	// +optional
+	ContentMode string `json:"contentMode,omitempty"`
+
+	// +optional
+	Filter *SysEventFilter `json:"filter,omitempty"`
+}
+
+// SysEventFilter selects the events delivered to the subscriber, all of the conditions must match
+type SysEventFilter struct {
+	// the users whose events are delivered, "*" for all the users.
+	// The registry in a namespace owned by a user only receives the events of the owner,
+	// the registry in a system namespace receives the events of all the users by default
+	// +optional
+	Users []string `json:"users,omitempty"`
+
+	// the names of the apps whose events are delivered
+	// +optional
+	Apps []string `json:"apps,omitempty"`
+
+	// selects the events by the labels of the object the event is about
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// a JSONPath filter expression over the payload, e.g. @.memory > 0.9 ,
+	// the event is delivered if the payload is selected
+	// +optional
+	Expression string `json:"expression,omitempty"`
 }
 
 type ActionType string
@@ -55,6 +88,21 @@ const (
 	CPUHigh            EventType = "metrics.cpu.high"
 	UserMemoryHigh     EventType = "metrics.user.memory.high"
 	UserCPUHigh        EventType = "metrics.user.cpu.high"
+	MemoryResolved     EventType = "metrics.memory.high.resolved"
+	CPUResolved        EventType = "metrics.cpu.high.resolved"
+	UserMemoryResolved EventType = "metrics.user.memory.high.resolved"
+	UserCPUResolved    EventType = "metrics.user.cpu.high.resolved"
+	NodeDiskHigh       EventType = "node.disk.high"
+	NodeDiskResolved   EventType = "node.disk.high.resolved"
+	NodeInodeHigh      EventType = "node.inode.high"
+	NodeInodeResolved  EventType = "node.inode.high.resolved"
+	NodeNotReady       EventType = "node.notready"
+	NodeReady          EventType = "node.ready"
+	PVCFull            EventType = "pvc.full"
+	PVCFullResolved    EventType = "pvc.full.resolved"
+	WorkloadCrashLoop  EventType = "workload.crashloop"
+	WorkloadOOMKilled  EventType = "workload.oomkilled"
+	CertExpiring       EventType = "cert.expiring"
 	RecommendInstall   EventType = "recommend.install"
 	RecommendUninstall EventType = "recommend.uninstall"
 )
@@ -65,4 +113,19 @@ type SysEventRegistryStatus struct {
 	State      string       `json:"state,omitempty"`
 	UpdateTime *metav1.Time `json:"updateTime"`
 	StatusTime *metav1.Time `json:"statusTime"`
+
+	// the delivery stats of the events to the callback
+	LastSuccessTime     *metav1.Time `json:"lastSuccessTime,omitempty"`
+	LastFailureTime     *metav1.Time `json:"lastFailureTime,omitempty"`
+	LastError           string       `json:"lastError,omitempty"`
+	ConsecutiveFailures int32        `json:"consecutiveFailures,omitempty"`
+
+	// the delivery to the callback is disabled after too many consecutive failures,
+	// the events are dead-lettered until redriven
+	Disabled bool `json:"disabled,omitempty"`
 }
+
+const (
+	SysEventRegistryActive   = "active"
+	SysEventRegistryDisabled = "disabled"
+)
@@ -40,6 +40,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&MiddlewareRequestList{},
 		&SysEventRegistry{},
 		&SysEventRegistryList{},
+		&AlertRule{},
+		&AlertRuleList{},
 		&RedixCluster{},
 		&RedixClusterList{},
 		&KVRocksBackup{},
@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
@@ -40,6 +41,63 @@ type PGClusterStatus struct {
 	State      string       `json:"state"`
 	UpdateTime *metav1.Time `json:"updateTime,omitempty"`
 	StatusTime *metav1.Time `json:"statusTime,omitempty"`
+
+	// Upgrade tracks the latest upgrade of the cluster image. A failed or rolled back
+	// upgrade is not retried until the image is changed.
+	// +optional
+	Upgrade *PGClusterUpgrade `json:"upgrade,omitempty"`
+
+	// Coordinator is the replication state of the coordinator if the standby is enabled
+	// +optional
+	Coordinator *PGCoordinatorStatus `json:"coordinator,omitempty"`
+}
+
+type PGCoordinatorStatus struct {
+	// Primary is the host of the active coordinator
+	Primary string `json:"primary"`
+	// Standby is the host of the streaming hot-standby replica, empty if it is not streaming
+	// +optional
+	Standby string `json:"standby,omitempty"`
+	// +optional
+	ReplicationState string `json:"replicationState,omitempty"`
+	// ReplicationLagBytes is the size of the WAL not replayed by the standby
+	// +optional
+	ReplicationLagBytes int64 `json:"replicationLagBytes,omitempty"`
+	// +optional
+	ReplayLagMilliseconds int64 `json:"replayLagMilliseconds,omitempty"`
+	// +optional
+	LastFailoverTime *metav1.Time `json:"lastFailoverTime,omitempty"`
+	// +optional
+	UpdateTime *metav1.Time `json:"updateTime,omitempty"`
+}
+
+type PGUpgradePhase string
+
+const (
+	PGUpgradePreflight          PGUpgradePhase = "Preflight"
+	PGUpgradeBackingUp          PGUpgradePhase = "BackingUp"
+	PGUpgradeUpgrading          PGUpgradePhase = "Upgrading"
+	PGUpgradeRestoring          PGUpgradePhase = "Restoring"
+	PGUpgradeUpdatingExtensions PGUpgradePhase = "UpdatingExtensions"
+	PGUpgradeCompleted          PGUpgradePhase = "Completed"
+	PGUpgradeRollingBack        PGUpgradePhase = "RollingBack"
+	PGUpgradeRolledBack         PGUpgradePhase = "RolledBack"
+	PGUpgradeFailed             PGUpgradePhase = "Failed"
+)
+
+type PGClusterUpgrade struct {
+	Phase     PGUpgradePhase `json:"phase"`
+	FromImage string         `json:"fromImage"`
+	ToImage   string         `json:"toImage"`
+	// BackupName is the backup created before the upgrade
+	// +optional
+	BackupName  string       `json:"backupName,omitempty"`
+	Message     string       `json:"message,omitempty"`
+	StartedAt   *metav1.Time `json:"startedAt,omitempty"`
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+	// PhaseStartedAt is the time the current phase started, the phase waiting too long fails the upgrade
+	// +optional
+	PhaseStartedAt *metav1.Time `json:"phaseStartedAt,omitempty"`
 }
 
 type PGClusterSpec struct {
@@ -53,6 +111,77 @@ type PGClusterSpec struct {
 	Password      PasswordVar `json:"password,omitempty"`
 	Owner         string      `json:"owner"`
 	BackupStorage string      `json:"backupStorage,omitempty"`
+
+	// Pooler deploys a PgBouncer in front of the cluster if it is set
+	// +optional
+	Pooler *PGPooler `json:"pooler,omitempty"`
+
+	// PostgresConfig are the postgresql.conf parameters of all nodes, applied with ALTER SYSTEM.
+	// The nodes are restarted if any of the parameters requires a restart
+	// +optional
+	PostgresConfig map[string]string `json:"postgresConfig,omitempty"`
+
+	// Resources of the postgres container of all nodes, the coordinator, the workers and the standby
+	// share one pod template
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Storage provisions the data volumes from a storage class instead of the user's host path.
+	// It only takes effect when the cluster is created
+	// +optional
+	Storage *PGClusterStorage `json:"storage,omitempty"`
+
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Standby runs a hot-standby replica of the coordinator with streaming replication
+	// +optional
+	Standby *PGStandby `json:"standby,omitempty"`
+}
+
+type PGStandby struct {
+	// AutoFailover promotes the standby if the coordinator is not ready for FailoverTimeoutSeconds.
+	// The failover is one-way, the old coordinator is fenced read-only when it is back
+	// +optional
+	AutoFailover bool `json:"autoFailover,omitempty"`
+	// FailoverTimeoutSeconds defaults to 60
+	// +kubebuilder:validation:Minimum=10
+	// +optional
+	FailoverTimeoutSeconds int32 `json:"failoverTimeoutSeconds,omitempty"`
+}
+
+type PGClusterStorage struct {
+	Size resource.Quantity `json:"size"`
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=session;transaction;statement
+type PGPoolMode string
+
+const (
+	PGPoolModeSession     PGPoolMode = "session"
+	PGPoolModeTransaction PGPoolMode = "transaction"
+	PGPoolModeStatement   PGPoolMode = "statement"
+)
+
+type PGPooler struct {
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Replicas defaults to 1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// PoolMode is the default pool mode of the databases. Defaults to transaction
+	// +optional
+	PoolMode PGPoolMode `json:"poolMode,omitempty"`
+	// DefaultPoolSize is the default server connections of a database and user pair. Defaults to 20
+	// +optional
+	DefaultPoolSize int32 `json:"defaultPoolSize,omitempty"`
+	// MaxClientConn defaults to 1000
+	// +optional
+	MaxClientConn int32 `json:"maxClientConn,omitempty"`
 }
 
 type PasswordVar struct {
@@ -77,6 +206,7 @@ type PasswordVarSource struct {
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Namespaced, shortName={mr}, categories={all}
+// +kubebuilder:subresource:status
 // MiddlewareRequest is the Schema for the application Middleware Request
 type MiddlewareRequest struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -99,8 +229,25 @@ type MiddlewareStatus struct {
 	State      string       `json:"state"`
 	UpdateTime *metav1.Time `json:"updateTime,omitempty"`
 	StatusTime *metav1.Time `json:"statusTime,omitempty"`
+
+	// Conditions are the latest observations of the request, e.g. Drifted
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+const (
+	// MiddlewareConditionDrifted is true if the backend state differs from the request
+	MiddlewareConditionDrifted = "Drifted"
+
+	// MiddlewareConditionAdopted is true if the existing resources are adopted by the request
+	MiddlewareConditionAdopted = "Adopted"
+
+	// MiddlewareConditionQuotaExceeded is true if the databases of the request exceed the size quota
+	MiddlewareConditionQuotaExceeded = "QuotaExceeded"
+)
+
 type MiddlewareSpec struct {
 	App          string         `json:"app"`
 	AppNamespace string         `json:"appNamespace"`
@@ -154,6 +301,107 @@ type PostgreSQL struct {
 
 	// +kubebuilder:validation:Pattern=`^([a-zA-Z0-9_]*)$`
 	User string `json:"user"`
+
+	// Roles are the additional roles of the app, e.g. read-only or migrator roles
+	// +optional
+	Roles []PGRole `json:"roles,omitempty"`
+
+	// Refs references the databases exported by other apps
+	// +optional
+	Refs []PGRef `json:"refs,omitempty"`
+
+	// Limits isolates the app from the others sharing the cluster
+	// +optional
+	Limits *PGLimits `json:"limits,omitempty"`
+
+	// Pool overrides the pool settings of the databases in the cluster pooler
+	// +optional
+	Pool *PGPool `json:"pool,omitempty"`
+}
+
+type PGPool struct {
+	// +optional
+	Mode PGPoolMode `json:"mode,omitempty"`
+	// +optional
+	Size int32 `json:"size,omitempty"`
+}
+
+type PGQuotaAction string
+
+const (
+	PGQuotaActionNotify PGQuotaAction = "Notify"
+	PGQuotaActionRevoke PGQuotaAction = "Revoke"
+)
+
+type PGLimits struct {
+	// ConnectionLimit of the app user on every node, -1 means unlimited. Defaults to 30,
+	// the limit removed from the request is reset to unlimited
+	// +optional
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+	// StatementTimeout of the databases, e.g. 30s
+	// +optional
+	StatementTimeout string `json:"statementTimeout,omitempty"`
+	// IdleInTransactionSessionTimeout of the databases, e.g. 10min
+	// +optional
+	IdleInTransactionSessionTimeout string `json:"idleInTransactionSessionTimeout,omitempty"`
+	// WorkMem of the databases, e.g. 4MB
+	// +optional
+	WorkMem string `json:"workMem,omitempty"`
+	// DatabaseSizeQuota is the soft quota of the total size of the databases in all nodes
+	// +optional
+	DatabaseSizeQuota *resource.Quantity `json:"databaseSizeQuota,omitempty"`
+	// QuotaExceededAction is the action when the quota is exceeded. Defaults to Notify
+	// +kubebuilder:validation:Enum=Notify;Revoke
+	// +optional
+	QuotaExceededAction PGQuotaAction `json:"quotaExceededAction,omitempty"`
+}
+
+type PGRole struct {
+	// +kubebuilder:validation:Pattern=`^([a-zA-Z0-9_]*)$`
+	Name     string      `json:"name"`
+	Password PasswordVar `json:"password,omitempty"`
+	// InheritOwner makes the role a member of the app user, so it can run DDL on the tables owned by the app
+	// +optional
+	InheritOwner bool      `json:"inheritOwner,omitempty"`
+	Grants       []PGGrant `json:"grants,omitempty"`
+}
+
+type PGGrant struct {
+	// Database is the name of the database in the request
+	Database string `json:"database"`
+	// Schema defaults to public
+	// +optional
+	Schema string `json:"schema,omitempty"`
+	// Tables are the tables in the schema, all tables if empty
+	// +optional
+	Tables []string `json:"tables,omitempty"`
+	// Privileges are the table privileges, e.g. SELECT, INSERT, UPDATE, DELETE
+	Privileges []string `json:"privileges,omitempty"`
+	// SchemaPrivileges are the privileges on the schema, e.g. CREATE. USAGE is always granted
+	// +optional
+	SchemaPrivileges []string `json:"schemaPrivileges,omitempty"`
+}
+
+type PGRef struct {
+	AppName      string          `json:"appName"`
+	AppNamespace string          `json:"appNamespace,omitempty"`
+	Databases    []PGRefDatabase `json:"databases"`
+}
+
+type PGRefDatabase struct {
+	// Name is the name of the database in the request of the referenced app
+	Name string `json:"name"`
+	// Schema defaults to public
+	// +optional
+	Schema string `json:"schema,omitempty"`
+	// Privileges are the table privileges, must be exported by the referenced app
+	Privileges []string `json:"privileges"`
+}
+
+type PGExport struct {
+	AppName string `json:"appName"`
+	// Privileges are the table privileges the app can be granted
+	Privileges []string `json:"privileges"`
 }
 
 type Zinc struct {
@@ -207,20 +455,58 @@ type MariaDB struct {
 	User      string          `json:"user"`
 	Password  PasswordVar     `json:"password,omitempty"`
 	Databases []MariaDatabase `json:"databases"`
+	// MaxUserConnections limits the concurrent connections of the user and the read-only user,
+	// 0 means unlimited
+	// +optional
+	MaxUserConnections int32 `json:"maxUserConnections,omitempty"`
+	// ReadOnlyUser is an optional companion user granted read-only privileges on all databases
+	// +optional
+	ReadOnlyUser *ReadOnlyUser `json:"readOnlyUser,omitempty"`
 }
 
 type Mysql struct {
 	User      string          `json:"user"`
 	Password  PasswordVar     `json:"password,omitempty"`
 	Databases []MysqlDatabase `json:"databases"`
+	// MaxUserConnections limits the concurrent connections of the user and the read-only user,
+	// 0 means unlimited
+	// +optional
+	MaxUserConnections int32 `json:"maxUserConnections,omitempty"`
+	// ReadOnlyUser is an optional companion user granted read-only privileges on all databases
+	// +optional
+	ReadOnlyUser *ReadOnlyUser `json:"readOnlyUser,omitempty"`
 }
 
 type MysqlDatabase struct {
 	Name string `json:"name"`
+	// +optional
+	Charset string `json:"charset,omitempty"`
+	// +optional
+	Collation string `json:"collation,omitempty"`
+	// Scripts are executed in the database on every reconciliation,
+	// $databasename and $dbusername will be replaced with the real names
+	Scripts []string `json:"scripts,omitempty"`
+	// Grants are the privileges granted to the user on the database, default is ALL PRIVILEGES
+	Grants []string `json:"grants,omitempty"`
 }
 
 type MariaDatabase struct {
 	Name string `json:"name"`
+	// +optional
+	Charset string `json:"charset,omitempty"`
+	// +optional
+	Collation string `json:"collation,omitempty"`
+	// Scripts are executed in the database on every reconciliation,
+	// $databasename and $dbusername will be replaced with the real names
+	Scripts []string `json:"scripts,omitempty"`
+	// Grants are the privileges granted to the user on the database, default is ALL PRIVILEGES
+	Grants []string `json:"grants,omitempty"`
+}
+
+type ReadOnlyUser struct {
+	// +kubebuilder:validation:Pattern=`^([a-zA-Z0-9_]*)$`
+	User     string      `json:"user"`
+	Password PasswordVar `json:"password,omitempty"`
 }
 
 type Subject struct {
@@ -258,6 +544,14 @@ type CitusDatabase struct {
 	Scripts    []string `json:"scripts,omitempty"`
 	// +optional
 	Distributed *bool `json:"distributed"`
+	// Export declares the apps allowed to reference this database
+	// +optional
+	Export []PGExport `json:"export,omitempty"`
+
+	// AdoptFrom is the existing database renamed to this database when the request is adopting
+	// +kubebuilder:validation:Pattern=`^([a-zA-Z0-9_]*)$`
+	// +optional
+	AdoptFrom string `json:"adoptFrom,omitempty"`
 }
 
 type MongoDatabase struct {
@@ -1,15 +1,109 @@
 //go:build !ignore_autogenerated
-// +build !ignore_autogenerated
 
 // Code generated by controller-gen. DO NOT EDIT.
 
 package v1alpha1
 
 import (
-	"k8s.io/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertRule) DeepCopyInto(out *AlertRule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertRule.
+func (in *AlertRule) DeepCopy() *AlertRule {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AlertRule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertRuleList) DeepCopyInto(out *AlertRuleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AlertRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertRuleList.
+func (in *AlertRuleList) DeepCopy() *AlertRuleList {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertRuleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AlertRuleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertRuleSpec) DeepCopyInto(out *AlertRuleSpec) {
+	*out = *in
+	out.Threshold = in.Threshold.DeepCopy()
+	if in.For != nil {
+		in, out := &in.For, &out.For
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Hysteresis != nil {
+		in, out := &in.Hysteresis, &out.Hysteresis
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RepeatInterval != nil {
+		in, out := &in.RepeatInterval, &out.RepeatInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertRuleSpec.
+func (in *AlertRuleSpec) DeepCopy() *AlertRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CitusDatabase) DeepCopyInto(out *CitusDatabase) {
 	*out = *in
@@ -28,6 +122,13 @@ func (in *CitusDatabase) DeepCopyInto(out *CitusDatabase) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Export != nil {
+		in, out := &in.Export, &out.Export
+		*out = make([]PGExport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CitusDatabase.
@@ -40,6 +141,42 @@ func (in *CitusDatabase) DeepCopy() *CitusDatabase {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Elasticsearch) DeepCopyInto(out *Elasticsearch) {
+	*out = *in
+	in.Password.DeepCopyInto(&out.Password)
+	if in.Indexes != nil {
+		in, out := &in.Indexes, &out.Indexes
+		*out = make([]ElasticsearchIndex, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Elasticsearch.
+func (in *Elasticsearch) DeepCopy() *Elasticsearch {
+	if in == nil {
+		return nil
+	}
+	out := new(Elasticsearch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticsearchIndex) DeepCopyInto(out *ElasticsearchIndex) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticsearchIndex.
+func (in *ElasticsearchIndex) DeepCopy() *ElasticsearchIndex {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticsearchIndex)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KVRocksBackup) DeepCopyInto(out *KVRocksBackup) {
 	*out = *in
@@ -252,7 +389,7 @@ func (in *KVRocksSpec) DeepCopyInto(out *KVRocksSpec) {
 	}
 	if in.Resources != nil {
 		in, out := &in.Resources, &out.Resources
-		*out = new(v1.ResourceRequirements)
+		*out = new(corev1.ResourceRequirements)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -267,6 +404,59 @@ func (in *KVRocksSpec) DeepCopy() *KVRocksSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariaDB) DeepCopyInto(out *MariaDB) {
+	*out = *in
+	in.Password.DeepCopyInto(&out.Password)
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]MariaDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReadOnlyUser != nil {
+		in, out := &in.ReadOnlyUser, &out.ReadOnlyUser
+		*out = new(ReadOnlyUser)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDB.
+func (in *MariaDB) DeepCopy() *MariaDB {
+	if in == nil {
+		return nil
+	}
+	out := new(MariaDB)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariaDatabase) DeepCopyInto(out *MariaDatabase) {
+	*out = *in
+	if in.Scripts != nil {
+		in, out := &in.Scripts, &out.Scripts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDatabase.
+func (in *MariaDatabase) DeepCopy() *MariaDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(MariaDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MiddlewareRequest) DeepCopyInto(out *MiddlewareRequest) {
 	*out = *in
@@ -334,6 +524,11 @@ func (in *MiddlewareSpec) DeepCopyInto(out *MiddlewareSpec) {
 	in.PostgreSQL.DeepCopyInto(&out.PostgreSQL)
 	in.Zinc.DeepCopyInto(&out.Zinc)
 	in.Nats.DeepCopyInto(&out.Nats)
+	in.Minio.DeepCopyInto(&out.Minio)
+	in.RabbitMQ.DeepCopyInto(&out.RabbitMQ)
+	in.Elasticsearch.DeepCopyInto(&out.Elasticsearch)
+	in.MariaDB.DeepCopyInto(&out.MariaDB)
+	in.Mysql.DeepCopyInto(&out.Mysql)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MiddlewareSpec.
@@ -357,6 +552,13 @@ func (in *MiddlewareStatus) DeepCopyInto(out *MiddlewareStatus) {
 		in, out := &in.StatusTime, &out.StatusTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MiddlewareStatus.
@@ -369,6 +571,42 @@ func (in *MiddlewareStatus) DeepCopy() *MiddlewareStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Minio) DeepCopyInto(out *Minio) {
+	*out = *in
+	in.Password.DeepCopyInto(&out.Password)
+	if in.Buckets != nil {
+		in, out := &in.Buckets, &out.Buckets
+		*out = make([]MinioBucket, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Minio.
+func (in *Minio) DeepCopy() *Minio {
+	if in == nil {
+		return nil
+	}
+	out := new(Minio)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MinioBucket) DeepCopyInto(out *MinioBucket) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinioBucket.
+func (in *MinioBucket) DeepCopy() *MinioBucket {
+	if in == nil {
+		return nil
+	}
+	out := new(MinioBucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoDB) DeepCopyInto(out *MongoDB) {
 	*out = *in
@@ -412,6 +650,59 @@ func (in *MongoDatabase) DeepCopy() *MongoDatabase {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Mysql) DeepCopyInto(out *Mysql) {
+	*out = *in
+	in.Password.DeepCopyInto(&out.Password)
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]MysqlDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReadOnlyUser != nil {
+		in, out := &in.ReadOnlyUser, &out.ReadOnlyUser
+		*out = new(ReadOnlyUser)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Mysql.
+func (in *Mysql) DeepCopy() *Mysql {
+	if in == nil {
+		return nil
+	}
+	out := new(Mysql)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlDatabase) DeepCopyInto(out *MysqlDatabase) {
+	*out = *in
+	if in.Scripts != nil {
+		in, out := &in.Scripts, &out.Scripts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabase.
+func (in *MysqlDatabase) DeepCopy() *MysqlDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Nats) DeepCopyInto(out *Nats) {
 	*out = *in
@@ -533,7 +824,7 @@ func (in *PGClusterBackupSpec) DeepCopyInto(out *PGClusterBackupSpec) {
 	*out = *in
 	if in.VolumeSpec != nil {
 		in, out := &in.VolumeSpec, &out.VolumeSpec
-		*out = new(v1.Volume)
+		*out = new(corev1.Volume)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -704,6 +995,47 @@ func (in *PGClusterRestoreStatus) DeepCopy() *PGClusterRestoreStatus {
 func (in *PGClusterSpec) DeepCopyInto(out *PGClusterSpec) {
 	*out = *in
 	in.Password.DeepCopyInto(&out.Password)
+	if in.Pooler != nil {
+		in, out := &in.Pooler, &out.Pooler
+		*out = new(PGPooler)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostgresConfig != nil {
+		in, out := &in.PostgresConfig, &out.PostgresConfig
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(PGClusterStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Standby != nil {
+		in, out := &in.Standby, &out.Standby
+		*out = new(PGStandby)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGClusterSpec.
@@ -727,6 +1059,16 @@ func (in *PGClusterStatus) DeepCopyInto(out *PGClusterStatus) {
 		in, out := &in.StatusTime, &out.StatusTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(PGClusterUpgrade)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Coordinator != nil {
+		in, out := &in.Coordinator, &out.Coordinator
+		*out = new(PGCoordinatorStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGClusterStatus.
@@ -739,6 +1081,267 @@ func (in *PGClusterStatus) DeepCopy() *PGClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGClusterStorage) DeepCopyInto(out *PGClusterStorage) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGClusterStorage.
+func (in *PGClusterStorage) DeepCopy() *PGClusterStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(PGClusterStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGClusterUpgrade) DeepCopyInto(out *PGClusterUpgrade) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.PhaseStartedAt != nil {
+		in, out := &in.PhaseStartedAt, &out.PhaseStartedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGClusterUpgrade.
+func (in *PGClusterUpgrade) DeepCopy() *PGClusterUpgrade {
+	if in == nil {
+		return nil
+	}
+	out := new(PGClusterUpgrade)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGCoordinatorStatus) DeepCopyInto(out *PGCoordinatorStatus) {
+	*out = *in
+	if in.LastFailoverTime != nil {
+		in, out := &in.LastFailoverTime, &out.LastFailoverTime
+		*out = (*in).DeepCopy()
+	}
+	if in.UpdateTime != nil {
+		in, out := &in.UpdateTime, &out.UpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGCoordinatorStatus.
+func (in *PGCoordinatorStatus) DeepCopy() *PGCoordinatorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PGCoordinatorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGExport) DeepCopyInto(out *PGExport) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGExport.
+func (in *PGExport) DeepCopy() *PGExport {
+	if in == nil {
+		return nil
+	}
+	out := new(PGExport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGGrant) DeepCopyInto(out *PGGrant) {
+	*out = *in
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SchemaPrivileges != nil {
+		in, out := &in.SchemaPrivileges, &out.SchemaPrivileges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGGrant.
+func (in *PGGrant) DeepCopy() *PGGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(PGGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGLimits) DeepCopyInto(out *PGLimits) {
+	*out = *in
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DatabaseSizeQuota != nil {
+		in, out := &in.DatabaseSizeQuota, &out.DatabaseSizeQuota
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGLimits.
+func (in *PGLimits) DeepCopy() *PGLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(PGLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGPool) DeepCopyInto(out *PGPool) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGPool.
+func (in *PGPool) DeepCopy() *PGPool {
+	if in == nil {
+		return nil
+	}
+	out := new(PGPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGPooler) DeepCopyInto(out *PGPooler) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGPooler.
+func (in *PGPooler) DeepCopy() *PGPooler {
+	if in == nil {
+		return nil
+	}
+	out := new(PGPooler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGRef) DeepCopyInto(out *PGRef) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]PGRefDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGRef.
+func (in *PGRef) DeepCopy() *PGRef {
+	if in == nil {
+		return nil
+	}
+	out := new(PGRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGRefDatabase) DeepCopyInto(out *PGRefDatabase) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGRefDatabase.
+func (in *PGRefDatabase) DeepCopy() *PGRefDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(PGRefDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGRole) DeepCopyInto(out *PGRole) {
+	*out = *in
+	in.Password.DeepCopyInto(&out.Password)
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]PGGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGRole.
+func (in *PGRole) DeepCopy() *PGRole {
+	if in == nil {
+		return nil
+	}
+	out := new(PGRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGStandby) DeepCopyInto(out *PGStandby) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGStandby.
+func (in *PGStandby) DeepCopy() *PGStandby {
+	if in == nil {
+		return nil
+	}
+	out := new(PGStandby)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PasswordVar) DeepCopyInto(out *PasswordVar) {
 	*out = *in
@@ -764,7 +1367,7 @@ func (in *PasswordVarSource) DeepCopyInto(out *PasswordVarSource) {
 	*out = *in
 	if in.SecretKeyRef != nil {
 		in, out := &in.SecretKeyRef, &out.SecretKeyRef
-		*out = new(v1.SecretKeySelector)
+		*out = new(corev1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -805,6 +1408,30 @@ func (in *PostgreSQL) DeepCopyInto(out *PostgreSQL) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]PGRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Refs != nil {
+		in, out := &in.Refs, &out.Refs
+		*out = make([]PGRef, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = new(PGLimits)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Pool != nil {
+		in, out := &in.Pool, &out.Pool
+		*out = new(PGPool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgreSQL.
@@ -817,6 +1444,58 @@ func (in *PostgreSQL) DeepCopy() *PostgreSQL {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RabbitMQ) DeepCopyInto(out *RabbitMQ) {
+	*out = *in
+	in.Password.DeepCopyInto(&out.Password)
+	if in.Vhosts != nil {
+		in, out := &in.Vhosts, &out.Vhosts
+		*out = make([]RabbitMQVhost, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RabbitMQ.
+func (in *RabbitMQ) DeepCopy() *RabbitMQ {
+	if in == nil {
+		return nil
+	}
+	out := new(RabbitMQ)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RabbitMQVhost) DeepCopyInto(out *RabbitMQVhost) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RabbitMQVhost.
+func (in *RabbitMQVhost) DeepCopy() *RabbitMQVhost {
+	if in == nil {
+		return nil
+	}
+	out := new(RabbitMQVhost)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadOnlyUser) DeepCopyInto(out *ReadOnlyUser) {
+	*out = *in
+	in.Password.DeepCopyInto(&out.Password)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadOnlyUser.
+func (in *ReadOnlyUser) DeepCopy() *ReadOnlyUser {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadOnlyUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Redis) DeepCopyInto(out *Redis) {
 	*out = *in
@@ -998,12 +1677,42 @@ func (in *Subject) DeepCopy() *Subject {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SysEventFilter) DeepCopyInto(out *SysEventFilter) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Apps != nil {
+		in, out := &in.Apps, &out.Apps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SysEventFilter.
+func (in *SysEventFilter) DeepCopy() *SysEventFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(SysEventFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SysEventRegistry) DeepCopyInto(out *SysEventRegistry) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -1060,6 +1769,11 @@ func (in *SysEventRegistryList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SysEventRegistrySpec) DeepCopyInto(out *SysEventRegistrySpec) {
 	*out = *in
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(SysEventFilter)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SysEventRegistrySpec.
@@ -1083,6 +1797,14 @@ func (in *SysEventRegistryStatus) DeepCopyInto(out *SysEventRegistryStatus) {
 		in, out := &in.StatusTime, &out.StatusTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastSuccessTime != nil {
+		in, out := &in.LastSuccessTime, &out.LastSuccessTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SysEventRegistryStatus.
@@ -0,0 +1,217 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"time"
+)
+
+const (
+	DefaultCAValidity   = 10 * 365 * 24 * time.Hour
+	DefaultCertValidity = 365 * 24 * time.Hour
+
+	// the certificates are renewed when less than a third of the validity remains
+	renewRatio = 3
+
+	certOrganization = "bytetrade.io"
+)
+
+// KeyPair is a certificate with its private key, in both parsed and PEM forms
+type KeyPair struct {
+	Cert    *x509.Certificate
+	Key     crypto.Signer
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// NewCA creates a self-signed CA
+func NewCA(commonName string, validity time.Duration) (*KeyPair, error) {
+	template, err := newTemplate(commonName, validity)
+	if err != nil {
+		return nil, err
+	}
+
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	return newKeyPair(template, nil)
+}
+
+// NewServerCert creates a server certificate signed by the CA, the certificate is valid for the dns names
+func NewServerCert(ca *KeyPair, commonName string, dnsNames []string, validity time.Duration) (*KeyPair, error) {
+	if ca == nil {
+		return nil, errors.New("ca is empty")
+	}
+
+	template, err := newTemplate(commonName, validity)
+	if err != nil {
+		return nil, err
+	}
+
+	template.DNSNames = dnsNames
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+
+	// never outlive the ca
+	if template.NotAfter.After(ca.Cert.NotAfter) {
+		template.NotAfter = ca.Cert.NotAfter
+	}
+
+	return newKeyPair(template, ca)
+}
+
+// ParseKeyPair parses the PEM encoded certificate and the private key
+func ParseKeyPair(certPEM, keyPEM []byte) (*KeyPair, error) {
+	cert, err := ParseCert(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("invalid private key pem")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key")
+	}
+
+	return &KeyPair{Cert: cert, Key: signer, CertPEM: certPEM, KeyPEM: keyPEM}, nil
+}
+
+// ParseCert parses the first certificate of the PEM data
+func ParseCert(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("invalid certificate pem")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// NeedsRenewal returns true if less than a third of the validity of the certificate remains
+func NeedsRenewal(cert *x509.Certificate, now time.Time) bool {
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	return now.Add(validity / renewRatio).After(cert.NotAfter)
+}
+
+// IsIssuedFor returns true if the certificate is signed by the ca and valid for all the dns names
+func IsIssuedFor(cert *x509.Certificate, ca *x509.Certificate, dnsNames []string) bool {
+	if err := cert.CheckSignatureFrom(ca); err != nil {
+		return false
+	}
+
+	for _, name := range dnsNames {
+		if err := cert.VerifyHostname(name); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MergeCABundle puts the ca at the head of the bundle, and drops the expired ones and the duplicates
+// from the old bundle. The clients trusting the old ca keep working until they reload the bundle.
+func MergeCABundle(caPEM, bundle []byte, now time.Time) []byte {
+	merged := append([]byte{}, caPEM...)
+	seen := map[string]bool{string(caPEM): true}
+
+	for rest := bundle; len(rest) > 0; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || now.After(cert.NotAfter) {
+			continue
+		}
+
+		encoded := pem.EncodeToMemory(block)
+		if !seen[string(encoded)] {
+			seen[string(encoded)] = true
+			merged = append(merged, encoded...)
+		}
+	}
+
+	return merged
+}
+
+// NewClientTLSConfig returns the tls config of the clients trusting the ca bundle
+func NewClientTLSConfig(bundle []byte) (*tls.Config, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.New("no certificate found in ca bundle")
+	}
+
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+func newTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: []string{certOrganization},
+		},
+		// tolerate the clock skew between nodes
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(validity),
+	}, nil
+}
+
+func newKeyPair(template *x509.Certificate, ca *KeyPair) (*KeyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, signer := template, crypto.Signer(key)
+	if ca != nil {
+		parent, signer = ca.Cert, ca.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyPair{
+		Cert:    cert,
+		Key:     key,
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestNewServerCert(t *testing.T) {
+	ca, err := NewCA("citus-ca", DefaultCAValidity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServerCert(ca, "citus", []string{"*.citus-headless.os-platform", "citus-master-svc.os-platform"}, DefaultCertValidity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+	if _, err = server.Cert.Verify(x509.VerifyOptions{DNSName: "citus-1.citus-headless.os-platform", Roots: pool}); err != nil {
+		t.Error("verify server certificate error, ", err)
+	}
+
+	if !IsIssuedFor(server.Cert, ca.Cert, []string{"citus-0.citus-headless.os-platform", "citus-master-svc.os-platform"}) {
+		t.Error("expected the certificate issued for the names")
+	}
+
+	if IsIssuedFor(server.Cert, ca.Cert, []string{"citus-pgbouncer-svc.os-platform"}) {
+		t.Error("unexpected the certificate issued for the pooler")
+	}
+
+	parsed, err := ParseKeyPair(server.CertPEM, server.KeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !parsed.Cert.Equal(server.Cert) {
+		t.Error("unexpected parsed certificate")
+	}
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	ca, err := NewCA("test-ca", 90*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if NeedsRenewal(ca.Cert, now) {
+		t.Error("unexpected renewal of the new certificate")
+	}
+
+	if !NeedsRenewal(ca.Cert, now.Add(70*24*time.Hour)) {
+		t.Error("expected renewal before expiry")
+	}
+}
+
+func TestMergeCABundle(t *testing.T) {
+	oldCA, err := NewCA("old-ca", DefaultCAValidity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCA, err := NewCA("new-ca", DefaultCAValidity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	bundle := MergeCABundle(newCA.CertPEM, oldCA.CertPEM, now)
+	if !bytes.HasPrefix(bundle, newCA.CertPEM) || !bytes.Contains(bundle, oldCA.CertPEM) {
+		t.Error("expected both cas in the bundle")
+	}
+
+	if merged := MergeCABundle(newCA.CertPEM, bundle, now); !bytes.Equal(merged, bundle) {
+		t.Error("unexpected duplicated ca in the bundle")
+	}
+
+	if merged := MergeCABundle(newCA.CertPEM, bundle, now.Add(DefaultCAValidity+time.Hour)); !bytes.Equal(merged, newCA.CertPEM) {
+		t.Error("expected the expired ca dropped")
+	}
+
+	if _, err = NewClientTLSConfig(bundle); err != nil {
+		t.Error(err)
+	}
+}
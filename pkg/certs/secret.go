@@ -0,0 +1,148 @@
+package certs
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// CACertKey is the key of the ca certificate in the ca secret, and the ca bundle in the tls secret
+	CACertKey = "ca.crt"
+	CAKeyKey  = "ca.key"
+)
+
+func CASecretName(name string) string {
+	return name + "-ca"
+}
+
+func TLSSecretName(name string) string {
+	return name + "-tls"
+}
+
+// EnsureCertificates issues the ca of the cluster into the secret <name>-ca, and the server certificate
+// into the secret <name>-tls with the ca bundle. Both are renewed before expiry, and the server certificate
+// is reissued if the dns names are changed. renewed is true if the tls secret is created or changed.
+func EnsureCertificates(ctx context.Context, client kubernetes.Interface,
+	namespace, name string, dnsNames []string) (secret *corev1.Secret, renewed bool, err error) {
+	now := time.Now()
+	ca, caRenewed, err := ensureCA(ctx, client, namespace, name, now)
+	if err != nil {
+		return nil, false, err
+	}
+
+	secret, err = client.CoreV1().Secrets(namespace).Get(ctx, TLSSecretName(name), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Error("get tls secret error, ", err, ", ", namespace, "/", TLSSecretName(name))
+		return nil, false, err
+	}
+
+	notFound := apierrors.IsNotFound(err)
+	var bundle []byte
+	if !notFound {
+		server, err := ParseKeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		if err == nil && !caRenewed && !NeedsRenewal(server.Cert, now) && IsIssuedFor(server.Cert, ca.Cert, dnsNames) {
+			return secret, false, nil
+		}
+
+		bundle = secret.Data[CACertKey]
+	}
+
+	server, err := NewServerCert(ca, name, dnsNames, DefaultCertValidity)
+	if err != nil {
+		klog.Error("issue server certificate error, ", err, ", ", namespace, "/", name)
+		return nil, false, err
+	}
+
+	data := map[string][]byte{
+		corev1.TLSCertKey:       server.CertPEM,
+		corev1.TLSPrivateKeyKey: server.KeyPEM,
+		CACertKey:               MergeCABundle(ca.CertPEM, bundle, now),
+	}
+
+	if notFound {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      TLSSecretName(name),
+				Namespace: namespace,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: data,
+		}
+		secret, err = client.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	} else {
+		secret.Data = data
+		secret, err = client.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	}
+
+	if err != nil {
+		klog.Error("save tls secret error, ", err, ", ", namespace, "/", TLSSecretName(name))
+		return nil, false, err
+	}
+
+	klog.Info("server certificate issued, ", namespace, "/", name, ", expires at ", server.Cert.NotAfter)
+	return secret, true, nil
+}
+
+// GetCABundle returns the ca bundle to verify the servers of the cluster
+func GetCABundle(ctx context.Context, client kubernetes.Interface, namespace, name string) ([]byte, error) {
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, TLSSecretName(name), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.Data[CACertKey], nil
+}
+
+func ensureCA(ctx context.Context, client kubernetes.Interface, namespace, name string, now time.Time) (*KeyPair, bool, error) {
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, CASecretName(name), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Error("get ca secret error, ", err, ", ", namespace, "/", CASecretName(name))
+		return nil, false, err
+	}
+
+	notFound := apierrors.IsNotFound(err)
+	if !notFound {
+		ca, err := ParseKeyPair(secret.Data[CACertKey], secret.Data[CAKeyKey])
+		if err == nil && !NeedsRenewal(ca.Cert, now) {
+			return ca, false, nil
+		}
+	}
+
+	ca, err := NewCA(name+"-ca", DefaultCAValidity)
+	if err != nil {
+		klog.Error("create ca error, ", err, ", ", namespace, "/", name)
+		return nil, false, err
+	}
+
+	data := map[string][]byte{
+		CACertKey: ca.CertPEM,
+		CAKeyKey:  ca.KeyPEM,
+	}
+
+	if notFound {
+		_, err = client.CoreV1().Secrets(namespace).Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      CASecretName(name),
+				Namespace: namespace,
+			},
+			Data: data,
+		}, metav1.CreateOptions{})
+	} else {
+		secret.Data = data
+		_, err = client.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	}
+
+	if err != nil {
+		klog.Error("save ca secret error, ", err, ", ", namespace, "/", CASecretName(name))
+		return nil, false, err
+	}
+
+	klog.Info("ca issued, ", namespace, "/", name, ", expires at ", ca.Cert.NotAfter)
+	return ca, true, nil
+}
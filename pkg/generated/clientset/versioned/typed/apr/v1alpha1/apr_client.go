@@ -12,6 +12,7 @@ import (
 
 type AprV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	AlertRulesGetter
 	KVRocksBackupsGetter
 	KVRocksRestoresGetter
 	MiddlewareRequestsGetter
@@ -27,6 +28,10 @@ type AprV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *AprV1alpha1Client) AlertRules() AlertRuleInterface {
+	return newAlertRules(c)
+}
+
 func (c *AprV1alpha1Client) KVRocksBackups(namespace string) KVRocksBackupInterface {
 	return newKVRocksBackups(c, namespace)
 }
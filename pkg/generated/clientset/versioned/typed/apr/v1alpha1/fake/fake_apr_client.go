@@ -12,6 +12,10 @@ type FakeAprV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeAprV1alpha1) AlertRules() v1alpha1.AlertRuleInterface {
+	return &FakeAlertRules{c}
+}
+
 func (c *FakeAprV1alpha1) KVRocksBackups(namespace string) v1alpha1.KVRocksBackupInterface {
 	return &FakeKVRocksBackups{c, namespace}
 }
@@ -2,6 +2,8 @@
 
 package v1alpha1
 
+type AlertRuleExpansion interface{}
+
 type KVRocksBackupExpansion interface{}
 
 type KVRocksRestoreExpansion interface{}
@@ -8,6 +8,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// AlertRules returns a AlertRuleInformer.
+	AlertRules() AlertRuleInformer
 	// KVRocksBackups returns a KVRocksBackupInformer.
 	KVRocksBackups() KVRocksBackupInformer
 	// KVRocksRestores returns a KVRocksRestoreInformer.
@@ -37,6 +39,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// AlertRules returns a AlertRuleInformer.
+func (v *version) AlertRules() AlertRuleInformer {
+	return &alertRuleInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // KVRocksBackups returns a KVRocksBackupInformer.
 func (v *version) KVRocksBackups() KVRocksBackupInformer {
 	return &kVRocksBackupInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -37,6 +37,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=apr.bytetrade.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("alertrules"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Apr().V1alpha1().AlertRules().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("kvrocksbackups"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Apr().V1alpha1().KVRocksBackups().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("kvrocksrestores"):
@@ -2,6 +2,10 @@
 
 package v1alpha1
 
+// AlertRuleListerExpansion allows custom methods to be added to
+// AlertRuleLister.
+type AlertRuleListerExpansion interface{}
+
 // KVRocksBackupListerExpansion allows custom methods to be added to
 // KVRocksBackupLister.
 type KVRocksBackupListerExpansion interface{}
@@ -3,6 +3,7 @@ package mongo
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"os/exec"
@@ -22,7 +23,10 @@ type MongoClient struct {
 	Password string
 	Database string
 	Addr     string
-	client   *mongo.Client
+
+	// TLSConfig encrypts the connection if it is set
+	TLSConfig *tls.Config
+	client    *mongo.Client
 }
 
 func (m *MongoClient) Connect(ctx context.Context) error {
@@ -30,7 +34,12 @@ func (m *MongoClient) Connect(ctx context.Context) error {
 	defer cancel()
 
 	dsn := fmt.Sprintf("mongodb://%s:%s@%s/%s", m.User, m.Password, m.Addr, m.Database)
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(dsn))
+	opts := options.Client().ApplyURI(dsn)
+	if m.TLSConfig != nil {
+		opts.SetTLSConfig(m.TLSConfig)
+	}
+
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -116,6 +125,50 @@ func (m *MongoClient) CreateOrUpdateUserWithDatabase(ctx context.Context, user,
 	return nil
 }
 
+// ChangePassword resets the password of the user created in the auth database
+func (m *MongoClient) ChangePassword(ctx context.Context, user, authDB, pwd string) error {
+	cmd := bson.D{{Key: "updateUser", Value: user}, {Key: "pwd", Value: pwd}}
+	return m.client.Database(authDB).RunCommand(ctx, cmd).Err()
+}
+
+// DatabaseStorageSize returns the storage size allocated to the collections of the database
+func (m *MongoClient) DatabaseStorageSize(ctx context.Context, db string) (int64, error) {
+	var res bson.M
+	err := m.client.Database(db).RunCommand(ctx, bson.D{{Key: "dbStats", Value: 1}}).Decode(&res)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size := res["storageSize"].(type) {
+	case int32:
+		return int64(size), nil
+	case int64:
+		return size, nil
+	case float64:
+		return int64(size), nil
+	}
+
+	return 0, nil
+}
+
+// UserExists returns true if the user is created in the auth database
+func (m *MongoClient) UserExists(ctx context.Context, user, authDB string) (bool, error) {
+	query := bson.D{{Key: "user", Value: user}, {Key: "db", Value: authDB}}
+	var result bson.M
+	err := m.client.Database("admin").Collection("system.users").FindOne(ctx, query).Decode(&result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ListDatabaseNames lists the databases, except the system databases
+func (m *MongoClient) ListDatabaseNames(ctx context.Context) ([]string, error) {
+	filter := bson.D{{Key: "name", Value: bson.D{{Key: "$nin", Value: bson.A{"admin", "config", "local"}}}}}
+	return m.client.ListDatabaseNames(ctx, filter)
+}
+
 func (m *MongoClient) DropUserAndDatabase(ctx context.Context, user string, db []v1alpha1.MongoDatabase) error {
 	if user != "" {
 		database := m.client.Database("admin")
@@ -0,0 +1,411 @@
+package mysql
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// error numbers returned by REVOKE when the user has no grants on the object
+	errNoSuchGrant      = 1141
+	errNoSuchTableGrant = 1147
+)
+
+var (
+	// names are always quoted with backticks, app namespace may contain '-'
+	identifierRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+	charsetRegexp    = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	privilegeRegexp  = regexp.MustCompile(`^[A-Z][A-Z ]*[A-Z]$`)
+
+	// ReadOnlyPrivileges is granted to the read-only companion user on every database
+	ReadOnlyPrivileges = []string{"SELECT", "SHOW VIEW"}
+
+	// AllPrivileges is granted if no privileges are specified
+	AllPrivileges = "ALL PRIVILEGES"
+
+	// databasePrivileges are the privileges of ALL PRIVILEGES on a database, revoked when the grants are narrowed
+	databasePrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "DROP", "REFERENCES", "INDEX",
+		"ALTER", "CREATE TEMPORARY TABLES", "LOCK TABLES", "EXECUTE", "CREATE VIEW", "SHOW VIEW", "CREATE ROUTINE",
+		"ALTER ROUTINE", "EVENT", "TRIGGER"}
+)
+
+// client works for both mysql and mariadb, they speak the same protocol
+type client struct {
+	DB      *sql.DB
+	builder *clientBuilder
+}
+
+type clientBuilder struct {
+	user            string
+	password        string
+	host            string
+	database        string
+	tlsConfig       *tls.Config
+	multiStatements bool
+}
+
+func NewClientBuilder(user, password, host string) *clientBuilder {
+	return &clientBuilder{
+		user:     user,
+		password: password,
+		host:     host,
+	}
+}
+
+func (cb *clientBuilder) WithDatabase(db string) *clientBuilder {
+	cb.database = db
+	return cb
+}
+
+// WithTLSConfig encrypts the connection with the tls config, the server name is the host if it is not set
+func (cb *clientBuilder) WithTLSConfig(config *tls.Config) *clientBuilder {
+	cb.tlsConfig = config
+	return cb
+}
+
+// WithMultiStatements allows the multiple statements in one query, required by the scripts only
+func (cb *clientBuilder) WithMultiStatements() *clientBuilder {
+	cb.multiStatements = true
+	return cb
+}
+
+func (cb *clientBuilder) Build() (*client, error) {
+	params := url.Values{}
+	if cb.multiStatements {
+		params.Set("multiStatements", "true")
+	}
+
+	if cb.tlsConfig != nil {
+		// the driver finds the tls config by the registered name
+		name := "tapr-" + cb.host
+		if err := mysql.RegisterTLSConfig(name, cb.tlsConfig); err != nil {
+			return nil, err
+		}
+		params.Set("tls", name)
+	}
+
+	dsn := DSN(cb.user, cb.password, cb.host, cb.database)
+	if len(params) > 0 {
+		dsn += "?" + params.Encode()
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{DB: db, builder: cb}, nil
+}
+
+func DSN(user, password, host, database string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s", user, password, host, database)
+}
+
+func (c *client) Close() {
+	if err := c.DB.Close(); err != nil {
+		klog.Error("close db error, ", err)
+	}
+}
+
+func (c *client) exec(ctx context.Context, query string) error {
+	_, err := c.DB.ExecContext(ctx, query)
+	if err != nil {
+		klog.Errorf("failed to execute sql %q, %v", redact(query), err)
+	}
+	return err
+}
+
+// CreateOrUpdateUser creates the user if not exists, and always resets the password and
+// the max user connections, so that changes in the request spec can take effect.
+// maxConnections 0 means unlimited.
+func (c *client) CreateOrUpdateUser(ctx context.Context, user, password string, maxConnections int32) error {
+	if err := ValidateIdentifier(user); err != nil {
+		return err
+	}
+
+	pwd := escapeString(password)
+	if err := c.exec(ctx, fmt.Sprintf("CREATE USER IF NOT EXISTS `%s` IDENTIFIED BY '%s'", user, pwd)); err != nil {
+		return err
+	}
+
+	return c.exec(ctx, fmt.Sprintf("ALTER USER `%s` IDENTIFIED BY '%s' WITH MAX_USER_CONNECTIONS %d", user, pwd, maxConnections))
+}
+
+func (c *client) DropUser(ctx context.Context, user string) error {
+	if err := ValidateIdentifier(user); err != nil {
+		return err
+	}
+
+	return c.exec(ctx, fmt.Sprintf("DROP USER IF EXISTS `%s`", user))
+}
+
+// ChangePassword resets the password of the user on all hosts
+func (c *client) ChangePassword(ctx context.Context, user, password string) error {
+	if err := ValidateIdentifier(user); err != nil {
+		return err
+	}
+
+	rows, err := c.DB.QueryContext(ctx, "SELECT host FROM mysql.user WHERE user = ?", user)
+	if err != nil {
+		return err
+	}
+
+	var hosts []string
+	for rows.Next() {
+		var host string
+		if err = rows.Scan(&host); err != nil {
+			rows.Close()
+			return err
+		}
+		hosts = append(hosts, host)
+	}
+	rows.Close()
+
+	if len(hosts) == 0 {
+		return fmt.Errorf("user %s not found", user)
+	}
+
+	pwd := escapeString(password)
+	for _, host := range hosts {
+		if err = c.exec(ctx, fmt.Sprintf("ALTER USER `%s`@'%s' IDENTIFIED BY '%s'", user, escapeString(host), pwd)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UserExists returns true if the user exists in the server
+func (c *client) UserExists(ctx context.Context, user string) (bool, error) {
+	rows, err := c.DB.QueryContext(ctx, "SELECT 1 FROM mysql.user WHERE user = ?", user)
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+// DatabaseExists returns true if the database exists in the server
+func (c *client) DatabaseExists(ctx context.Context, db string) (bool, error) {
+	rows, err := c.DB.QueryContext(ctx, "SELECT 1 FROM information_schema.schemata WHERE schema_name = ?", db)
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+// ListDatabases lists the databases in the server, except the system schemas
+func (c *client) ListDatabases(ctx context.Context) ([]string, error) {
+	rows, err := c.DB.QueryContext(ctx, "SELECT schema_name FROM information_schema.schemata "+
+		"WHERE schema_name NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var databases []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		databases = append(databases, name)
+	}
+
+	return databases, rows.Err()
+}
+
+// CreateOrUpdateDatabase creates the database if not exists, and updates the default
+// charset and collation of the database if specified.
+func (c *client) CreateOrUpdateDatabase(ctx context.Context, db, charset, collation string) error {
+	if err := ValidateIdentifier(db); err != nil {
+		return err
+	}
+
+	var options string
+	if charset != "" {
+		if !charsetRegexp.MatchString(charset) {
+			return fmt.Errorf("invalid charset %q", charset)
+		}
+		options += " CHARACTER SET " + charset
+	}
+
+	if collation != "" {
+		if !charsetRegexp.MatchString(collation) {
+			return fmt.Errorf("invalid collation %q", collation)
+		}
+		options += " COLLATE " + collation
+	}
+
+	if err := c.exec(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`%s", db, options)); err != nil {
+		return err
+	}
+
+	if options == "" {
+		return nil
+	}
+
+	return c.exec(ctx, fmt.Sprintf("ALTER DATABASE `%s`%s", db, options))
+}
+
+func (c *client) DropDatabase(ctx context.Context, db string) error {
+	if err := ValidateIdentifier(db); err != nil {
+		return err
+	}
+
+	return c.exec(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", db))
+}
+
+// Privileges returns the normalized privileges to grant, ALL PRIVILEGES if privileges is empty
+func Privileges(privileges []string) ([]string, error) {
+	if len(privileges) == 0 {
+		return []string{AllPrivileges}, nil
+	}
+
+	var privs []string
+	for _, p := range privileges {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if !privilegeRegexp.MatchString(p) {
+			return nil, fmt.Errorf("invalid privilege %q", p)
+		}
+
+		if p == "ALL" || p == AllPrivileges {
+			return []string{AllPrivileges}, nil
+		}
+		privs = append(privs, p)
+	}
+
+	return privs, nil
+}
+
+// GrantPrivileges grants the privileges of user on db, and then revokes the privileges granted
+// before, in prev, but not in privileges. The user keeps the access if the grant fails.
+// If privileges is empty, all privileges will be granted.
+func (c *client) GrantPrivileges(ctx context.Context, db, user string, privileges, prev []string) error {
+	if err := ValidateIdentifier(db); err != nil {
+		return err
+	}
+
+	if err := ValidateIdentifier(user); err != nil {
+		return err
+	}
+
+	grants, err := Privileges(privileges)
+	if err != nil {
+		return err
+	}
+
+	if err = c.exec(ctx, fmt.Sprintf("GRANT %s ON `%s`.* TO `%s`", strings.Join(grants, ", "), db, user)); err != nil {
+		return err
+	}
+
+	revokes, err := revokedPrivileges(prev, grants)
+	if err != nil || len(revokes) == 0 {
+		return err
+	}
+
+	_, err = c.DB.ExecContext(ctx, fmt.Sprintf("REVOKE %s ON `%s`.* FROM `%s`", strings.Join(revokes, ", "), db, user))
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if !errors.As(err, &mysqlErr) || (mysqlErr.Number != errNoSuchGrant && mysqlErr.Number != errNoSuchTableGrant) {
+			klog.Errorf("failed to revoke privileges on %s from %s, %v", db, user, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokedPrivileges returns the privileges in prev not in grants
+func revokedPrivileges(prev, grants []string) ([]string, error) {
+	if len(prev) == 0 || grants[0] == AllPrivileges {
+		return nil, nil
+	}
+
+	prev, err := Privileges(prev)
+	if err != nil {
+		return nil, err
+	}
+
+	if prev[0] == AllPrivileges {
+		prev = databasePrivileges
+	}
+
+	return removedPrivileges(prev, grants), nil
+}
+
+func removedPrivileges(prev, grants []string) []string {
+	var ret []string
+	for _, p := range prev {
+		found := false
+		for _, g := range grants {
+			if p == g {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			ret = append(ret, p)
+		}
+	}
+
+	return ret
+}
+
+func (c *client) FlushPrivileges(ctx context.Context) error {
+	return c.exec(ctx, "FLUSH PRIVILEGES")
+}
+
+// ExecuteScript executes the scripts in the database, the scripts are executed on every
+// reconciliation, so they must be idempotent.
+func (c *client) ExecuteScript(ctx context.Context, databaseName, dbUsername string, scripts []string) error {
+	if err := ValidateIdentifier(databaseName); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("USE `%s`;", databaseName))
+	for _, cmd := range scripts {
+		// replace databasename, dbusername with real database name and username in mysql
+		cmd = strings.ReplaceAll(cmd, "$databasename", databaseName)
+		cmd = strings.ReplaceAll(cmd, "$dbusername", dbUsername)
+		sb.WriteString(cmd)
+		if !strings.HasSuffix(strings.TrimSpace(cmd), ";") {
+			sb.WriteString(";")
+		}
+	}
+
+	return c.exec(ctx, sb.String())
+}
+
+func ValidateIdentifier(name string) error {
+	if !identifierRegexp.MatchString(name) {
+		return fmt.Errorf("invalid identifier %q", name)
+	}
+	return nil
+}
+
+func escapeString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+func redact(query string) string {
+	if i := strings.Index(query, "IDENTIFIED BY"); i >= 0 {
+		return query[:i] + "IDENTIFIED BY ***"
+	}
+	return query
+}
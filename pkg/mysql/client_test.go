@@ -0,0 +1,42 @@
+package mysql
+
+import "testing"
+
+func TestValidateIdentifier(t *testing.T) {
+	for _, name := range []string{"user1", "app-alice_db", "APP_01"} {
+		if err := ValidateIdentifier(name); err != nil {
+			t.Errorf("expected %q to be valid, %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"", "db`; drop database x", "a.b", "a b"} {
+		if err := ValidateIdentifier(name); err == nil {
+			t.Errorf("expected %q to be invalid", name)
+		}
+	}
+}
+
+func TestEscapeString(t *testing.T) {
+	if s := escapeString(`pa'ss\word`); s != `pa\'ss\\word` {
+		t.Errorf("unexpected escaped string %s", s)
+	}
+}
+
+func TestRevokedPrivileges(t *testing.T) {
+	cases := []struct {
+		prev, grants []string
+		revoked      int
+	}{
+		{nil, []string{"SELECT"}, 0},
+		{[]string{"SELECT", "INSERT"}, []string{"SELECT"}, 1},
+		{[]string{"SELECT"}, []string{AllPrivileges}, 0},
+		{[]string{AllPrivileges}, []string{"SELECT", "INSERT"}, len(databasePrivileges) - 2},
+	}
+
+	for _, c := range cases {
+		revoked, err := revokedPrivileges(c.prev, c.grants)
+		if err != nil || len(revoked) != c.revoked {
+			t.Errorf("unexpected revoked privileges of %v to %v, %v, %v", c.prev, c.grants, revoked, err)
+		}
+	}
+}
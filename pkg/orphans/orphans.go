@@ -0,0 +1,191 @@
+package orphans
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ConfigMapName is the configmap keeping the orphans found by the gc, and the approvals of the cleanup
+	ConfigMapName = "middleware-orphans"
+
+	orphansKey    = "orphans"
+	quarantineKey = "quarantine"
+
+	// DefaultQuarantine is the minimum time an orphan is kept after it is found
+	DefaultQuarantine = 7 * 24 * time.Hour
+)
+
+const (
+	KindDatabase  = "database"
+	KindRole      = "role"
+	KindUser      = "user"
+	KindNamespace = "namespace"
+	KindBucket    = "bucket"
+	KindVhost     = "vhost"
+	KindIndex     = "index"
+)
+
+// Orphan is an object in the backend following the tapr naming scheme, but owned by no middleware request
+type Orphan struct {
+	Middleware aprv1.MiddlewareType `json:"middleware"`
+	Kind       string               `json:"kind"`
+	Name       string               `json:"name"`
+	FirstSeen  metav1.Time          `json:"firstSeen"`
+
+	// Cleanup is approved by the admin, the orphan is deleted after the quarantine
+	Cleanup bool `json:"cleanup"`
+}
+
+func (o *Orphan) Key() string {
+	return string(o.Middleware) + "/" + o.Kind + "/" + o.Name
+}
+
+// Due returns true if the cleanup of the orphan is approved, and the quarantine is over
+func (o *Orphan) Due(quarantine time.Duration, now time.Time) bool {
+	return o.Cleanup && !now.Before(o.FirstSeen.Add(quarantine))
+}
+
+// Merge keeps the first seen time and the approval of the stored orphans still found,
+// and drops the ones not found any more
+func Merge(stored, found []Orphan, now time.Time) []Orphan {
+	storedMap := make(map[string]Orphan)
+	for _, o := range stored {
+		storedMap[o.Key()] = o
+	}
+
+	merged := make([]Orphan, 0, len(found))
+	seen := make(map[string]bool)
+	for _, o := range found {
+		key := o.Key()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if s, ok := storedMap[key]; ok {
+			o.FirstSeen = s.FirstSeen
+			o.Cleanup = s.Cleanup
+		} else {
+			o.FirstSeen = metav1.NewTime(now)
+			o.Cleanup = false
+		}
+
+		merged = append(merged, o)
+	}
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Key() < merged[j].Key() })
+	return merged
+}
+
+// Approve sets the cleanup approval of the orphans with the keys, nothing is changed if keys is empty.
+// Returns the number of the orphans changed
+func Approve(orphans []Orphan, keys []string, cleanup bool) int {
+	keySet := make(map[string]bool)
+	for _, k := range keys {
+		keySet[k] = true
+	}
+
+	changed := 0
+	for i := range orphans {
+		if !keySet[orphans[i].Key()] {
+			continue
+		}
+
+		if orphans[i].Cleanup != cleanup {
+			orphans[i].Cleanup = cleanup
+			changed++
+		}
+	}
+
+	return changed
+}
+
+// Load returns the orphans and the quarantine in the configmap, empty if the configmap is not found
+func Load(ctx context.Context, client kubernetes.Interface, namespace string) ([]Orphan, time.Duration, error) {
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, DefaultQuarantine, nil
+		}
+		return nil, 0, err
+	}
+
+	return parse(cm)
+}
+
+// Update applies the change to the orphans in the configmap, retries on conflict
+func Update(ctx context.Context, client kubernetes.Interface, namespace string,
+	change func(orphans []Orphan, quarantine time.Duration) []Orphan) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+		notFound := apierrors.IsNotFound(err)
+		if err != nil && !notFound {
+			return err
+		}
+
+		if notFound {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ConfigMapName,
+					Namespace: namespace,
+				},
+			}
+		}
+
+		orphans, quarantine, err := parse(cm)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(change(orphans, quarantine))
+		if err != nil {
+			return err
+		}
+
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[orphansKey] = string(data)
+
+		if notFound {
+			_, err = client.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+		} else {
+			_, err = client.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		}
+
+		return err
+	})
+}
+
+func parse(cm *corev1.ConfigMap) ([]Orphan, time.Duration, error) {
+	quarantine := DefaultQuarantine
+	if q, ok := cm.Data[quarantineKey]; ok {
+		d, err := time.ParseDuration(q)
+		if err != nil {
+			klog.Warning("invalid orphan quarantine, ", q, ", use the default")
+		} else {
+			quarantine = d
+		}
+	}
+
+	var orphans []Orphan
+	if data, ok := cm.Data[orphansKey]; ok && data != "" {
+		if err := json.Unmarshal([]byte(data), &orphans); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return orphans, quarantine, nil
+}
@@ -0,0 +1,79 @@
+package orphans
+
+import (
+	"testing"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMerge(t *testing.T) {
+	now := time.Now()
+	firstSeen := metav1.NewTime(now.Add(-time.Hour))
+	stored := []Orphan{
+		{Middleware: aprv1.TypePostgreSQL, Kind: KindDatabase, Name: "app_alice_db", FirstSeen: firstSeen, Cleanup: true},
+		{Middleware: aprv1.TypeMinio, Kind: KindBucket, Name: "app-alice-bucket", FirstSeen: firstSeen},
+	}
+
+	found := []Orphan{
+		{Middleware: aprv1.TypePostgreSQL, Kind: KindDatabase, Name: "app_alice_db"},
+		{Middleware: aprv1.TypeMongoDB, Kind: KindDatabase, Name: "app-alice_db"},
+		{Middleware: aprv1.TypeMongoDB, Kind: KindDatabase, Name: "app-alice_db"},
+	}
+
+	merged := Merge(stored, found, now)
+	if len(merged) != 2 {
+		t.Fatalf("unexpected orphans %v", merged)
+	}
+
+	// sorted by the key
+	if merged[0].Middleware != aprv1.TypeMongoDB || merged[0].Cleanup || !merged[0].FirstSeen.Time.Equal(now) {
+		t.Errorf("unexpected new orphan %v", merged[0])
+	}
+
+	if !merged[1].Cleanup || !merged[1].FirstSeen.Equal(&firstSeen) {
+		t.Errorf("expected the stored orphan kept, %v", merged[1])
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Now()
+	o := Orphan{FirstSeen: metav1.NewTime(now.Add(-2 * time.Hour))}
+	if o.Due(time.Hour, now) {
+		t.Error("unexpected due without approval")
+	}
+
+	if Approve([]Orphan{o}, nil, true) != 0 {
+		t.Error("unexpected approval without keys")
+	}
+
+	if Approve([]Orphan{o}, []string{o.Key()}, true) != 1 {
+		t.Error("expected the orphan approved")
+	}
+
+	o.Cleanup = true
+	if !o.Due(time.Hour, now) {
+		t.Error("expected due after the quarantine")
+	}
+
+	if o.Due(3*time.Hour, now) {
+		t.Error("unexpected due in the quarantine")
+	}
+}
+
+func TestApprove(t *testing.T) {
+	orphans := []Orphan{
+		{Middleware: aprv1.TypeRabbitMQ, Kind: KindVhost, Name: "app-alice-vhost"},
+		{Middleware: aprv1.TypeElasticsearch, Kind: KindIndex, Name: "app-alice-index"},
+	}
+
+	if changed := Approve(orphans, []string{"rabbitmq/vhost/app-alice-vhost"}, true); changed != 1 {
+		t.Errorf("unexpected changed %d", changed)
+	}
+
+	if !orphans[0].Cleanup || orphans[1].Cleanup {
+		t.Errorf("unexpected approvals %v", orphans)
+	}
+}
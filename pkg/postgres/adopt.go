@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// DatabaseOwner returns the owner of the database, empty if the database does not exist
+func (c *client) DatabaseOwner(ctx context.Context, db string) (string, error) {
+	rows, err := c.DB.NamedQueryContext(ctx,
+		"select pg_catalog.pg_get_userbyid(datdba) as rolname from pg_catalog.pg_database where datname=:name",
+		map[string]interface{}{"name": db})
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close()
+	if !rows.Next() {
+		return "", nil
+	}
+
+	row := new(PGRoleName)
+	if err = rows.StructScan(row); err != nil {
+		return "", err
+	}
+
+	return row.Name, nil
+}
+
+// RenameDatabase renames the database, the database must have no connections
+func (c *client) RenameDatabase(ctx context.Context, from, to string) error {
+	for _, id := range []string{from, to} {
+		if err := ValidateIdentifier(id); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.DB.ExecContext(ctx, fmt.Sprintf("alter database %s rename to %s", from, to))
+	return err
+}
+
+// AdoptDatabase transfers the current database to owner. The objects of the previous owner are reassigned
+// to owner, so the owner can alter and drop them, and all privileges on the objects in the schemas not owned
+// by the superusers, e.g. the schemas of the extensions, are granted.
+// The objects of the superusers are not reassigned, REASSIGN OWNED also transfers the other databases of from
+func (c *client) AdoptDatabase(ctx context.Context, db, from, owner string) error {
+	for _, id := range []string{db, from, owner} {
+		if err := ValidateIdentifier(id); err != nil {
+			return err
+		}
+	}
+
+	var superuser bool
+	err := c.DB.QueryRowxContext(ctx, "select rolsuper from pg_catalog.pg_roles where rolname = $1", from).Scan(&superuser)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.DB.QueryxContext(ctx, `select n.nspname from pg_catalog.pg_namespace n
+		join pg_catalog.pg_roles r on r.oid = n.nspowner
+		where (not r.rolsuper or n.nspname = 'public') and n.nspname not like 'pg\_%' and n.nspname <> 'information_schema'`)
+	if err != nil {
+		return err
+	}
+
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err = rows.Scan(&schema); err != nil {
+			rows.Close()
+			return err
+		}
+
+		schemas = append(schemas, schema)
+	}
+	rows.Close()
+
+	sqls := []string{fmt.Sprintf("alter database %s owner to %s", db, owner)}
+	if from != owner && !superuser {
+		sqls = append(sqls, fmt.Sprintf("reassign owned by %s to %s", from, owner))
+	}
+
+	for _, schema := range schemas {
+		if err = ValidateIdentifier(schema); err != nil {
+			klog.Warning("skip the schema with invalid identifier, ", schema)
+			continue
+		}
+
+		sqls = append(sqls,
+			fmt.Sprintf("grant all on schema %s to %s", schema, owner),
+			fmt.Sprintf("grant all on all tables in schema %s to %s", schema, owner),
+			fmt.Sprintf("grant all on all sequences in schema %s to %s", schema, owner),
+			fmt.Sprintf("grant all on all functions in schema %s to %s", schema, owner),
+		)
+	}
+
+	tx, err := c.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, sql := range sqls {
+		c.DB.log(sql)
+		if _, err = tx.ExecContext(ctx, sql); err != nil {
+			klog.Error("adopt database error, ", err, ", ", sql)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -2,10 +2,15 @@ package postgres
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"bytetrade.io/web3os/tapr/pkg/utils"
 
@@ -71,6 +76,26 @@ type clientBuilder struct {
 	host     string
 	port     int
 	database string
+	rootCA   []byte
+}
+
+var (
+	defaultRootCAMu sync.RWMutex
+	defaultRootCA   []byte
+)
+
+// SetDefaultRootCA sets the ca bundle to verify the servers for the clients built without WithRootCA,
+// the connections are not encrypted if the bundle is empty
+func SetDefaultRootCA(ca []byte) {
+	defaultRootCAMu.Lock()
+	defer defaultRootCAMu.Unlock()
+	defaultRootCA = ca
+}
+
+func getDefaultRootCA() []byte {
+	defaultRootCAMu.RLock()
+	defer defaultRootCAMu.RUnlock()
+	return defaultRootCA
 }
 
 func NewClientBuidler(user, password, host string, port int) *clientBuilder {
@@ -88,7 +113,28 @@ func (cb *clientBuilder) WithDatabase(db string) *clientBuilder {
 	return cb
 }
 
+// WithRootCA encrypts the connection, and verifies the server by the ca bundle
+func (cb *clientBuilder) WithRootCA(ca []byte) *clientBuilder {
+	cb.rootCA = ca
+	return cb
+}
+
 func (cb *clientBuilder) Build() (*client, error) {
+	rootCA := cb.rootCA
+	if len(rootCA) == 0 {
+		rootCA = getDefaultRootCA()
+	}
+
+	if len(rootCA) > 0 {
+		rootCertFile, err := writeRootCA(rootCA)
+		if err != nil {
+			klog.Error("write root ca error, ", err)
+			return nil, err
+		}
+
+		return newClient(TLSDSN(cb.user, cb.password, cb.host, cb.port, cb.database, rootCertFile), cb)
+	}
+
 	return newClient(DSN(cb.user, cb.password, cb.host, cb.port, cb.database), cb)
 }
 
@@ -96,6 +142,37 @@ func DSN(user, password, host string, port int, database string) string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", user, password, host, port, database)
 }
 
+// TLSDSN returns the dsn verifying the server certificate by the ca bundle file
+func TLSDSN(user, password, host string, port int, database string, rootCertFile string) string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=verify-ca&sslrootcert=%s",
+		user, password, host, port, database, url.QueryEscape(rootCertFile))
+}
+
+// writeRootCA writes the ca bundle into a file named by its digest, the driver reads the bundle from file only
+func writeRootCA(ca []byte) (string, error) {
+	file := filepath.Join(os.TempDir(), fmt.Sprintf("pg-root-ca-%x.crt", sha256.Sum256(ca)))
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+
+	tmp, err := os.CreateTemp(os.TempDir(), "pg-root-ca-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(ca); err != nil {
+		tmp.Close()
+		return "", err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+
+	return file, os.Rename(tmp.Name(), file)
+}
+
 func newClient(dsn string, builder *clientBuilder) (*client, error) {
 	db, err := sqlx.Connect("postgres", dsn)
 	if err != nil {
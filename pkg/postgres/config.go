@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+var paramNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_.]*$`)
+
+type PGSetting struct {
+	Name    string `db:"name"`
+	Setting string `db:"setting"`
+}
+
+// ValidateParamName returns error if the name is not a valid postgresql.conf parameter name
+func ValidateParamName(name string) error {
+	if !paramNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid parameter name %q", name)
+	}
+	return nil
+}
+
+// AlterSystem writes the parameters to postgresql.auto.conf, and resets the parameters set before
+// but not in params any more. The configuration is reloaded, and the changed parameters which can
+// only be set at server start (context 'postmaster'), or still pending restart, are returned.
+func (c *client) AlterSystem(ctx context.Context, params map[string]string) (pendingRestart []string, err error) {
+	current, err := c.listSettings(ctx, "select name, setting from pg_catalog.pg_file_settings where sourcefile like '%postgresql.auto.conf'")
+	if err != nil {
+		klog.Error("list auto config parameters error, ", err)
+		return nil, err
+	}
+
+	var changed []string
+	for _, s := range current {
+		if _, ok := params[s.Name]; !ok {
+			if err = ValidateParamName(s.Name); err != nil {
+				return nil, err
+			}
+
+			if _, err = c.DB.ExecContext(ctx, fmt.Sprintf("alter system reset %s", s.Name)); err != nil {
+				klog.Error("reset parameter error, ", err, ", ", s.Name)
+				return nil, err
+			}
+
+			changed = append(changed, s.Name)
+		}
+	}
+
+	for name, value := range params {
+		if err = ValidateParamName(name); err != nil {
+			return nil, err
+		}
+
+		if func() bool {
+			for _, s := range current {
+				if s.Name == name && s.Setting == value {
+					return true
+				}
+			}
+			return false
+		}() {
+			continue
+		}
+
+		if _, err = c.DB.ExecContext(ctx, fmt.Sprintf("alter system set %s = '%s'", name, strings.ReplaceAll(value, "'", "''"))); err != nil {
+			klog.Error("set parameter error, ", err, ", ", name)
+			return nil, err
+		}
+
+		changed = append(changed, name)
+	}
+
+	if _, err = c.DB.ExecContext(ctx, "select pg_reload_conf()"); err != nil {
+		klog.Error("reload pg config error, ", err)
+		return nil, err
+	}
+
+	sql := "select name, setting from pg_catalog.pg_settings where pending_restart"
+	if len(changed) > 0 {
+		// the names are validated
+		sql += fmt.Sprintf(" or (context = 'postmaster' and name in ('%s'))", strings.Join(changed, "','"))
+	}
+
+	settings, err := c.listSettings(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range settings {
+		pendingRestart = append(pendingRestart, s.Name)
+	}
+
+	return pendingRestart, nil
+}
+
+func (c *client) listSettings(ctx context.Context, sql string) ([]*PGSetting, error) {
+	rows, err := c.DB.QueryxContext(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var settings []*PGSetting
+	for rows.Next() {
+		row := new(PGSetting)
+		if err = rows.StructScan(row); err != nil {
+			return nil, err
+		}
+
+		settings = append(settings, row)
+	}
+
+	return settings, nil
+}
+
+// ReloadConf reloads the configuration files and the ssl certificates of the server
+func (c *client) ReloadConf(ctx context.Context) error {
+	_, err := c.DB.ExecContext(ctx, "select pg_reload_conf()")
+	return err
+}
@@ -12,3 +12,8 @@ type PGTable struct {
 type PGPid struct {
 	PID int `db:"pid"`
 }
+
+type PGExtension struct {
+	Name    string `db:"extname"`
+	Version string `db:"extversion"`
+}
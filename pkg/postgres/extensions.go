@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// ListDatabases lists the databases which accept connections, except the templates
+func (c *client) ListDatabases(ctx context.Context) ([]string, error) {
+	rows, err := c.DB.QueryxContext(ctx, "select datname as name from pg_catalog.pg_database where not datistemplate and datallowconn")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var databases []string
+	for rows.Next() {
+		row := new(PGTable)
+		if err = rows.StructScan(row); err != nil {
+			return nil, err
+		}
+
+		databases = append(databases, row.Name)
+	}
+
+	return databases, nil
+}
+
+// ListExtensions lists the extensions installed in the current database
+func (c *client) ListExtensions(ctx context.Context) ([]*PGExtension, error) {
+	rows, err := c.DB.QueryxContext(ctx, "select extname, extversion from pg_catalog.pg_extension")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var extensions []*PGExtension
+	for rows.Next() {
+		row := new(PGExtension)
+		if err = rows.StructScan(row); err != nil {
+			return nil, err
+		}
+
+		extensions = append(extensions, row)
+	}
+
+	return extensions, nil
+}
+
+// UpdateExtension updates the extension to the default version of the server,
+// if it is installed in the current database
+func (c *client) UpdateExtension(ctx context.Context, extension string) error {
+	if err := ValidateIdentifier(extension); err != nil {
+		return err
+	}
+
+	extensions, err := c.ListExtensions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range extensions {
+		if e.Name == extension {
+			_, err = c.DB.ExecContext(ctx, fmt.Sprintf("alter extension %s update", extension))
+			return err
+		}
+	}
+
+	return nil
+}
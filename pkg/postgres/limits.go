@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	StatementTimeout                = "statement_timeout"
+	IdleInTransactionSessionTimeout = "idle_in_transaction_session_timeout"
+	WorkMem                         = "work_mem"
+)
+
+var (
+	limitSettings = []string{StatementTimeout, IdleInTransactionSessionTimeout, WorkMem}
+
+	// number with an optional time or memory unit, e.g. 30s, 10min, 4MB
+	settingValueRegexp = regexp.MustCompile(`^[0-9]+(us|ms|s|min|h|d|B|kB|MB|GB|TB)?$`)
+)
+
+type PGSize struct {
+	Size int64 `db:"size"`
+}
+
+type PGPrivilege struct {
+	Granted bool `db:"granted"`
+}
+
+// ValidateSettingValue returns error if the value is not a number with an optional unit
+func ValidateSettingValue(value string) error {
+	if !settingValueRegexp.MatchString(value) {
+		return fmt.Errorf("invalid setting value %q", value)
+	}
+	return nil
+}
+
+// SetConnectionLimit sets the connection limit of role on the current node, -1 means unlimited
+func (c *client) SetConnectionLimit(ctx context.Context, role string, limit int32) error {
+	if err := ValidateIdentifier(role); err != nil {
+		return err
+	}
+
+	_, err := c.DB.ExecContext(ctx, fmt.Sprintf("alter role %s connection limit %d", role, limit))
+	return err
+}
+
+// SetDatabaseLimits sets the limit settings as the session defaults of the database,
+// the settings with empty value are reset.
+func (c *client) SetDatabaseLimits(ctx context.Context, db string, settings map[string]string) error {
+	if err := ValidateIdentifier(db); err != nil {
+		return err
+	}
+
+	for _, name := range limitSettings {
+		value := settings[name]
+		sql := fmt.Sprintf("alter database %s reset %s", db, name)
+		if value != "" {
+			if err := ValidateSettingValue(value); err != nil {
+				return err
+			}
+
+			sql = fmt.Sprintf("alter database %s set %s = '%s'", db, name, value)
+		}
+
+		if _, err := c.DB.ExecContext(ctx, sql); err != nil {
+			klog.Error("set database limit error, ", err, ", ", db, ", ", name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DatabaseSize returns the size of the database on the current node, 0 if it does not exist
+func (c *client) DatabaseSize(ctx context.Context, db string) (int64, error) {
+	sql := "select coalesce(sum(pg_database_size(datname)), 0)::bigint as size from pg_catalog.pg_database where datname=:db"
+	rows, err := c.DB.NamedQueryContext(ctx, sql, map[string]interface{}{
+		"db": db,
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+	size := new(PGSize)
+	if rows.Next() {
+		if err = rows.StructScan(size); err != nil {
+			return 0, err
+		}
+	}
+
+	return size.Size, nil
+}
+
+// ConnectionCount returns the number of the backends connected to the database on the current node
+func (c *client) ConnectionCount(ctx context.Context, db string) (int64, error) {
+	sql := "select count(*)::bigint as size from pg_catalog.pg_stat_activity where datname=:db"
+	rows, err := c.DB.NamedQueryContext(ctx, sql, map[string]interface{}{
+		"db": db,
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+	count := new(PGSize)
+	if rows.Next() {
+		if err = rows.StructScan(count); err != nil {
+			return 0, err
+		}
+	}
+
+	return count.Size, nil
+}
+
+// HasConnectPrivilege returns whether role can connect to the database
+func (c *client) HasConnectPrivilege(ctx context.Context, db, role string) (bool, error) {
+	sql := "select has_database_privilege(:role, :db, 'CONNECT') as granted"
+	rows, err := c.DB.NamedQueryContext(ctx, sql, map[string]interface{}{
+		"role": role,
+		"db":   db,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+	priv := new(PGPrivilege)
+	if rows.Next() {
+		if err = rows.StructScan(priv); err != nil {
+			return false, err
+		}
+	}
+
+	return priv.Granted, nil
+}
+
+// RevokeConnect revokes the CONNECT privilege on the database from public and role,
+// the sessions already connected are kept.
+func (c *client) RevokeConnect(ctx context.Context, db, role string) error {
+	return c.changeConnect(ctx, db, role, false)
+}
+
+// GrantConnect restores the CONNECT privilege revoked by RevokeConnect
+func (c *client) GrantConnect(ctx context.Context, db, role string) error {
+	return c.changeConnect(ctx, db, role, true)
+}
+
+func (c *client) changeConnect(ctx context.Context, db, role string, grant bool) error {
+	for _, id := range []string{db, role} {
+		if err := ValidateIdentifier(id); err != nil {
+			return err
+		}
+	}
+
+	sqls := []string{
+		fmt.Sprintf("revoke connect on database %s from public", db),
+		fmt.Sprintf("revoke connect on database %s from %s", db, role),
+	}
+	if grant {
+		sqls = []string{
+			fmt.Sprintf("grant connect on database %s to public", db),
+			fmt.Sprintf("grant connect on database %s to %s", db, role),
+		}
+	}
+
+	for _, sql := range sqls {
+		if _, err := c.DB.ExecContext(ctx, sql); err != nil {
+			klog.Error("change connect privilege error, ", err, ", ", sql)
+			return err
+		}
+	}
+
+	return nil
+}
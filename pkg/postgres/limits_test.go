@@ -0,0 +1,17 @@
+package postgres
+
+import "testing"
+
+func TestValidateSettingValue(t *testing.T) {
+	for _, v := range []string{"30s", "10min", "4MB", "500", "100ms"} {
+		if err := ValidateSettingValue(v); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, v := range []string{"", "4 MB", "1s'; drop table x; --", "-1s"} {
+		if err := ValidateSettingValue(v); err == nil {
+			t.Errorf("expected %q to be invalid", v)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const DefaultSchema = "public"
+
+var (
+	identifierRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	tablePrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER", "ALL"}
+
+	schemaPrivileges = []string{"USAGE", "CREATE", "ALL"}
+)
+
+type PGRoleName struct {
+	Name string `db:"rolname"`
+}
+
+func ValidateIdentifier(name string) error {
+	if !identifierRegexp.MatchString(name) {
+		return fmt.Errorf("invalid identifier %q", name)
+	}
+	return nil
+}
+
+func normalizePrivileges(privileges, allowed []string) (string, error) {
+	var privs []string
+	for _, p := range privileges {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		found := false
+		for _, a := range allowed {
+			if a == p {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return "", fmt.Errorf("invalid privilege %q", p)
+		}
+
+		privs = append(privs, p)
+	}
+
+	return strings.Join(privs, ", "), nil
+}
+
+// ValidateTablePrivileges returns error if any of the privileges is not a table privilege
+func ValidateTablePrivileges(privileges []string) error {
+	_, err := normalizePrivileges(privileges, tablePrivileges)
+	return err
+}
+
+// CreateOrUpdateRole creates a login role without any extra privileges, the comment is used to
+// find out the roles created for a middleware request.
+func (c *client) CreateOrUpdateRole(ctx context.Context, role, pwd, comment string) error {
+	if err := ValidateIdentifier(role); err != nil {
+		return err
+	}
+
+	exists, err := c.RoleExists(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	pwd = strings.ReplaceAll(pwd, "'", "''")
+	sql := fmt.Sprintf("create role %s with login password '%s'", role, pwd)
+	if exists {
+		sql = fmt.Sprintf("alter role %s with login password '%s'", role, pwd)
+	}
+
+	if _, err = c.DB.ExecContext(ctx, sql); err != nil {
+		klog.Error("create or update role error, ", err, ", ", role)
+		return err
+	}
+
+	comment = strings.ReplaceAll(comment, "'", "''")
+	_, err = c.DB.ExecContext(ctx, fmt.Sprintf("comment on role %s is '%s'", role, comment))
+
+	return err
+}
+
+// RoleExists returns true if the role exists in the cluster
+func (c *client) RoleExists(ctx context.Context, role string) (bool, error) {
+	rows, err := c.DB.NamedQueryContext(ctx, "select rolname from pg_catalog.pg_roles where rolname=:role", map[string]interface{}{
+		"role": role,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+	return rows.Next(), nil
+}
+
+// ListLoginRoles lists the roles can login, except the superusers and the replication roles
+func (c *client) ListLoginRoles(ctx context.Context) ([]string, error) {
+	rows, err := c.DB.QueryxContext(ctx, `select rolname from pg_catalog.pg_roles
+		where rolcanlogin and not rolsuper and not rolreplication and rolname not like 'pg\_%'`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var roles []string
+	for rows.Next() {
+		row := new(PGRoleName)
+		if err = rows.StructScan(row); err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, row.Name)
+	}
+
+	return roles, nil
+}
+
+// ListRolesByComment lists the roles created by CreateOrUpdateRole with the comment
+func (c *client) ListRolesByComment(ctx context.Context, comment string) ([]string, error) {
+	sql := `select r.rolname from pg_catalog.pg_roles r
+		join pg_catalog.pg_shdescription d on d.objoid = r.oid and d.classoid = 'pg_catalog.pg_authid'::regclass
+		where d.description=:comment`
+	rows, err := c.DB.NamedQueryContext(ctx, sql, map[string]interface{}{
+		"comment": comment,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var roles []string
+	for rows.Next() {
+		row := new(PGRoleName)
+		if err = rows.StructScan(row); err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, row.Name)
+	}
+
+	return roles, nil
+}
+
+// GrantRoleMembership makes the role a member of group, and revokes it if grant is false
+func (c *client) GrantRoleMembership(ctx context.Context, group, role string, grant bool) error {
+	if err := ValidateIdentifier(group); err != nil {
+		return err
+	}
+
+	if err := ValidateIdentifier(role); err != nil {
+		return err
+	}
+
+	sql := fmt.Sprintf("grant %s to %s", group, role)
+	if !grant {
+		sql = fmt.Sprintf("revoke %s from %s", group, role)
+	}
+
+	_, err := c.DB.ExecContext(ctx, sql)
+	return err
+}
+
+// GrantDatabasePrivileges grants the privileges on the tables of schema in the current database to role.
+// The previous table privileges of role in the schema are revoked in the same transaction,
+// If tables is empty, the privileges are applied to all tables, and the tables created by owner in the future.
+func (c *client) GrantDatabasePrivileges(ctx context.Context, database, owner, role, schema string,
+	tables, privileges, schemaPrivs []string) error {
+	for _, id := range append([]string{database, owner, role}, tables...) {
+		if err := ValidateIdentifier(id); err != nil {
+			return err
+		}
+	}
+
+	if schema == "" {
+		schema = DefaultSchema
+	}
+
+	if err := ValidateIdentifier(schema); err != nil {
+		return err
+	}
+
+	tablePrivs, err := normalizePrivileges(privileges, tablePrivileges)
+	if err != nil {
+		return err
+	}
+
+	schemaPrivs = append([]string{"USAGE"}, schemaPrivs...)
+	nsPrivs, err := normalizePrivileges(schemaPrivs, schemaPrivileges)
+	if err != nil {
+		return err
+	}
+
+	sqls := []string{
+		fmt.Sprintf("grant connect on database %s to %s", database, role),
+		fmt.Sprintf("revoke all on all tables in schema %s from %s", schema, role),
+		fmt.Sprintf("alter default privileges for role %s in schema %s revoke all on tables from %s", owner, schema, role),
+		fmt.Sprintf("grant %s on schema %s to %s", nsPrivs, schema, role),
+	}
+
+	if tablePrivs != "" {
+		if len(tables) == 0 {
+			sqls = append(sqls,
+				fmt.Sprintf("grant %s on all tables in schema %s to %s", tablePrivs, schema, role),
+				fmt.Sprintf("alter default privileges for role %s in schema %s grant %s on tables to %s", owner, schema, tablePrivs, role),
+			)
+		} else {
+			for _, t := range tables {
+				sqls = append(sqls, fmt.Sprintf("grant %s on table %s.%s to %s", tablePrivs, schema, t, role))
+			}
+		}
+	}
+
+	tx, err := c.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, sql := range sqls {
+		c.DB.log(sql)
+		if _, err = tx.ExecContext(ctx, sql); err != nil {
+			klog.Error("grant privileges error, ", err, ", ", sql)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RevokeDatabasePrivileges revokes the privileges granted by GrantDatabasePrivileges on the tables and the
+// schema in the current database from role, and the connect privilege on the database.
+// The default privileges of the tables created by owner are revoked too, if owner is not empty
+func (c *client) RevokeDatabasePrivileges(ctx context.Context, database, owner, role, schema string) error {
+	for _, id := range []string{database, role} {
+		if err := ValidateIdentifier(id); err != nil {
+			return err
+		}
+	}
+
+	if schema == "" {
+		schema = DefaultSchema
+	}
+
+	if err := ValidateIdentifier(schema); err != nil {
+		return err
+	}
+
+	sqls := []string{
+		fmt.Sprintf("revoke all on all tables in schema %s from %s", schema, role),
+	}
+
+	if owner != "" {
+		if err := ValidateIdentifier(owner); err != nil {
+			return err
+		}
+
+		sqls = append(sqls, fmt.Sprintf("alter default privileges for role %s in schema %s revoke all on tables from %s", owner, schema, role))
+	}
+
+	sqls = append(sqls,
+		fmt.Sprintf("revoke all on schema %s from %s", schema, role),
+		fmt.Sprintf("revoke connect on database %s from %s", database, role),
+	)
+
+	tx, err := c.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, sql := range sqls {
+		c.DB.log(sql)
+		if _, err = tx.ExecContext(ctx, sql); err != nil {
+			klog.Error("revoke privileges error, ", err, ", ", sql)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListDependentDatabases lists the databases in which the role has privileges or owns objects
+func (c *client) ListDependentDatabases(ctx context.Context, role string) ([]string, error) {
+	sql := `select distinct d.datname as name from pg_catalog.pg_shdepend s
+		join pg_catalog.pg_database d on d.oid = s.dbid
+		join pg_catalog.pg_roles r on r.oid = s.refobjid
+		where s.refclassid = 'pg_catalog.pg_authid'::regclass and r.rolname=:role`
+	rows, err := c.DB.NamedQueryContext(ctx, sql, map[string]interface{}{
+		"role": role,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var databases []string
+	for rows.Next() {
+		row := new(PGTable)
+		if err = rows.StructScan(row); err != nil {
+			return nil, err
+		}
+
+		databases = append(databases, row.Name)
+	}
+
+	return databases, nil
+}
+
+// DropOwned revokes all the privileges of role in the current database. The objects owned by
+// role are reassigned to reassignTo if it is not empty, otherwise they are dropped.
+func (c *client) DropOwned(ctx context.Context, role, reassignTo string) error {
+	if err := ValidateIdentifier(role); err != nil {
+		return err
+	}
+
+	if reassignTo != "" {
+		if err := ValidateIdentifier(reassignTo); err != nil {
+			return err
+		}
+
+		if _, err := c.DB.ExecContext(ctx, fmt.Sprintf("reassign owned by %s to %s", role, reassignTo)); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.DB.ExecContext(ctx, fmt.Sprintf("drop owned by %s", role))
+	return err
+}
+
+// DropRole revokes the privileges of role in all databases, and drops it
+func (c *client) DropRole(ctx context.Context, role, reassignTo string) error {
+	exists, err := c.RoleExists(ctx, role)
+	if err != nil || !exists {
+		return err
+	}
+
+	dbs, err := c.ListDependentDatabases(ctx, role)
+	if err != nil {
+		klog.Error("list role dependent databases error, ", err, ", ", role)
+		return err
+	}
+
+	current := c.builder.database
+	for _, db := range dbs {
+		if err = c.SwitchDatabase(db); err != nil {
+			return err
+		}
+
+		if err = c.DropOwned(ctx, role, reassignTo); err != nil {
+			klog.Error("drop owned by role error, ", err, ", ", role, ", ", db)
+			return err
+		}
+	}
+
+	if len(dbs) > 0 {
+		if err = c.SwitchDatabase(current); err != nil {
+			return err
+		}
+	}
+
+	return c.DeleteUser(ctx, role)
+}
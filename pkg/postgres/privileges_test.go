@@ -0,0 +1,36 @@
+package postgres
+
+import "testing"
+
+func TestNormalizePrivileges(t *testing.T) {
+	privs, err := normalizePrivileges([]string{"select", " Insert "}, tablePrivileges)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if privs != "SELECT, INSERT" {
+		t.Errorf("unexpected privileges %q", privs)
+	}
+
+	if _, err = normalizePrivileges([]string{"select; drop table x"}, tablePrivileges); err == nil {
+		t.Error("expected invalid privilege error")
+	}
+
+	if _, err = normalizePrivileges([]string{"CREATE"}, tablePrivileges); err == nil {
+		t.Error("expected schema privilege is not allowed on tables")
+	}
+}
+
+func TestValidateIdentifier(t *testing.T) {
+	for _, id := range []string{"app_user", "_x1"} {
+		if err := ValidateIdentifier(id); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, id := range []string{"", "1abc", "user-space", "a;drop"} {
+		if err := ValidateIdentifier(id); err == nil {
+			t.Errorf("expected %q to be invalid", id)
+		}
+	}
+}
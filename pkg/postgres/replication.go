@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+
+	"k8s.io/klog/v2"
+)
+
+type PGReplicationStat struct {
+	State       string `db:"state"`
+	LagBytes    int64  `db:"lag_bytes"`
+	ReplayLagMs int64  `db:"replay_lag_ms"`
+}
+
+type PGRecovery struct {
+	InRecovery bool `db:"in_recovery"`
+}
+
+// CreateReplicationSlot creates the physical replication slot if it does not exist
+func (c *client) CreateReplicationSlot(ctx context.Context, slot string) error {
+	sql := "select pg_create_physical_replication_slot(:slot) where not exists (select 1 from pg_catalog.pg_replication_slots where slot_name = :slot)"
+	rows, err := c.DB.NamedQueryContext(ctx, sql, map[string]interface{}{
+		"slot": slot,
+	})
+
+	if err != nil {
+		klog.Error("create replication slot error, ", err, ", ", slot)
+		return err
+	}
+
+	return rows.Close()
+}
+
+// DropReplicationSlot drops the physical replication slot if it exists and is not active
+func (c *client) DropReplicationSlot(ctx context.Context, slot string) error {
+	sql := "select pg_drop_replication_slot(slot_name) from pg_catalog.pg_replication_slots where slot_name = :slot and not active"
+	rows, err := c.DB.NamedQueryContext(ctx, sql, map[string]interface{}{
+		"slot": slot,
+	})
+
+	if err != nil {
+		klog.Error("drop replication slot error, ", err, ", ", slot)
+		return err
+	}
+
+	return rows.Close()
+}
+
+// ReplicationStat returns the state and the lag of the standby streaming from the slot,
+// nil if the standby is not connected
+func (c *client) ReplicationStat(ctx context.Context, slot string) (*PGReplicationStat, error) {
+	sql := `select r.state,
+		coalesce(pg_wal_lsn_diff(pg_current_wal_lsn(), r.replay_lsn), 0)::bigint as lag_bytes,
+		coalesce(extract(epoch from r.replay_lag) * 1000, 0)::bigint as replay_lag_ms
+		from pg_catalog.pg_stat_replication r join pg_catalog.pg_replication_slots s on s.active_pid = r.pid
+		where s.slot_name = :slot`
+	rows, err := c.DB.NamedQueryContext(ctx, sql, map[string]interface{}{
+		"slot": slot,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, nil
+	}
+
+	stat := new(PGReplicationStat)
+	if err = rows.StructScan(stat); err != nil {
+		return nil, err
+	}
+
+	return stat, nil
+}
+
+// IsInRecovery returns whether the server is a standby
+func (c *client) IsInRecovery(ctx context.Context) (bool, error) {
+	rows, err := c.DB.QueryxContext(ctx, "select pg_is_in_recovery() as in_recovery")
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+	recovery := new(PGRecovery)
+	if rows.Next() {
+		if err = rows.StructScan(recovery); err != nil {
+			return false, err
+		}
+	}
+
+	return recovery.InRecovery, nil
+}
+
+// Promote promotes the standby to primary, and waits for the promotion complete
+func (c *client) Promote(ctx context.Context) error {
+	_, err := c.DB.ExecContext(ctx, "select pg_promote(true, 60)")
+	return err
+}
+
+// SetReadOnly makes the new transactions of the server read-only by default
+func (c *client) SetReadOnly(ctx context.Context) error {
+	settings, err := c.listSettings(ctx, "select name, setting from pg_catalog.pg_settings where name = 'default_transaction_read_only'")
+	if err != nil {
+		return err
+	}
+
+	if len(settings) > 0 && settings[0].Setting == "on" {
+		return nil
+	}
+
+	for _, sql := range []string{
+		"alter system set default_transaction_read_only = on",
+		"select pg_reload_conf()",
+	} {
+		if _, err := c.DB.ExecContext(ctx, sql); err != nil {
+			klog.Error("set server read-only error, ", err)
+			return err
+		}
+	}
+
+	return nil
+}
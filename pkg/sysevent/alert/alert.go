@@ -0,0 +1,205 @@
+package alert
+
+import (
+	"fmt"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+)
+
+// DefaultInterval is the evaluation interval of the rule not set
+const DefaultInterval = 5 * time.Second
+
+type Status string
+
+const (
+	Inactive Status = "inactive"
+	Pending  Status = "pending"
+	Firing   Status = "firing"
+	Resolved Status = "resolved"
+)
+
+// Transition is the change of the alert to send the event of
+type Transition int
+
+const (
+	None Transition = iota
+	Fire
+	Resolve
+)
+
+// Rule is the condition of the alert rule, the thresholds are scaled by the limit of the user
+type Rule struct {
+	Operator       aprv1.AlertOperator
+	Threshold      float64
+	Hysteresis     float64
+	For            time.Duration
+	RepeatInterval time.Duration
+}
+
+// Alert is the state of a series of the rule
+type Alert struct {
+	Status   Status
+	ActiveAt time.Time
+	LastSent time.Time
+	Value    float64
+	Labels   map[string]string
+}
+
+// NewRule returns the condition of the rule spec, the thresholds are multiplied by the scale
+func NewRule(spec *aprv1.AlertRuleSpec, scale float64) *Rule {
+	r := &Rule{
+		Operator:  spec.Operator,
+		Threshold: spec.Threshold.AsApproximateFloat64() * scale,
+	}
+
+	if r.Operator == "" {
+		r.Operator = aprv1.AlertGreaterThan
+	}
+
+	if spec.Hysteresis != nil {
+		r.Hysteresis = spec.Hysteresis.AsApproximateFloat64() * scale
+	}
+
+	if spec.For != nil {
+		r.For = spec.For.Duration
+	}
+
+	if spec.RepeatInterval != nil {
+		r.RepeatInterval = spec.RepeatInterval.Duration
+	}
+
+	return r
+}
+
+// Validate returns an error if the rule spec can not be evaluated
+func Validate(spec *aprv1.AlertRuleSpec) error {
+	if spec.Metric == "" && spec.Expr == "" {
+		return fmt.Errorf("either metric or expr is required")
+	}
+
+	if spec.Event == "" {
+		return fmt.Errorf("event is required")
+	}
+
+	if _, err := compare(spec.Operator, 0, 0); spec.Operator != "" && err != nil {
+		return err
+	}
+
+	if spec.RelativeToLimit && (spec.Level != aprv1.AlertLevelUser || spec.Resource == "") {
+		return fmt.Errorf("relativeToLimit requires the user level and the resource")
+	}
+
+	return nil
+}
+
+// ResolvedEvent returns the event type sent when the alert of the rule is resolved
+func ResolvedEvent(spec *aprv1.AlertRuleSpec) aprv1.EventType {
+	if spec.ResolvedEvent != "" {
+		return spec.ResolvedEvent
+	}
+
+	return spec.Event + ".resolved"
+}
+
+// Interval returns the evaluation interval of the rule
+func Interval(spec *aprv1.AlertRuleSpec) time.Duration {
+	if spec.Interval == nil || spec.Interval.Duration <= 0 {
+		return DefaultInterval
+	}
+
+	return spec.Interval.Duration
+}
+
+// Breached returns true if the value breaks the threshold
+func (r *Rule) Breached(value float64) bool {
+	ok, _ := compare(r.Operator, value, r.Threshold)
+	return ok
+}
+
+// Recovered returns true if the value crosses back the threshold by the hysteresis
+func (r *Rule) Recovered(value float64) bool {
+	threshold := r.Threshold
+	switch r.Operator {
+	case aprv1.AlertGreaterThan, aprv1.AlertGreaterThanEqual:
+		threshold -= r.Hysteresis
+	case aprv1.AlertLessThan, aprv1.AlertLessThanEqual:
+		threshold += r.Hysteresis
+	}
+
+	ok, _ := compare(r.Operator, value, threshold)
+	return !ok
+}
+
+// Evaluate updates the alert with the value at the time, and returns the event to send
+func (r *Rule) Evaluate(a *Alert, value float64, now time.Time) Transition {
+	a.Value = value
+
+	switch a.Status {
+	case Firing:
+		if r.Recovered(value) {
+			a.Status = Resolved
+			return Resolve
+		}
+
+		if r.RepeatInterval > 0 && now.Sub(a.LastSent) >= r.RepeatInterval {
+			a.LastSent = now
+			return Fire
+		}
+
+		return None
+
+	case Pending:
+		if !r.Breached(value) {
+			a.Status = Inactive
+			return None
+		}
+
+	default:
+		if !r.Breached(value) {
+			a.Status = Inactive
+			return None
+		}
+
+		a.Status = Pending
+		a.ActiveAt = now
+	}
+
+	if now.Sub(a.ActiveAt) < r.For {
+		return None
+	}
+
+	a.Status = Firing
+	a.LastSent = now
+	return Fire
+}
+
+// Vanish updates the alert whose series is absent, the firing alert is resolved
+func (r *Rule) Vanish(a *Alert) Transition {
+	if a.Status == Firing {
+		a.Status = Resolved
+		return Resolve
+	}
+
+	a.Status = Inactive
+	return None
+}
+
+func compare(op aprv1.AlertOperator, value, threshold float64) (bool, error) {
+	switch op {
+	case aprv1.AlertGreaterThan:
+		return value > threshold, nil
+	case aprv1.AlertGreaterThanEqual:
+		return value >= threshold, nil
+	case aprv1.AlertLessThan:
+		return value < threshold, nil
+	case aprv1.AlertLessThanEqual:
+		return value <= threshold, nil
+	case aprv1.AlertEqual:
+		return value == threshold, nil
+	case aprv1.AlertNotEqual:
+		return value != threshold, nil
+	}
+
+	return false, fmt.Errorf("unknown operator %q", op)
+}
@@ -0,0 +1,104 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewRule(t *testing.T) {
+	h := resource.MustParse("0.1")
+	r := NewRule(&aprv1.AlertRuleSpec{
+		Threshold:  resource.MustParse("0.9"),
+		Hysteresis: &h,
+		For:        &metav1.Duration{Duration: time.Minute},
+	}, 4)
+
+	if r.Operator != aprv1.AlertGreaterThan {
+		t.Errorf("expected > by default, %s", r.Operator)
+	}
+
+	if r.Threshold < 3.59 || r.Threshold > 3.61 || r.Hysteresis < 0.39 || r.Hysteresis > 0.41 {
+		t.Errorf("expected the thresholds scaled, %f, %f", r.Threshold, r.Hysteresis)
+	}
+
+	if r.For != time.Minute {
+		t.Errorf("unexpected for, %s", r.For)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	r := &Rule{Operator: aprv1.AlertGreaterThan, Threshold: 0.9, Hysteresis: 0.1, For: time.Minute}
+	now := time.Now()
+	a := &Alert{}
+
+	steps := []struct {
+		after      time.Duration
+		value      float64
+		transition Transition
+		status     Status
+	}{
+		{0, 0.5, None, Inactive},
+		{0, 0.95, None, Pending},
+		{30 * time.Second, 0.5, None, Inactive},
+		{40 * time.Second, 0.95, None, Pending},
+		{time.Minute + 40*time.Second, 0.96, Fire, Firing},
+		{2 * time.Minute, 0.97, None, Firing},
+		// within the hysteresis
+		{3 * time.Minute, 0.85, None, Firing},
+		{4 * time.Minute, 0.79, Resolve, Resolved},
+		{5 * time.Minute, 0.5, None, Inactive},
+	}
+
+	for i, s := range steps {
+		if tr := r.Evaluate(a, s.value, now.Add(s.after)); tr != s.transition || a.Status != s.status {
+			t.Errorf("step %d: got %v %s, expected %v %s", i, tr, a.Status, s.transition, s.status)
+		}
+	}
+}
+
+func TestEvaluateRepeat(t *testing.T) {
+	r := &Rule{Operator: aprv1.AlertLessThan, Threshold: 10, RepeatInterval: time.Minute}
+	now := time.Now()
+	a := &Alert{}
+
+	if tr := r.Evaluate(a, 5, now); tr != Fire {
+		t.Errorf("expected firing immediately, %v", tr)
+	}
+
+	if tr := r.Evaluate(a, 5, now.Add(30*time.Second)); tr != None {
+		t.Errorf("expected not repeated, %v", tr)
+	}
+
+	if tr := r.Evaluate(a, 5, now.Add(time.Minute)); tr != Fire {
+		t.Errorf("expected repeated, %v", tr)
+	}
+
+	if tr := r.Vanish(a); tr != Resolve {
+		t.Errorf("expected resolved when the series is absent, %v", tr)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		spec aprv1.AlertRuleSpec
+		ok   bool
+	}{
+		{"metric", aprv1.AlertRuleSpec{Metric: "cluster_cpu_utilisation", Event: aprv1.CPUHigh}, true},
+		{"no query", aprv1.AlertRuleSpec{Event: aprv1.CPUHigh}, false},
+		{"no event", aprv1.AlertRuleSpec{Expr: "up"}, false},
+		{"bad operator", aprv1.AlertRuleSpec{Expr: "up", Event: "x", Operator: "~"}, false},
+		{"relative cluster", aprv1.AlertRuleSpec{Expr: "up", Event: "x", Resource: "cpu", RelativeToLimit: true}, false},
+		{"relative user", aprv1.AlertRuleSpec{Expr: "up", Event: "x", Level: aprv1.AlertLevelUser, Resource: "cpu", RelativeToLimit: true}, true},
+	}
+
+	for _, c := range cases {
+		if err := Validate(&c.spec); (err == nil) != c.ok {
+			t.Errorf("%s: unexpected %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package cloudevents wraps the sys events in the CloudEvents 1.0 envelope,
+// and encodes them in the structured or binary HTTP content mode.
+package cloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SpecVersion = "1.0"
+
+	// Source is the source of the events emitted by the sys-event watchers
+	Source = "/sys-event"
+
+	// CustomSource is the source of the custom events fired through the api
+	CustomSource = Source + "/custom"
+
+	ContentTypeJSON       = "application/json"
+	ContentTypeCloudEvent = "application/cloudevents+json"
+
+	// headerPrefix is the prefix of the headers of the attributes in the binary content mode
+	headerPrefix = "Ce-"
+)
+
+// ContentMode is the HTTP content mode of the event
+type ContentMode string
+
+const (
+	// Structured encodes the whole envelope in the body
+	Structured ContentMode = "structured"
+
+	// Binary encodes the attributes in the ce- headers, and the data in the body
+	Binary ContentMode = "binary"
+)
+
+var ErrInvalidEvent = errors.New("invalid cloud event")
+
+// Event is the CloudEvents 1.0 envelope of the sys event
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+
+	// User is the extension of the user the event belongs to, empty for the events of the cluster
+	User string `json:"user,omitempty"`
+}
+
+// New returns the event with a new id of the type, the data is encoded in json,
+// and the schema of the data is set if the type is known
+func New(eventType, source, subject string, data interface{}) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.NewString(),
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: ContentTypeJSON,
+		DataSchema:      DataSchema(eventType),
+		Data:            raw,
+	}, nil
+}
+
+// Validate checks the required attributes of the event
+func (e *Event) Validate() error {
+	switch {
+	case e.SpecVersion != SpecVersion:
+		return fmt.Errorf("%w, unsupported specversion %q", ErrInvalidEvent, e.SpecVersion)
+	case e.ID == "":
+		return fmt.Errorf("%w, id is empty", ErrInvalidEvent)
+	case e.Source == "":
+		return fmt.Errorf("%w, source is empty", ErrInvalidEvent)
+	case e.Type == "":
+		return fmt.Errorf("%w, type is empty", ErrInvalidEvent)
+	}
+
+	return nil
+}
+
+// Encode returns the body of the event in the content mode, and sets the headers.
+// The binary mode is the default, the body is the data of the event as before the envelope
+func (e *Event) Encode(mode ContentMode, header http.Header) ([]byte, error) {
+	if mode == Structured {
+		header.Set("Content-Type", ContentTypeCloudEvent)
+		return json.Marshal(e)
+	}
+
+	header.Set(headerPrefix+"Specversion", e.SpecVersion)
+	header.Set(headerPrefix+"Id", e.ID)
+	header.Set(headerPrefix+"Source", e.Source)
+	header.Set(headerPrefix+"Type", e.Type)
+	header.Set(headerPrefix+"Time", e.Time.Format(time.RFC3339Nano))
+	setIfNotEmpty(header, headerPrefix+"Subject", e.Subject)
+	setIfNotEmpty(header, headerPrefix+"Dataschema", e.DataSchema)
+	setIfNotEmpty(header, headerPrefix+"User", e.User)
+
+	contentType := e.DataContentType
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	header.Set("Content-Type", contentType)
+
+	return e.Data, nil
+}
+
+// Decode reads the event of the request in either content mode
+func Decode(header http.Header, body []byte) (*Event, error) {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType == ContentTypeCloudEvent {
+		var e Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			return nil, fmt.Errorf("%w, %s", ErrInvalidEvent, err.Error())
+		}
+
+		return &e, e.Validate()
+	}
+
+	e := &Event{
+		SpecVersion:     header.Get(headerPrefix + "Specversion"),
+		ID:              header.Get(headerPrefix + "Id"),
+		Source:          header.Get(headerPrefix + "Source"),
+		Type:            header.Get(headerPrefix + "Type"),
+		Subject:         header.Get(headerPrefix + "Subject"),
+		DataSchema:      header.Get(headerPrefix + "Dataschema"),
+		User:            header.Get(headerPrefix + "User"),
+		DataContentType: header.Get("Content-Type"),
+		Data:            body,
+	}
+
+	if t := header.Get(headerPrefix + "Time"); t != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return nil, fmt.Errorf("%w, invalid time %q", ErrInvalidEvent, t)
+		}
+		e.Time = parsed
+	}
+
+	return e, e.Validate()
+}
+
+// SourceOf returns the source of the events of the type, grouped by the prefix of the type, e.g. /sys-event/app
+func SourceOf(eventType string) string {
+	group, _, _ := strings.Cut(eventType, ".")
+	return Source + "/" + group
+}
+
+func setIfNotEmpty(header http.Header, key, value string) {
+	if value != "" {
+		header.Set(key, value)
+	}
+}
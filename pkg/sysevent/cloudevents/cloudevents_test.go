@@ -0,0 +1,61 @@
+package cloudevents
+
+import (
+	"net/http"
+	"testing"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	e, err := New(string(aprv1.AppInstall), SourceOf(string(aprv1.AppInstall)), "files",
+		&AppData{Name: "files", Namespace: "files-alice", Owner: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.User = "alice"
+
+	if e.Source != "/sys-event/app" || e.DataSchema != "/events/schemas/app.install/v1" {
+		t.Errorf("unexpected source or schema, %s, %s", e.Source, e.DataSchema)
+	}
+
+	for _, mode := range []ContentMode{Structured, Binary, ""} {
+		header := http.Header{}
+		body, err := e.Encode(mode, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mode != Structured && string(body) != string(e.Data) {
+			t.Errorf("expected the data in the body in binary mode, %q, %s", mode, body)
+		}
+
+		decoded, err := Decode(header, body)
+		if err != nil {
+			t.Fatalf("decode %s error, %v", mode, err)
+		}
+
+		if decoded.ID != e.ID || decoded.Type != e.Type || decoded.Subject != e.Subject || decoded.User != e.User ||
+			!decoded.Time.Equal(e.Time) || string(decoded.Data) != string(e.Data) {
+			t.Errorf("unexpected decoded event in %s mode, %+v", mode, decoded)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", ContentTypeJSON)
+	if _, err := Decode(header, []byte(`{}`)); err == nil {
+		t.Error("expected the request without the attributes invalid")
+	}
+}
+
+func TestDataSchema(t *testing.T) {
+	if s := DataSchema("custom.event"); s != "" {
+		t.Errorf("expected no schema of the unknown type, %s", s)
+	}
+
+	if n := len(Schemas()); n != len(schemas) {
+		t.Errorf("unexpected schemas %d", n)
+	}
+}
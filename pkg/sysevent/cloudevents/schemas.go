@@ -0,0 +1,141 @@
+package cloudevents
+
+import (
+	"sort"
+	"time"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+)
+
+// SchemaPath is the path the data schemas are served on
+const SchemaPath = "/events/schemas"
+
+// UserData is the data of the user.* events, v1
+type UserData struct {
+	Name  string `json:"name"`
+	Role  string `json:"role,omitempty"`
+	Email string `json:"email"`
+}
+
+// AppData is the data of the app.* events, v1
+type AppData struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Owner     string `json:"owner"`
+	Cause     string `json:"cause,omitempty"`
+}
+
+// MetricsData is the data of the metrics.* events, v1
+type MetricsData struct {
+	CPU    float64 `json:"cpu"`
+	Memory float64 `json:"memory"`
+	User   string  `json:"user,omitempty"`
+
+	// the alert rule the event is sent by
+	Rule      string            `json:"rule,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	Severity  string            `json:"severity,omitempty"`
+	Value     float64           `json:"value"`
+	Threshold float64           `json:"threshold"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// NodeData is the data of the node.ready and node.notready events, v1
+type NodeData struct {
+	Name    string `json:"name"`
+	Ready   bool   `json:"ready"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// WorkloadData is the data of the workload.* events, v1
+type WorkloadData struct {
+	Pod          string `json:"pod"`
+	Namespace    string `json:"namespace"`
+	App          string `json:"app,omitempty"`
+	Owner        string `json:"owner,omitempty"`
+	Container    string `json:"container"`
+	Reason       string `json:"reason"`
+	RestartCount int32  `json:"restartCount"`
+	Message      string `json:"message,omitempty"`
+}
+
+// CertData is the data of the cert.* events, v1
+type CertData struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace,omitempty"`
+	Subject   string    `json:"subject"`
+	DNSNames  []string  `json:"dnsNames,omitempty"`
+	NotAfter  time.Time `json:"notAfter"`
+	DaysLeft  int       `json:"daysLeft"`
+}
+
+// RecommendData is the data of the recommend.* events, v1
+type RecommendData struct {
+	Name string `json:"name"`
+}
+
+// Schema describes the version and the data type of the event type
+type Schema struct {
+	Type    string      `json:"type"`
+	Version string      `json:"version"`
+	URI     string      `json:"dataschema"`
+	Example interface{} `json:"example"`
+}
+
+// schemas are the versions and the data of the event types, bump the version
+// and keep the old fields when the data is changed incompatibly
+var schemas = map[aprv1.EventType]struct {
+	version string
+	example interface{}
+}{
+	aprv1.UserCreate:         {"v1", UserData{}},
+	aprv1.UserDelete:         {"v1", UserData{}},
+	aprv1.UserActive:         {"v1", UserData{}},
+	aprv1.UserLogin:          {"v1", UserData{}},
+	aprv1.AppInstall:         {"v1", AppData{}},
+	aprv1.AppUninstall:       {"v1", AppData{}},
+	aprv1.AppSuspend:         {"v1", AppData{}},
+	aprv1.MemoryHigh:         {"v1", MetricsData{}},
+	aprv1.CPUHigh:            {"v1", MetricsData{}},
+	aprv1.UserMemoryHigh:     {"v1", MetricsData{}},
+	aprv1.UserCPUHigh:        {"v1", MetricsData{}},
+	aprv1.MemoryResolved:     {"v1", MetricsData{}},
+	aprv1.CPUResolved:        {"v1", MetricsData{}},
+	aprv1.UserMemoryResolved: {"v1", MetricsData{}},
+	aprv1.UserCPUResolved:    {"v1", MetricsData{}},
+	aprv1.NodeDiskHigh:       {"v1", MetricsData{}},
+	aprv1.NodeDiskResolved:   {"v1", MetricsData{}},
+	aprv1.NodeInodeHigh:      {"v1", MetricsData{}},
+	aprv1.NodeInodeResolved:  {"v1", MetricsData{}},
+	aprv1.PVCFull:            {"v1", MetricsData{}},
+	aprv1.PVCFullResolved:    {"v1", MetricsData{}},
+	aprv1.NodeNotReady:       {"v1", NodeData{}},
+	aprv1.NodeReady:          {"v1", NodeData{}},
+	aprv1.WorkloadCrashLoop:  {"v1", WorkloadData{}},
+	aprv1.WorkloadOOMKilled:  {"v1", WorkloadData{}},
+	aprv1.CertExpiring:       {"v1", CertData{}},
+	aprv1.RecommendInstall:   {"v1", RecommendData{}},
+	aprv1.RecommendUninstall: {"v1", RecommendData{}},
+}
+
+// DataSchema returns the uri of the data schema of the event type, empty if the type is unknown
+func DataSchema(eventType string) string {
+	s, ok := schemas[aprv1.EventType(eventType)]
+	if !ok {
+		return ""
+	}
+
+	return SchemaPath + "/" + eventType + "/" + s.version
+}
+
+// Schemas returns the schemas of all the known event types
+func Schemas() []Schema {
+	ret := make([]Schema, 0, len(schemas))
+	for t, s := range schemas {
+		ret = append(ret, Schema{Type: string(t), Version: s.version, URI: DataSchema(string(t)), Example: s.example})
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Type < ret[j].Type })
+	return ret
+}
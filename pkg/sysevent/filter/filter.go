@@ -0,0 +1,148 @@
+// Package filter matches the sys events against the subscriptions of the SysEventRegistries
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// AllUsers in the users of the filter matches the events of all the users
+const AllUsers = "*"
+
+// Event is the metadata of the event to match the subscriptions
+type Event struct {
+	Type aprv1.EventType
+
+	// the user the event belongs to, empty for the events of the cluster
+	Owner string
+
+	// the app the event is about
+	App string
+
+	// the labels of the object the event is about
+	Labels map[string]string
+}
+
+// MatchType returns true if the event type is subscribed, the subscribed type
+// ends with the wildcard matches the group of the events, e.g. app.* matches app.install
+func MatchType(subscribed, event aprv1.EventType) bool {
+	if subscribed == "*" {
+		return true
+	}
+
+	if prefix, ok := strings.CutSuffix(string(subscribed), ".*"); ok {
+		return strings.HasPrefix(string(event), prefix+".")
+	}
+
+	return subscribed == event
+}
+
+// Users returns the users whose events the registry receives, nil for all the users.
+// The registry in a namespace owned by a user is limited to the owner
+func Users(f *aprv1.SysEventFilter, namespaceOwner string) []string {
+	if namespaceOwner != "" {
+		return []string{namespaceOwner}
+	}
+
+	if f == nil {
+		return nil
+	}
+
+	for _, u := range f.Users {
+		if u == AllUsers {
+			return nil
+		}
+	}
+
+	return f.Users
+}
+
+// Match returns true if the event with the payload is selected by the filter,
+// users are the users whose events are allowed, nil for all
+func Match(f *aprv1.SysEventFilter, users []string, e *Event, payload []byte) (bool, error) {
+	if e.Owner != "" && users != nil && !contains(users, e.Owner) {
+		return false, nil
+	}
+
+	if f == nil {
+		return true, nil
+	}
+
+	if len(f.Apps) > 0 && !contains(f.Apps, e.App) {
+		return false, nil
+	}
+
+	if f.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(f.Selector)
+		if err != nil {
+			return false, fmt.Errorf("invalid label selector, %w", err)
+		}
+
+		if !selector.Matches(labels.Set(e.Labels)) {
+			return false, nil
+		}
+	}
+
+	if f.Expression != "" {
+		return matchExpression(f.Expression, payload)
+	}
+
+	return true, nil
+}
+
+// Validate checks the label selector and the expression of the filter
+func Validate(f *aprv1.SysEventFilter) error {
+	if f == nil {
+		return nil
+	}
+
+	if f.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(f.Selector); err != nil {
+			return fmt.Errorf("invalid label selector, %w", err)
+		}
+	}
+
+	if f.Expression != "" {
+		if err := jsonpath.New("filter").Parse("{[?(" + f.Expression + ")]}"); err != nil {
+			return fmt.Errorf("invalid filter expression, %w", err)
+		}
+	}
+
+	return nil
+}
+
+// matchExpression applies the JSONPath filter expression to the payload
+func matchExpression(expression string, payload []byte) (bool, error) {
+	j := jsonpath.New("filter").AllowMissingKeys(true)
+	if err := j.Parse("{[?(" + expression + ")]}"); err != nil {
+		return false, fmt.Errorf("invalid filter expression, %w", err)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return false, err
+	}
+
+	results, err := j.FindResults([]interface{}{data})
+	if err != nil {
+		return false, err
+	}
+
+	return len(results) > 0 && len(results[0]) > 0, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, i := range list {
+		if i == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package filter
+
+import (
+	"testing"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatchType(t *testing.T) {
+	cases := []struct {
+		subscribed, event aprv1.EventType
+		match             bool
+	}{
+		{aprv1.AppInstall, aprv1.AppInstall, true},
+		{aprv1.AppInstall, aprv1.AppUninstall, false},
+		{"app.*", aprv1.AppInstall, true},
+		{"app.*", aprv1.UserLogin, false},
+		{"metrics.*", aprv1.UserMemoryHigh, true},
+		{"metrics.user.*", aprv1.MemoryHigh, false},
+		{"*", aprv1.UserLogin, true},
+	}
+
+	for _, c := range cases {
+		if m := MatchType(c.subscribed, c.event); m != c.match {
+			t.Errorf("match %s with %s, expected %v", c.event, c.subscribed, c.match)
+		}
+	}
+}
+
+func TestUsers(t *testing.T) {
+	f := &aprv1.SysEventFilter{Users: []string{"bob"}}
+	if u := Users(f, "alice"); len(u) != 1 || u[0] != "alice" {
+		t.Errorf("expected limited to the namespace owner, %v", u)
+	}
+
+	if u := Users(nil, ""); u != nil {
+		t.Errorf("expected all the users, %v", u)
+	}
+
+	if u := Users(&aprv1.SysEventFilter{Users: []string{"bob", AllUsers}}, ""); u != nil {
+		t.Errorf("expected all the users, %v", u)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	e := &Event{Type: aprv1.AppInstall, Owner: "alice", App: "files", Labels: map[string]string{"tier": "web"}}
+	payload := []byte(`{"name":"files","memory":0.95}`)
+
+	cases := []struct {
+		name   string
+		filter *aprv1.SysEventFilter
+		users  []string
+		match  bool
+	}{
+		{"no filter", nil, nil, true},
+		{"owner", nil, []string{"alice"}, true},
+		{"other user", nil, []string{"bob"}, false},
+		{"app", &aprv1.SysEventFilter{Apps: []string{"files"}}, nil, true},
+		{"other app", &aprv1.SysEventFilter{Apps: []string{"vault"}}, nil, false},
+		{"selector", &aprv1.SysEventFilter{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web"}}}, nil, true},
+		{"selector mismatch", &aprv1.SysEventFilter{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "db"}}}, nil, false},
+		{"expression", &aprv1.SysEventFilter{Expression: `@.name == "files"`}, nil, true},
+		{"expression number", &aprv1.SysEventFilter{Expression: `@.memory > 0.9`}, nil, true},
+		{"expression mismatch", &aprv1.SysEventFilter{Expression: `@.name == "vault"`}, nil, false},
+		{"expression missing key", &aprv1.SysEventFilter{Expression: `@.cpu > 0.9`}, nil, false},
+	}
+
+	for _, c := range cases {
+		m, err := Match(c.filter, c.users, e, payload)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+
+		if m != c.match {
+			t.Errorf("%s: expected %v", c.name, c.match)
+		}
+	}
+
+	// the events of the cluster are not owned by any user
+	if m, _ := Match(nil, []string{"bob"}, &Event{Type: aprv1.CPUHigh}, []byte(`{}`)); !m {
+		t.Error("expected the cluster event matched")
+	}
+
+	if _, err := Match(&aprv1.SysEventFilter{Expression: `@.name ==`}, nil, e, payload); err == nil {
+		t.Error("expected invalid expression error")
+	}
+
+	if err := Validate(&aprv1.SysEventFilter{Expression: `@.name ==`}); err == nil {
+		t.Error("expected invalid expression error")
+	}
+}
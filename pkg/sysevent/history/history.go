@@ -0,0 +1,129 @@
+// Package history keeps the sys events emitted for the retention, to query them
+// and to replay them to the subscribers from a cursor after downtime.
+package history
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRetention is the time the events are kept if not configured
+	DefaultRetention = 7 * 24 * time.Hour
+
+	// DefaultLimit is the page size of the query if not set
+	DefaultLimit = 100
+
+	// MaxLimit is the max page size of the query
+	MaxLimit = 1000
+)
+
+// Event is the sys event recorded, the seq is the cursor of the event
+type Event struct {
+	Seq     int64           `json:"seq" db:"seq"`
+	ID      string          `json:"id" db:"event_id"`
+	Type    string          `json:"type" db:"type"`
+	User    string          `json:"user,omitempty" db:"user_name"`
+	App     string          `json:"app,omitempty" db:"app"`
+	Labels  Labels          `json:"labels,omitempty" db:"labels"`
+	Time    time.Time       `json:"time" db:"time"`
+	Payload json.RawMessage `json:"event" db:"payload"`
+}
+
+// Labels are the labels of the object the event is about, saved in json
+type Labels map[string]string
+
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(l)
+}
+
+func (l *Labels) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	}
+
+	return errors.New("unsupported labels type")
+}
+
+// Query selects the events after the cursor in order, empty fields match all
+type Query struct {
+	// the event type, the wildcard suffix matches a group of events, e.g. app.*
+	Type string
+	User string
+	App  string
+
+	Since time.Time
+	Until time.Time
+
+	// After is the cursor, the seq of the last event read
+	After int64
+	Limit int
+}
+
+// Normalize sets the default page size, and caps it
+func (q *Query) Normalize() {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+}
+
+func (q *Query) match(e *Event) bool {
+	switch {
+	case e.Seq <= q.After:
+		return false
+	case q.Type != "" && !matchType(q.Type, e.Type):
+		return false
+	case q.User != "" && q.User != e.User:
+		return false
+	case q.App != "" && q.App != e.App:
+		return false
+	case !q.Since.IsZero() && e.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !e.Time.Before(q.Until):
+		return false
+	}
+
+	return true
+}
+
+func matchType(query, eventType string) bool {
+	if prefix, ok := strings.CutSuffix(query, ".*"); ok {
+		return strings.HasPrefix(eventType, prefix+".")
+	}
+
+	return query == eventType
+}
+
+type Store interface {
+	// NextSeq reserves the seq of the next event, the events may be appended out of the order of the seq
+	NextSeq(ctx context.Context) (int64, error)
+
+	// Append records the event, and sets its seq if not reserved by NextSeq
+	Append(ctx context.Context, e *Event) error
+
+	// List returns the events matching the query in the order of the seq
+	List(ctx context.Context, q Query) ([]*Event, error)
+
+	// Purge removes the events emitted before the time
+	Purge(ctx context.Context, before time.Time) error
+
+	Close() error
+}
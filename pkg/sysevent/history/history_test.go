@@ -0,0 +1,65 @@
+package history
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	now := time.Now()
+	events := []*Event{
+		{ID: "1", Type: "app.install", User: "alice", App: "files", Time: now.Add(-2 * time.Hour)},
+		{ID: "2", Type: "user.login", User: "bob", Time: now.Add(-time.Hour)},
+		{ID: "3", Type: "app.uninstall", User: "alice", App: "files", Time: now},
+	}
+	for _, e := range events {
+		if err := s.Append(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if list, _ := s.List(ctx, Query{Type: "app.*", User: "alice"}); len(list) != 2 {
+		t.Errorf("expected 2 app events of alice, %d", len(list))
+	}
+
+	if list, _ := s.List(ctx, Query{Since: now.Add(-90 * time.Minute), Until: now}); len(list) != 1 || list[0].ID != "2" {
+		t.Errorf("unexpected events in the time range, %v", list)
+	}
+
+	// page through the events by the cursor
+	page, _ := s.List(ctx, Query{Limit: 2})
+	if len(page) != 2 {
+		t.Fatalf("expected the page limited, %d", len(page))
+	}
+
+	next, _ := s.List(ctx, Query{After: page[len(page)-1].Seq})
+	if len(next) != 1 || next[0].ID != "3" {
+		t.Errorf("unexpected next page, %v", next)
+	}
+
+	s.Purge(ctx, now.Add(-30*time.Minute))
+	if list, _ := s.List(ctx, Query{}); len(list) != 1 || list[0].ID != "3" {
+		t.Errorf("expected the old events purged, %v", list)
+	}
+}
+
+func TestMemoryStoreReservedSeq(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	first, _ := s.NextSeq(ctx)
+	second, _ := s.NextSeq(ctx)
+
+	// the event of the later seq is appended first
+	s.Append(ctx, &Event{Seq: second, ID: "2", Type: "user.login"})
+	s.Append(ctx, &Event{Seq: first, ID: "1", Type: "user.login"})
+
+	list, _ := s.List(ctx, Query{})
+	if len(list) != 2 || list[0].ID != "1" || list[1].ID != "2" {
+		t.Errorf("expected the events in the order of the seq, %v", list)
+	}
+}
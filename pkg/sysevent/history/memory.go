@@ -0,0 +1,88 @@
+package history
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	nextSeq int64
+	events  []*Event
+}
+
+var _ Store = &memoryStore{}
+
+// NewMemoryStore returns the store keeping the events in memory, they are lost on restart
+func NewMemoryStore() Store {
+	return &memoryStore{}
+}
+
+func (s *memoryStore) NextSeq(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextSeq++
+	return s.nextSeq, nil
+}
+
+func (s *memoryStore) Append(_ context.Context, e *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.Seq == 0 {
+		s.nextSeq++
+		e.Seq = s.nextSeq
+	}
+
+	// keep the events in the order of the seq, the reserved ones may come late
+	i := len(s.events)
+	for i > 0 && s.events[i-1].Seq > e.Seq {
+		i--
+	}
+
+	saved := *e
+	s.events = append(s.events, nil)
+	copy(s.events[i+1:], s.events[i:])
+	s.events[i] = &saved
+	return nil
+}
+
+func (s *memoryStore) List(_ context.Context, q Query) ([]*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q.Normalize()
+	ret := []*Event{}
+	for _, e := range s.events {
+		if !q.match(e) {
+			continue
+		}
+
+		event := *e
+		ret = append(ret, &event)
+		if len(ret) >= q.Limit {
+			break
+		}
+	}
+
+	return ret, nil
+}
+
+func (s *memoryStore) Purge(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.events[:0]
+	for _, e := range s.events {
+		if !e.Time.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	s.events = kept
+
+	return nil
+}
+
+func (s *memoryStore) Close() error { return nil }
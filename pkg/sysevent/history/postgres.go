@@ -0,0 +1,148 @@
+package history
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"k8s.io/klog/v2"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS sys_event_history (
+	seq       BIGSERIAL PRIMARY KEY,
+	event_id  TEXT NOT NULL,
+	type      TEXT NOT NULL,
+	user_name TEXT NOT NULL DEFAULT '',
+	app       TEXT NOT NULL DEFAULT '',
+	labels    JSONB NOT NULL DEFAULT '{}',
+	time      TIMESTAMPTZ NOT NULL,
+	payload   JSONB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sys_event_history_time ON sys_event_history (time);
+CREATE INDEX IF NOT EXISTS sys_event_history_user ON sys_event_history (user_name, seq);
+CREATE INDEX IF NOT EXISTS sys_event_history_type ON sys_event_history (type, seq);
+`
+
+const columns = `seq, event_id, type, user_name, app, labels, time, payload`
+
+type postgresStore struct {
+	db *sqlx.DB
+}
+
+var _ Store = &postgresStore{}
+
+// NewPostgresStore connects to the database, and creates the history table if not exists
+func NewPostgresStore(ctx context.Context, dsn string) (Store, error) {
+	db, err := sqlx.ConnectContext(ctx, "postgres", dsn)
+	if err != nil {
+		klog.Error("connect to history database error, ", err)
+		return nil, err
+	}
+
+	if _, err = db.ExecContext(ctx, schema); err != nil {
+		klog.Error("create history table error, ", err)
+		db.Close()
+		return nil, err
+	}
+
+	return &postgresStore{db: db}, nil
+}
+
+func (s *postgresStore) NextSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	err := s.db.QueryRowxContext(ctx, `SELECT nextval(pg_get_serial_sequence('sys_event_history', 'seq'))`).Scan(&seq)
+	if err != nil {
+		klog.Error("reserve history seq error, ", err)
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+func (s *postgresStore) Append(ctx context.Context, e *Event) error {
+	if e.Seq > 0 {
+		_, err := s.db.ExecContext(ctx,
+			`INSERT INTO sys_event_history (seq, event_id, type, user_name, app, labels, time, payload)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			e.Seq, e.ID, e.Type, e.User, e.App, e.Labels, e.Time, []byte(e.Payload))
+		if err != nil {
+			klog.Error("insert history event error, ", err, ", ", e.ID)
+			return err
+		}
+
+		return nil
+	}
+
+	err := s.db.QueryRowxContext(ctx,
+		`INSERT INTO sys_event_history (event_id, type, user_name, app, labels, time, payload)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING seq`,
+		e.ID, e.Type, e.User, e.App, e.Labels, e.Time, []byte(e.Payload)).Scan(&e.Seq)
+	if err != nil {
+		klog.Error("insert history event error, ", err, ", ", e.ID)
+		return err
+	}
+
+	return nil
+}
+
+func (s *postgresStore) List(ctx context.Context, q Query) ([]*Event, error) {
+	q.Normalize()
+
+	conds := []string{"seq > $1"}
+	args := []interface{}{q.After}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if prefix, ok := strings.CutSuffix(q.Type, ".*"); ok {
+		add("type LIKE ?", escapeLike(prefix)+".%")
+	} else if q.Type != "" {
+		add("type = ?", q.Type)
+	}
+
+	if q.User != "" {
+		add("user_name = ?", q.User)
+	}
+
+	if q.App != "" {
+		add("app = ?", q.App)
+	}
+
+	if !q.Since.IsZero() {
+		add("time >= ?", q.Since)
+	}
+
+	if !q.Until.IsZero() {
+		add("time < ?", q.Until)
+	}
+
+	args = append(args, q.Limit)
+	events := []*Event{}
+	err := s.db.SelectContext(ctx, &events,
+		`SELECT `+columns+` FROM sys_event_history WHERE `+strings.Join(conds, " AND ")+
+			` ORDER BY seq LIMIT $`+strconv.Itoa(len(args)),
+		args...)
+	if err != nil {
+		klog.Error("list history events error, ", err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *postgresStore) Purge(ctx context.Context, before time.Time) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM sys_event_history WHERE time < $1`, before)
+	return err
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,165 @@
+package outbox
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	mu       sync.Mutex
+	nextID   int64
+	messages map[int64]*Message
+}
+
+var _ Store = &memoryStore{}
+
+// NewMemoryStore returns the store keeping the messages in memory, they are lost on restart
+func NewMemoryStore() Store {
+	return &memoryStore{messages: make(map[int64]*Message)}
+}
+
+func (s *memoryStore) Enqueue(_ context.Context, messages ...*Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, m := range messages {
+		s.nextID++
+		m.ID = s.nextID
+		if m.State == "" {
+			m.State = StatePending
+		}
+		m.NextAttempt, m.CreatedAt, m.UpdatedAt = now, now, now
+
+		saved := *m
+		s.messages[m.ID] = &saved
+	}
+
+	return nil
+}
+
+func (s *memoryStore) Claim(_ context.Context, limit int) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var due []*Message
+	for _, m := range s.messages {
+		if m.State == StatePending && !m.NextAttempt.After(now) {
+			due = append(due, m)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	ret := make([]*Message, 0, len(due))
+	for _, m := range due {
+		m.NextAttempt = now.Add(ClaimTimeout)
+		claimed := *m
+		ret = append(ret, &claimed)
+	}
+
+	return ret, nil
+}
+
+func (s *memoryStore) update(id int64, f func(m *Message)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m, ok := s.messages[id]; ok {
+		f(m)
+		m.UpdatedAt = time.Now()
+	}
+}
+
+func (s *memoryStore) Delivered(_ context.Context, id int64) error {
+	s.update(id, func(m *Message) {
+		m.State = StateDelivered
+		m.Attempts++
+		m.LastError = ""
+	})
+
+	return nil
+}
+
+func (s *memoryStore) Failed(_ context.Context, id int64, attempts int, next time.Time, dead bool, lastError string) error {
+	s.update(id, func(m *Message) {
+		m.Attempts = attempts
+		m.NextAttempt = next
+		m.LastError = lastError
+		if dead {
+			m.State = StateDead
+		}
+	})
+
+	return nil
+}
+
+func (s *memoryStore) Defer(_ context.Context, ids []int64, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if m, ok := s.messages[id]; ok && m.State == StatePending {
+			m.NextAttempt = next
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryStore) ListDead(_ context.Context, filter Filter) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret := []*Message{}
+	for _, m := range s.messages {
+		if m.State == StateDead && filter.match(m) {
+			dead := *m
+			ret = append(ret, &dead)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret, nil
+}
+
+func (s *memoryStore) Redrive(_ context.Context, filter Filter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	count := 0
+	for _, m := range s.messages {
+		if m.State == StateDead && filter.match(m) {
+			m.State = StatePending
+			m.Attempts = 0
+			m.NextAttempt = now
+			m.UpdatedAt = now
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *memoryStore) Purge(_ context.Context, deliveredBefore, deadBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, m := range s.messages {
+		if (m.State == StateDelivered && m.UpdatedAt.Before(deliveredBefore)) ||
+			(m.State == StateDead && m.UpdatedAt.Before(deadBefore)) {
+			delete(s.messages, id)
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryStore) Close() error { return nil }
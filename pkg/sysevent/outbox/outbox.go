@@ -0,0 +1,126 @@
+// Package outbox persists the sys events to deliver to the subscribers, every message is delivered to one
+// subscriber independently, retried with exponential backoff, and dead-lettered after the max attempts.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateDelivered State = "delivered"
+	StateDead      State = "dead"
+)
+
+const (
+	// MaxAttempts is the max attempts to deliver a message before it is dead-lettered
+	MaxAttempts = 12
+
+	// ClaimTimeout is the time a claimed message is hidden from the other deliveries
+	ClaimTimeout = 5 * time.Minute
+
+	// DeliveredRetention is the time the delivered messages are kept
+	DeliveredRetention = 24 * time.Hour
+
+	// DeadRetention is the time the dead-lettered messages are kept to be redriven
+	DeadRetention = 7 * 24 * time.Hour
+
+	backoffBase = 2 * time.Second
+	backoffCap  = 30 * time.Minute
+)
+
+// Message is an event to deliver to the subscriber registered by the SysEventRegistry
+type Message struct {
+	ID                int64           `json:"id" db:"id"`
+	EventID           string          `json:"eventId" db:"event_id"`
+	Event             string          `json:"event" db:"event"`
+	RegistryNamespace string          `json:"registryNamespace" db:"registry_namespace"`
+	RegistryName      string          `json:"registryName" db:"registry_name"`
+	Payload           json.RawMessage `json:"payload" db:"payload"`
+	State             State           `json:"state" db:"state"`
+	Attempts          int             `json:"attempts" db:"attempts"`
+	NextAttempt       time.Time       `json:"nextAttempt" db:"next_attempt"`
+	LastError         string          `json:"lastError,omitempty" db:"last_error"`
+	CreatedAt         time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt         time.Time       `json:"updatedAt" db:"updated_at"`
+}
+
+// Filter selects the messages of the registry, empty fields match all
+type Filter struct {
+	RegistryNamespace string  `json:"registryNamespace,omitempty"`
+	RegistryName      string  `json:"registryName,omitempty"`
+	IDs               []int64 `json:"ids,omitempty"`
+}
+
+func (f *Filter) match(m *Message) bool {
+	if f.RegistryNamespace != "" && f.RegistryNamespace != m.RegistryNamespace {
+		return false
+	}
+
+	if f.RegistryName != "" && f.RegistryName != m.RegistryName {
+		return false
+	}
+
+	if len(f.IDs) == 0 {
+		return true
+	}
+
+	for _, id := range f.IDs {
+		if id == m.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Store interface {
+	// Enqueue saves the messages, the pending ones are delivered immediately
+	Enqueue(ctx context.Context, messages ...*Message) error
+
+	// Claim returns at most limit pending messages due, and hides them for ClaimTimeout
+	Claim(ctx context.Context, limit int) ([]*Message, error)
+
+	// Delivered marks the message delivered
+	Delivered(ctx context.Context, id int64) error
+
+	// Failed records the failure, the message is retried at next, or dead-lettered if dead is true
+	Failed(ctx context.Context, id int64, attempts int, next time.Time, dead bool, lastError string) error
+
+	// Defer hides the pending messages until next, they are claimed again with the failed message
+	// ahead of them in order
+	Defer(ctx context.Context, ids []int64, next time.Time) error
+
+	// ListDead returns the dead-lettered messages matching the filter
+	ListDead(ctx context.Context, filter Filter) ([]*Message, error)
+
+	// Redrive moves the dead-lettered messages matching the filter back to pending, and returns the count
+	Redrive(ctx context.Context, filter Filter) (int, error)
+
+	// Purge removes the delivered messages updated before deliveredBefore, and the dead-lettered
+	// messages updated before deadBefore
+	Purge(ctx context.Context, deliveredBefore, deadBefore time.Time) error
+
+	Close() error
+}
+
+// Backoff returns the delay before the next attempt after the failed attempts
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	d := backoffBase
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= backoffCap {
+			return backoffCap
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	if d := Backoff(1); d != backoffBase {
+		t.Errorf("unexpected first backoff %v", d)
+	}
+
+	if d := Backoff(3); d != 4*backoffBase {
+		t.Errorf("unexpected third backoff %v", d)
+	}
+
+	if d := Backoff(MaxAttempts * 10); d != backoffCap {
+		t.Errorf("expected backoff capped, %v", d)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	a := &Message{EventID: "e1", RegistryNamespace: "ns", RegistryName: "a", Payload: []byte(`{}`)}
+	b := &Message{EventID: "e1", RegistryNamespace: "ns", RegistryName: "b", Payload: []byte(`{}`)}
+	if err := s.Enqueue(ctx, a, b); err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, _ := s.Claim(ctx, 10)
+	if len(claimed) != 2 {
+		t.Fatalf("expected 2 messages claimed, %d", len(claimed))
+	}
+
+	if again, _ := s.Claim(ctx, 10); len(again) != 0 {
+		t.Errorf("expected claimed messages hidden, %d", len(again))
+	}
+
+	// the failure of a does not block b
+	s.Failed(ctx, a.ID, MaxAttempts, time.Now(), true, "refused")
+	s.Delivered(ctx, b.ID)
+
+	dead, _ := s.ListDead(ctx, Filter{RegistryName: "a"})
+	if len(dead) != 1 || dead[0].LastError != "refused" {
+		t.Fatalf("unexpected dead-lettered messages %v", dead)
+	}
+
+	if n, _ := s.Redrive(ctx, Filter{IDs: []int64{b.ID}}); n != 0 {
+		t.Errorf("expected the delivered message not redriven, %d", n)
+	}
+
+	if n, _ := s.Redrive(ctx, Filter{RegistryNamespace: "ns"}); n != 1 {
+		t.Errorf("expected 1 message redriven, %d", n)
+	}
+
+	claimed, _ = s.Claim(ctx, 10)
+	if len(claimed) != 1 || claimed[0].ID != a.ID || claimed[0].Attempts != 0 {
+		t.Errorf("unexpected redriven messages %v", claimed)
+	}
+
+	s.Purge(ctx, time.Now().Add(time.Second), time.Now().Add(-time.Hour))
+	if n := len(s.(*memoryStore).messages); n != 1 {
+		t.Errorf("expected the delivered message purged, %d", n)
+	}
+
+	s.Failed(ctx, a.ID, MaxAttempts, time.Now(), true, "refused")
+	s.Purge(ctx, time.Now(), time.Now().Add(time.Second))
+	if n := len(s.(*memoryStore).messages); n != 0 {
+		t.Errorf("expected the dead-lettered message purged, %d", n)
+	}
+}
+
+func TestMemoryStoreDefer(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	a := &Message{EventID: "e1", RegistryNamespace: "ns", RegistryName: "a", Payload: []byte(`{}`)}
+	b := &Message{EventID: "e2", RegistryNamespace: "ns", RegistryName: "a", Payload: []byte(`{}`)}
+	s.Enqueue(ctx, a, b)
+	s.Claim(ctx, 10)
+
+	// b waits behind the retry of a
+	next := time.Now().Add(-time.Second)
+	s.Failed(ctx, a.ID, 1, next, false, "refused")
+	s.Defer(ctx, []int64{b.ID}, next)
+
+	claimed, _ := s.Claim(ctx, 10)
+	if len(claimed) != 2 || claimed[0].ID != a.ID || claimed[1].ID != b.ID {
+		t.Errorf("expected the messages claimed in order, %v", claimed)
+	}
+
+	s.Delivered(ctx, a.ID)
+	s.Defer(ctx, []int64{a.ID}, time.Now().Add(time.Hour))
+	if m := s.(*memoryStore).messages[a.ID]; m.State != StateDelivered {
+		t.Errorf("expected the delivered message not deferred, %s", m.State)
+	}
+}
@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"k8s.io/klog/v2"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS sys_event_outbox (
+	id                 BIGSERIAL PRIMARY KEY,
+	event_id           TEXT NOT NULL,
+	event              TEXT NOT NULL,
+	registry_namespace TEXT NOT NULL,
+	registry_name      TEXT NOT NULL,
+	payload            JSONB NOT NULL,
+	state              TEXT NOT NULL DEFAULT 'pending',
+	attempts           INT NOT NULL DEFAULT 0,
+	next_attempt       TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_error         TEXT NOT NULL DEFAULT '',
+	created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS sys_event_outbox_due ON sys_event_outbox (next_attempt) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS sys_event_outbox_registry ON sys_event_outbox (registry_namespace, registry_name, state);
+`
+
+const columns = `id, event_id, event, registry_namespace, registry_name, payload, state, attempts,
+	next_attempt, last_error, created_at, updated_at`
+
+type postgresStore struct {
+	db *sqlx.DB
+}
+
+var _ Store = &postgresStore{}
+
+// NewPostgresStore connects to the database, and creates the outbox table if not exists
+func NewPostgresStore(ctx context.Context, dsn string) (Store, error) {
+	db, err := sqlx.ConnectContext(ctx, "postgres", dsn)
+	if err != nil {
+		klog.Error("connect to outbox database error, ", err)
+		return nil, err
+	}
+
+	if _, err = db.ExecContext(ctx, schema); err != nil {
+		klog.Error("create outbox table error, ", err)
+		db.Close()
+		return nil, err
+	}
+
+	return &postgresStore{db: db}, nil
+}
+
+func (s *postgresStore) Enqueue(ctx context.Context, messages ...*Message) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, m := range messages {
+		if m.State == "" {
+			m.State = StatePending
+		}
+
+		err = tx.QueryRowxContext(ctx,
+			`INSERT INTO sys_event_outbox (event_id, event, registry_namespace, registry_name, payload, state, last_error)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			m.EventID, m.Event, m.RegistryNamespace, m.RegistryName, []byte(m.Payload), m.State, m.LastError).Scan(&m.ID)
+		if err != nil {
+			klog.Error("insert outbox message error, ", err, ", ", m.EventID)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *postgresStore) Claim(ctx context.Context, limit int) ([]*Message, error) {
+	var messages []*Message
+	err := s.db.SelectContext(ctx, &messages,
+		`UPDATE sys_event_outbox SET next_attempt = now() + $1 * interval '1 second'
+		WHERE id IN (
+			SELECT id FROM sys_event_outbox WHERE state = 'pending' AND next_attempt <= now()
+			ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED
+		) RETURNING `+columns,
+		int64(ClaimTimeout/time.Second), limit)
+	if err != nil {
+		klog.Error("claim outbox messages error, ", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (s *postgresStore) Delivered(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE sys_event_outbox SET state = 'delivered', attempts = attempts + 1, last_error = '', updated_at = now()
+		WHERE id = $1`, id)
+	return err
+}
+
+func (s *postgresStore) Failed(ctx context.Context, id int64, attempts int, next time.Time, dead bool, lastError string) error {
+	state := StatePending
+	if dead {
+		state = StateDead
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE sys_event_outbox SET state = $2, attempts = $3, next_attempt = $4, last_error = $5, updated_at = now()
+		WHERE id = $1`, id, state, attempts, next, lastError)
+	return err
+}
+
+func (s *postgresStore) Defer(ctx context.Context, ids []int64, next time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE sys_event_outbox SET next_attempt = $2 WHERE state = 'pending' AND id = ANY($1)`,
+		pq.Array(ids), next)
+	return err
+}
+
+// where returns the condition of the filter, the parameters start from $2
+func where(filter Filter) (string, []interface{}) {
+	return `($2 = '' OR registry_namespace = $2) AND ($3 = '' OR registry_name = $3) AND
+		(cardinality($4::BIGINT[]) = 0 OR id = ANY($4))`,
+		[]interface{}{filter.RegistryNamespace, filter.RegistryName, pq.Array(filter.IDs)}
+}
+
+func (s *postgresStore) ListDead(ctx context.Context, filter Filter) ([]*Message, error) {
+	cond, args := where(filter)
+	messages := []*Message{}
+	err := s.db.SelectContext(ctx, &messages,
+		`SELECT `+columns+` FROM sys_event_outbox WHERE state = $1 AND `+cond+` ORDER BY id`,
+		append([]interface{}{StateDead}, args...)...)
+	if err != nil {
+		klog.Error("list dead-lettered messages error, ", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (s *postgresStore) Redrive(ctx context.Context, filter Filter) (int, error) {
+	cond, args := where(filter)
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE sys_event_outbox SET state = 'pending', attempts = 0, next_attempt = now(), updated_at = now()
+		WHERE state = $1 AND `+cond,
+		append([]interface{}{StateDead}, args...)...)
+	if err != nil {
+		klog.Error("redrive dead-lettered messages error, ", err)
+		return 0, err
+	}
+
+	count, err := res.RowsAffected()
+	return int(count), err
+}
+
+func (s *postgresStore) Purge(ctx context.Context, deliveredBefore, deadBefore time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM sys_event_outbox WHERE (state = 'delivered' AND updated_at < $1) OR (state = 'dead' AND updated_at < $2)`,
+		deliveredBefore, deadBefore)
+	return err
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
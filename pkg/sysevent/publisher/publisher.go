@@ -0,0 +1,111 @@
+// Package publisher publishes the sys events to the os stream of NATS JetStream,
+// on the subjects os.sys.<type>.<user>, so the apps consume them durably and replay them.
+// The events not owned by any user are published on os.sys.<type>._cluster, and the custom
+// events fired through the api on os.sys.custom.<type>.<user>, apart from the sys events
+package publisher
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+	natsworkload "bytetrade.io/web3os/tapr/pkg/workload/nats"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"k8s.io/klog/v2"
+)
+
+const (
+	publishTimeout = 5 * time.Second
+
+	// customTypePrefix is prefixed to the type of the custom events in the subjects
+	customTypePrefix = "custom."
+)
+
+type Publisher interface {
+	// Publish publishes the event, and waits for it persisted in the stream
+	Publish(ctx context.Context, e *cloudevents.Event) error
+
+	Close() error
+}
+
+type natsPublisher struct {
+	nc *nats.Conn
+	js jetstream.JetStream
+}
+
+var _ Publisher = &natsPublisher{}
+
+// NewNatsPublisher connects to the nats server, and creates the os stream if not exists
+func NewNatsPublisher(ctx context.Context, url, user, password string) (Publisher, error) {
+	nc, err := nats.Connect(url, nats.UserInfo(user, password), nats.MaxReconnects(-1))
+	if err != nil {
+		klog.Error("connect to nats error, ", err)
+		return nil, err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	_, err = js.CreateStream(ctx, natsworkload.OSStreamConfig)
+	if err != nil && !errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		klog.Error("create os stream error, ", err)
+		nc.Close()
+		return nil, err
+	}
+
+	return &natsPublisher{nc: nc, js: js}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, e *cloudevents.Event) error {
+	msg := nats.NewMsg(subject(e))
+
+	// the event in the binary content mode, the attributes in the headers
+	header := make(map[string][]string)
+	data, err := e.Encode(cloudevents.Binary, header)
+	if err != nil {
+		return err
+	}
+	msg.Header = header
+	msg.Data = data
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	// deduplicated by the stream if published again
+	_, err = p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(e.ID))
+	if err != nil {
+		klog.Error("publish sys event error, ", err, ", ", msg.Subject, ", ", e.ID)
+		return err
+	}
+
+	return nil
+}
+
+// subject returns the subject of the event in the os stream, the custom events can not take
+// the subjects of the sys events whatever their types are
+func subject(e *cloudevents.Event) string {
+	if e.Source == cloudevents.CustomSource {
+		return natsworkload.SysEventSubject(customTypePrefix+e.Type, e.User)
+	}
+
+	return natsworkload.SysEventSubject(e.Type, e.User)
+}
+
+func (p *natsPublisher) Close() error {
+	return p.nc.Drain()
+}
+
+type nopPublisher struct{}
+
+// NewNopPublisher returns the publisher dropping the events, if no nats server is configured
+func NewNopPublisher() Publisher { return nopPublisher{} }
+
+func (nopPublisher) Publish(context.Context, *cloudevents.Event) error { return nil }
+
+func (nopPublisher) Close() error { return nil }
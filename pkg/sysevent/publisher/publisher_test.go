@@ -0,0 +1,23 @@
+package publisher
+
+import (
+	"testing"
+
+	"bytetrade.io/web3os/tapr/pkg/sysevent/cloudevents"
+)
+
+func TestSubject(t *testing.T) {
+	cases := []struct {
+		source, eventType, user, subject string
+	}{
+		{cloudevents.SourceOf("app.install"), "app.install", "alice", "os.sys.app.install.alice"},
+		{cloudevents.CustomSource, "app.install", "alice", "os.sys.custom.app.install.alice"},
+		{cloudevents.CustomSource, "backup.done", "", "os.sys.custom.backup.done._cluster"},
+	}
+
+	for _, c := range cases {
+		if s := subject(&cloudevents.Event{Source: c.source, Type: c.eventType, User: c.user}); s != c.subject {
+			t.Errorf("unexpected subject of %s from %s, %s", c.eventType, c.source, s)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package signature signs the sys event callbacks, and verifies them in the app backends.
+//
+// Every callback carries the event id, the unix timestamp and the signature of the body in the headers.
+// The signature is the hex HMAC-SHA256 of "<id>.<timestamp>.<body>" keyed by the secret of the
+// SysEventRegistry, which is stored in the Secret named by SecretName in the namespace of the registry.
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderEventID   = "X-Sys-Event-Id"
+	HeaderEventType = "X-Sys-Event-Type"
+	HeaderTimestamp = "X-Sys-Event-Timestamp"
+	HeaderSignature = "X-Sys-Event-Signature"
+
+	// SecretKey is the key of the signing secret in the Secret
+	SecretKey = "secret"
+
+	// DefaultTolerance is the max age of the timestamp accepted, to reject the replayed callbacks
+	DefaultTolerance = 5 * time.Minute
+
+	version = "v1"
+)
+
+var (
+	ErrMissingHeader     = errors.New("sys event signature headers missing")
+	ErrInvalidTimestamp  = errors.New("sys event timestamp invalid")
+	ErrTimestampExpired  = errors.New("sys event timestamp out of tolerance")
+	ErrSignatureMismatch = errors.New("sys event signature mismatch")
+)
+
+// SecretName returns the name of the Secret of the signing secret of the registry
+func SecretName(registry string) string {
+	return registry + "-sys-event-secret"
+}
+
+// Sign returns the signature of the callback
+func Sign(secret []byte, id string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return version + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetHeaders signs the callback with the current time, and sets the headers
+func SetHeaders(header http.Header, secret []byte, id string, body []byte) {
+	timestamp := time.Now().Unix()
+	header.Set(HeaderEventID, id)
+	header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(HeaderSignature, Sign(secret, id, timestamp, body))
+}
+
+// Verify verifies the signature of the callback and the age of the timestamp, tolerance 0 skips the age check
+func Verify(secret []byte, header http.Header, body []byte, tolerance time.Duration) error {
+	id, ts, sig := header.Get(HeaderEventID), header.Get(HeaderTimestamp), header.Get(HeaderSignature)
+	if id == "" || ts == "" || sig == "" {
+		return ErrMissingHeader
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+
+	if tolerance > 0 {
+		age := time.Since(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrTimestampExpired
+		}
+	}
+
+	expected := Sign(secret, id, timestamp, body)
+	for _, s := range strings.Split(sig, ",") {
+		if hmac.Equal([]byte(strings.TrimSpace(s)), []byte(expected)) {
+			return nil
+		}
+	}
+
+	return ErrSignatureMismatch
+}
+
+// VerifyRequest verifies the callback request with the default tolerance, the body is kept readable
+func VerifyRequest(secret []byte, r *http.Request) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return Verify(secret, r.Header, body, DefaultTolerance)
+}
+
+// Middleware rejects the callbacks not signed by the secret with 401
+func Middleware(secret []byte, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyRequest(secret, r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
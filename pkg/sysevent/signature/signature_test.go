@@ -0,0 +1,64 @@
+package signature
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"name":"alice"}`)
+
+	header := http.Header{}
+	SetHeaders(header, secret, "id-1", body)
+	if err := Verify(secret, header, body, DefaultTolerance); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Verify([]byte("other"), header, body, DefaultTolerance); err != ErrSignatureMismatch {
+		t.Errorf("expected signature mismatch with other secret, %v", err)
+	}
+
+	if err := Verify(secret, header, []byte(`{"name":"bob"}`), DefaultTolerance); err != ErrSignatureMismatch {
+		t.Errorf("expected signature mismatch with tampered body, %v", err)
+	}
+
+	old := time.Now().Add(-time.Hour).Unix()
+	header.Set(HeaderTimestamp, strconv.FormatInt(old, 10))
+	header.Set(HeaderSignature, Sign(secret, "id-1", old, body))
+	if err := Verify(secret, header, body, DefaultTolerance); err != ErrTimestampExpired {
+		t.Errorf("expected timestamp expired, %v", err)
+	}
+
+	header.Del(HeaderEventID)
+	if err := Verify(secret, header, body, 0); err != ErrMissingHeader {
+		t.Errorf("expected headers missing, %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	body := `{"name":"alice"}`
+	handler := Middleware(secret, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body))
+	SetHeaders(req.Header, secret, "id-1", []byte(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("unexpected status of signed callback, %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unexpected status of unsigned callback, %d", rec.Code)
+	}
+}
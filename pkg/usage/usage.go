@@ -0,0 +1,111 @@
+package usage
+
+import (
+	"sort"
+	"sync"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the measures of the middleware objects
+const (
+	MetricSizeBytes   = "size_bytes"
+	MetricConnections = "connections"
+	MetricKeys        = "keys"
+	MetricObjects     = "objects"
+	MetricMessages    = "messages"
+	MetricDocuments   = "documents"
+)
+
+// Object is a database, namespace, bucket, vhost or index of the request, and its measures
+type Object struct {
+	Name    string           `json:"name"`
+	Metrics map[string]int64 `json:"metrics"`
+}
+
+// RequestUsage is the usage of the middleware objects owned by the request
+type RequestUsage struct {
+	Namespace    string               `json:"namespace"`
+	Name         string               `json:"name"`
+	App          string               `json:"app"`
+	AppNamespace string               `json:"appNamespace"`
+	Middleware   aprv1.MiddlewareType `json:"middleware"`
+	Objects      []Object             `json:"objects"`
+	CollectTime  metav1.Time          `json:"collectTime"`
+
+	// Error is set if the usage could not be collected from the backend
+	Error string `json:"error,omitempty"`
+}
+
+// Total returns the sum of the metric of all objects
+func (u *RequestUsage) Total(metric string) int64 {
+	var total int64
+	for _, o := range u.Objects {
+		total += o.Metrics[metric]
+	}
+
+	return total
+}
+
+var usageGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "tapr",
+	Subsystem: "middleware",
+	Name:      "usage",
+	Help:      "The usage of the middleware objects owned by the middleware requests",
+}, []string{"middleware", "namespace", "request", "app", "app_namespace", "object", "metric"})
+
+func init() {
+	prometheus.MustRegister(usageGauge)
+}
+
+// Store keeps the usage collected last time, and exports it to prometheus
+type Store struct {
+	mu    sync.RWMutex
+	usage []*RequestUsage
+}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Replace replaces all the usage, the series of the requests removed are dropped
+func (s *Store) Replace(usage []*RequestUsage) {
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Namespace != usage[j].Namespace {
+			return usage[i].Namespace < usage[j].Namespace
+		}
+		return usage[i].Name < usage[j].Name
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usage = usage
+	usageGauge.Reset()
+	for _, u := range usage {
+		for _, o := range u.Objects {
+			for metric, value := range o.Metrics {
+				usageGauge.WithLabelValues(string(u.Middleware), u.Namespace, u.Name, u.App, u.AppNamespace, o.Name, metric).
+					Set(float64(value))
+			}
+		}
+	}
+}
+
+// List returns the usage matching the filter, all if filter is nil
+func (s *Store) List(filter func(u *RequestUsage) bool) []*RequestUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ret := make([]*RequestUsage, 0, len(s.usage))
+	for _, u := range s.usage {
+		if filter == nil || filter(u) {
+			ret = append(ret, u)
+		}
+	}
+
+	return ret
+}
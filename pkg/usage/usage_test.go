@@ -0,0 +1,45 @@
+package usage
+
+import (
+	"testing"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStoreReplace(t *testing.T) {
+	s := NewStore()
+	s.Replace([]*RequestUsage{
+		{Namespace: "user-system-bob", Name: "b", Middleware: aprv1.TypeMinio,
+			Objects: []Object{{Name: "b-bucket", Metrics: map[string]int64{MetricSizeBytes: 10, MetricObjects: 2}}}},
+		{Namespace: "user-system-alice", Name: "a", Middleware: aprv1.TypePostgreSQL,
+			Objects: []Object{
+				{Name: "a_db1", Metrics: map[string]int64{MetricSizeBytes: 100}},
+				{Name: "a_db2", Metrics: map[string]int64{MetricSizeBytes: 50}},
+			}},
+	})
+
+	list := s.List(nil)
+	if len(list) != 2 || list[0].Name != "a" {
+		t.Fatalf("unexpected usage %v", list)
+	}
+
+	if total := list[0].Total(MetricSizeBytes); total != 150 {
+		t.Errorf("unexpected total %d", total)
+	}
+
+	if n := testutil.CollectAndCount(usageGauge); n != 4 {
+		t.Errorf("unexpected series %d", n)
+	}
+
+	s.Replace([]*RequestUsage{list[1]})
+	if n := testutil.CollectAndCount(usageGauge); n != 2 {
+		t.Errorf("expected the series of the removed request dropped, %d", n)
+	}
+
+	minio := s.List(func(u *RequestUsage) bool { return u.Middleware == aprv1.TypeMinio })
+	if len(minio) != 1 || minio[0].Name != "b" {
+		t.Errorf("unexpected filtered usage %v", minio)
+	}
+}
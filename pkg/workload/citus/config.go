@@ -0,0 +1,161 @@
+package citus
+
+import (
+	"context"
+	"strconv"
+
+	"bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	pgMaxConnectionsParam = "max_connections"
+
+	// pgRestartedForAnnotation is the generation of the cluster define the nodes are restarted for
+	pgRestartedForAnnotation = "apr.bytetrade.io/restartedForGeneration"
+)
+
+// DefaultPGMaxConnections is passed to postgres by the command line if max_connections is not configured,
+// the command line parameters can not be overridden by ALTER SYSTEM
+var DefaultPGMaxConnections = "1000"
+
+// ApplyPGClusterWorkloadSpec applies the args, resources and scheduling of the cluster define to the statefulset
+func ApplyPGClusterWorkloadSpec(sts *appv1.StatefulSet, clusterDef *v1alpha1.PGCluster) {
+	podSpec := &sts.Spec.Template.Spec
+	for i, c := range podSpec.Containers {
+		if c.Name == "postgres" {
+			ptrC := &podSpec.Containers[i]
+			ptrC.Args = nil
+			if _, ok := clusterDef.Spec.PostgresConfig[pgMaxConnectionsParam]; !ok {
+				ptrC.Args = []string{"-N", DefaultPGMaxConnections}
+			}
+
+			ptrC.Resources = corev1.ResourceRequirements{}
+			if clusterDef.Spec.Resources != nil {
+				ptrC.Resources = *clusterDef.Spec.Resources.DeepCopy()
+			}
+			break
+		}
+	}
+
+	podSpec.NodeSelector = clusterDef.Spec.NodeSelector
+	podSpec.Tolerations = clusterDef.Spec.Tolerations
+}
+
+// ApplyPGClusterStorage replaces the host path data volume with a volume claim template
+func ApplyPGClusterStorage(sts *appv1.StatefulSet, storage *v1alpha1.PGClusterStorage) {
+	var volumes []corev1.Volume
+	for _, vol := range sts.Spec.Template.Spec.Volumes {
+		if vol.Name != CitusVolumeName {
+			volumes = append(volumes, vol)
+		}
+	}
+	sts.Spec.Template.Spec.Volumes = volumes
+
+	sts.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: CitusVolumeName,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: storage.StorageClassName,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: storage.Size,
+					},
+				},
+			},
+		},
+	}
+}
+
+// UpdatePGClusterWorkload updates the statefulset if the args, resources, scheduling or tls of the
+// cluster define are changed, the nodes will be restarted by the rolling update
+func UpdatePGClusterWorkload(ctx context.Context, client *kubernetes.Clientset,
+	sts *appv1.StatefulSet, clusterDef *v1alpha1.PGCluster) (bool, error) {
+	newSts := sts.DeepCopy()
+	ApplyPGClusterWorkloadSpec(newSts, clusterDef)
+	ApplyPGClusterTLS(newSts)
+
+	if equality.Semantic.DeepEqual(newSts.Spec.Template, sts.Spec.Template) {
+		return false, nil
+	}
+
+	klog.Info("update pg cluster workload, ", sts.Namespace, "/", sts.Name)
+	_, err := client.AppsV1().StatefulSets(sts.Namespace).Update(ctx, newSts, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Error("update pg cluster workload error, ", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ApplyPGClusterConfig applies the postgresql.conf parameters to all nodes. The reload-only parameters
+// take effect immediately, and restart is true if any parameter requires a restart.
+func ApplyPGClusterConfig(ctx context.Context, sts *appv1.StatefulSet, clusterDef *v1alpha1.PGCluster,
+	admin, pwd string) (restart bool, err error) {
+	var index int32 = 0
+	for index < *sts.Spec.Replicas {
+		nodeHost := GetNodeHost(clusterDef, sts.Namespace, index)
+		if err = func() error {
+			nodeClient, err := postgres.NewClientBuidler(admin, pwd, nodeHost, postgres.PG_PORT).Build()
+			if err != nil {
+				klog.Error("connect to pg node error, ", err, ", ", nodeHost)
+				return err
+			}
+			defer nodeClient.Close()
+
+			pending, err := nodeClient.AlterSystem(ctx, clusterDef.Spec.PostgresConfig)
+			if err != nil {
+				klog.Error("apply pg config error, ", err, ", ", nodeHost)
+				return err
+			}
+
+			if len(pending) > 0 {
+				klog.Info("pg config parameters require restart, ", pending, ", ", nodeHost)
+				restart = true
+			}
+
+			return nil
+		}(); err != nil {
+			return false, err
+		}
+
+		index += 1
+	}
+
+	return restart, nil
+}
+
+// RestartPGCluster restarts all nodes of the cluster by the rolling update of the statefulset,
+// the nodes are restarted once for a generation of the cluster define
+func RestartPGCluster(ctx context.Context, client *kubernetes.Clientset, sts *appv1.StatefulSet, generation int64) error {
+	current, err := client.AppsV1().StatefulSets(sts.Namespace).Get(ctx, sts.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	restartedFor := strconv.FormatInt(generation, 10)
+	if current.Spec.Template.Annotations[pgRestartedForAnnotation] == restartedFor {
+		klog.Info("pg cluster is restarted for the generation already, ", sts.Namespace, "/", sts.Name, ", ", restartedFor)
+		return nil
+	}
+
+	if current.Spec.Template.Annotations == nil {
+		current.Spec.Template.Annotations = make(map[string]string)
+	}
+	current.Spec.Template.Annotations[pgRestartedForAnnotation] = restartedFor
+
+	klog.Info("restart pg cluster, ", sts.Namespace, "/", sts.Name)
+	_, err = client.AppsV1().StatefulSets(sts.Namespace).Update(ctx, current, metav1.UpdateOptions{})
+	return err
+}
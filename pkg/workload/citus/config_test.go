@@ -0,0 +1,39 @@
+package citus
+
+import (
+	"testing"
+
+	"bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestApplyPGClusterWorkloadSpec(t *testing.T) {
+	sts := CitusStatefulset.DeepCopy()
+	ApplyPGClusterWorkloadSpec(sts, &v1alpha1.PGCluster{})
+	if args := sts.Spec.Template.Spec.Containers[0].Args; len(args) != 2 || args[1] != DefaultPGMaxConnections {
+		t.Errorf("unexpected default args %v", args)
+	}
+
+	ApplyPGClusterWorkloadSpec(sts, &v1alpha1.PGCluster{
+		Spec: v1alpha1.PGClusterSpec{
+			PostgresConfig: map[string]string{"max_connections": "200"},
+			NodeSelector:   map[string]string{"disk": "ssd"},
+			Resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+			},
+		},
+	})
+	if args := sts.Spec.Template.Spec.Containers[0].Args; len(args) != 0 {
+		t.Errorf("max_connections should be applied by the config, got args %v", args)
+	}
+
+	if sts.Spec.Template.Spec.NodeSelector["disk"] != "ssd" {
+		t.Error("node selector is not applied")
+	}
+
+	if mem := sts.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory]; mem.Cmp(resource.MustParse("4Gi")) != 0 {
+		t.Errorf("unexpected memory request %s", mem.String())
+	}
+}
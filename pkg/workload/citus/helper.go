@@ -78,6 +78,12 @@ func GetPGClusterDefineByUser(ctx context.Context, client *kubernetes.Clientset,
 	}
 	sts.Spec.Template.Spec.PriorityClassName = "system-cluster-critical"
 
+	ApplyPGClusterWorkloadSpec(sts, clusterDef)
+	ApplyPGClusterTLS(sts)
+	if clusterDef.Spec.Storage != nil {
+		ApplyPGClusterStorage(sts, clusterDef.Spec.Storage)
+	}
+
 	return sts, nil
 }
 
@@ -197,9 +203,7 @@ func createPGCluster(ctx context.Context, client *kubernetes.Clientset,
 		if err != nil {
 			return nil, err
 		}
-		if len(clusterDef.Spec.CitusImage) > 0 {
-			sts.Spec.Template.Spec.Containers[0].Image = clusterDef.Spec.CitusImage
-		}
+		ApplyPGClusterImage(sts, GetPGClusterImage(clusterDef))
 
 		if clusterDef.Spec.AdminUser != "" {
 			for i, c := range sts.Spec.Template.Spec.Containers {
@@ -289,7 +293,7 @@ func WaitForPodRunning(ctx context.Context, client *kubernetes.Clientset, namesp
 }
 
 func ScalePGClusterNodes(ctx context.Context, client *kubernetes.Clientset,
-	namespace string, replicas int32, admin, pwd string) (effected int32, err error) {
+	namespace string, replicas int32, admin, pwd string, standby bool) (effected int32, err error) {
 	cluster, err := client.AppsV1().StatefulSets(namespace).GetScale(ctx, PGClusterName, metav1.GetOptions{})
 	if err != nil {
 		return 0, err
@@ -314,7 +318,6 @@ func ScalePGClusterNodes(ctx context.Context, client *kubernetes.Clientset,
 
 	if effected > 0 {
 		// update hba
-		masterNode := PGClusterName + "-0.citus-headless." + namespace + ".svc.cluster.local"
 		for index < cluster.Spec.Replicas {
 			podName := PGClusterName + "-" + strconv.Itoa(int(index))
 			klog.Info("update node hba config, ", podName)
@@ -323,93 +326,105 @@ func ScalePGClusterNodes(ctx context.Context, client *kubernetes.Clientset,
 				return 0, err
 			}
 
-			nodeClient, err := postgres.NewClientBuidler(admin, pwd,
-				ip, postgres.PG_PORT).Build()
-			if err != nil {
-				klog.Error("connect to pg node error, ", err, ", ", admin, ", ", pwd)
+			if err = SetPGNodeHBA(ctx, namespace, podName, ip, admin, pwd, standby); err != nil {
 				return 0, err
 			}
 
-			if err = func() error {
-				defer nodeClient.Close()
-
-				hba := PGNodeHBATrust +
-					"\n" +
-					"host all all " + masterNode + " trust" +
-					"\n" +
-					PGNodeHBAScram
+			index += 1
 
-				hbas := strings.Split(hba, "\n")
+		}
+	}
 
-				_, err = nodeClient.DB.ExecContext(ctx, "drop table if exists hba")
-				if err != nil {
-					klog.Error("drop hba table error, ", err)
-					return err
-				}
+	return
+}
 
-				configFile := "/var/lib/postgresql/data/" + podName + "/pg_hba.conf"
-				res, err := nodeClient.DB.QueryxContext(ctx, "select setting from pg_settings where name like '%hba%'")
-				if err != nil {
-					klog.Error("find hba config file error, ", err)
-					return err
-				}
+// SetPGNodeHBA trusts the connections from the master node, and requires scram-sha-256 for the others.
+// If the standby is configured, it is trusted to connect to the workers as the coordinator after the
+// failover, and its replication connections still require scram-sha-256
+func SetPGNodeHBA(ctx context.Context, namespace, podName, ip, admin, pwd string, standby bool) error {
+	masterNode := PGClusterName + "-0.citus-headless." + namespace + ".svc.cluster.local"
+	nodeClient, err := postgres.NewClientBuidler(admin, pwd,
+		ip, postgres.PG_PORT).Build()
+	if err != nil {
+		klog.Error("connect to pg node error, ", err, ", ", admin, ", ", pwd)
+		return err
+	}
+	defer nodeClient.Close()
 
-				path := struct {
-					Settings string `db:"setting"`
-				}{}
-				if res.Next() {
-					err = res.StructScan(&path)
-					res.Close()
-					if err != nil {
-						return err
-					}
-
-					configFile = path.Settings
-				}
+	hba := PGNodeHBATrust +
+		"\n" +
+		"host all all " + masterNode + " trust" +
+		"\n"
+	if standby {
+		standbyNode := GetStandbyHost(namespace) + ".svc.cluster.local"
+		hba += "host replication all " + standbyNode + " scram-sha-256" +
+			"\n" +
+			"host all all " + standbyNode + " trust" +
+			"\n"
+	}
+	hba += PGNodeHBAScram
 
-				if _, err = nodeClient.DB.ExecContext(ctx, "create table if not exists hba(lines text)"); err != nil {
-					klog.Error("create hba table error, ", err)
-					return err
-				}
+	hbas := strings.Split(hba, "\n")
 
-				tx, err := nodeClient.DB.Begin()
-				if err != nil {
-					return err
-				}
-				for _, h := range hbas {
-					if _, err = nodeClient.DB.NamedExecContext(ctx, "insert into hba values(:line)", map[string]interface{}{
-						"line": h,
-					}); err != nil {
-						tx.Rollback()
-						return err
-					}
-				}
-				if err = tx.Commit(); err != nil {
-					tx.Rollback()
-					return err
-				}
+	_, err = nodeClient.DB.ExecContext(ctx, "drop table if exists hba")
+	if err != nil {
+		klog.Error("drop hba table error, ", err)
+		return err
+	}
 
-				if _, err = nodeClient.DB.ExecContext(ctx, fmt.Sprintf("copy hba to '%s'", configFile)); err != nil {
-					klog.Error("save pg_hba.conf error, ", err)
-					return err
-				}
+	configFile := "/var/lib/postgresql/data/" + podName + "/pg_hba.conf"
+	res, err := nodeClient.DB.QueryxContext(ctx, "select setting from pg_settings where name like '%hba%'")
+	if err != nil {
+		klog.Error("find hba config file error, ", err)
+		return err
+	}
 
-				if _, err = nodeClient.DB.ExecContext(ctx, "SELECT pg_reload_conf()"); err != nil {
-					klog.Error("reload pg config error, ", err)
-					return err
-				}
+	path := struct {
+		Settings string `db:"setting"`
+	}{}
+	if res.Next() {
+		err = res.StructScan(&path)
+		res.Close()
+		if err != nil {
+			return err
+		}
 
-				return nil
-			}(); err != nil {
-				return 0, err
-			}
+		configFile = path.Settings
+	}
 
-			index += 1
+	if _, err = nodeClient.DB.ExecContext(ctx, "create table if not exists hba(lines text)"); err != nil {
+		klog.Error("create hba table error, ", err)
+		return err
+	}
 
+	tx, err := nodeClient.DB.Begin()
+	if err != nil {
+		return err
+	}
+	for _, h := range hbas {
+		if _, err = nodeClient.DB.NamedExecContext(ctx, "insert into hba values(:line)", map[string]interface{}{
+			"line": h,
+		}); err != nil {
+			tx.Rollback()
+			return err
 		}
 	}
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	return
+	if _, err = nodeClient.DB.ExecContext(ctx, fmt.Sprintf("copy hba to '%s'", configFile)); err != nil {
+		klog.Error("save pg_hba.conf error, ", err)
+		return err
+	}
+
+	if _, err = nodeClient.DB.ExecContext(ctx, "SELECT pg_reload_conf()"); err != nil {
+		klog.Error("reload pg config error, ", err)
+		return err
+	}
+
+	return nil
 }
 
 func MustUpdateClusterAdminUser(ctx context.Context, client *kubernetes.Clientset, namespace string,
@@ -706,3 +721,18 @@ func WaitForAllRestoreComplete(ctx context.Context, client *aprclientset.Clients
 func GetDatabaseName(namespace, db string) string {
 	return strings.ReplaceAll(fmt.Sprintf("%s_%s", namespace, db), "-", "_")
 }
+
+// GetRefAppNamespace returns the app namespace of the referenced app, the app in the same
+// namespace is referenced if refNamespace is empty
+func GetRefAppNamespace(refNamespace, appNamespace, ownerName string) string {
+	switch {
+	case refNamespace == "":
+		return appNamespace
+	case strings.HasPrefix(refNamespace, "user-space"):
+		return fmt.Sprintf("user-space-%s", ownerName)
+	case strings.HasPrefix(refNamespace, "user-system"):
+		return fmt.Sprintf("user-system-%s", ownerName)
+	}
+
+	return refNamespace
+}
@@ -0,0 +1,378 @@
+package citus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/certs"
+	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
+	listerv1alpha1 "bytetrade.io/web3os/tapr/pkg/generated/listers/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	PGPoolerName             = "citus-pgbouncer"
+	PGPoolerServiceName      = "citus-pgbouncer-svc"
+	PGPoolerConfigSecretName = "citus-pgbouncer-config"
+	PGPoolerPort             = 6432
+
+	pgPoolerConfigDir = "/etc/pgbouncer"
+	pgPoolerTLSDir    = "/etc/pgbouncer-tls"
+)
+
+var (
+	PGPoolerImage                         = "edoburu/pgbouncer:v1.23.1-p2"
+	DefaultPGPoolMode                     = v1alpha1.PGPoolModeTransaction
+	DefaultPGPoolSize               int32 = 20
+	DefaultPGPoolerMaxClientConn    int32 = 1000
+	DefaultPGPoolerReplicas         int32 = 1
+	pgPoolerLabels                        = map[string]string{"app": PGPoolerName, "app.kubernetes.io/name": PGPoolerName}
+	pgPoolerIgnoreStartupParameters       = "extra_float_digits,search_path"
+)
+
+// pgPoolerReload runs pgbouncer, and reloads it with SIGHUP once the kubelet updates the mounted
+// config or certificate, so the pools are changed without dropping the client connections
+var pgPoolerReload = `pgbouncer ` + pgPoolerConfigDir + `/pgbouncer.ini &
+pid=$!
+trap 'kill -TERM $pid' TERM INT
+checksum() { cat ` + pgPoolerConfigDir + `/* ` + pgPoolerTLSDir + `/* 2>/dev/null | md5sum; }
+sum=$(checksum)
+while kill -0 $pid 2>/dev/null; do
+  sleep 10 & wait $!
+  new=$(checksum)
+  if [ "$new" != "$sum" ]; then
+    sum=$new
+    kill -HUP $pid
+  fi
+done
+wait $pid
+`
+
+// PGPoolerUser is a user in the pooler userlist
+type PGPoolerUser struct {
+	Name     string
+	Password string
+}
+
+// PGPoolerDatabase is a database pool of the pooler
+type PGPoolerDatabase struct {
+	Name string
+	Mode v1alpha1.PGPoolMode
+	Size int32
+}
+
+// GetPGPoolerConfig generates the pgbouncer.ini and userlist.txt of the pooler, the databases are
+// served by the coordinator. The pooler connects to the coordinator with ssl, and accepts ssl from the clients.
+func GetPGPoolerConfig(coordinatorHost string, pooler *v1alpha1.PGPooler,
+	users []PGPoolerUser, databases []PGPoolerDatabase) (ini, userlist string) {
+	poolMode := DefaultPGPoolMode
+	if pooler.PoolMode != "" {
+		poolMode = pooler.PoolMode
+	}
+
+	poolSize := DefaultPGPoolSize
+	if pooler.DefaultPoolSize > 0 {
+		poolSize = pooler.DefaultPoolSize
+	}
+
+	maxClientConn := DefaultPGPoolerMaxClientConn
+	if pooler.MaxClientConn > 0 {
+		maxClientConn = pooler.MaxClientConn
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[databases]\n")
+	sort.Slice(databases, func(i, j int) bool { return databases[i].Name < databases[j].Name })
+	for _, db := range databases {
+		sb.WriteString(fmt.Sprintf("%s = host=%s port=%d dbname=%s", db.Name, coordinatorHost, postgres.PG_PORT, db.Name))
+		if db.Mode != "" {
+			sb.WriteString(" pool_mode=" + string(db.Mode))
+		}
+		if db.Size > 0 {
+			sb.WriteString(fmt.Sprintf(" pool_size=%d", db.Size))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n[pgbouncer]\n")
+	for _, kv := range [][2]string{
+		{"listen_addr", "0.0.0.0"},
+		{"listen_port", fmt.Sprintf("%d", PGPoolerPort)},
+		{"auth_type", "scram-sha-256"},
+		{"auth_file", pgPoolerConfigDir + "/userlist.txt"},
+		{"pool_mode", string(poolMode)},
+		{"default_pool_size", fmt.Sprintf("%d", poolSize)},
+		{"max_client_conn", fmt.Sprintf("%d", maxClientConn)},
+		{"ignore_startup_parameters", pgPoolerIgnoreStartupParameters},
+		{"server_tls_sslmode", "verify-ca"},
+		{"server_tls_ca_file", pgPoolerTLSDir + "/" + certs.CACertKey},
+		{"client_tls_sslmode", "prefer"},
+		{"client_tls_cert_file", pgPoolerTLSDir + "/" + corev1.TLSCertKey},
+		{"client_tls_key_file", pgPoolerTLSDir + "/" + corev1.TLSPrivateKeyKey},
+	} {
+		sb.WriteString(kv[0] + " = " + kv[1] + "\n")
+	}
+	ini = sb.String()
+
+	sb.Reset()
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	for _, u := range users {
+		sb.WriteString(quotePGPoolerValue(u.Name) + " " + quotePGPoolerValue(u.Password) + "\n")
+	}
+	userlist = sb.String()
+
+	return
+}
+
+// quotePGPoolerValue quotes the value in userlist.txt, the double quotes are escaped by doubling them
+func quotePGPoolerValue(v string) string {
+	return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+}
+
+// ReconcilePGPooler deploys the pooler with the pools generated from the postgres middleware requests,
+// or removes the pooler if it is not defined in the cluster
+func ReconcilePGPooler(ctx context.Context, client *kubernetes.Clientset, aprclient *aprclientset.Clientset,
+	requestLister listerv1alpha1.MiddlewareRequestLister, cluster *v1alpha1.PGCluster) error {
+	namespace := cluster.Namespace
+	if cluster.Spec.Pooler == nil {
+		return deletePGPooler(ctx, client, namespace)
+	}
+
+	adminUser, adminPwd, err := GetPGClusterAdminUserAndPassword(ctx, aprclient, client, namespace)
+	if err != nil {
+		klog.Error("get pg cluster admin user error, ", err)
+		return err
+	}
+
+	requests, err := requestLister.List(labels.Everything())
+	if err != nil {
+		klog.Error("list middleware requests error, ", err)
+		return err
+	}
+
+	users := []PGPoolerUser{{Name: adminUser, Password: adminPwd}}
+	var databases []PGPoolerDatabase
+	for _, req := range requests {
+		if req.Spec.Middleware != v1alpha1.TypePostgreSQL {
+			continue
+		}
+
+		pwd, err := req.Spec.PostgreSQL.Password.GetVarValue(ctx, client, req.Namespace)
+		if err != nil {
+			klog.Error("get middleware request password error, ", err, ", ", req.Namespace, "/", req.Name)
+			return err
+		}
+		users = append(users, PGPoolerUser{Name: req.Spec.PostgreSQL.User, Password: pwd})
+
+		for _, role := range req.Spec.PostgreSQL.Roles {
+			pwd, err := role.Password.GetVarValue(ctx, client, req.Namespace)
+			if err != nil {
+				klog.Error("get role password error, ", err, ", ", role.Name)
+				return err
+			}
+			users = append(users, PGPoolerUser{Name: role.Name, Password: pwd})
+		}
+
+		for _, db := range req.Spec.PostgreSQL.Databases {
+			pool := PGPoolerDatabase{Name: GetDatabaseName(req.Spec.AppNamespace, db.Name)}
+			if req.Spec.PostgreSQL.Pool != nil {
+				pool.Mode = req.Spec.PostgreSQL.Pool.Mode
+				pool.Size = req.Spec.PostgreSQL.Pool.Size
+			}
+			databases = append(databases, pool)
+		}
+	}
+
+	ini, userlist := GetPGPoolerConfig(GetCoordinatorHost(cluster), cluster.Spec.Pooler, users, databases)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PGPoolerConfigSecretName,
+			Namespace: namespace,
+		},
+		StringData: map[string]string{
+			"pgbouncer.ini": ini,
+			"userlist.txt":  userlist,
+		},
+	}
+
+	current, err := client.CoreV1().Secrets(namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = client.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	case err == nil:
+		current.Data = nil
+		current.StringData = secret.StringData
+		_, err = client.CoreV1().Secrets(namespace).Update(ctx, current, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		klog.Error("create or update pooler config error, ", err)
+		return err
+	}
+
+	// the certificate of the cluster is mounted to the pooler
+	_, err = client.CoreV1().Secrets(namespace).Get(ctx, certs.TLSSecretName(PGClusterCertName), metav1.GetOptions{})
+	if err != nil {
+		klog.Error("get pg cluster tls secret error, ", err)
+		return err
+	}
+
+	// the changes of the config and the certificate are reloaded by the pooler itself
+	deploy := getPGPoolerDeployment(namespace, cluster.Spec.Pooler)
+	currentDeploy, err := client.AppsV1().Deployments(namespace).Get(ctx, deploy.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = client.AppsV1().Deployments(namespace).Create(ctx, deploy, metav1.CreateOptions{})
+	case err == nil:
+		currentDeploy.Spec = deploy.Spec
+		_, err = client.AppsV1().Deployments(namespace).Update(ctx, currentDeploy, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		klog.Error("create or update pooler deployment error, ", err)
+		return err
+	}
+
+	_, err = client.CoreV1().Services(namespace).Get(ctx, PGPoolerServiceName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      PGPoolerServiceName,
+				Namespace: namespace,
+			},
+			Spec: corev1.ServiceSpec{
+				Selector: pgPoolerLabels,
+				Ports: []corev1.ServicePort{
+					{
+						Name:       "pgbouncer",
+						Port:       PGPoolerPort,
+						TargetPort: intstr.FromInt(PGPoolerPort),
+					},
+				},
+			},
+		}
+		_, err = client.CoreV1().Services(namespace).Create(ctx, svc, metav1.CreateOptions{})
+	}
+	if err != nil {
+		klog.Error("create pooler service error, ", err)
+		return err
+	}
+
+	return nil
+}
+
+func getPGPoolerDeployment(namespace string, pooler *v1alpha1.PGPooler) *appv1.Deployment {
+	image := PGPoolerImage
+	if pooler.Image != "" {
+		image = pooler.Image
+	}
+
+	replicas := DefaultPGPoolerReplicas
+	if pooler.Replicas != nil {
+		replicas = *pooler.Replicas
+	}
+
+	return &appv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PGPoolerName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"managed-by": "citus-operator",
+			},
+		},
+		Spec: appv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: pgPoolerLabels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                         PGPoolerName,
+						"app.kubernetes.io/name":      PGPoolerName,
+						"app.bytetrade.io/middleware": "true",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            "pgbouncer",
+							Image:           image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         []string{"sh", "-c", pgPoolerReload},
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "pgbouncer",
+									Protocol:      corev1.ProtocolTCP,
+									ContainerPort: PGPoolerPort,
+								},
+							},
+							LivenessProbe: &corev1.Probe{
+								InitialDelaySeconds: 5,
+								ProbeHandler: corev1.ProbeHandler{
+									TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(PGPoolerPort)},
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "config",
+									MountPath: pgPoolerConfigDir,
+									ReadOnly:  true,
+								},
+								{
+									Name:      "tls",
+									MountPath: pgPoolerTLSDir,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "config",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{SecretName: PGPoolerConfigSecretName},
+							},
+						},
+						{
+							Name: "tls",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{SecretName: certs.TLSSecretName(PGClusterCertName)},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func deletePGPooler(ctx context.Context, client *kubernetes.Clientset, namespace string) error {
+	err := client.AppsV1().Deployments(namespace).Delete(ctx, PGPoolerName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Error("delete pooler deployment error, ", err)
+		return err
+	}
+
+	err = client.CoreV1().Services(namespace).Delete(ctx, PGPoolerServiceName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Error("delete pooler service error, ", err)
+		return err
+	}
+
+	err = client.CoreV1().Secrets(namespace).Delete(ctx, PGPoolerConfigSecretName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Error("delete pooler config error, ", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package citus
+
+import (
+	"strings"
+	"testing"
+
+	"bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+)
+
+func TestGetPGPoolerConfig(t *testing.T) {
+	ini, userlist := GetPGPoolerConfig("citus-0.citus-headless.os-platform", &v1alpha1.PGPooler{DefaultPoolSize: 10},
+		[]PGPoolerUser{{Name: "u2", Password: `p"2`}, {Name: "u1", Password: "p1"}},
+		[]PGPoolerDatabase{{Name: "app_db", Mode: v1alpha1.PGPoolModeSession, Size: 5}})
+
+	if !strings.Contains(ini, "app_db = host=citus-0.citus-headless.os-platform port=5432 dbname=app_db pool_mode=session pool_size=5\n") {
+		t.Errorf("unexpected databases in config:\n%s", ini)
+	}
+
+	if !strings.Contains(ini, "pool_mode = transaction\n") || !strings.Contains(ini, "default_pool_size = 10\n") {
+		t.Errorf("unexpected pgbouncer settings in config:\n%s", ini)
+	}
+
+	if !strings.Contains(ini, "server_tls_sslmode = verify-ca\n") || !strings.Contains(ini, "client_tls_sslmode = prefer\n") {
+		t.Errorf("unexpected tls settings in config:\n%s", ini)
+	}
+
+	if userlist != "\"u1\" \"p1\"\n\"u2\" \"p\"\"2\"\n" {
+		t.Errorf("unexpected userlist:\n%s", userlist)
+	}
+}
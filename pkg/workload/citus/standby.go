@@ -0,0 +1,406 @@
+package citus
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/certs"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	PGStandbyName                = "citus-standby"
+	PGStandbyHeadlessServiceName = "citus-standby-headless"
+	PGReadOnlyServiceName        = "citus-readonly-svc"
+	PGStandbySlotName            = "citus_standby"
+
+	podNameLabel = "statefulset.kubernetes.io/pod-name"
+)
+
+// pgStandbyBaseBackup clones the data directory from the coordinator if it is not initialized,
+// the standby starts streaming from the replication slot with the written recovery config
+var pgStandbyBaseBackup = `set -e
+if [ ! -s "$PGDATA/PG_VERSION" ]; then
+  mkdir -p "$PGDATA"
+  chown postgres:postgres "$PGDATA"
+  chmod 700 "$PGDATA"
+  PGPASSWORD="$POSTGRES_PASSWORD" gosu postgres pg_basebackup -h "$PRIMARY_HOST" -p 5432 -U "$POSTGRES_USER" \
+    -D "$PGDATA" -R -X stream -S "$SLOT_NAME" -c fast
+fi
+`
+
+var pgStandbyLabels = map[string]string{
+	"app":                    PGStandbyName,
+	"app.kubernetes.io/name": PGStandbyName,
+}
+
+// GetStandbyHost returns the host of the standby of the coordinator
+func GetStandbyHost(namespace string) string {
+	return PGStandbyName + "-0." + PGStandbyHeadlessServiceName + "." + namespace
+}
+
+// GetNodeHost returns the host of the node of the cluster, node 0 is the active coordinator
+func GetNodeHost(cluster *v1alpha1.PGCluster, namespace string, index int32) string {
+	if index == 0 && cluster != nil && cluster.Status.Coordinator != nil && cluster.Status.Coordinator.Primary != "" {
+		return cluster.Status.Coordinator.Primary
+	}
+
+	return PGClusterName + "-" + strconv.Itoa(int(index)) + "." + CitusHeadlessServiceName + "." + namespace
+}
+
+// GetCoordinatorHost returns the host of the active coordinator, which is node 0 of the cluster
+// unless the coordinator failed over to the standby
+func GetCoordinatorHost(cluster *v1alpha1.PGCluster) string {
+	return GetNodeHost(cluster, cluster.Namespace, 0)
+}
+
+// IsCoordinatorFailedOver returns whether the standby is promoted to the coordinator
+func IsCoordinatorFailedOver(cluster *v1alpha1.PGCluster) bool {
+	return GetCoordinatorHost(cluster) == GetStandbyHost(cluster.Namespace)
+}
+
+// HasPGStandby returns whether the standby of the coordinator is configured, or is the coordinator now
+func HasPGStandby(cluster *v1alpha1.PGCluster) bool {
+	return cluster.Spec.Standby != nil || IsCoordinatorFailedOver(cluster)
+}
+
+// GetPGStandbyDefine returns the standby of the coordinator, derived from the cluster statefulset
+func GetPGStandbyDefine(sts *appv1.StatefulSet) *appv1.StatefulSet {
+	var replicas int32 = 1
+	standby := &appv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PGStandbyName,
+			Namespace: sts.Namespace,
+			Labels: map[string]string{
+				"managed-by": "citus-operator",
+			},
+		},
+		Spec: *sts.Spec.DeepCopy(),
+	}
+
+	standby.Spec.Replicas = &replicas
+	standby.Spec.ServiceName = PGStandbyHeadlessServiceName
+	standby.Spec.Selector = &metav1.LabelSelector{MatchLabels: pgStandbyLabels}
+	standby.Spec.Template.Labels = map[string]string{
+		"app":                         PGStandbyName,
+		"app.kubernetes.io/name":      PGStandbyName,
+		"app.bytetrade.io/middleware": "true",
+	}
+	standby.Spec.Template.Annotations = nil
+
+	podSpec := &standby.Spec.Template.Spec
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+	podSpec.Affinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+			{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{podNameLabel: PGClusterName + "-0"},
+					},
+					TopologyKey: corev1.LabelHostname,
+				},
+			},
+		},
+	}
+
+	podSpec.InitContainers = nil
+	for _, c := range podSpec.Containers {
+		if c.Name == "postgres" {
+			env := append([]corev1.EnvVar{}, c.Env...)
+			env = append(env,
+				corev1.EnvVar{Name: "PRIMARY_HOST", Value: PGClusterName + "-0." + CitusHeadlessServiceName + "." + sts.Namespace},
+				corev1.EnvVar{Name: "SLOT_NAME", Value: PGStandbySlotName},
+			)
+
+			// stream from the primary with ssl if the certificates are mounted
+			for _, m := range c.VolumeMounts {
+				if m.Name == pgTLSVolumeName {
+					env = append(env,
+						corev1.EnvVar{Name: "PGSSLMODE", Value: "verify-ca"},
+						corev1.EnvVar{Name: "PGSSLROOTCERT", Value: PGClusterTLSDir + "/" + certs.CACertKey},
+					)
+				}
+			}
+
+			podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+				Name:            "basebackup",
+				Image:           c.Image,
+				ImagePullPolicy: c.ImagePullPolicy,
+				Env:             env,
+				Command:         []string{"sh", "-c", pgStandbyBaseBackup},
+				VolumeMounts:    c.VolumeMounts,
+			})
+		}
+	}
+
+	return standby
+}
+
+// ReconcilePGStandby deploys the standby of the coordinator and the read-only service,
+// or removes them if the standby is not defined in the cluster
+func ReconcilePGStandby(ctx context.Context, client *kubernetes.Clientset, cluster *v1alpha1.PGCluster, admin, pwd string) error {
+	namespace := cluster.Namespace
+	if IsCoordinatorFailedOver(cluster) {
+		// the standby is the coordinator now
+		return nil
+	}
+
+	if cluster.Spec.Standby == nil {
+		return deletePGStandby(ctx, client, cluster, admin, pwd)
+	}
+
+	sts, err := client.AppsV1().StatefulSets(namespace).Get(ctx, PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	standby := GetPGStandbyDefine(sts)
+	current, err := client.AppsV1().StatefulSets(namespace).Get(ctx, PGStandbyName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if apierrors.IsNotFound(err) {
+		// allow the replication from the standby, and trust the standby on the workers for failover
+		if err = SetPGClusterHBA(ctx, client, namespace, admin, pwd, true); err != nil {
+			klog.Error("set pg cluster hba error, ", err)
+			return err
+		}
+
+		masterHost := GetCoordinatorHost(cluster)
+		masterClient, err := postgres.NewClientBuidler(admin, pwd, masterHost, postgres.PG_PORT).Build()
+		if err != nil {
+			klog.Error("connect to master error, ", err, ", ", masterHost)
+			return err
+		}
+
+		err = masterClient.CreateReplicationSlot(ctx, PGStandbySlotName)
+		masterClient.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, svc := range getPGStandbyServices(namespace) {
+			_, err = client.CoreV1().Services(namespace).Create(ctx, svc, metav1.CreateOptions{})
+			if err != nil && !apierrors.IsAlreadyExists(err) {
+				klog.Error("create pg standby service error, ", err, ", ", svc.Name)
+				return err
+			}
+		}
+
+		klog.Info("create pg coordinator standby, ", namespace)
+		_, err = client.AppsV1().StatefulSets(namespace).Create(ctx, standby, metav1.CreateOptions{})
+		if err != nil {
+			klog.Error("create pg standby error, ", err)
+		}
+
+		return err
+	}
+
+	// the image, resources and args follow the cluster
+	currentPod, standbyPod := current.Spec.Template.Spec, standby.Spec.Template.Spec
+	if equality.Semantic.DeepEqual(currentPod.Containers, standbyPod.Containers) &&
+		equality.Semantic.DeepEqual(currentPod.NodeSelector, standbyPod.NodeSelector) &&
+		equality.Semantic.DeepEqual(currentPod.Tolerations, standbyPod.Tolerations) {
+		return nil
+	}
+
+	klog.Info("update pg coordinator standby, ", namespace)
+	current.Spec.Template = standby.Spec.Template
+	_, err = client.AppsV1().StatefulSets(namespace).Update(ctx, current, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Error("update pg standby error, ", err)
+	}
+
+	return err
+}
+
+func getPGStandbyServices(namespace string) []*corev1.Service {
+	ports := []corev1.ServicePort{
+		{
+			Name:       "citus",
+			Port:       postgres.PG_PORT,
+			TargetPort: intstr.FromInt(postgres.PG_PORT),
+		},
+	}
+
+	return []*corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      PGStandbyHeadlessServiceName,
+				Namespace: namespace,
+			},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: corev1.ClusterIPNone,
+				Selector:  pgStandbyLabels,
+				Ports:     ports,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      PGReadOnlyServiceName,
+				Namespace: namespace,
+			},
+			Spec: corev1.ServiceSpec{
+				Selector: pgStandbyLabels,
+				Ports:    ports,
+			},
+		},
+	}
+}
+
+func deletePGStandby(ctx context.Context, client *kubernetes.Clientset, cluster *v1alpha1.PGCluster, admin, pwd string) error {
+	namespace := cluster.Namespace
+	_, err := client.AppsV1().StatefulSets(namespace).Get(ctx, PGStandbyName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	klog.Info("delete pg coordinator standby, ", namespace)
+	err = client.AppsV1().StatefulSets(namespace).Delete(ctx, PGStandbyName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Error("delete pg standby error, ", err)
+		return err
+	}
+
+	for _, svc := range []string{PGStandbyHeadlessServiceName, PGReadOnlyServiceName} {
+		err = client.CoreV1().Services(namespace).Delete(ctx, svc, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Error("delete pg standby service error, ", err, ", ", svc)
+			return err
+		}
+	}
+
+	// the slot can be dropped after the standby stopped streaming
+	err = wait.PollWithContext(ctx, 2*time.Second, 5*time.Minute, func(ctx context.Context) (bool, error) {
+		_, err := client.CoreV1().Pods(namespace).Get(ctx, PGStandbyName+"-0", metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		return false, err
+	})
+	if err != nil {
+		klog.Error("wait for pg standby stopped error, ", err)
+		return err
+	}
+
+	masterHost := GetCoordinatorHost(cluster)
+	masterClient, err := postgres.NewClientBuidler(admin, pwd, masterHost, postgres.PG_PORT).Build()
+	if err != nil {
+		klog.Error("connect to master error, ", err, ", ", masterHost)
+		return err
+	}
+	defer masterClient.Close()
+
+	if err = masterClient.DropReplicationSlot(ctx, PGStandbySlotName); err != nil {
+		return err
+	}
+
+	// the standby is not trusted any more
+	return SetPGClusterHBA(ctx, client, namespace, admin, pwd, false)
+}
+
+// PromotePGStandby promotes the standby to the coordinator, updates the coordinator of the distributed
+// databases, and switches the master service to the standby
+func PromotePGStandby(ctx context.Context, client *kubernetes.Clientset, cluster *v1alpha1.PGCluster, admin, pwd string) error {
+	namespace := cluster.Namespace
+	standbyHost := GetStandbyHost(namespace)
+	standbyClient, err := postgres.NewClientBuidler(admin, pwd, standbyHost, postgres.PG_PORT).Build()
+	if err != nil {
+		klog.Error("connect to pg standby error, ", err, ", ", standbyHost)
+		return err
+	}
+	defer standbyClient.Close()
+
+	inRecovery, err := standbyClient.IsInRecovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	if inRecovery {
+		klog.Info("promote pg standby, ", standbyHost)
+		if err = standbyClient.Promote(ctx); err != nil {
+			klog.Error("promote pg standby error, ", err)
+			return err
+		}
+	}
+
+	databases, err := standbyClient.ListDatabases(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, db := range databases {
+		if err = standbyClient.SwitchDatabase(db); err != nil {
+			klog.Error("switch database error, ", err, ", ", db)
+			return err
+		}
+
+		extensions, err := standbyClient.ListExtensions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range extensions {
+			if e.Name == "citus" {
+				klog.Info("update the coordinator of database, ", db, ", ", standbyHost)
+				if err = standbyClient.SetMasterNode(ctx, standbyHost, postgres.PG_PORT); err != nil {
+					klog.Error("set master node error, ", err, ", ", db)
+					return err
+				}
+			}
+		}
+	}
+
+	svc, err := client.CoreV1().Services(namespace).Get(ctx, CitusMasterServiceName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		svc.Spec.Selector = map[string]string{podNameLabel: PGStandbyName + "-0"}
+		if _, err = client.CoreV1().Services(namespace).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+			klog.Error("switch pg master service error, ", err)
+			return err
+		}
+	case !apierrors.IsNotFound(err):
+		return err
+	}
+
+	// the standby is writable now
+	err = client.CoreV1().Services(namespace).Delete(ctx, PGReadOnlyServiceName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Error("delete pg read-only service error, ", err)
+		return err
+	}
+
+	return nil
+}
+
+// FencePGNode makes the old coordinator read-only if it is back after the failover
+func FencePGNode(ctx context.Context, host, admin, pwd string) error {
+	nodeClient, err := postgres.NewClientBuidler(admin, pwd, host, postgres.PG_PORT).Build()
+	if err != nil {
+		return err
+	}
+	defer nodeClient.Close()
+
+	return nodeClient.SetReadOnly(ctx)
+}
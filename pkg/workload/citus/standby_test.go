@@ -0,0 +1,71 @@
+package citus
+
+import (
+	"testing"
+
+	"bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetNodeHost(t *testing.T) {
+	cluster := &v1alpha1.PGCluster{ObjectMeta: metav1.ObjectMeta{Name: PGClusterName, Namespace: "os-platform"}}
+	if host := GetCoordinatorHost(cluster); host != "citus-0.citus-headless.os-platform" {
+		t.Errorf("unexpected coordinator host %s", host)
+	}
+
+	if IsCoordinatorFailedOver(cluster) {
+		t.Error("unexpected failed over")
+	}
+
+	cluster.Status.Coordinator = &v1alpha1.PGCoordinatorStatus{Primary: GetStandbyHost("os-platform")}
+	if host := GetCoordinatorHost(cluster); host != "citus-standby-0.citus-standby-headless.os-platform" {
+		t.Errorf("unexpected coordinator host %s", host)
+	}
+
+	if !IsCoordinatorFailedOver(cluster) {
+		t.Error("expected failed over")
+	}
+
+	if host := GetNodeHost(cluster, "os-platform", 2); host != "citus-2.citus-headless.os-platform" {
+		t.Errorf("unexpected worker host %s", host)
+	}
+}
+
+func TestGetPGStandbyDefine(t *testing.T) {
+	sts := CitusStatefulset.DeepCopy()
+	sts.Namespace = "os-platform"
+
+	standby := GetPGStandbyDefine(sts)
+	if standby.Name != PGStandbyName || *standby.Spec.Replicas != 1 || standby.Spec.ServiceName != PGStandbyHeadlessServiceName {
+		t.Errorf("unexpected standby %s, %d, %s", standby.Name, *standby.Spec.Replicas, standby.Spec.ServiceName)
+	}
+
+	if standby.Spec.Template.Labels["app"] != PGStandbyName || standby.Spec.Selector.MatchLabels["app"] != PGStandbyName {
+		t.Error("the standby must not be selected by the cluster")
+	}
+
+	if len(standby.Spec.Template.Spec.InitContainers) != 1 {
+		t.Fatal("expected the base backup init container")
+	}
+
+	init := standby.Spec.Template.Spec.InitContainers[0]
+	if init.Image != CitusImage {
+		t.Errorf("unexpected image %s", init.Image)
+	}
+
+	var primary string
+	for _, env := range init.Env {
+		if env.Name == "PRIMARY_HOST" {
+			primary = env.Value
+		}
+	}
+	if primary != "citus-0.citus-headless.os-platform" {
+		t.Errorf("unexpected primary host %s", primary)
+	}
+
+	// the cluster statefulset is not changed
+	if len(sts.Spec.Template.Spec.InitContainers) != 0 || sts.Spec.Template.Labels["app"] != "citus" {
+		t.Error("the cluster statefulset is changed")
+	}
+}
@@ -37,6 +37,7 @@ host    replication     all             ::1/128                 trust
 `
 	PGNodeHBAScram = `
 host all all all scram-sha-256
+host replication all all scram-sha-256
 `
 )
 
@@ -101,7 +102,6 @@ var (
 							Name:            "postgres",
 							Image:           CitusImage,
 							ImagePullPolicy: corev1.PullIfNotPresent,
-							Args:            []string{"-N", "1000"},
 							Env: []corev1.EnvVar{
 								{
 									Name: "POD_NAME",
@@ -0,0 +1,187 @@
+package citus
+
+import (
+	"context"
+
+	"bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/certs"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/workload/utils"
+
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// PGClusterCertName names the ca secret citus-ca and the tls secret citus-tls of the cluster
+	PGClusterCertName = PGClusterName
+	PGClusterTLSDir   = "/etc/citus/tls"
+
+	pgTLSVolumeName = "citus-tls"
+
+	// the gid of postgres in the citus image, the private key must not be readable by others
+	pgPostgresGID int64 = 999
+	pgTLSKeyMode  int32 = 0640
+)
+
+// PGClusterDNSNames returns the names of the nodes, the standby and the services of the cluster
+func PGClusterDNSNames(namespace string) []string {
+	var names []string
+	for _, name := range []string{
+		"*." + CitusHeadlessServiceName + "." + namespace,
+		"*." + PGStandbyHeadlessServiceName + "." + namespace,
+		CitusMasterServiceName + "." + namespace,
+		PGReadOnlyServiceName + "." + namespace,
+		PGPoolerServiceName + "." + namespace,
+	} {
+		names = append(names, name, name+".svc", name+".svc.cluster.local")
+	}
+
+	return append(names, "localhost")
+}
+
+// EnsurePGClusterCertificates issues the certificates of the cluster, and renews them before expiry
+func EnsurePGClusterCertificates(ctx context.Context, client *kubernetes.Clientset, namespace string) (*corev1.Secret, bool, error) {
+	return certs.EnsureCertificates(ctx, client, namespace, PGClusterCertName, PGClusterDNSNames(namespace))
+}
+
+// ApplyPGClusterTLS mounts the certificates into the nodes and turns on ssl. The nodes connect to each
+// other with ssl, and verify the certificates by the ca of the cluster.
+func ApplyPGClusterTLS(sts *appv1.StatefulSet) {
+	podSpec := &sts.Spec.Template.Spec
+	if podSpec.SecurityContext == nil {
+		podSpec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if podSpec.SecurityContext.FSGroup == nil {
+		podSpec.SecurityContext.FSGroup = pointer.Int64(pgPostgresGID)
+		podSpec.SecurityContext.FSGroupChangePolicy = utils.AnyPtr(corev1.FSGroupChangeOnRootMismatch)
+	}
+
+	podSpec.Volumes = setVolume(podSpec.Volumes, corev1.Volume{
+		Name: pgTLSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  certs.TLSSecretName(PGClusterCertName),
+				DefaultMode: pointer.Int32(pgTLSKeyMode),
+			},
+		},
+	})
+
+	for i, c := range podSpec.Containers {
+		if c.Name != "postgres" {
+			continue
+		}
+
+		ptrC := &podSpec.Containers[i]
+		ptrC.VolumeMounts = setVolumeMount(ptrC.VolumeMounts, corev1.VolumeMount{
+			Name:      pgTLSVolumeName,
+			MountPath: PGClusterTLSDir,
+			ReadOnly:  true,
+		})
+
+		tlsArgs := []string{
+			"-c", "ssl=on",
+			"-c", "ssl_cert_file=" + PGClusterTLSDir + "/" + corev1.TLSCertKey,
+			"-c", "ssl_key_file=" + PGClusterTLSDir + "/" + corev1.TLSPrivateKeyKey,
+			"-c", "citus.node_conninfo=sslmode=verify-ca sslrootcert=" + PGClusterTLSDir + "/" + certs.CACertKey,
+		}
+
+		found := false
+		for _, arg := range ptrC.Args {
+			if arg == "ssl=on" {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			ptrC.Args = append(ptrC.Args, tlsArgs...)
+		}
+	}
+}
+
+// IsPGClusterTLSReady returns true if all nodes are rolled out with ssl
+func IsPGClusterTLSReady(sts *appv1.StatefulSet) bool {
+	found := false
+	for _, vol := range sts.Spec.Template.Spec.Volumes {
+		if vol.Name == pgTLSVolumeName {
+			found = true
+			break
+		}
+	}
+
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	return found && sts.Status.ObservedGeneration >= sts.Generation &&
+		sts.Status.UpdateRevision == sts.Status.CurrentRevision &&
+		sts.Status.UpdatedReplicas == replicas && sts.Status.ReadyReplicas == replicas
+}
+
+// ReloadPGClusterCertificates reloads the renewed certificates on all nodes and the standby
+func ReloadPGClusterCertificates(ctx context.Context, client *kubernetes.Clientset, sts *appv1.StatefulSet,
+	cluster *v1alpha1.PGCluster, admin, pwd string) error {
+	var hosts []string
+	for index := int32(0); index < *sts.Spec.Replicas; index++ {
+		hosts = append(hosts, GetNodeHost(cluster, sts.Namespace, index))
+	}
+
+	if !IsCoordinatorFailedOver(cluster) {
+		_, err := client.AppsV1().StatefulSets(sts.Namespace).Get(ctx, PGStandbyName, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		if err == nil {
+			hosts = append(hosts, GetStandbyHost(sts.Namespace))
+		}
+	}
+
+	for _, host := range hosts {
+		if err := func() error {
+			nodeClient, err := postgres.NewClientBuidler(admin, pwd, host, postgres.PG_PORT).Build()
+			if err != nil {
+				klog.Error("connect to pg node error, ", err, ", ", host)
+				return err
+			}
+			defer nodeClient.Close()
+
+			klog.Info("reload pg node certificates, ", host)
+			return nodeClient.ReloadConf(ctx)
+		}(); err != nil {
+			klog.Error("reload pg node certificates error, ", err, ", ", host)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setVolume(volumes []corev1.Volume, volume corev1.Volume) []corev1.Volume {
+	for i, v := range volumes {
+		if v.Name == volume.Name {
+			volumes[i] = volume
+			return volumes
+		}
+	}
+
+	return append(volumes, volume)
+}
+
+func setVolumeMount(mounts []corev1.VolumeMount, mount corev1.VolumeMount) []corev1.VolumeMount {
+	for i, m := range mounts {
+		if m.Name == mount.Name {
+			mounts[i] = mount
+			return mounts
+		}
+	}
+
+	return append(mounts, mount)
+}
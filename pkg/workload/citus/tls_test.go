@@ -0,0 +1,49 @@
+package citus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyPGClusterTLS(t *testing.T) {
+	sts := CitusStatefulset.DeepCopy()
+	ApplyPGClusterTLS(sts)
+	ApplyPGClusterTLS(sts)
+
+	podSpec := sts.Spec.Template.Spec
+	if podSpec.SecurityContext == nil || podSpec.SecurityContext.FSGroup == nil || *podSpec.SecurityContext.FSGroup != pgPostgresGID {
+		t.Error("expected the fs group of postgres")
+	}
+
+	volumes := 0
+	for _, v := range podSpec.Volumes {
+		if v.Name == pgTLSVolumeName {
+			volumes++
+			if v.Secret == nil || v.Secret.SecretName != "citus-tls" {
+				t.Error("unexpected tls volume")
+			}
+		}
+	}
+	if volumes != 1 {
+		t.Errorf("unexpected tls volumes %d", volumes)
+	}
+
+	for _, c := range podSpec.Containers {
+		if c.Name != "postgres" {
+			continue
+		}
+
+		args := strings.Join(c.Args, " ")
+		if strings.Count(args, "ssl=on") != 1 {
+			t.Errorf("unexpected args %s", args)
+		}
+
+		if !strings.Contains(args, "citus.node_conninfo=sslmode=verify-ca sslrootcert=/etc/citus/tls/ca.crt") {
+			t.Errorf("expected the node conninfo with ssl, %s", args)
+		}
+	}
+
+	if IsPGClusterTLSReady(sts) {
+		t.Error("unexpected tls ready before rolled out")
+	}
+}
@@ -0,0 +1,511 @@
+package citus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
+	"bytetrade.io/web3os/tapr/pkg/postgres"
+	"bytetrade.io/web3os/tapr/pkg/workload/utils"
+
+	appv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	PGClusterUpgradeBackup = "citus-upgrade"
+
+	pgDataDirPrefix       = "/var/lib/postgresql/data/$(POD_NAME)"
+	pgUpgradePreflightJob = "pgc-upgrade-preflight-job"
+
+	// pgUpgradeTargetAnnotation is the image the preflight job checks for
+	pgUpgradeTargetAnnotation = "apr.bytetrade.io/upgrade-to"
+
+	// the major version of the data directory without version suffix
+	pgLegacyMajorVersion = 11
+)
+
+// UpgradeCompatibleExtensions are the extensions which can be dumped and restored into
+// the new major version
+var UpgradeCompatibleExtensions = []string{
+	"citus", "citus_columnar", "plpgsql", "pg_trgm", "pgcrypto", "uuid-ossp", "hstore",
+	"btree_gin", "btree_gist", "fuzzystrmatch", "unaccent", "vector", "postgis",
+}
+
+// pgUpgradeDiskCheck succeeds if the data volume has DATA_KB available and the backup volume
+// has BACKUP_KB available, or the sum of them if they are the same file system
+var pgUpgradeDiskCheck = `set -e
+data=$(df -Pk /pgdata | awk 'NR==2 {print $4}')
+backup=$(df -Pk /pgbackup | awk 'NR==2 {print $4}')
+echo "available data: ${data}KB, backup: ${backup}KB, required data: ${DATA_KB}KB, backup: ${BACKUP_KB}KB"
+if [ "$(stat -c %d /pgdata)" = "$(stat -c %d /pgbackup)" ]; then
+  [ "$data" -ge $((DATA_KB + BACKUP_KB)) ]
+else
+  [ "$data" -ge "$DATA_KB" ] && [ "$backup" -ge "$BACKUP_KB" ]
+fi
+`
+
+// CitusMajorVersion returns the major version of the image tag, e.g. 11 of beclab/citus:11.3
+func CitusMajorVersion(image string) (int, error) {
+	tag := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(tag, ":")
+	if i < 0 {
+		return 0, fmt.Errorf("image %q has no version tag", image)
+	}
+
+	tag = strings.TrimPrefix(tag[i+1:], "v")
+	major, err := strconv.Atoi(strings.SplitN(tag, ".", 2)[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid version tag of image %q", image)
+	}
+
+	return major, nil
+}
+
+// PGDataDir returns the data directory of the major version, the new major version is
+// initialized in a new directory, and the data directory of the old one is kept for rollback
+func PGDataDir(major int) string {
+	if major <= pgLegacyMajorVersion {
+		return pgDataDirPrefix
+	}
+
+	return pgDataDirPrefix + "-citus" + strconv.Itoa(major)
+}
+
+// GetPGClusterImage returns the image defined by the cluster, or the default image
+func GetPGClusterImage(clusterDef *v1alpha1.PGCluster) string {
+	if clusterDef.Spec.CitusImage != "" {
+		return clusterDef.Spec.CitusImage
+	}
+
+	return CitusImage
+}
+
+// GetPGClusterWorkloadImage returns the image of the postgres container
+func GetPGClusterWorkloadImage(sts *appv1.StatefulSet) string {
+	for _, c := range sts.Spec.Template.Spec.Containers {
+		if c.Name == "postgres" {
+			return c.Image
+		}
+	}
+
+	return ""
+}
+
+// ApplyPGClusterImage sets the image, and the data directory of the image's major version. The data
+// directory is kept if the major version can not be told from the image, e.g. the latest tag or a digest
+func ApplyPGClusterImage(sts *appv1.StatefulSet, image string) {
+	major, err := CitusMajorVersion(image)
+	for i, c := range sts.Spec.Template.Spec.Containers {
+		if c.Name == "postgres" {
+			ptrC := &sts.Spec.Template.Spec.Containers[i]
+			ptrC.Image = image
+			if err != nil {
+				continue
+			}
+
+			for n, env := range c.Env {
+				if env.Name == "PGDATA" {
+					ptrC.Env[n].Value = PGDataDir(major)
+				}
+			}
+		}
+	}
+}
+
+// NewPGClusterBackup returns a backup define of the cluster, stored in the backup storage of the cluster
+func NewPGClusterBackup(ctx context.Context, client *kubernetes.Clientset, cluster *v1alpha1.PGCluster, name string) (*v1alpha1.PGClusterBackup, error) {
+	backup := ClusterBackup.DeepCopy()
+	backup.Name = name
+	backup.Namespace = cluster.Namespace
+	backup.Spec.ClusterName = cluster.Name
+
+	if cluster.Spec.BackupStorage != "" {
+		backup.Spec.VolumeSpec.HostPath.Path = cluster.Spec.BackupStorage
+		return backup, nil
+	}
+
+	klog.Info("find cluster hostpath, ", cluster.Namespace)
+	var pvc string
+	if cluster.Spec.Owner == "system" {
+		pvcRes, err := client.CoreV1().PersistentVolumeClaims(cluster.Namespace).Get(ctx, "citus-data-pvc", metav1.GetOptions{})
+		if err != nil {
+			klog.Error("find pg pvc error, ", err)
+			return nil, err
+		}
+
+		pvRes, err := client.CoreV1().PersistentVolumes().Get(ctx, pvcRes.Spec.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			klog.Error("backup find pg pv error, ", err)
+			return nil, err
+		}
+
+		pvc = pvRes.Spec.HostPath.Path
+	} else {
+		bflNamespace := "user-space-" + cluster.Spec.Owner
+		var err error
+		pvc, err = utils.GetUserDBPVCName(ctx, client, bflNamespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	backup.Spec.VolumeSpec.HostPath.Path = pvc + "/pg_backup"
+
+	return backup, nil
+}
+
+// PreflightPGClusterUpgrade checks the version, the installed extensions and the disk space
+// of the cluster before upgrading from the current image to the new image. Returns false if the
+// disk space is still being checked
+func PreflightPGClusterUpgrade(ctx context.Context, client *kubernetes.Clientset, sts *appv1.StatefulSet,
+	backup *v1alpha1.PGClusterBackup, fromImage, toImage, admin, pwd string) (bool, error) {
+	fromMajor, err := CitusMajorVersion(fromImage)
+	if err != nil {
+		return false, err
+	}
+
+	toMajor, err := CitusMajorVersion(toImage)
+	if err != nil {
+		return false, err
+	}
+
+	if toMajor < fromMajor {
+		return false, fmt.Errorf("downgrade from %s to %s is not supported", fromImage, toImage)
+	}
+
+	masterHost := sts.Name + "-0." + CitusHeadlessServiceName + "." + sts.Namespace
+	masterClient, err := postgres.NewClientBuidler(admin, pwd, masterHost, postgres.PG_PORT).Build()
+	if err != nil {
+		klog.Error("connect to master error, ", err, ", ", masterHost)
+		return false, err
+	}
+	defer masterClient.Close()
+
+	databases, err := masterClient.ListDatabases(ctx)
+	if err != nil {
+		klog.Error("list databases error, ", err)
+		return false, err
+	}
+
+	var size int64
+	for _, db := range databases {
+		dbSize, err := masterClient.DatabaseSize(ctx, db)
+		if err != nil {
+			return false, err
+		}
+		size += dbSize
+
+		if toMajor == fromMajor {
+			continue
+		}
+
+		if err = masterClient.SwitchDatabase(db); err != nil {
+			klog.Error("switch database error, ", err, ", ", db)
+			return false, err
+		}
+
+		extensions, err := masterClient.ListExtensions(ctx)
+		if err != nil {
+			klog.Error("list extensions error, ", err, ", ", db)
+			return false, err
+		}
+
+		for _, e := range extensions {
+			if !func() bool {
+				for _, compatible := range UpgradeCompatibleExtensions {
+					if e.Name == compatible {
+						return true
+					}
+				}
+				return false
+			}() {
+				return false, fmt.Errorf("extension %s %s in database %s is not compatible with the upgrade", e.Name, e.Version, db)
+			}
+		}
+	}
+
+	// the new major version is restored into a new data directory
+	var dataSize int64
+	if toMajor != fromMajor {
+		dataSize = size
+	}
+
+	return checkPGClusterDiskSpace(ctx, client, sts, backup, toImage, dataSize, size)
+}
+
+// checkPGClusterDiskSpace runs a job on the node of the coordinator to check the disk space, returns false
+// if the job is not finished
+func checkPGClusterDiskSpace(ctx context.Context, client *kubernetes.Clientset, sts *appv1.StatefulSet,
+	backup *v1alpha1.PGClusterBackup, image string, dataSize, backupSize int64) (bool, error) {
+	propagation := metav1.DeletePropagationBackground
+	deleteJob := func() error {
+		err := client.BatchV1().Jobs(sts.Namespace).Delete(ctx, pgUpgradePreflightJob, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Error("delete preflight job error, ", err)
+			return err
+		}
+
+		return nil
+	}
+
+	current, err := client.BatchV1().Jobs(sts.Namespace).Get(ctx, pgUpgradePreflightJob, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return false, createPGClusterDiskCheckJob(ctx, client, sts, backup, image, dataSize, backupSize)
+	case err != nil:
+		return false, err
+	case current.DeletionTimestamp != nil:
+		return false, nil
+	case current.Annotations[pgUpgradeTargetAnnotation] != image:
+		// the job of the previous upgrade
+		return false, deleteJob()
+	case current.Status.Succeeded > 0:
+		return true, deleteJob()
+	case current.Status.Failed > 0:
+		if err = deleteJob(); err != nil {
+			return false, err
+		}
+
+		return false, fmt.Errorf("insufficient disk space, %s for data and %s for backup required",
+			resource.NewQuantity(dataSize, resource.BinarySI), resource.NewQuantity(backupSize, resource.BinarySI))
+	}
+
+	return false, nil
+}
+
+func createPGClusterDiskCheckJob(ctx context.Context, client *kubernetes.Clientset, sts *appv1.StatefulSet,
+	backup *v1alpha1.PGClusterBackup, image string, dataSize, backupSize int64) error {
+	masterPod, err := client.CoreV1().Pods(sts.Namespace).Get(ctx, sts.Name+"-0", metav1.GetOptions{})
+	if err != nil {
+		klog.Error("get master pod error, ", err)
+		return err
+	}
+
+	dataVolume := corev1.Volume{Name: CitusVolumeName}
+	for _, vol := range masterPod.Spec.Volumes {
+		if vol.Name == CitusVolumeName {
+			dataVolume.VolumeSource = vol.VolumeSource
+		}
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pgUpgradePreflightJob,
+			Namespace: sts.Namespace,
+			Annotations: map[string]string{
+				pgUpgradeTargetAnnotation: image,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            pointer.Int32(0),
+			TTLSecondsAfterFinished: &jobTTL,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					NodeName:      masterPod.Spec.NodeName,
+					Containers: []corev1.Container{
+						{
+							Name:            "preflight",
+							Image:           image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Env: []corev1.EnvVar{
+								{
+									Name:  "DATA_KB",
+									Value: strconv.FormatInt(dataSize/1024, 10),
+								},
+								{
+									Name:  "BACKUP_KB",
+									Value: strconv.FormatInt(backupSize/1024+1, 10),
+								},
+							},
+							Command: []string{"sh", "-c", pgUpgradeDiskCheck},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      CitusVolumeName,
+									MountPath: "/pgdata",
+								},
+								{
+									Name:      CitusBackupVolumeName,
+									MountPath: "/pgbackup",
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						dataVolume,
+						{
+							Name:         CitusBackupVolumeName,
+							VolumeSource: backup.Spec.VolumeSpec.VolumeSource,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	klog.Info("check the disk space of pg cluster, ", dataSize, ", ", backupSize)
+	if _, err = client.BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		klog.Error("create preflight job error, ", err)
+		return err
+	}
+
+	return nil
+}
+
+// PGClusterBackupReady returns true if the backup is ready, or an error if the backup failed
+func PGClusterBackupReady(ctx context.Context, client *aprclientset.Clientset, namespace, name string) (bool, error) {
+	backup, err := client.AprV1alpha1().PGClusterBackups(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.Error("get pg backup error, ", err, ", ", name)
+		return false, err
+	}
+
+	switch backup.Status.State {
+	case v1alpha1.BackupStateReady:
+		return true, nil
+	case v1alpha1.BackupStateRejected, v1alpha1.BackupStateError:
+		return false, fmt.Errorf("backup pg failed, %s, %s, %s", backup.Status.Error, backup.Name, backup.Namespace)
+	}
+
+	return false, nil
+}
+
+// PGClusterRestoreReady returns true if the restore is ready, or an error if the restore failed
+func PGClusterRestoreReady(ctx context.Context, client *aprclientset.Clientset, namespace, name string) (bool, error) {
+	restore, err := client.AprV1alpha1().PGClusterRestores(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.Error("get pg restore error, ", err, ", ", name)
+		return false, err
+	}
+
+	switch restore.Status.State {
+	case v1alpha1.RestoreStateReady:
+		return true, nil
+	case v1alpha1.RestoreStateRejected, v1alpha1.RestoreStateError:
+		return false, fmt.Errorf("restore pg failed, %s, %s, %s", restore.Status.Error, restore.Name, restore.Namespace)
+	}
+
+	return false, nil
+}
+
+// SetPGClusterImage updates the image of the cluster, nothing to do if the image is set already
+func SetPGClusterImage(ctx context.Context, client *kubernetes.Clientset, namespace, image string) error {
+	sts, err := client.AppsV1().StatefulSets(namespace).Get(ctx, PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if GetPGClusterWorkloadImage(sts) == image {
+		return nil
+	}
+
+	ApplyPGClusterImage(sts, image)
+
+	klog.Info("update pg cluster image, ", namespace, ", ", image)
+	_, err = client.AppsV1().StatefulSets(namespace).Update(ctx, sts, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Error("update pg cluster image error, ", err)
+	}
+
+	return err
+}
+
+// PGClusterRolledOut returns true if all nodes of the cluster are running the current workload define
+func PGClusterRolledOut(ctx context.Context, client *kubernetes.Clientset, namespace string) (bool, error) {
+	current, err := client.AppsV1().StatefulSets(namespace).Get(ctx, PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	status := current.Status
+	replicas := *current.Spec.Replicas
+	return status.ObservedGeneration >= current.Generation &&
+		status.UpdatedReplicas == replicas &&
+		status.ReadyReplicas == replicas &&
+		status.CurrentRevision == status.UpdateRevision, nil
+}
+
+// UpdatePGClusterExtensions updates the citus extension in all databases on all nodes
+func UpdatePGClusterExtensions(ctx context.Context, client *kubernetes.Clientset, cluster *v1alpha1.PGCluster, admin, pwd string) error {
+	namespace := cluster.Namespace
+	sts, err := client.AppsV1().StatefulSets(namespace).Get(ctx, PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// the workers first, the coordinator checks the version of the workers
+	index := *sts.Spec.Replicas - 1
+	for index >= 0 {
+		nodeHost := GetNodeHost(cluster, namespace, index)
+		if err = func() error {
+			nodeClient, err := postgres.NewClientBuidler(admin, pwd, nodeHost, postgres.PG_PORT).Build()
+			if err != nil {
+				klog.Error("connect to pg node error, ", err, ", ", nodeHost)
+				return err
+			}
+			defer nodeClient.Close()
+
+			databases, err := nodeClient.ListDatabases(ctx)
+			if err != nil {
+				return err
+			}
+
+			for _, db := range databases {
+				if err = nodeClient.SwitchDatabase(db); err != nil {
+					klog.Error("switch database error, ", err, ", ", nodeHost, ", ", db)
+					return err
+				}
+
+				klog.Info("update citus extension, ", nodeHost, ", ", db)
+				if err = nodeClient.UpdateExtension(ctx, "citus"); err != nil {
+					klog.Error("update citus extension error, ", err, ", ", nodeHost, ", ", db)
+					return err
+				}
+			}
+
+			return nil
+		}(); err != nil {
+			return err
+		}
+
+		index -= 1
+	}
+
+	return nil
+}
+
+// SetPGClusterHBA sets the hba config of all nodes, the standby is trusted if it is configured
+func SetPGClusterHBA(ctx context.Context, client *kubernetes.Clientset, namespace, admin, pwd string, standby bool) error {
+	sts, err := client.AppsV1().StatefulSets(namespace).Get(ctx, PGClusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	var index int32 = 0
+	for index < *sts.Spec.Replicas {
+		podName := PGClusterName + "-" + strconv.Itoa(int(index))
+		ip, err := WaitForPodRunning(ctx, client, namespace, podName)
+		if err != nil {
+			return err
+		}
+
+		if err = SetPGNodeHBA(ctx, namespace, podName, ip, admin, pwd, standby); err != nil {
+			return err
+		}
+
+		index += 1
+	}
+
+	return nil
+}
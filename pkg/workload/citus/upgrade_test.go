@@ -0,0 +1,69 @@
+package citus
+
+import (
+	"testing"
+
+	appv1 "k8s.io/api/apps/v1"
+)
+
+func TestCitusMajorVersion(t *testing.T) {
+	for image, expected := range map[string]int{
+		"beclab/citus:11.3":                 11,
+		"beclab/citus:12":                   12,
+		"registry.local:5000/citus:v13.0.1": 13,
+	} {
+		major, err := CitusMajorVersion(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if major != expected {
+			t.Errorf("unexpected major version of %s, %d", image, major)
+		}
+	}
+
+	for _, image := range []string{"beclab/citus", "registry.local:5000/citus", "beclab/citus:latest"} {
+		if _, err := CitusMajorVersion(image); err == nil {
+			t.Errorf("expected error of %s", image)
+		}
+	}
+}
+
+func TestApplyPGClusterImage(t *testing.T) {
+	sts := CitusStatefulset.DeepCopy()
+	ApplyPGClusterImage(sts, "beclab/citus:12.1")
+
+	if image := GetPGClusterWorkloadImage(sts); image != "beclab/citus:12.1" {
+		t.Errorf("unexpected image %s", image)
+	}
+
+	if dir := pgDataEnv(sts); dir != "/var/lib/postgresql/data/$(POD_NAME)-citus12" {
+		t.Errorf("unexpected data directory %s", dir)
+	}
+
+	// rollback to the legacy data directory
+	ApplyPGClusterImage(sts, CitusImage)
+	if dir := pgDataEnv(sts); dir != "/var/lib/postgresql/data/$(POD_NAME)" {
+		t.Errorf("unexpected data directory %s", dir)
+	}
+
+	// the data directory is kept for the image without a version tag
+	ApplyPGClusterImage(sts, "beclab/citus:latest")
+	if image := GetPGClusterWorkloadImage(sts); image != "beclab/citus:latest" {
+		t.Errorf("unexpected image %s", image)
+	}
+
+	if dir := pgDataEnv(sts); dir != "/var/lib/postgresql/data/$(POD_NAME)" {
+		t.Errorf("unexpected data directory %s", dir)
+	}
+}
+
+func pgDataEnv(sts *appv1.StatefulSet) string {
+	for _, env := range sts.Spec.Template.Spec.Containers[0].Env {
+		if env.Name == "PGDATA" {
+			return env.Value
+		}
+	}
+
+	return ""
+}
@@ -12,7 +12,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-func ListRabbitMQClusters(ctx context.Context, ctrlClient client.Client, namespace string) (clusters []kbappsv1.Cluster, err error) {
+func ListElasticsearchClusters(ctx context.Context, ctrlClient client.Client, namespace string) (clusters []kbappsv1.Cluster, err error) {
 	var clusterList kbappsv1.ClusterList
 	err = ctrlClient.List(ctx, &clusterList)
 	if err != nil {
@@ -2,12 +2,14 @@ package kvrocks
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	"bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+	"bytetrade.io/web3os/tapr/pkg/certs"
 	aprclientset "bytetrade.io/web3os/tapr/pkg/generated/clientset/versioned"
 	"bytetrade.io/web3os/tapr/pkg/workload/utils"
 	redis "github.com/go-redis/redis/v8"
@@ -20,6 +22,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/keymutex"
 )
 
 func GetKVRocksDefineByUser(ctx context.Context, client *kubernetes.Clientset,
@@ -114,6 +117,13 @@ func GetKVRocksDefineByUser(ctx context.Context, client *kubernetes.Clientset,
 			append(sts.Spec.Template.Spec.Containers[0].Command, []string{"--requirepass", password}...)
 	}
 
+	secret, _, err := EnsureKVRocksCertificates(ctx, client, namespace)
+	if err != nil {
+		klog.Error("issue kvrocks certificates error, ", err)
+		return nil, err
+	}
+	applyKVRocksTLS(sts, secret)
+
 	return sts, nil
 
 }
@@ -144,7 +154,7 @@ func createKVRocks(ctx context.Context, client *kubernetes.Clientset,
 
 func createKVRocksService(ctx context.Context, client *kubernetes.Clientset, clusterName, namespace string) error {
 	svcName := getServiceName(clusterName)
-	_, err := client.CoreV1().Services(namespace).Get(ctx, svcName, metav1.GetOptions{})
+	svc, err := client.CoreV1().Services(namespace).Get(ctx, svcName, metav1.GetOptions{})
 
 	if err != nil && !apierrors.IsNotFound(err) {
 		return err
@@ -159,6 +169,32 @@ func createKVRocksService(ctx context.Context, client *kubernetes.Clientset, clu
 		if err != nil {
 			return err
 		}
+
+		return nil
+	}
+
+	// add the ports of the new version, e.g. tls
+	missing := false
+	for _, port := range KVRocksService.Spec.Ports {
+		found := false
+		for _, p := range svc.Spec.Ports {
+			if p.Name == port.Name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			svc.Spec.Ports = append(svc.Spec.Ports, port)
+			missing = true
+		}
+	}
+
+	if missing {
+		_, err = client.CoreV1().Services(namespace).Update(ctx, svc, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -295,6 +331,16 @@ func getServiceName(_ string) string {
 	return "redis-cluster-proxy"
 }
 
+// clusterLocks serializes the backups and restores of the same cluster
+var clusterLocks = keymutex.NewHashed(0)
+
+// LockCluster locks the cluster for a backup or restore, and returns the function to unlock it
+func LockCluster(namespace, name string) func() {
+	key := namespace + "/" + name
+	clusterLocks.LockKey(key)
+	return func() { clusterLocks.UnlockKey(key) }
+}
+
 func BackupKVRocks(ctx context.Context,
 	client *kubernetes.Clientset,
 	clusterDef *v1alpha1.RedixCluster,
@@ -637,11 +683,23 @@ func GetKVRocksClient(ctx context.Context, client *kubernetes.Clientset,
 
 	svcName := getServiceName(clusterDef.Name)
 	svcPort := KVRocksService.Spec.Ports[0].Port
+
+	// connect to the tls port after the pod is rolled out with tls
+	tlsConfig, err := getKVRocksTLSConfig(ctx, client, clusterDef)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		svcPort = KVRocksTLSServicePort
+		tlsConfig.ServerName = svcName
+	}
 	klog.Info("find kvrocks service, ", svcName, ":", svcPort)
 
 	cli := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", svcName, svcPort),
-		Password: password,
+		Addr:      fmt.Sprintf("%s:%d", svcName, svcPort),
+		Password:  password,
+		TLSConfig: tlsConfig,
 		// other options with default
 	})
 
@@ -653,6 +711,29 @@ func GetKVRocksClient(ctx context.Context, client *kubernetes.Clientset,
 	return &kvrClient{cli}, nil
 }
 
+func getKVRocksTLSConfig(ctx context.Context, client *kubernetes.Clientset,
+	clusterDef *v1alpha1.RedixCluster) (*tls.Config, error) {
+	sts, err := client.AppsV1().StatefulSets(clusterDef.Namespace).Get(ctx, clusterDef.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !IsKVRocksTLSReady(sts) {
+		return nil, nil
+	}
+
+	bundle, err := certs.GetCABundle(ctx, client, clusterDef.Namespace, KVRocksCertName)
+	if err != nil {
+		klog.Error("get kvrocks ca bundle error, ", err)
+		return nil, err
+	}
+
+	return certs.NewClientTLSConfig(bundle)
+}
+
 func (cli *kvrClient) Namespace(ctx context.Context, arg ...interface{}) *redis.Cmd {
 	return cli.Do(ctx, append([]interface{}{"namespace"}, arg...)...)
 }
@@ -702,6 +783,26 @@ func (cli *kvrClient) ListNamespace(ctx context.Context) ([]*Namespace, error) {
 	return ns, nil
 }
 
+// NamespaceKeys returns the number of the keys in the namespace counted by the last scan, and starts a new scan.
+// The namespaces share the column families of kvrocks, the disk usage is not tracked per namespace
+func (cli *kvrClient) NamespaceKeys(ctx context.Context, token string) (int64, error) {
+	opts := *cli.Options()
+	opts.Password = token
+	nsCli := redis.NewClient(&opts)
+	defer nsCli.Close()
+
+	keys, err := nsCli.DBSize(ctx).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if err = nsCli.Do(ctx, "dbsize", "scan").Err(); err != nil {
+		klog.Warning("start kvrocks namespace scan error, ", err)
+	}
+
+	return keys, nil
+}
+
 func (cli *kvrClient) UpdateNamespace(ctx context.Context, namespace, token string) error {
 	if err := cli.Namespace(ctx, "set", namespace, token).Err(); err != nil {
 		return err
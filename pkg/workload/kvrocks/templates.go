@@ -20,6 +20,15 @@ const (
 	KVRocksConfDir          = "/var/lib/kvrocks"
 	KVRocksBackupName       = "kvrocks-backup"
 	KVRocksRestoreName      = "kvrocks-restore"
+	KVRocksTLSDir           = "/etc/kvrocks/tls"
+	KVRocksTLSPort          = 6667
+	KVRocksTLSServicePort   = 6380
+
+	// KVRocksCertName names the certificate secrets, the kvrocks clusters in a namespace share one service
+	KVRocksCertName = "kvrocks"
+
+	kvrocksTLSVolumeName         = "kvrocks-tls"
+	kvrocksTLSChecksumAnnotation = "apr.bytetrade.io/tls-checksum"
 )
 
 var (
@@ -46,7 +55,7 @@ var (
 						"app":                         "kvrocks",
 						"app.kubernetes.io/name":      "kvrocks",
 						"app.bytetrade.io/middleware": "true",
-						"pod-template-version":        "v1.1",
+						"pod-template-version":        "v1.2",
 					},
 				},
 
@@ -189,6 +198,12 @@ var (
 					Protocol:   corev1.ProtocolTCP,
 					TargetPort: intstr.FromInt(6666),
 				},
+				{
+					Name:       "kvrocks-tls",
+					Port:       KVRocksTLSServicePort,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(KVRocksTLSPort),
+				},
 			},
 		},
 	}
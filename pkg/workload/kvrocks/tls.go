@@ -0,0 +1,79 @@
+package kvrocks
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"bytetrade.io/web3os/tapr/pkg/certs"
+
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// KVRocksDNSNames returns the names of the kvrocks service
+func KVRocksDNSNames(namespace string) []string {
+	svcName := getServiceName("")
+	return []string{
+		svcName,
+		svcName + "." + namespace,
+		svcName + "." + namespace + ".svc",
+		svcName + "." + namespace + ".svc.cluster.local",
+		"localhost",
+	}
+}
+
+// EnsureKVRocksCertificates issues the certificates of the kvrocks in the namespace, and renews them before expiry
+func EnsureKVRocksCertificates(ctx context.Context, client *kubernetes.Clientset, namespace string) (*corev1.Secret, bool, error) {
+	return certs.EnsureCertificates(ctx, client, namespace, KVRocksCertName, KVRocksDNSNames(namespace))
+}
+
+// applyKVRocksTLS serves tls on a separate port besides the plain port, kvrocks does not reload the
+// certificates, so the checksum of the certificate restarts the pod when it is renewed
+func applyKVRocksTLS(sts *appv1.StatefulSet, secret *corev1.Secret) {
+	podTemplate := &sts.Spec.Template
+	if podTemplate.Annotations == nil {
+		podTemplate.Annotations = make(map[string]string)
+	}
+	podTemplate.Annotations[kvrocksTLSChecksumAnnotation] = fmt.Sprintf("%x", sha256.Sum256(secret.Data[corev1.TLSCertKey]))
+
+	podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, corev1.Volume{
+		Name: kvrocksTLSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: secret.Name},
+		},
+	})
+
+	for i, c := range podTemplate.Spec.Containers {
+		if c.Name != "kvrocks" {
+			continue
+		}
+
+		ptrC := &podTemplate.Spec.Containers[i]
+		ptrC.Command = append(ptrC.Command,
+			"--tls-port", fmt.Sprintf("%d", KVRocksTLSPort),
+			"--tls-cert-file", KVRocksTLSDir+"/"+corev1.TLSCertKey,
+			"--tls-key-file", KVRocksTLSDir+"/"+corev1.TLSPrivateKeyKey,
+			"--tls-ca-cert-file", KVRocksTLSDir+"/"+certs.CACertKey,
+			"--tls-auth-clients", "no",
+		)
+		ptrC.Ports = append(ptrC.Ports, corev1.ContainerPort{
+			Name:          "kvrocks-tls",
+			Protocol:      corev1.ProtocolTCP,
+			ContainerPort: KVRocksTLSPort,
+		})
+		ptrC.VolumeMounts = append(ptrC.VolumeMounts, corev1.VolumeMount{
+			Name:      kvrocksTLSVolumeName,
+			MountPath: KVRocksTLSDir,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// IsKVRocksTLSReady returns true if the pod is rolled out with tls
+func IsKVRocksTLSReady(sts *appv1.StatefulSet) bool {
+	_, ok := sts.Spec.Template.Annotations[kvrocksTLSChecksumAnnotation]
+	return ok && sts.Status.ObservedGeneration >= sts.Generation &&
+		sts.Status.UpdateRevision == sts.Status.CurrentRevision && sts.Status.ReadyReplicas > 0
+}
@@ -44,6 +44,14 @@ const tmpl = `{
       ]
     }
   },
+  {{- if .TLS }}
+  "tls": {
+    "cert_file": "{{ .TLS.CertFile }}",
+    "key_file": "{{ .TLS.KeyFile }}",
+    "ca_file": "{{ .TLS.CAFile }}"
+  },
+  "allow_non_tls": {{ .AllowNonTLS }},
+  {{- end }}
   "port": {{ .Port }},
   "pid_file": "{{ .PidFile }}"
   "server_name": "{{ .ServerName }}"
@@ -2,6 +2,8 @@ package nats
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -62,3 +64,42 @@ func TestRenderConfigFile(t *testing.T) {
 	}
 	fmt.Println(string(data))
 }
+
+func TestRenderTLSConfigFile(t *testing.T) {
+	t.Setenv("ADMIN_PASSWORD", "admin")
+	config := Config{
+		Port:       4222,
+		PidFile:    "/var/run/nats/nats.pid",
+		ServerName: "nats-0",
+		Accounts: Accounts{
+			Terminus: Terminus{
+				Users: []User{{Username: "admin"}},
+			},
+		},
+		TLS: &TLS{
+			CertFile: TLSDir + "/tls.crt",
+			KeyFile:  TLSDir + "/tls.key",
+			CAFile:   TLSDir + "/ca.crt",
+		},
+		AllowNonTLS: true,
+	}
+
+	data, err := renderConfigFile(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "nats.conf")
+	if err = os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.TLS == nil || *parsed.TLS != *config.TLS || !parsed.AllowNonTLS {
+		t.Errorf("unexpected tls config %+v, %v", parsed.TLS, parsed.AllowNonTLS)
+	}
+}
@@ -0,0 +1,70 @@
+package nats
+
+import (
+	"fmt"
+	"sort"
+
+	aprv1 "bytetrade.io/web3os/tapr/pkg/apis/apr/v1alpha1"
+)
+
+// DiffUser compares the user and the permissions in the config with the request,
+// returns the differences, empty if the config is in sync
+func DiffUser(request *aprv1.MiddlewareRequest, namespace string) ([]string, error) {
+	allowPub, allowSub, err := getAllowPubSubSubjectFromMR(request, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := loadConf()
+	if err != nil {
+		return nil, err
+	}
+
+	return diffUser(config, request.Spec.Nats.User, allowPub, allowSub), nil
+}
+
+func diffUser(config *Config, username string, allowPub, allowSub []string) []string {
+	var user *User
+	for i, u := range config.Accounts.Terminus.Users {
+		if u.Username == username {
+			user = &config.Accounts.Terminus.Users[i]
+			break
+		}
+	}
+
+	if user == nil {
+		return []string{fmt.Sprintf("nats user %s not found", username)}
+	}
+
+	var diffs []string
+	if missing, extra := diffSubjects(allowPub, user.Permissions.Publish.Allow); len(missing) > 0 || len(extra) > 0 {
+		diffs = append(diffs, fmt.Sprintf("nats user %s publish permissions drifted, missing %v, unexpected %v", username, missing, extra))
+	}
+
+	if missing, extra := diffSubjects(allowSub, user.Permissions.Subscribe.Allow); len(missing) > 0 || len(extra) > 0 {
+		diffs = append(diffs, fmt.Sprintf("nats user %s subscribe permissions drifted, missing %v, unexpected %v", username, missing, extra))
+	}
+
+	return diffs
+}
+
+func diffSubjects(expected, actual []string) (missing, extra []string) {
+	set := make(map[string]bool)
+	for _, s := range actual {
+		set[s] = true
+	}
+
+	for _, s := range expected {
+		if !set[s] {
+			missing = append(missing, s)
+		}
+		delete(set, s)
+	}
+
+	for s := range set {
+		extra = append(extra, s)
+	}
+	sort.Strings(extra)
+
+	return missing, extra
+}
@@ -0,0 +1,40 @@
+package nats
+
+import (
+	"testing"
+)
+
+func TestDiffUser(t *testing.T) {
+	config := &Config{
+		Accounts: Accounts{
+			Terminus: Terminus{
+				Users: []User{
+					{
+						Username: "user1",
+						Permissions: Permissions{
+							Publish:   Publish{Allow: []string{"subject1", "subject2"}},
+							Subscribe: Subscribe{Allow: []string{"subject1"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if diffs := diffUser(config, "user1", []string{"subject2", "subject1"}, []string{"subject1"}); len(diffs) != 0 {
+		t.Errorf("unexpected diffs %v", diffs)
+	}
+
+	if diffs := diffUser(config, "user2", nil, nil); len(diffs) != 1 {
+		t.Errorf("expected the user not found, %v", diffs)
+	}
+
+	diffs := diffUser(config, "user1", []string{"subject1"}, []string{"subject1", "subject3"})
+	if len(diffs) != 2 {
+		t.Fatalf("expected both permissions drifted, %v", diffs)
+	}
+
+	if diffs[0] != "nats user user1 publish permissions drifted, missing [], unexpected [subject2]" {
+		t.Errorf("unexpected diff %s", diffs[0])
+	}
+}
@@ -99,6 +99,10 @@ func getAllowPubSubSubjectFromMR(request *aprv1.MiddlewareRequest, namespace str
 
 	}
 	for i, ref := range req.Refs {
+		if ref.AppName == SysEventApp {
+			continue
+		}
+
 		for j, s := range ref.Subjects {
 			req.Refs[i].Subjects[j].Name = MakeRealNameForRefSubjectName(ref.AppNamespace, ref.AppName, s.Name, GetOwnerNameFromNs(request.Namespace))
 		}
@@ -157,6 +161,20 @@ func getAllowPubSubSubjectFromMR(request *aprv1.MiddlewareRequest, namespace str
 	klog.Infof("req.Nats: %#v", req)
 
 	for _, ref := range req.Refs {
+		// the sys events are subscribed only, limited to the events of the owner and of the cluster
+		if ref.AppName == SysEventApp {
+			for _, subject := range ref.Subjects {
+				if funk.Contains(subject.Perm, "pub") {
+					return allowPubSubject, allowSubSubject, fmt.Errorf("sys event subject %s can not be published", subject.Name)
+				}
+
+				if funk.Contains(subject.Perm, "sub") {
+					allowSubSubject = append(allowSubSubject, sysEventSubjects(subject.Name, GetOwnerNameFromNs(request.Namespace))...)
+				}
+			}
+			continue
+		}
+
 		for _, subject := range ref.Subjects {
 			if _, ok := appExportMap[request.Spec.App]; !ok {
 				return allowPubSubject, allowPubSubject, errors.New("not found export permission")
@@ -206,13 +224,21 @@ func getAllowPubSubSubjectFromMR(request *aprv1.MiddlewareRequest, namespace str
 	return allowPubSubject, allowSubSubject, nil
 }
 
+// OSStreamConfig is the stream of the os subjects, shared by the apps and the sys events
+var OSStreamConfig = jetstream.StreamConfig{
+	Name:     "os-stream",
+	Subjects: []string{"os.>"},
+	Storage:  jetstream.FileStorage,
+	MaxAge:   24 * 60 * 60 * time.Second,
+}
+
 func CreateOrUpdateStream(appNamespace, app string) error {
 	//name := fmt.Sprintf("%s-%s", appNamespace, app)
 	adminPassword, err := getAdminPassword()
 	if err != nil {
 		return err
 	}
-	nc, err := nats.Connect("nats://nats."+constants.PlatformNamespace, nats.UserInfo("admin", adminPassword))
+	nc, err := connect("admin", adminPassword)
 	if err != nil {
 		return err
 	}
@@ -221,15 +247,9 @@ func CreateOrUpdateStream(appNamespace, app string) error {
 	if err != nil {
 		return err
 	}
-	cfg := jetstream.StreamConfig{
-		Name:     "os-stream",
-		Subjects: []string{"os.>"},
-		Storage:  jetstream.FileStorage,
-		MaxAge:   24 * 60 * 60 * time.Second,
-	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	_, err = js.CreateStream(ctx, cfg)
+	_, err = js.CreateStream(ctx, OSStreamConfig)
 	if err != nil && !errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
 		klog.Errorf("create os-stream failed %v", err)
 		return err
@@ -243,7 +263,7 @@ func DeleteStream(appNamespace, app string) error {
 	if err != nil {
 		return err
 	}
-	nc, err := nats.Connect("nats://nats."+constants.PlatformNamespace, nats.UserInfo("admin", adminPassword))
+	nc, err := connect("admin", adminPassword)
 	if err != nil {
 		return err
 	}
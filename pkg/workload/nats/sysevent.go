@@ -0,0 +1,49 @@
+package nats
+
+import "strings"
+
+const (
+	// SysEventApp is the app name of the refs to the sys events, e.g.
+	// refs: [{appName: sys-event, subjects: [{name: app.*, perm: [sub]}]}]
+	SysEventApp = "sys-event"
+
+	// SysEventSubjectPrefix is the prefix of the subjects of the sys events in the os stream
+	SysEventSubjectPrefix = "os.sys"
+
+	// SysEventClusterToken is the user token of the subjects of the events not owned by any user
+	SysEventClusterToken = "_cluster"
+)
+
+// SysEventSubject returns the subject of the event, e.g. os.sys.app.install.alice
+func SysEventSubject(eventType, user string) string {
+	if user == "" {
+		user = SysEventClusterToken
+	}
+
+	return SysEventSubjectPrefix + "." + subjectToken(eventType, true) + "." + subjectToken(user, false)
+}
+
+// sysEventSubjects returns the subjects of the events of the type the owner is allowed to subscribe,
+// the events of the owner and of the cluster. The type follows the nats wildcards, e.g. app.* matches
+// app.install, and metrics.user.*.high matches metrics.user.cpu.high
+func sysEventSubjects(eventType, owner string) []string {
+	prefix := SysEventSubjectPrefix + "." + strings.ReplaceAll(eventType, ">", "*")
+	return []string{
+		prefix + "." + subjectToken(owner, false),
+		prefix + "." + SysEventClusterToken,
+	}
+}
+
+// subjectToken replaces the characters not allowed in the subject tokens,
+// the dots are kept in the event type to make its segments tokens
+func subjectToken(s string, keepDots bool) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '.' && keepDots:
+			return r
+		case r == '.', r == '*', r == '>', r == ' ', r == '\t', r == '\r', r == '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,33 @@
+package nats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSysEventSubject(t *testing.T) {
+	if s := SysEventSubject("app.install", "alice"); s != "os.sys.app.install.alice" {
+		t.Errorf("unexpected subject %s", s)
+	}
+
+	if s := SysEventSubject("metrics.cpu.high", ""); s != "os.sys.metrics.cpu.high._cluster" {
+		t.Errorf("unexpected cluster subject %s", s)
+	}
+
+	if s := SysEventSubject("custom.event", "a.b*"); s != "os.sys.custom.event.a_b_" {
+		t.Errorf("expected the user token escaped, %s", s)
+	}
+}
+
+func TestSysEventSubjects(t *testing.T) {
+	expected := []string{"os.sys.app.*.alice", "os.sys.app.*._cluster"}
+	if s := sysEventSubjects("app.*", "alice"); !reflect.DeepEqual(s, expected) {
+		t.Errorf("unexpected subjects %v", s)
+	}
+
+	// the full wildcard is not allowed across the user token
+	expected = []string{"os.sys.*.alice", "os.sys.*._cluster"}
+	if s := sysEventSubjects(">", "alice"); !reflect.DeepEqual(s, expected) {
+		t.Errorf("unexpected subjects %v", s)
+	}
+}
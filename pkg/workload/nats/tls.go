@@ -0,0 +1,94 @@
+package nats
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"bytetrade.io/web3os/tapr/pkg/certs"
+	"bytetrade.io/web3os/tapr/pkg/constants"
+
+	"github.com/nats-io/nats.go"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// NatsCertName names the certificate secrets of the nats server
+	NatsCertName = "nats"
+
+	// TLSDir is next to the config, the server and the operator share the directory
+	TLSDir = "/dbdata/nats_data/config/tls"
+)
+
+// NatsDNSNames returns the names of the nats service and the pods
+func NatsDNSNames(namespace string) []string {
+	var names []string
+	for _, name := range []string{"nats." + namespace, "*.nats-headless." + namespace} {
+		names = append(names, name, name+".svc", name+".svc.cluster.local")
+	}
+
+	return append(names, "localhost")
+}
+
+// EnableTLS writes the certificates next to the config, and turns on tls of the server.
+// The plain connections are still allowed for the clients not upgraded yet.
+func EnableTLS(secret *corev1.Secret) error {
+	if err := os.MkdirAll(TLSDir, 0700); err != nil {
+		return err
+	}
+
+	filesChanged := false
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey, certs.CACertKey} {
+		changed, err := writeFileIfChanged(filepath.Join(TLSDir, key), secret.Data[key])
+		if err != nil {
+			klog.Error("write nats certificate error, ", err, ", ", key)
+			return err
+		}
+		filesChanged = filesChanged || changed
+	}
+
+	config, err := loadConf()
+	if err != nil {
+		return err
+	}
+
+	tls := &TLS{
+		CertFile: filepath.Join(TLSDir, corev1.TLSCertKey),
+		KeyFile:  filepath.Join(TLSDir, corev1.TLSPrivateKeyKey),
+		CAFile:   filepath.Join(TLSDir, certs.CACertKey),
+	}
+
+	if !filesChanged && config.TLS != nil && *config.TLS == *tls && config.AllowNonTLS {
+		return nil
+	}
+
+	// the server reloads the certificates with the config
+	klog.Info("enable nats tls")
+	config.TLS = tls
+	config.AllowNonTLS = true
+	return RenderConfigFile(config)
+}
+
+// connect connects to the nats server with tls if it is enabled
+func connect(user, password string) (*nats.Conn, error) {
+	url := "nats://nats." + constants.PlatformNamespace
+	opts := []nats.Option{nats.UserInfo(user, password)}
+
+	caFile := filepath.Join(TLSDir, certs.CACertKey)
+	if _, err := os.Stat(caFile); err == nil {
+		url = "tls://nats." + constants.PlatformNamespace
+		opts = append(opts, nats.RootCAs(caFile))
+	}
+
+	return nats.Connect(url, opts...)
+}
+
+func writeFileIfChanged(file string, data []byte) (bool, error) {
+	current, err := os.ReadFile(file)
+	if err == nil && bytes.Equal(current, data) {
+		return false, nil
+	}
+
+	return true, os.WriteFile(file, data, 0600)
+}
@@ -7,6 +7,15 @@ type Config struct {
 	Port       int       `json:"port" mapstructure:"port"`
 	PidFile    string    `json:"pid_file" mapstructure:"pid_file"`
 	ServerName string    `json:"server_name" mapstructure:"server_name"`
+
+	TLS         *TLS `json:"tls,omitempty" mapstructure:"tls"`
+	AllowNonTLS bool `json:"allow_non_tls" mapstructure:"allow_non_tls"`
+}
+
+type TLS struct {
+	CertFile string `json:"cert_file" mapstructure:"cert_file"`
+	KeyFile  string `json:"key_file" mapstructure:"key_file"`
+	CAFile   string `json:"ca_file" mapstructure:"ca_file"`
 }
 
 type Jetstream struct {
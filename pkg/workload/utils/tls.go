@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bytetrade.io/web3os/tapr/pkg/certs"
+
+	kbappsv1 "github.com/apecloud/kubeblocks/apis/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// KBClusterDNSNames returns the names of the services and the pods of the kubeblocks cluster
+func KBClusterDNSNames(cluster *kbappsv1.Cluster) []string {
+	hosts := []string{"*." + cluster.Namespace}
+	for _, comp := range cluster.Spec.ComponentSpecs {
+		hosts = append(hosts, "*."+cluster.Name+"-"+comp.Name+"-headless."+cluster.Namespace)
+	}
+
+	var names []string
+	for _, host := range hosts {
+		names = append(names, host, host+".svc", host+".svc.cluster.local")
+	}
+
+	return append(names, "localhost")
+}
+
+// EnableKBClusterTLS makes all components of the cluster serve tls with the certificates in the secret,
+// returns true if the cluster is changed
+func EnableKBClusterTLS(cluster *kbappsv1.Cluster, secretName string) bool {
+	issuer := &kbappsv1.Issuer{
+		Name: kbappsv1.IssuerUserProvided,
+		SecretRef: &kbappsv1.TLSSecretRef{
+			Namespace: cluster.Namespace,
+			Name:      secretName,
+			CA:        certs.CACertKey,
+			Cert:      corev1.TLSCertKey,
+			Key:       corev1.TLSPrivateKeyKey,
+		},
+	}
+
+	changed := false
+	for i, comp := range cluster.Spec.ComponentSpecs {
+		if comp.TLS && isIssuedBy(comp.Issuer, issuer) {
+			continue
+		}
+
+		cluster.Spec.ComponentSpecs[i].TLS = true
+		cluster.Spec.ComponentSpecs[i].Issuer = issuer.DeepCopy()
+		changed = true
+	}
+
+	return changed
+}
+
+// IsKBClusterTLSEnabled returns true if all components of the cluster serve tls with the user provided certificates
+func IsKBClusterTLSEnabled(cluster *kbappsv1.Cluster) bool {
+	if len(cluster.Spec.ComponentSpecs) == 0 {
+		return false
+	}
+
+	for _, comp := range cluster.Spec.ComponentSpecs {
+		if !comp.TLS || comp.Issuer == nil || comp.Issuer.Name != kbappsv1.IssuerUserProvided {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isIssuedBy(issuer, expected *kbappsv1.Issuer) bool {
+	return issuer != nil && issuer.Name == expected.Name &&
+		issuer.SecretRef != nil && *issuer.SecretRef == *expected.SecretRef
+}
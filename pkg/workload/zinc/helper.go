@@ -106,6 +106,30 @@ func DeleteIndex(admin, pwd, namespace, index string) error {
 
 }
 
+// GetIndexStats returns the document count and the storage size of the index
+func GetIndexStats(user, pwd, index string) (*IndexStats, error) {
+	host := ZincServerService + "." + constants.PlatformNamespace
+	endpoint := fmt.Sprintf("http://%s/api/index/%s", host, index)
+
+	client := resty.New().SetTimeout(2 * time.Second)
+
+	info := &IndexInfo{}
+	resp, err := client.R().SetBasicAuth(user, pwd).
+		SetResult(info).
+		Get(endpoint)
+
+	if err != nil {
+		klog.Error("get index error, ", err, ",", index)
+		return nil, err
+	}
+
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("get index %s response error, %d", index, resp.StatusCode())
+	}
+
+	return &info.Stats, nil
+}
+
 func GetIndexName(namespace, index string) string {
 	return fmt.Sprintf("%s_%s", namespace, index)
 }
@@ -116,3 +116,13 @@ var RoleUser = &Role{
 		"document.CreateUpdate",
 	},
 }
+
+type IndexStats struct {
+	DocNum      int64 `json:"doc_num"`
+	StorageSize int64 `json:"storage_size"`
+}
+
+type IndexInfo struct {
+	Name  string     `json:"name"`
+	Stats IndexStats `json:"stats"`
+}